@@ -12,3 +12,35 @@ func (ld *DirectoryList) Parse(r io.Reader) error {
 func (gd *DirectoryFetch) Parse(r io.Reader) error {
 	return json.NewDecoder(r).Decode(gd)
 }
+
+func (dh *DirectoryHistory) Parse(r io.Reader) error {
+	return json.NewDecoder(r).Decode(dh)
+}
+
+func (tf *DirectoryTreeFetch) Parse(r io.Reader) error {
+	return json.NewDecoder(r).Decode(tf)
+}
+
+func (sr *DirectorySearchResults) Parse(r io.Reader) error {
+	return json.NewDecoder(r).Decode(sr)
+}
+
+func (sf *DirectoryStatsFetch) Parse(r io.Reader) error {
+	return json.NewDecoder(r).Decode(sf)
+}
+
+func (br *BatchResponse) Parse(r io.Reader) error {
+	return json.NewDecoder(r).Decode(br)
+}
+
+func (br *BatchGetDirectoriesResponse) Parse(r io.Reader) error {
+	return json.NewDecoder(r).Decode(br)
+}
+
+func (rq *RootQuota) Parse(r io.Reader) error {
+	return json.NewDecoder(r).Decode(rq)
+}
+
+func (ds *DirectorySchema) Parse(r io.Reader) error {
+	return json.NewDecoder(r).Decode(ds)
+}
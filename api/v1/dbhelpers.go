@@ -28,10 +28,13 @@ func (dm DirectoryMetadata) Value() (driver.Value, error) {
 }
 
 func (dm *DirectoryMetadata) Scan(value interface{}) error {
-	b, ok := value.([]byte)
-	if !ok {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, &dm)
+	case string:
+		// Some databases, e.g. SQLite, return JSON columns as text.
+		return json.Unmarshal([]byte(v), &dm)
+	default:
 		return errors.New("type assertion to []byte failed")
 	}
-
-	return json.Unmarshal(b, &dm)
 }
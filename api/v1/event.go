@@ -15,6 +15,8 @@ const (
 	EventTypeUpdate     EventType = "update"
 	EventTypeDelete     EventType = "delete"
 	EventTypeDeleteHard EventType = "deletehard"
+	EventTypeMove       EventType = "move"
+	EventTypeRestore    EventType = "restore"
 )
 
 // DirectoryEvent is the event that is sent to the event stream.
@@ -23,4 +25,14 @@ type DirectoryEvent struct {
 	Type      EventType `json:"type"`
 	Time      time.Time `json:"time"`
 	Directory Directory `json:"directory"`
+
+	// OldParent and NewParent are only set for move events.
+	// A nil value means the directory was or became a root directory.
+	OldParent *DirectoryID `json:"oldParent,omitempty"`
+	NewParent *DirectoryID `json:"newParent,omitempty"`
+
+	// TraceContext holds the trace context the event was published with,
+	// as propagated alongside the event, e.g. in the NATS message headers.
+	// It isn't part of the event body.
+	TraceContext map[string]string `json:"-"`
 }
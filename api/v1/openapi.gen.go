@@ -18,29 +18,93 @@ import (
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/+xZTXPbNhP+Kxi875ESZefGWxo5rWacNOM2p9STgcgViYQEEGBp2dXwv3cAUiJBUpGU",
-	"2mnc6iZR+/XsPthdUBsay0JJAQINjTZUMc0KQNDuW84LjvYDFzSiX0rQDzSgghVAo+bHgJo4g4JZqQRW",
-	"rMyRRrOA4oOyQlwgpKBpVQVUsRT2GXO/nWBrzTH7mEAOCMk+m57MqO0Vyw3s7C+lzIEJWlkHtbTLwisN",
-	"DGHONcQo9cMNfCnBuLSwPP91RaMPG/p/DSsa0f+FbTrDxkTY13wDyGgVfF3pLax3erS6rQLafj3as28k",
-	"2FClpQKNHByw2AFLXjosK6kLhjSiCUOYIC9sPZrMGNRcpLQKaJPOU1S4K5D/OKD3k1ROmoe7GBdz6nii",
-	"QTgHUiGXguU0Ql1CcIqRUiWnYasCquFLybUl1AcbddBJUNfg7U5XLj9BjL3yvAaMs0dgh1+rpFv9o+wN",
-	"ILUmDiC45o9C73EAzVeOUJgTidE8ZVqz/eis/SG+A6ftHUu5YJZsvVR0MUV9SHegjVUZoOjHthUciYte",
-	"aS310HYsE/CoywW+uKTDZhjQAoxpWuvXw3A2W/mxaK65+DwMJnOp21Orptv+cnP1euDRKQ78WEVZWAYo",
-	"fGi6cBVQr1sNYigAWcLqMgzNjXDmzVah2k6EMQCSKT6xiUlBTOAeNZsgS53HJReJFYtaSFUfoDM8lsgO",
-	"o7yT5IPajsTdRLoYK7CV+mj4nz3R8cnYja6Zqq3+6MHwQ/qYc/HZHOoyLbxrJ9733FgZbzR95UGtBdzj",
-	"oQgcU6tqJPXvXad+gnn95IQcgXPrKMfFStZdQSCLHZbm2C3ESjOUqWYqA01elphJbey80jmNaIaoojBM",
-	"OWblchrLIuSeQj3TTay5qrlKf9cABROEG8JIwQRLQZOV1GQ3PghqADOlAc15DMJAJ5yXisUZkMvpzAvB",
-	"RGG4Xq+nzP08lToNG10TXi9eXb397WpyOZ1NMyxy1+k55tAGQ4O219LZdDa9sEJSgWCK04i+mF44h4ph",
-	"5moTdqZBuOFJVZ8cu7vUn7qI5+65hdtCZCIhLM+JXBGOhsQZzxMNwoK2HHDkXSQ75bZvBd4K/aHvazG3",
-	"JjuplKQJK6h3WAuhXWHdFtKeq3oLahfZo2dndWvNGCVtxq3m5Wy25VOzazGlch47YOEnU7et1tNR58Xt",
-	"DY6vPmi2WkGMkJBOVcg2nJqBTU97pIjqmToSSSngXtWxQCuTAg5ZcQNYauGzYskMJEQKwojhIs2BLOZD",
-	"SvwM+Hf4oJ3jJ+bDnn2ojTX0bk9HyLtZc4RcfW38PoSsV/ERHrQJ/4FoqFhzcfCF63FmCGZAUn4HokMX",
-	"25ftc6XlHU8gIYv5gI69cXgcI61Rj5X19ed7dCk3c3+SycOj1WHPQjBSmNcc8sS0eHcJTjrp85FWAyJf",
-	"/OeJLM1IQ61fo9iGKmBNTLmctMHXvLaJbulXvwbwMu8Tu/de5nhi9y3betdXfdvYvcC4eJ6U3/POaqSE",
-	"b2E9lokzzw/xvAqGa2a4XRStpwNrRc4NWkY6FW81sk2HDXr92KphN65XW5ffumrstlvr+Lx3POEi/EPu",
-	"v6M8rlukOYHGvaZ6Oo/fNS6/mcZNzGcWn1nsszjclAJ5Xj0Nm4kzfjrR31u1Z8L2g0EZlKqJjIt0FxzD",
-	"8djKBvv5MP5rD6OW8qT5YeW75236hxg9PTfO7uDYPG8K7P4NO4ELg//Dngc3jrseemzYd/WzVJj3Lub/",
-	"7EXKD/t8mzqhZVTVXwEAAP//DEVIZ4wiAAA=",
+	"H4sIAAAAAAAC/+w9a2/cOJJ/has7YHcBudvJDA5YA/chG2d2fEgyuTgLHDAKAlqq7uZGIhWS7XZf0P99",
+	"USQlURKlftjttCf5lFjNR7FYbxaLX6NUFKXgwLWKLr5GC6AZSPNf0HSO/2agUslKzQSPLqL3cMsUE5yI",
+	"GdELIBL0UnLISMYkpFrI9SSKI5UuoKDYW69LiC4ipSXj82iziSMtNM0/pWLJdX/4l/gZx66GY6AITaVQ",
+	"itA8JyWdg4rJagGcSPiyBKUhC87IuIY5yGiDc5ZU0gK0WxlVn8QstDRcijLLak1vPq3JCiQQqoleUE1K",
+	"wbgmjBPNCojJTMiCag1Zwqki7395SX766ae/TcgHVoAyzRZAZku9xCEkom22VJDFODj+rU07kWcgcXye",
+	"cOywYAoxSj5DqcnN2kJGNb2hCiYJj+KIIeBfliDXURxxWuDK7fJ8lFjooosooxrOcK4oDuxMupRKyD5i",
+	"fivplyUQ+3Oz425VHO40yRn/jNtGSSnhlomlMls1GQDRzTROKGz2qaA6XQQA4vmalCBxXQYGUYKk+CNh",
+	"s9b+rQlTds8Q55Z0E17BmK97q3n1gc6J5QIPw/ZDA//V7OyNAW18BTkrmCHyEA7sj/4AGczoMtfRxXnc",
+	"p2Ik4jkMDWZ+22MsBbnBzwBu5QArrBZCASlAUyRDYrYHLGdi01QUBT1TgOxmeAFZlEkogGt1QZLoM6z/",
+	"+5bmS0ii2P75p87fuA9/ofHNX5svXGjvY8LN1yQif8EpTRdFFOi/EsozkkR/6v3K/6xNg2GWqdGxg+jq",
+	"o+xFrkQLZeluUsyRnBk14TMGeWbWgB//7+wDfj6zAtERJDF/MT7HJgUp6JrcAIG7ErhityMiwQIepI8Z",
+	"zRXUNHIjRA6Um9WumF58yiAHDdkQ3bXa7DX+pmptRPLfkZL+AfqyQdh7K97xV5rnv82ii9+/Rv8pYRZd",
+	"RP8xbdTW1A0zrfquXc83oGm0ib9GpRQlSM3ATMUy+4+GQvW3OY7uzubizH2sh7y6jDb1IqiUdG1Q5Og7",
+	"iy5+N+N+rNuIm39BqqPNx00cXpwqBVfw4KvzKK61yp2G7q8xjgqmFCLmGCjzgW1mGkHib5Wcjy66C2fZ",
+	"npBVUszr5uYb6Pam6rCpyL8nI+JIlMHPJZXAdf+nUQhtpyuewV3IsImjSqO1dDzj+r9+joJS38e9KAOI",
+	"dmg+FuvVanp32uxs+zaK8mYYIaOjcZ8Etcz1nqt7bzptXVo19vi6jMztgkVnM0grKX5vJo5rvl3vJVmC",
+	"rLEbVb6UQDV0N+EBtm+s01tY+fB3kbrk7MsSrtlNzvj8LS1A9S2DV3wmZAqK2MYExYYitBB8TnJ2C0TZ",
+	"7rWLoCWAtaM5rBIuhdCeZ0VeLlietawKxhcgmSZMk5kUxhpmkljJYe2BgGYP0M+lv6W7IXULflKzZ9kL",
+	"vasHEkfOkNiny95iv5HEwuwSGnRaLiHeZ5C9BG88QCrdjYmjZZnth7KeCRLFHt79AT2Yg/B8HKeKX8A5",
+	"YkcxVvaTIyXVAZ/wJeWCs5Sida0XVXiiniEmBc2g+mz5UMwI08pyWYz/dXyjjBWOf4sVbzHRAN6bdWxB",
+	"46/WoT+C3rF7e4DNVwV0dtA/1RwBCT0+2Ts6Z9wp8BY+XrMjWBlDJvA9FN6A47djtEpwP9CAsauE18Er",
+	"gi5U4wcS42CROmDVIr8hW85f8cNtzv8uhbWO2+gt6J1RQxJ4HyO2M0ldA8QN0HTRjg4WjLNiWQRjEzGO",
+	"fwlliMVfwy3kqovvG8jFyiAQ+XjH8VUKPKMu6BlawiFj9zVrHAVptofTW5CVOhmXNFXDj+NTNdqpPQ8N",
+	"x3xe4GeyWrB0YcUkrjhdUD6HmEBR6jXG1Zb8M0eBGFLDdKZB7iXFb2AmJOzVpSaiq32VvvD9xrF+r26B",
+	"6w/4/46WH4+C15ARgwgPf4itHSwEo94PU/o+Vlpavll07DbezTNKPNd1COd4qn6PnWsiSjTLmDXY3nkj",
+	"t0y3ZjlKLGUKw8xtY+l2cBc/JILHNQ+YwBrlhPIUlBYy4d2dDtkFIys51GK4BiqHHbtDDSgJQdH3zv7Q",
+	"p+plSbQw35SBxz/viRNeeR4kzYUChaisgpHHChmto2Yd4/TsIVB9c4c/vK+Hu/6H6vZrTS3UHZ9tULG/",
+	"Rm8162v3Nk8EZZvz6kaV7rWYaeIaDmj3rbPsrdM958A442j1Kw+Q8ER7e5w5VfqNyNiMQdYH7TVV2hz/",
+	"EeoxnMOsWt6YiMCKKuJ8OSKkD99uXjIubxT/3vbuifddzLV8h9Fjcu4O7yT8WRGOImS75Ws93opq21TQ",
+	"X3aQFr0ldPZqXK4gB9VucZuNVMVdu4kC03oT728Fxm6mUUA/SIBxTt9PcpnxHigoOCLcawC3Lm5gE7Rb",
+	"916LOmALzDwhIF9JKWQfrlRk0I0b/fQ8zFuglDv0HYfGjNm0D0FzxW9pzrI33qlHGy6owO1JD3MyubuO",
+	"q6b4BbtZJATI5TALbXIPm8susJ65XlcIW68Z/9xH0cKsekD4uxPRX9+/+qU3tenYmwc7igJxWuq1Oynd",
+	"xFEAfz1IDOh95FVd8eDb89wQk2b1iEh6I5Y2IaI+x6eKUDzbzyHo3O1MhhaqcTp8I26PEchvo+eg4zYp",
+	"hA7FZcMB81b0ux8kefjDRewoaMnOkNnnwM/gTkt6punczHjDeIbNLpod2XT3xwwc2hTPTmxtRRerc2id",
+	"7T8LCS1s9Umx/+80Pd+qy10OS9M/aO62QfqEiUdbJVKzvNemeXdmN0rYFet27u01JkBtgwC7hgNEJsr2",
+	"6i4FyCDrDz4slevkov4WfKkid23p8AHFgJuJmO4xgcl8QioDKMj9HbY4WOyacSrYKvBDxPheCF3HHh9U",
+	"LIA5B2W3u9sFFg4fpft1uy/yOkhrFhCm1WuwmHucA9Luch0ENoJ0LNF+UECog1U3RhiH/zR+1tFV1DHS",
+	"T0KaCj8yPhPW9OSapmYtVSIjn0mqxVzScgGSvFjqhZAKjwhlHl1EC63Li+l0zvRieTNJRTFlrQ6V4+3J",
+	"GAlQUG4MDVJQTucgyQx91saukwBqYkRACi4Lw4HzoqTpAsjzyXkLBHUxna5Wqwk1P0+EnE9dXzV9ffXy",
+	"1dvrV2fPJ+eThS5yG03VOTTARJ5dH51PzifPXEyY05JFF9FPk2dmQjw4NHszvanyTkuhAlnKL8oyN0c9",
+	"nAiZgTTiVJmToCYBhVAtCjyQzNcT8oppxK9LlEy430wCoWbALEbnngtenVMW+OMk4dfLshTSOP/tfvas",
+	"N3axARPAsB7uhNiUCa9Dwm1rQkk7rYAseQ4K98uaTbh3c3YLPCZgwb5ZE2YCDzdrm5jMMCcJgaScAJU5",
+	"A0nSzoRVSoPBpT3l8hKaJgm3jOYvyRyULWhZwmgirwGuTueNE27SwRFt1k+wocd6XDwtsPkxkRUDoPTf",
+	"RbauOMJZimYPUtNj+i9lLaFG2OyQxWMGtkZXN3+6XqEWZq/XkS+QUGQZCWWzkgwJPj8/f2j47OghAO0W",
+	"ybpFHP18/rcHm965gP1paSsHxj9EySXQDEnB5tMqWtgzewPas+ePAVqL8LjQ3Szy5voBsgflFQ8KP1ft",
+	"5+f7wSo47KBh2ibjNi3ddfw3HwPrxZWtJNNAVmKZZ0TTz9AkJJXUxvRdxgQx5ogy0spIs9qRzASYbGuT",
+	"GG6aWigSbjWFcwaOvXtLjjnRKe4NVG02cTT1QpDTryzbWMmO8jIQhzDfVSsqi/IVRbhDQxWimvSkje18",
+	"2T6rqG+g/N6d6+rSP9Reo5RwYLl0btRMTdI1y3riYzhvfdTWDOO3gXVa38HYfDyigGrng4TY0SUwtoLI",
+	"LXn1GEJhD5Fg1F19S+Rb034czUEPX3jySRyvFmVEcEIJJmPnQK4u+/TtJbMfQtw2F+abE3fr8sIO7U2A",
+	"Yod21rXdoaG9qfU4rGWj5KNUXTNU7N8DxBtRQ7O4ZlNzV3Cz+fZ0XoYvjFlTU3nWY7PqmdVipJTilmWQ",
+	"kavLHr13fMLdSL5tvmrhLIRTk+kPbw8PeNCBTTQhbtXgpt4M/yRom6H87Mkyy/duZd9Hof78/OEA7JnI",
+	"YQN5q5l7Aoo+HLCwwQBlrxVgVsFZg3orD3F9jdhygQCfC9sCsXMhY3eB2B0Zeb+OS7QBY8e2EI4k/gYu",
+	"qwS28C2sQpj4IfO+kcz74a2fsrc+9XNWtjg0VTg27V2YQguD9ozAkJODzujLJrXoMCenSZabCXlkefYE",
+	"PB5X+GGHlvWV/E3cRXa/REGTzlWnqlKka74muc1CC2WcJfwZKYByFBFI/awoIGOoiurIDnmR563hbfEO",
+	"7yoHWXKTmiLJ+fD9+8yllzW7On6PYFfPcXtDe3Pl20ZvgkGblgppVTvYpkv8AjKbzUlKqkVzz2tUUPnG",
+	"LuImFRI90CEZFaP5BkqTGZMKjzle1L0J8/rDLci1TSrtnaG464HVmVEWJ7wQt/ihSr8VEndJC4kfaY53",
+	"Ves7SuYeASnoZ1eEwt1ySHhVt6KZiyrCtMlctVc9bPS0ez0i4e/r9SNvlTbbwkbVUfzEhFaVZ5iq6uaE",
+	"TniQ+noX7Q4W2375nVOQ3E869lTtxqhxWWHbD+ueHmcjrwwfDGNyWzueuuQZSOd3dX2fScLfSVEIU9GF",
+	"tsmwe0gb27zvunH714RX89g5YlIslXbFYXKWMm00pndgpUCbkcw4qDDlEkJc1UrXOzDuZXD2h496BRMb",
+	"A6R2CUq7hLIeth71WPiH5/d9Rrs6DunDuJenKa29m247OowdGb2/x+ju0B1ueTiYf/iL+/mLPzyik/aI",
+	"HFVPvy65ZvnmOBxJzOD7M+s/sdsT4ditQCktSgcZasAKOKrDsC3d2n8IlB8C5UkJlKWcj3hi70AWFOc0",
+	"jk/h/DIlZvqse814OK8r4Zet4qcsXZggBRpv/lDGObM/L+htEz40YciEuy4xSSmG22+AGOAxivFhwZSX",
+	"MuuYUBGaFYwTmqagVMgte4cDHOCXDSBACwvSYxy4nWwO2enRuAvDDVP5e9tgjLTb8buaMi21rkBCwqte",
+	"phXTE3LZK/nbZIYrzUxI3HapKdqBGozMOSgflFwr1HzPBHvCDvXp8VIzz1DG73unJnAx/3P921tiLzLZ",
+	"cowdizImytZDaRzhhFfFYpS7z6EInVPGJ+RDc5OZi5X5dY3IZMoZavfSBZ1kYwv1gaE6qyr9q9di9kfg",
+	"MYeUAAG5ZZ6MIoi3nxj5xNnKSwpRKhKZSSmwFdkNRSp9UZU3rNIC/IhOVcWnomc0apBcsSQH0nLCHdKq",
+	"Mon2Rr0xjUTBNK6HzexFJscJIbr1k4jvSbT1kY0D7Pie2A+q7SaeLYNUW+Y03VmmOgvbCU8trFg1hQnq",
+	"Ujj1ZbXmVN6ooKpcJ/nQ4QiT8wJ3TGlff+EE9mWAdAHp53sJ4OuHImQF+vFF78OfhPTuAAeIqBJfZtHf",
+	"5thjL+76+fz8+JzVqniCtGkE94naU6aA2WAcz9Y3g35+TlBHXV3imSSS94SYMw/G5wn3ebWVd8M4mS3z",
+	"PK5v396sbV4DE9xPWUi4Xw5bjZaxCzM2trpsvVKwN19bB6qbQ1QB8NgBQ6z2bHR+SaW2Cj+2XIjwoOIc",
+	"erPnyyhIBeOvgc/1wq8K0txL74Lxq1hVxdAluHdkMnwhBu5oqu3bL6WEGbvD/wtJEnt5nXGVRFZPJNx2",
+	"U24g93uVmGKgjwmbc4FAkHT0zaSi95pPzXH1xFEcAceUrd8tlFHsQIzips3H4OL/cFHRxzF/WqUbQ3La",
+	"Eq0rj/ht5LUhJDSybbUlZ027lFpVFc09URle1akbdTmwFVOapapblnBAll+0HkFKONPKBhGq6FNceRrB",
+	"sou+rIyd/ihRmLtOGUAJStvOTdu4fjspHympmHC/pmLhSv1tdU8Mog4Q/u6IX4uyg7snd7T7qIzf1FYc",
+	"zaPwKPO0A7lVLcKdr+F6YVtL8p71oEw6mckTwXENX6A55Ps22wj6gyXBB6Dn0yTm7y9hvJOISO+wdfgl",
+	"Ou+tmZjkYmVMaC3Is/NzU4M1p3KOr829Nv+adsp/tXIyaETdfeIiA9UC3CsBd37uFdB/dp/E90cRQ01x",
+	"0VEpZLTJN7E9zMwLo8dku8CuXlCOR5piZb2jZmtOTD5e3LjH8cYOuQYeo6wOtawFwjJD2XXdAhfZ6Bzi",
+	"dv1I7G+8yOZB2yoJj2WqsSlYZiJRGJ/KRHNpCgWGJx0lmLQVyEj1CGZuEuHd03aYsa9JIZRGXjMQu1Lz",
+	"zaxUE8FTGKxh1H5HsC/Dj6j2j1Q5KfzsY4CcrrJeaXoXJ5xBU93pEWsqDbzpuGt+xmOKCrd7RlpwYUhP",
+	"SKKFsErPorU4CelgyrBNv+I/m51u0LTMk8ohwe79R58qx0MKoRM+E42E7D4LFXoLKq7ZOjOl0FRO1QKU",
+	"q6I5pWkBUyiATku6Nm/eTsi1bWJGKUGmwDVRbM6t+GI84U0YxP1+BjwVGWQT8g4xUYmIpbISy0TUqzUZ",
+	"Gy3hLTEUdweyJqMTiwrmRf02XO+cXuS38M5acaO24bvQG1vNWYz/kIYqKScKbkHSvJpdhS1G99euNuNT",
+	"d3P+SHn31QZ3Y7bOYbDcSFWdR1BTPepS+x7dSQgfKcRe+eKtezgDjhcmeLw34x5bW5/Kfd0TS63c74ED",
+	"m4/Te5Ll+8q53K2SSPsW2kCVECT9y84Np29bc6MN9ndSeOM0RKuNh9XVtLfadqalH2uyQef2DtqAsxFq",
+	"iohbkJJlGb6yWJWTsqGOOj5sGzJObD1t43CZXxTIW5DEocrmca3vkRnwD9BNNfOdw209rnqqiSzN2gNk",
+	"Ynf2SWWw9CiM+AQWosxJwl/bTnX2OBo8CjSZYcb5DU0/N2fSLeK7X0LKkyC7o6SetArgBzbf7YcW1T7C",
+	"owYOnhJPbDabfw8AOg00aiGKAAA=",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// PathSeparator separates the names of a directory path, which starts with it.
+const PathSeparator = "/"
+
+var ErrParsingPath = errors.New("error parsing path")
+
+// pathEscaper escapes the separator within names, along with the escape character.
+var pathEscaper = strings.NewReplacer("%", "%25", PathSeparator, "%2F")
+
+// FormatPath returns the path made of the provided names, from the name
+// of a root down to the name of the directory, e.g. /acme/emea/payments.
+// Slashes and percent signs within names are percent-encoded.
+func FormatPath(names []string) string {
+	var b strings.Builder
+
+	for _, name := range names {
+		b.WriteString(PathSeparator)
+		b.WriteString(pathEscaper.Replace(name))
+	}
+
+	return b.String()
+}
+
+// ParsePath returns the names the path is made of, see FormatPath.
+func ParsePath(path string) ([]string, error) {
+	if !strings.HasPrefix(path, PathSeparator) {
+		return nil, ErrParsingPath
+	}
+
+	names := strings.Split(strings.TrimPrefix(path, PathSeparator), PathSeparator)
+
+	for i, escaped := range names {
+		name, err := url.PathUnescape(escaped)
+		if err != nil || name == "" {
+			return nil, ErrParsingPath
+		}
+
+		names[i] = name
+	}
+
+	return names, nil
+}
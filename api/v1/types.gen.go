@@ -7,28 +7,90 @@ import (
 	"time"
 )
 
+// BatchGetDirectoriesRequest defines model for BatchGetDirectoriesRequest.
+type BatchGetDirectoriesRequest struct {
+	Ids     []DirectoryID `json:"ids"`
+	Version string        `json:"version"`
+}
+
+// BatchGetDirectoriesResponse defines model for BatchGetDirectoriesResponse.
+type BatchGetDirectoriesResponse struct {
+	Directories []Directory   `json:"directories"`
+	Missing     []DirectoryID `json:"missing"`
+	Version     string        `json:"version"`
+}
+
+// BatchOperation defines model for BatchOperation.
+type BatchOperation struct {
+	Id          *DirectoryID       `json:"id,omitempty"`
+	Metadata    *DirectoryMetadata `json:"metadata,omitempty"`
+	Name        *string            `json:"name,omitempty"`
+	Op          string             `json:"op"`
+	Parent      *DirectoryID       `json:"parent,omitempty"`
+	ParentIndex *int               `json:"parentIndex,omitempty"`
+	Revision    *int64             `json:"revision,omitempty"`
+}
+
+// BatchRequest defines model for BatchRequest.
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+	Version    string           `json:"version"`
+}
+
+// BatchResponse defines model for BatchResponse.
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+	Version string        `json:"version"`
+}
+
+// BatchResult defines model for BatchResult.
+type BatchResult struct {
+	Affected  *[]DirectoryID `json:"affected,omitempty"`
+	Directory *Directory     `json:"directory,omitempty"`
+	Op        string         `json:"op"`
+}
+
 // CreateDirectoryRequest defines model for CreateDirectoryRequest.
 type CreateDirectoryRequest struct {
 	Metadata *DirectoryMetadata `json:"metadata,omitempty"`
 	Name     string             `binding:"required" json:"name"`
-	Version  string             `json:"version"`
+
+	// UniqueSiblingNames Enforces unique names among live siblings in the tree of a new
+	// root directory. Child directories inherit it from their parent.
+	UniqueSiblingNames *bool  `json:"uniqueSiblingNames,omitempty"`
+	Version            string `json:"version"`
 }
 
 // Directory defines model for Directory.
 type Directory struct {
-	CreatedAt time.Time          `json:"createdAt"`
-	DeletedAt *time.Time         `json:"deletedAt,omitempty"`
-	Id        DirectoryID        `json:"id"`
-	Metadata  *DirectoryMetadata `json:"metadata,omitempty"`
-	Name      string             `binding:"required" json:"name"`
-	Parent    *DirectoryID       `json:"parent,omitempty"`
-	UpdatedAt time.Time          `json:"updatedAt"`
+	CreatedAt          time.Time          `json:"createdAt"`
+	DeletedAt          *time.Time         `json:"deletedAt,omitempty"`
+	Id                 DirectoryID        `json:"id"`
+	Metadata           *DirectoryMetadata `json:"metadata,omitempty"`
+	Name               string             `binding:"required" json:"name"`
+	Parent             *DirectoryID       `json:"parent,omitempty"`
+	Revision           int64              `json:"revision"`
+	UniqueSiblingNames bool               `json:"uniqueSiblingNames"`
+	UpdatedAt          time.Time          `json:"updatedAt"`
 }
 
 // DirectoryFetch defines model for DirectoryFetch.
 type DirectoryFetch struct {
 	Directory Directory `json:"directory"`
-	Version   string    `json:"version"`
+
+	// Path Canonical path of the directory, made of the names of its
+	// root, its parents and its own.
+	Path    *string `json:"path,omitempty"`
+	Version string  `json:"version"`
+}
+
+// DirectoryHistory defines model for DirectoryHistory.
+type DirectoryHistory struct {
+	Links     PaginationLinks     `json:"_links"`
+	Page      int                 `json:"page"`
+	PageSize  int                 `json:"page_size"`
+	Revisions []DirectoryRevision `json:"revisions"`
+	Version   string              `json:"version"`
 }
 
 // DirectoryList defines model for DirectoryList.
@@ -37,7 +99,23 @@ type DirectoryList struct {
 	Directories []DirectoryID   `json:"directories"`
 	Page        int             `json:"page"`
 	PageSize    int             `json:"page_size"`
-	Version     string          `json:"version"`
+
+	// Total Count of directories across all pages, only returned when
+	// requested with the total query parameter.
+	Total   *int   `json:"total,omitempty"`
+	Version string `json:"version"`
+}
+
+// DirectoryQuota defines model for DirectoryQuota.
+type DirectoryQuota struct {
+	// MaxChildren Direct children of each directory.
+	MaxChildren *int `json:"maxChildren,omitempty"`
+
+	// MaxDepth Levels of directories below the root.
+	MaxDepth *int `json:"maxDepth,omitempty"`
+
+	// MaxDescendants Directories below the root.
+	MaxDescendants *int `json:"maxDescendants,omitempty"`
 }
 
 // DirectoryRequestMeta defines model for DirectoryRequestMeta.
@@ -45,17 +123,123 @@ type DirectoryRequestMeta struct {
 	Version string `json:"version"`
 }
 
+// DirectoryRevision defines model for DirectoryRevision.
+type DirectoryRevision struct {
+	// Actor Actor which made the change, empty if unknown.
+	Actor       string      `json:"actor"`
+	After       *Directory  `json:"after,omitempty"`
+	Before      *Directory  `json:"before,omitempty"`
+	DirectoryId DirectoryID `json:"directoryId"`
+	Operation   EventType   `json:"operation"`
+
+	// Revision Revision of the directory after the change.
+	Revision int64     `json:"revision"`
+	Time     time.Time `json:"time"`
+}
+
+// DirectorySchema defines model for DirectorySchema.
+type DirectorySchema struct {
+	Directory DirectoryID             `json:"directory"`
+	Schema    *map[string]interface{} `json:"schema,omitempty"`
+
+	// Source Directory the schema is set on, which may be an ancestor
+	// of the directory.
+	Source  *DirectoryID `json:"source,omitempty"`
+	Version string       `json:"version"`
+}
+
+// DirectorySearchResult defines model for DirectorySearchResult.
+type DirectorySearchResult struct {
+	Directory Directory `json:"directory"`
+
+	// Parents Parents of the directory up to the searched directory,
+	// from the closest one.
+	Parents []DirectoryID `json:"parents"`
+}
+
+// DirectorySearchResults defines model for DirectorySearchResults.
+type DirectorySearchResults struct {
+	Links    PaginationLinks         `json:"_links"`
+	Page     int                     `json:"page"`
+	PageSize int                     `json:"page_size"`
+	Results  []DirectorySearchResult `json:"results"`
+	Version  string                  `json:"version"`
+}
+
+// DirectoryStats defines model for DirectoryStats.
+type DirectoryStats struct {
+	// Children Live direct children of the directory.
+	Children int `json:"children"`
+
+	// DeletedDescendants Soft deleted directories below the directory.
+	DeletedDescendants int `json:"deletedDescendants"`
+
+	// Descendants Directories below the directory, live and soft deleted.
+	Descendants int         `json:"descendants"`
+	Id          DirectoryID `json:"id"`
+
+	// LastModified Last time a directory of the subtree was updated or deleted.
+	LastModified time.Time `json:"lastModified"`
+
+	// LiveDescendants Live directories below the directory.
+	LiveDescendants int `json:"liveDescendants"`
+
+	// MaxDepth Levels of live directories below the directory, 0 if there's none.
+	MaxDepth int `json:"maxDepth"`
+}
+
+// DirectoryStatsFetch defines model for DirectoryStatsFetch.
+type DirectoryStatsFetch struct {
+	Stats   DirectoryStats `json:"stats"`
+	Version string         `json:"version"`
+}
+
+// DirectoryTree defines model for DirectoryTree.
+type DirectoryTree struct {
+	Children  []DirectoryTree `json:"children"`
+	Directory Directory       `json:"directory"`
+}
+
+// DirectoryTreeFetch defines model for DirectoryTreeFetch.
+type DirectoryTreeFetch struct {
+	Tree    DirectoryTree `json:"tree"`
+	Version string        `json:"version"`
+}
+
 // Error defines model for Error.
 type Error struct {
 	Code    int32  `json:"code"`
 	Message string `json:"message"`
 }
 
+// InvalidMetadata defines model for InvalidMetadata.
+type InvalidMetadata struct {
+	Error  string               `json:"error"`
+	Fields []MetadataFieldError `json:"fields"`
+
+	// Source Directory the schema is set on.
+	Source DirectoryID `json:"source"`
+}
+
 // Link defines model for Link.
 type Link struct {
 	HREF string `json:"href"`
 }
 
+// MetadataFieldError defines model for MetadataFieldError.
+type MetadataFieldError struct {
+	// Field Metadata key, empty if the error is about the metadata as a whole.
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// MoveDirectoryRequest defines model for MoveDirectoryRequest.
+type MoveDirectoryRequest struct {
+	Parent  *DirectoryID `json:"parent,omitempty"`
+	Root    *bool        `json:"root,omitempty"`
+	Version string       `json:"version"`
+}
+
 // NewDirectory defines model for NewDirectory.
 type NewDirectory struct {
 	Metadata *DirectoryMetadata `json:"metadata,omitempty"`
@@ -74,6 +258,43 @@ type PaginationLinks struct {
 	Next *Link `json:"next"`
 }
 
+// QuotaExceeded defines model for QuotaExceeded.
+type QuotaExceeded struct {
+	Error string `json:"error"`
+	Limit int    `json:"limit"`
+
+	// Quota The exceeded limit, e.g. maxDepth.
+	Quota string      `json:"quota"`
+	Root  DirectoryID `json:"root"`
+}
+
+// RootQuota defines model for RootQuota.
+type RootQuota struct {
+	Effective DirectoryQuota `json:"effective"`
+	Quota     DirectoryQuota `json:"quota"`
+	Root      DirectoryID    `json:"root"`
+	Version   string         `json:"version"`
+}
+
+// SetQuotaRequest defines model for SetQuotaRequest.
+type SetQuotaRequest struct {
+	// MaxChildren Direct children of each directory.
+	MaxChildren *int `json:"maxChildren,omitempty"`
+
+	// MaxDepth Levels of directories below the root.
+	MaxDepth *int `json:"maxDepth,omitempty"`
+
+	// MaxDescendants Directories below the root.
+	MaxDescendants *int   `json:"maxDescendants,omitempty"`
+	Version        string `json:"version"`
+}
+
+// SetSchemaRequest defines model for SetSchemaRequest.
+type SetSchemaRequest struct {
+	Schema  map[string]interface{} `json:"schema"`
+	Version string                 `json:"version"`
+}
+
 // UpdateDirectoryRequest defines model for UpdateDirectoryRequest.
 type UpdateDirectoryRequest struct {
 	Metadata *DirectoryMetadata `json:"metadata,omitempty"`
@@ -81,20 +302,57 @@ type UpdateDirectoryRequest struct {
 	Version  string             `json:"version"`
 }
 
+// AsOf defines model for as_of.
+type AsOf = time.Time
+
+// Cursor defines model for cursor.
+type Cursor = string
+
+// IfMatch defines model for if_match.
+type IfMatch = string
+
 // Limit defines model for limit.
 type Limit = int
 
 // Page defines model for page.
 type Page = int
 
+// Selector defines model for selector.
+type Selector = string
+
 // WithDeleted defines model for with_deleted.
 type WithDeleted = bool
 
+// BatchGetDirectoriesParams defines parameters for BatchGetDirectories.
+type BatchGetDirectoriesParams struct {
+	WithDeleted *WithDeleted `form:"with_deleted,omitempty" json:"with_deleted,omitempty"`
+	AsOf        *AsOf        `form:"as_of,omitempty" json:"as_of,omitempty"`
+}
+
+// DeleteDirectoryParams defines parameters for DeleteDirectory.
+type DeleteDirectoryParams struct {
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
 // GetDirectoryParams defines parameters for GetDirectory.
 type GetDirectoryParams struct {
 	WithDeleted *WithDeleted `form:"with_deleted,omitempty" json:"with_deleted,omitempty"`
 	Page        *Page        `form:"page,omitempty" json:"page,omitempty"`
 	Limit       *Limit       `form:"limit,omitempty" json:"limit,omitempty"`
+	AsOf        *AsOf        `form:"as_of,omitempty" json:"as_of,omitempty"`
+}
+
+// GetDirectoryTreeParams defines parameters for GetDirectoryTree.
+type GetDirectoryTreeParams struct {
+	WithDeleted *WithDeleted `form:"with_deleted,omitempty" json:"with_deleted,omitempty"`
+
+	// Depth Only returns the descendants up to that many levels below the directory,
+	// 1 meaning its immediate children. All descendants are returned when unset or 0.
+	Depth *int `form:"depth,omitempty" json:"depth,omitempty"`
+
+	// MaxNodes Maximum count of directories in the tree, lowered to 1000 if larger. Larger trees are refused.
+	MaxNodes *int  `form:"max_nodes,omitempty" json:"max_nodes,omitempty"`
+	AsOf     *AsOf `form:"as_of,omitempty" json:"as_of,omitempty"`
 }
 
 // ListChildrenParams defines parameters for ListChildren.
@@ -102,6 +360,20 @@ type ListChildrenParams struct {
 	WithDeleted *WithDeleted `form:"with_deleted,omitempty" json:"with_deleted,omitempty"`
 	Page        *Page        `form:"page,omitempty" json:"page,omitempty"`
 	Limit       *Limit       `form:"limit,omitempty" json:"limit,omitempty"`
+	Cursor      *Cursor      `form:"cursor,omitempty" json:"cursor,omitempty"`
+	Selector    *Selector    `form:"selector,omitempty" json:"selector,omitempty"`
+
+	// Depth Only returns the descendants up to that many levels below the directory,
+	// 1 meaning its immediate children. All descendants are returned when unset or 0.
+	Depth *int  `form:"depth,omitempty" json:"depth,omitempty"`
+	AsOf  *AsOf `form:"as_of,omitempty" json:"as_of,omitempty"`
+}
+
+// ListDirectoryHistoryParams defines parameters for ListDirectoryHistory.
+type ListDirectoryHistoryParams struct {
+	Page  *Page  `form:"page,omitempty" json:"page,omitempty"`
+	Limit *Limit `form:"limit,omitempty" json:"limit,omitempty"`
+	AsOf  *AsOf  `form:"as_of,omitempty" json:"as_of,omitempty"`
 }
 
 // ListParentsParams defines parameters for ListParents.
@@ -109,6 +381,8 @@ type ListParentsParams struct {
 	WithDeleted *WithDeleted `form:"with_deleted,omitempty" json:"with_deleted,omitempty"`
 	Page        *Page        `form:"page,omitempty" json:"page,omitempty"`
 	Limit       *Limit       `form:"limit,omitempty" json:"limit,omitempty"`
+	Cursor      *Cursor      `form:"cursor,omitempty" json:"cursor,omitempty"`
+	AsOf        *AsOf        `form:"as_of,omitempty" json:"as_of,omitempty"`
 }
 
 // ListParentsUntilParams defines parameters for ListParentsUntil.
@@ -116,6 +390,8 @@ type ListParentsUntilParams struct {
 	WithDeleted *WithDeleted `form:"with_deleted,omitempty" json:"with_deleted,omitempty"`
 	Page        *Page        `form:"page,omitempty" json:"page,omitempty"`
 	Limit       *Limit       `form:"limit,omitempty" json:"limit,omitempty"`
+	Cursor      *Cursor      `form:"cursor,omitempty" json:"cursor,omitempty"`
+	AsOf        *AsOf        `form:"as_of,omitempty" json:"as_of,omitempty"`
 }
 
 // ListRootsParams defines parameters for ListRoots.
@@ -123,13 +399,41 @@ type ListRootsParams struct {
 	WithDeleted *WithDeleted `form:"with_deleted,omitempty" json:"with_deleted,omitempty"`
 	Page        *Page        `form:"page,omitempty" json:"page,omitempty"`
 	Limit       *Limit       `form:"limit,omitempty" json:"limit,omitempty"`
+	Cursor      *Cursor      `form:"cursor,omitempty" json:"cursor,omitempty"`
+	Selector    *Selector    `form:"selector,omitempty" json:"selector,omitempty"`
+	AsOf        *AsOf        `form:"as_of,omitempty" json:"as_of,omitempty"`
+}
+
+// MoveDirectoryParams defines parameters for MoveDirectory.
+type MoveDirectoryParams struct {
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
+// UpdateDirectoryParams defines parameters for UpdateDirectory.
+type UpdateDirectoryParams struct {
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
 }
 
+// BatchJSONRequestBody defines body for Batch for application/json ContentType.
+type BatchJSONRequestBody = BatchRequest
+
 // UpdateDirectoryJSONRequestBody defines body for UpdateDirectory for application/json ContentType.
 type UpdateDirectoryJSONRequestBody = UpdateDirectoryRequest
 
 // CreateDirectoryJSONRequestBody defines body for CreateDirectory for application/json ContentType.
 type CreateDirectoryJSONRequestBody = CreateDirectoryRequest
 
+// MoveDirectoryJSONRequestBody defines body for MoveDirectory for application/json ContentType.
+type MoveDirectoryJSONRequestBody = MoveDirectoryRequest
+
+// SetDirectorySchemaJSONRequestBody defines body for SetDirectorySchema for application/json ContentType.
+type SetDirectorySchemaJSONRequestBody = SetSchemaRequest
+
+// BatchGetDirectoriesJSONRequestBody defines body for BatchGetDirectories for application/json ContentType.
+type BatchGetDirectoriesJSONRequestBody = BatchGetDirectoriesRequest
+
 // CreateRootDirectoryJSONRequestBody defines body for CreateRootDirectory for application/json ContentType.
 type CreateRootDirectoryJSONRequestBody = CreateDirectoryRequest
+
+// SetRootQuotaJSONRequestBody defines body for SetRootQuota for application/json ContentType.
+type SetRootQuotaJSONRequestBody = SetQuotaRequest
@@ -55,3 +55,35 @@ func (did *DirectoryID) UnmarshalJSON(b []byte) error {
 }
 
 type DirectoryMetadata map[string]string
+
+// NewDirectoryTree builds the tree of the provided directory out of its descendants.
+// Descendants which aren't connected to the directory are left out,
+// and children keep the order in which they were provided.
+func NewDirectoryTree(root *Directory, descendants []*Directory) *DirectoryTree {
+	byParent := map[DirectoryID][]*Directory{}
+
+	for _, d := range descendants {
+		if d.Parent != nil {
+			byParent[*d.Parent] = append(byParent[*d.Parent], d)
+		}
+	}
+
+	var build func(d *Directory) DirectoryTree
+
+	build = func(d *Directory) DirectoryTree {
+		tree := DirectoryTree{
+			Directory: *d,
+			Children:  make([]DirectoryTree, len(byParent[d.Id])),
+		}
+
+		for i, child := range byParent[d.Id] {
+			tree.Children[i] = build(child)
+		}
+
+		return tree
+	}
+
+	tree := build(root)
+
+	return &tree
+}
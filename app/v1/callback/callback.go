@@ -14,6 +14,10 @@ type Config struct {
 	CreateDirectory func(context.Context, *apiv1.Directory) error
 	UpdateDirectory func(context.Context, *apiv1.Directory) error
 	DeleteDirectory func(context.Context, apiv1.DirectoryID) error
+	// MoveDirectory is optional, it's only called if set.
+	MoveDirectory func(context.Context, *apiv1.Directory) error
+	// RestoreDirectory is optional, it's only called if set.
+	RestoreDirectory func(context.Context, apiv1.DirectoryID) error
 }
 
 // AppStorageWithCallback is an implementation of AppStorage that
@@ -55,6 +59,17 @@ func (s *AppStorageWithCallback) UpdateDirectory(ctx context.Context, d *apiv1.D
 	return nil
 }
 
+// UpdateDirectoryIfRevision conditionally updates the directory in storage
+// and then calls the update callback.
+func (s *AppStorageWithCallback) UpdateDirectoryIfRevision(
+	ctx context.Context, d *apiv1.Directory, revision int64,
+) error {
+	if err := s.impl.UpdateDirectoryIfRevision(ctx, d, revision); err != nil {
+		return err
+	}
+	return s.cfg.UpdateDirectory(ctx, d)
+}
+
 func (s *AppStorageWithCallback) DeleteDirectory(
 	ctx context.Context, id apiv1.DirectoryID,
 ) ([]*apiv1.Directory, error) {
@@ -64,6 +79,38 @@ func (s *AppStorageWithCallback) DeleteDirectory(
 	return s.impl.DeleteDirectory(ctx, id)
 }
 
+// MoveDirectory moves the directory in storage and then calls the callback if configured.
+func (s *AppStorageWithCallback) MoveDirectory(
+	ctx context.Context, id, parent apiv1.DirectoryID,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	d, oldParent, err := s.impl.MoveDirectory(ctx, id, parent)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.cfg.MoveDirectory != nil {
+		if err := s.cfg.MoveDirectory(ctx, d); err != nil {
+			return nil, nil, err
+		}
+	}
+	return d, oldParent, nil
+}
+
+// RestoreDirectory restores the directory in storage and then calls the callback if configured.
+func (s *AppStorageWithCallback) RestoreDirectory(
+	ctx context.Context, id apiv1.DirectoryID,
+) ([]*apiv1.Directory, error) {
+	affected, err := s.impl.RestoreDirectory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.cfg.RestoreDirectory != nil {
+		if err := s.cfg.RestoreDirectory(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return affected, nil
+}
+
 func (s *AppStorageWithCallback) IsDirectoryTracked(ctx context.Context, id apiv1.DirectoryID) (bool, error) {
 	return s.impl.IsDirectoryTracked(ctx, id)
 }
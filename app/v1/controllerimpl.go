@@ -6,10 +6,17 @@ import (
 	"math/rand"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
 	apiv1 "github.com/infratographer/fertilesoil/api/v1"
 	clientv1 "github.com/infratographer/fertilesoil/client/v1"
 )
 
+var tracer = otel.Tracer("github.com/infratographer/fertilesoil/app/v1")
+
 type controller struct {
 	c       clientv1.ReadOnlyClient
 	w       clientv1.Watcher
@@ -27,6 +34,9 @@ const (
 	frMinimumInterval = 5
 	frMaximumInterval = 10
 	frDuration        = time.Minute
+
+	// frBatchSize is the count of directories fetched at once during full reconciles.
+	frBatchSize = 100
 )
 
 // NewController creates a new controller.
@@ -90,7 +100,7 @@ func (c *controller) Run(ctx context.Context) error {
 
 	if c.c != nil {
 		// initialize directories
-		err := c.InitializeDirectories(ctx)
+		err := c.fullReconcile(ctx)
 		if err != nil {
 			return err
 		}
@@ -106,7 +116,7 @@ func (c *controller) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			err := c.InitializeDirectories(ctx)
+			err := c.fullReconcile(ctx)
 			if err != nil {
 				return err
 			}
@@ -115,7 +125,7 @@ func (c *controller) Run(ctx context.Context) error {
 		case err := <-errCh:
 			return err
 		case ev := <-evCh:
-			err := c.processIncomingEvent(ctx, ev)
+			err := c.handleEvent(ctx, ev)
 			if err != nil {
 				return err
 			}
@@ -123,6 +133,41 @@ func (c *controller) Run(ctx context.Context) error {
 	}
 }
 
+// fullReconcile reconciles all the directories of the base directory within a span.
+func (c *controller) fullReconcile(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "full reconcile",
+		trace.WithAttributes(attribute.String("directory.base", c.baseDir.String())),
+	)
+	defer span.End()
+
+	return spanError(span, c.InitializeDirectories(ctx))
+}
+
+// handleEvent processes the event within a span, which joins the trace the
+// event was published with.
+func (c *controller) handleEvent(ctx context.Context, ev *apiv1.DirectoryEvent) error {
+	ctx, span := tracer.Start(clientv1.EventContext(ctx, ev), "process "+string(ev.Type)+" event",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("directory.base", c.baseDir.String()),
+			attribute.String("directory.id", ev.Directory.Id.String()),
+		),
+	)
+	defer span.End()
+
+	return spanError(span, c.processIncomingEvent(ctx, ev))
+}
+
+// spanError records the error, if any, on the span and returns it.
+func spanError(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
+
 // persistDirectory persists the directory in the store.
 // If the directory is not up-to-date on the store, the reconciler is called.
 func (c *controller) persistDirectory(ctx context.Context, d *apiv1.Directory) error {
@@ -167,6 +212,28 @@ func (c *controller) processIncomingEvent(ctx context.Context, ev *apiv1.Directo
 		return nil
 	}
 
+	// Hard deleted directories were already soft deleted,
+	// so there's nothing left to persist.
+	if ev.Type == apiv1.EventTypeDeleteHard {
+		return c.r.Reconcile(ctx, *ev)
+	}
+
+	if ev.Type == apiv1.EventTypeRestore {
+		if err = c.restoreDirectory(ctx, ev); err != nil {
+			return fmt.Errorf("error restoring directory: %w", err)
+		}
+
+		return nil
+	}
+
+	if ev.Type == apiv1.EventTypeMove {
+		if err = c.moveDirectory(ctx, ev); err != nil {
+			return fmt.Errorf("error moving directory: %w", err)
+		}
+
+		return nil
+	}
+
 	if err = c.persistDirectory(ctx, &ev.Directory); err != nil {
 		return fmt.Errorf("error persisting directory: %w", err)
 	}
@@ -187,9 +254,9 @@ func (c *controller) isRelevantEvent(ctx context.Context, ev *apiv1.DirectoryEve
 		return true, nil
 	}
 
-	// Otherwise, we only care if it's a create event and
+	// Otherwise, we only care if it's a create or move event and
 	// if it's a subdirectory of a directory we're already tracking.
-	if ev.Type != apiv1.EventTypeCreate {
+	if ev.Type != apiv1.EventTypeCreate && ev.Type != apiv1.EventTypeMove {
 		return false, nil
 	}
 
@@ -207,6 +274,38 @@ func (c *controller) isRelevantEvent(ctx context.Context, ev *apiv1.DirectoryEve
 	return trackingParent, nil
 }
 
+// moveDirectory handles a directory being moved in the tree.
+// Directories moved under a tracked directory are persisted as new directories.
+func (c *controller) moveDirectory(ctx context.Context, ev *apiv1.DirectoryEvent) error {
+	d := &ev.Directory
+
+	tracking, err := c.store.IsDirectoryTracked(ctx, d.Id)
+	if err != nil {
+		return fmt.Errorf("error checking if directory is tracked: %w", err)
+	}
+
+	if !tracking {
+		return c.persistDirectory(ctx, d)
+	}
+
+	if d.Parent != nil {
+		if _, _, err := c.store.MoveDirectory(ctx, d.Id, *d.Parent); err != nil {
+			return err
+		}
+	}
+
+	return c.r.Reconcile(ctx, *ev)
+}
+
+// restoreDirectory brings back a tracked directory which was restored in the tree.
+func (c *controller) restoreDirectory(ctx context.Context, ev *apiv1.DirectoryEvent) error {
+	if _, err := c.store.RestoreDirectory(ctx, ev.Directory.Id); err != nil {
+		return err
+	}
+
+	return c.r.Reconcile(ctx, *ev)
+}
+
 // getRandomTickerDuration returns a random duration between
 // the frMinimumInterval and frMaximumInterval values.
 func (c *controller) getRandomTickerDuration() time.Duration {
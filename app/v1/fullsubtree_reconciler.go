@@ -39,11 +39,26 @@ func (c *controller) InitializeDirectories(ctx context.Context) error {
 		return err
 	}
 
-	for _, subdir := range subdirs.Directories {
-		err := c.persistIfUpToDate(ctx, subdir)
+	// subdirectories are fetched in batches, to avoid a request per directory.
+	for start := 0; start < len(subdirs.Directories); start += frBatchSize {
+		end := start + frBatchSize
+		if end > len(subdirs.Directories) {
+			end = len(subdirs.Directories)
+		}
+
+		resp, err := c.c.GetDirectories(ctx, subdirs.Directories[start:end])
 		if err != nil {
 			return err
 		}
+
+		// missing directories were deleted since listing the children,
+		// their deletion is handled through events or the next full reconcile.
+		for i := range resp.Directories {
+			err := c.persistDirectoryIfOutdated(ctx, &resp.Directories[i])
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -63,7 +78,12 @@ func (c *controller) persistIfUpToDate(ctx context.Context, dir apiv1.DirectoryI
 		return err
 	}
 
-	d := &fd.Directory
+	return c.persistDirectoryIfOutdated(ctx, &fd.Directory)
+}
+
+// persistDirectoryIfOutdated persists the directory, unless
+// it is already up-to-date on the store.
+func (c *controller) persistDirectoryIfOutdated(ctx context.Context, d *apiv1.Directory) error {
 	upToDate, err := c.store.IsDirectoryInfoUpdated(ctx, d)
 	if err != nil {
 		return err
@@ -14,5 +14,5 @@ CREATE TABLE  IF NOT EXISTS tracked_directories (
 
 -- +goose Down
 -- +goose StatementBegin
-DROP DATABASE IF EXISTS tracked_directories;
+DROP TABLE IF EXISTS tracked_directories;
 -- +goose StatementEnd
\ No newline at end of file
@@ -82,13 +82,26 @@ func (s *sqlstorage) UpdateDirectory(ctx context.Context, d *apiv1.Directory) er
 	return nil
 }
 
+func (s *sqlstorage) UpdateDirectoryIfRevision(ctx context.Context, d *apiv1.Directory, revision int64) error {
+	// nothing to be done, revisions aren't tracked.
+	return nil
+}
+
+func (s *sqlstorage) MoveDirectory(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	// nothing to be done, parents aren't tracked.
+	return &apiv1.Directory{Id: id, Parent: &parent}, nil, nil
+}
+
 func (s *sqlstorage) DeleteDirectory(ctx context.Context, id apiv1.DirectoryID) ([]*apiv1.Directory, error) {
 	// soft delete directory
 	var affected []*apiv1.Directory
 
 	deleteQuery := `
 		UPDATE tracked_directories
-		SET deleted_at = NOW()
+		SET deleted_at = CURRENT_TIMESTAMP
 		WHERE
 			deleted_at IS NULL
 			AND id = $1
@@ -119,6 +132,43 @@ func (s *sqlstorage) DeleteDirectory(ctx context.Context, id apiv1.DirectoryID)
 	return affected, nil
 }
 
+func (s *sqlstorage) RestoreDirectory(ctx context.Context, id apiv1.DirectoryID) ([]*apiv1.Directory, error) {
+	// restore soft deleted directory
+	var affected []*apiv1.Directory
+
+	restoreQuery := `
+		UPDATE tracked_directories
+		SET deleted_at = NULL
+		WHERE
+			deleted_at IS NOT NULL
+			AND id = $1
+		RETURNING id`
+
+	rows, err := s.db.QueryContext(ctx, restoreQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("error restoring directory: %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var d apiv1.Directory
+
+		err := rows.Scan(&d.Id)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning directory: %w", err)
+		}
+
+		affected = append(affected, &d)
+	}
+
+	if len(affected) != 1 {
+		return affected, fmt.Errorf("expected 1 row affected, got %d", len(affected))
+	}
+
+	return affected, nil
+}
+
 // compareDeletedAt compares the observed deleted at time with the expected deleted at time.
 // It will return true if the observed and expected deleted at times are equal.
 func compareDeletedAt(observed sql.NullTime, expected *time.Time) bool {
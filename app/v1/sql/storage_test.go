@@ -0,0 +1,100 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "github.com/infratographer/fertilesoil/api/v1"
+	appsql "github.com/infratographer/fertilesoil/app/v1/sql"
+	"github.com/infratographer/fertilesoil/app/v1/sql/migrations"
+	"github.com/infratographer/fertilesoil/storage/sqlite/utils"
+)
+
+// dbdialect is the dialect the migrations are run with. The tests
+// aren't run in parallel, as goose isn't thread-safe.
+const dbdialect = "sqlite3"
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dbConn, err := sql.Open(dbdialect, utils.DSN(filepath.Join(t.TempDir(), "app.db")))
+	assert.NoError(t, err, "failed to open db connection")
+
+	t.Cleanup(func() {
+		dbConn.Close()
+	})
+
+	return dbConn
+}
+
+func TestMigrations(t *testing.T) {
+	dbConn := newTestDB(t)
+
+	assert.NoError(t, migrations.BootStrap(dbdialect, dbConn), "failed to run migrations")
+
+	goose.SetBaseFS(migrations.Migrations)
+	assert.NoError(t, goose.Down(dbConn, "."), "failed to roll back migrations")
+}
+
+func TestTrackedDirectories(t *testing.T) {
+	ctx := context.Background()
+	dbConn := newTestDB(t)
+
+	assert.NoError(t, migrations.BootStrap(dbdialect, dbConn), "failed to run migrations")
+
+	store := appsql.New(dbConn)
+	dir := &apiv1.Directory{Id: apiv1.DirectoryID(uuid.New())}
+
+	tracked, err := store.IsDirectoryTracked(ctx, dir.Id)
+	assert.NoError(t, err, "error checking if directory is tracked")
+	assert.False(t, tracked, "directory should not be tracked yet")
+
+	_, err = store.CreateDirectory(ctx, dir)
+	assert.NoError(t, err, "error creating directory")
+
+	tracked, err = store.IsDirectoryTracked(ctx, dir.Id)
+	assert.NoError(t, err, "error checking if directory is tracked")
+	assert.True(t, tracked, "directory should be tracked")
+
+	updated, err := store.IsDirectoryInfoUpdated(ctx, dir)
+	assert.NoError(t, err, "error checking if directory is updated")
+	assert.True(t, updated, "created directory should be up to date")
+
+	_, err = store.CreateDirectory(ctx, dir)
+	assert.Error(t, err, "directory should not be created twice")
+
+	deleted, err := store.DeleteDirectory(ctx, dir.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	if assert.Len(t, deleted, 1, "directory should be deleted") {
+		assert.NotNil(t, deleted[0].DeletedAt, "deleted directory should have a deletion time")
+
+		updated, err = store.IsDirectoryInfoUpdated(ctx, deleted[0])
+		assert.NoError(t, err, "error checking if directory is updated")
+		assert.True(t, updated, "deleted directory should be up to date")
+	}
+
+	updated, err = store.IsDirectoryInfoUpdated(ctx, dir)
+	assert.NoError(t, err, "error checking if directory is updated")
+	assert.False(t, updated, "live directory should not be up to date once deleted")
+
+	_, err = store.DeleteDirectory(ctx, dir.Id)
+	assert.Error(t, err, "directory should not be deleted twice")
+
+	restored, err := store.RestoreDirectory(ctx, dir.Id)
+	assert.NoError(t, err, "error restoring directory")
+	assert.Len(t, restored, 1, "directory should be restored")
+
+	updated, err = store.IsDirectoryInfoUpdated(ctx, dir)
+	assert.NoError(t, err, "error checking if directory is updated")
+	assert.True(t, updated, "restored directory should be up to date")
+
+	_, err = store.RestoreDirectory(ctx, dir.Id)
+	assert.Error(t, err, "live directory should not be restored")
+}
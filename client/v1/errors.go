@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// DirectoryNameConflictError is returned when a write is refused because
+// a live sibling directory already has the same name.
+// It wraps storage.ErrDirectoryNameConflict.
+type DirectoryNameConflictError struct {
+	// Op describes the refused operation, e.g. "creating directory".
+	Op string
+}
+
+func (e *DirectoryNameConflictError) Error() string {
+	return fmt.Sprintf("error %s: %v", e.Op, storage.ErrDirectoryNameConflict)
+}
+
+func (e *DirectoryNameConflictError) Unwrap() error {
+	return storage.ErrDirectoryNameConflict
+}
+
+// nameConflictFromResponse returns a DirectoryNameConflictError if
+// the response reports a name conflict, and nil otherwise.
+func nameConflictFromResponse(resp *http.Response, op string) error {
+	if resp.StatusCode != http.StatusConflict {
+		return nil
+	}
+
+	if !strings.Contains(responseError(resp), storage.ErrDirectoryNameConflict.Error()) {
+		return nil
+	}
+
+	return &DirectoryNameConflictError{Op: op}
+}
+
+// unprocessableFromResponse returns a *storage.QuotaExceededError or a
+// *storage.InvalidMetadataError, wrapped with the operation, if the response
+// reports an exceeded quota or invalid metadata, and nil otherwise.
+func unprocessableFromResponse(resp *http.Response, op string) error {
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		return nil
+	}
+
+	// Both errors are decoded at once, and told apart by their fields.
+	var body struct {
+		v1.QuotaExceeded
+		Source *v1.DirectoryID         `json:"source"`
+		Fields []v1.MetadataFieldError `json:"fields"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("error %s: %s", op, resp.Status)
+	}
+
+	if body.Source != nil {
+		fields := make([]storage.FieldError, len(body.Fields))
+
+		for i, f := range body.Fields {
+			fields[i] = storage.FieldError{Field: f.Field, Message: f.Message}
+		}
+
+		return fmt.Errorf("error %s: %w", op, &storage.InvalidMetadataError{
+			Source: *body.Source,
+			Fields: fields,
+		})
+	}
+
+	return fmt.Errorf("error %s: %w", op, &storage.QuotaExceededError{
+		Root:  body.Root,
+		Quota: body.Quota,
+		Limit: body.Limit,
+	})
+}
+
+// responseError returns the error message of an error response.
+// An empty string is returned if the response holds no message.
+func responseError(resp *http.Response) string {
+	var body struct {
+		Error string `json:"error"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return ""
+	}
+
+	return body.Error
+}
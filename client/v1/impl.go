@@ -10,6 +10,10 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
 	v1 "github.com/infratographer/fertilesoil/api/v1"
 	"github.com/infratographer/fertilesoil/storage"
@@ -33,8 +37,17 @@ func newFullHTTPClient(cfg *ClientConfig) HTTPRootClient {
 		}
 	}
 
+	// Requests carry the trace context of the caller to the server.
+	// The provided client is copied, so it's left as it was.
+	c := *cfg.client
+	if c.Transport == nil {
+		c.Transport = http.DefaultTransport
+	}
+
+	c.Transport = otelhttp.NewTransport(c.Transport)
+
 	return &httpClient{
-		c:          cfg.client,
+		c:          &c,
 		managerURL: cfg.managerURL,
 	}
 }
@@ -75,6 +88,14 @@ func (c *httpClient) CreateDirectory(
 	}
 	defer resp.Body.Close()
 
+	if err := nameConflictFromResponse(resp, "creating directory"); err != nil {
+		return nil, err
+	}
+
+	if err := unprocessableFromResponse(resp, "creating directory"); err != nil {
+		return nil, err
+	}
+
 	if resp.StatusCode != http.StatusCreated {
 		return nil, fmt.Errorf("error creating directory: %s", resp.Status)
 	}
@@ -92,6 +113,7 @@ func (c *httpClient) UpdateDirectory(
 	ctx context.Context,
 	id v1.DirectoryID,
 	udr *v1.UpdateDirectoryRequest,
+	expectedRevision *int64,
 ) (*v1.DirectoryFetch, error) {
 	r, err := c.encode(udr)
 	if err != nil {
@@ -103,12 +125,30 @@ func (c *httpClient) UpdateDirectory(
 		return nil, fmt.Errorf("error updating directory: %w", err)
 	}
 
-	resp, err := c.DoRaw(ctx, http.MethodPatch, path, r)
+	header := http.Header{}
+
+	if expectedRevision != nil {
+		header.Set("If-Match", strconv.Quote(strconv.FormatInt(*expectedRevision, 10)))
+	}
+
+	resp, err := c.doRawWithHeader(ctx, http.MethodPatch, path, r, header)
 	if err != nil {
 		return nil, fmt.Errorf("error updating directory: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return nil, fmt.Errorf("error updating directory: %w", storage.ErrRevisionConflict)
+	}
+
+	if err := nameConflictFromResponse(resp, "updating directory"); err != nil {
+		return nil, err
+	}
+
+	if err := unprocessableFromResponse(resp, "updating directory"); err != nil {
+		return nil, err
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("error updating directory: %s", resp.Status)
 	}
@@ -122,6 +162,48 @@ func (c *httpClient) UpdateDirectory(
 	return &dir, nil
 }
 
+func (c *httpClient) MoveDirectory(
+	ctx context.Context,
+	id v1.DirectoryID,
+	mdr *v1.MoveDirectoryRequest,
+) (*v1.DirectoryFetch, error) {
+	r, err := c.encode(mdr)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := url.JoinPath("/api/v1/directories", id.String(), "move")
+	if err != nil {
+		return nil, fmt.Errorf("error moving directory: %w", err)
+	}
+
+	resp, err := c.DoRaw(ctx, http.MethodPost, path, r)
+	if err != nil {
+		return nil, fmt.Errorf("error moving directory: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := nameConflictFromResponse(resp, "moving directory"); err != nil {
+		return nil, err
+	}
+
+	if err := unprocessableFromResponse(resp, "moving directory"); err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error moving directory: %s", resp.Status)
+	}
+
+	var dir v1.DirectoryFetch
+	err = dir.Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &dir, nil
+}
+
 func (c *httpClient) CreateRoot(
 	ctx context.Context,
 	cdr *v1.CreateDirectoryRequest,
@@ -156,18 +238,33 @@ func (c *httpClient) ListRoots(ctx context.Context, options ...storage.Option) (
 		return nil, fmt.Errorf("error adding options to url: %w", err)
 	}
 
-	resp, err := c.DoRaw(ctx, http.MethodGet, path, nil)
+	dirList, err := c.listDirectories(ctx, path, options)
 	if err != nil {
 		return nil, fmt.Errorf("error listing roots: %w", err)
 	}
+
+	return dirList, nil
+}
+
+func (c *httpClient) DeleteDirectory(ctx context.Context, id v1.DirectoryID) (*v1.DirectoryList, error) {
+	path, err := url.JoinPath("/api/v1/directories", id.String())
+	if err != nil {
+		return nil, fmt.Errorf("error getting directory: %w", err)
+	}
+
+	resp, err := c.DoRaw(ctx, http.MethodDelete, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting directory: %w", err)
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error listing roots: %s", resp.Status)
+		return nil, fmt.Errorf("error getting directory: %s", resp.Status)
 	}
 
 	var dirList v1.DirectoryList
 	err = dirList.Parse(resp.Body)
+
 	if err != nil {
 		return nil, fmt.Errorf("error parsing response: %w", err)
 	}
@@ -175,20 +272,50 @@ func (c *httpClient) ListRoots(ctx context.Context, options ...storage.Option) (
 	return &dirList, nil
 }
 
-func (c *httpClient) DeleteDirectory(ctx context.Context, id v1.DirectoryID) (*v1.DirectoryList, error) {
-	path, err := url.JoinPath("/api/v1/directories", id.String())
+func (c *httpClient) RestoreDirectory(ctx context.Context, id v1.DirectoryID) (*v1.DirectoryList, error) {
+	path, err := url.JoinPath("/api/v1/directories", id.String(), "restore")
 	if err != nil {
-		return nil, fmt.Errorf("error getting directory: %w", err)
+		return nil, fmt.Errorf("error restoring directory: %w", err)
 	}
 
-	resp, err := c.DoRaw(ctx, http.MethodDelete, path, nil)
+	resp, err := c.DoRaw(ctx, http.MethodPost, path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("error getting directory: %w", err)
+		return nil, fmt.Errorf("error restoring directory: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if err := nameConflictFromResponse(resp, "restoring directory"); err != nil {
+		return nil, err
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error getting directory: %s", resp.Status)
+		return nil, fmt.Errorf("error restoring directory: %s", resp.Status)
+	}
+
+	var dirList v1.DirectoryList
+	err = dirList.Parse(resp.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &dirList, nil
+}
+
+func (c *httpClient) PurgeDirectory(ctx context.Context, id v1.DirectoryID) (*v1.DirectoryList, error) {
+	path, err := url.JoinPath("/api/v1/directories", id.String(), "purge")
+	if err != nil {
+		return nil, fmt.Errorf("error purging directory: %w", err)
+	}
+
+	resp, err := c.DoRaw(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error purging directory: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error purging directory: %s", resp.Status)
 	}
 
 	var dirList v1.DirectoryList
@@ -201,6 +328,201 @@ func (c *httpClient) DeleteDirectory(ctx context.Context, id v1.DirectoryID) (*v
 	return &dirList, nil
 }
 
+func (c *httpClient) GetQuota(ctx context.Context, root v1.DirectoryID) (*v1.RootQuota, error) {
+	path, err := url.JoinPath("/api/v1/roots", root.String(), "quota")
+	if err != nil {
+		return nil, fmt.Errorf("error getting quota: %w", err)
+	}
+
+	resp, err := c.DoRaw(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting quota: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error getting quota: %s", resp.Status)
+	}
+
+	var rq v1.RootQuota
+	err = rq.Parse(resp.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &rq, nil
+}
+
+func (c *httpClient) SetQuota(
+	ctx context.Context,
+	root v1.DirectoryID,
+	sqr *v1.SetQuotaRequest,
+) (*v1.RootQuota, error) {
+	r, err := c.encode(sqr)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := url.JoinPath("/api/v1/roots", root.String(), "quota")
+	if err != nil {
+		return nil, fmt.Errorf("error setting quota: %w", err)
+	}
+
+	resp, err := c.DoRaw(ctx, http.MethodPut, path, r)
+	if err != nil {
+		return nil, fmt.Errorf("error setting quota: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error setting quota: %s", resp.Status)
+	}
+
+	var rq v1.RootQuota
+	err = rq.Parse(resp.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &rq, nil
+}
+
+func (c *httpClient) GetSchema(ctx context.Context, id v1.DirectoryID) (*v1.DirectorySchema, error) {
+	path, err := url.JoinPath("/api/v1/directories", id.String(), "schema")
+	if err != nil {
+		return nil, fmt.Errorf("error getting schema: %w", err)
+	}
+
+	resp, err := c.DoRaw(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting schema: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error getting schema: %s", resp.Status)
+	}
+
+	var ds v1.DirectorySchema
+	err = ds.Parse(resp.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &ds, nil
+}
+
+func (c *httpClient) SetSchema(
+	ctx context.Context,
+	id v1.DirectoryID,
+	ssr *v1.SetSchemaRequest,
+) (*v1.DirectorySchema, error) {
+	r, err := c.encode(ssr)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := url.JoinPath("/api/v1/directories", id.String(), "schema")
+	if err != nil {
+		return nil, fmt.Errorf("error setting schema: %w", err)
+	}
+
+	resp, err := c.DoRaw(ctx, http.MethodPut, path, r)
+	if err != nil {
+		return nil, fmt.Errorf("error setting schema: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest {
+		return nil, fmt.Errorf("error setting schema: %w: %s", storage.ErrInvalidSchema, responseError(resp))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error setting schema: %s", resp.Status)
+	}
+
+	var ds v1.DirectorySchema
+	err = ds.Parse(resp.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &ds, nil
+}
+
+func (c *httpClient) DeleteSchema(ctx context.Context, id v1.DirectoryID) (*v1.DirectorySchema, error) {
+	path, err := url.JoinPath("/api/v1/directories", id.String(), "schema")
+	if err != nil {
+		return nil, fmt.Errorf("error deleting schema: %w", err)
+	}
+
+	resp, err := c.DoRaw(ctx, http.MethodDelete, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting schema: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound &&
+		strings.Contains(responseError(resp), storage.ErrSchemaNotFound.Error()) {
+		return nil, fmt.Errorf("error deleting schema: %w", storage.ErrSchemaNotFound)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error deleting schema: %s", resp.Status)
+	}
+
+	var ds v1.DirectorySchema
+	err = ds.Parse(resp.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &ds, nil
+}
+
+func (c *httpClient) Batch(ctx context.Context, br *v1.BatchRequest) (*v1.BatchResponse, error) {
+	r, err := c.encode(br)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.DoRaw(ctx, http.MethodPost, "/api/v1/batch", r)
+	if err != nil {
+		return nil, fmt.Errorf("error applying batch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return nil, fmt.Errorf("error applying batch: %w", storage.ErrRevisionConflict)
+	}
+
+	if err := nameConflictFromResponse(resp, "applying batch"); err != nil {
+		return nil, err
+	}
+
+	if err := unprocessableFromResponse(resp, "applying batch"); err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error applying batch: %s", resp.Status)
+	}
+
+	var batchResp v1.BatchResponse
+	err = batchResp.Parse(resp.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &batchResp, nil
+}
+
 func (c *httpClient) GetDirectory(
 	ctx context.Context,
 	id v1.DirectoryID,
@@ -235,6 +557,307 @@ func (c *httpClient) GetDirectory(
 	return &dir, nil
 }
 
+func (c *httpClient) ResolvePath(
+	ctx context.Context,
+	path string,
+	options ...storage.Option,
+) (*v1.DirectoryFetch, error) {
+	names, err := v1.ParsePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving path: %w", err)
+	}
+
+	// Each name is escaped within the path, and escaped again within the URL.
+	reqPath := "/api/v1/paths"
+
+	for _, name := range strings.Split(v1.FormatPath(names), v1.PathSeparator)[1:] {
+		reqPath += "/" + url.PathEscape(name)
+	}
+
+	reqPath, err = addStorageOptionsToURL(reqPath, options)
+	if err != nil {
+		return nil, fmt.Errorf("error adding options to url: %w", err)
+	}
+
+	resp, err := c.DoRaw(ctx, http.MethodGet, reqPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving path: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		return nil, fmt.Errorf("error resolving path: %w", storage.AmbiguousPathError(names))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error resolving path: %s", resp.Status)
+	}
+
+	var dir v1.DirectoryFetch
+	err = dir.Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &dir, nil
+}
+
+func (c *httpClient) GetDirectories(
+	ctx context.Context,
+	ids []v1.DirectoryID,
+	options ...storage.Option,
+) (*v1.BatchGetDirectoriesResponse, error) {
+	path, err := addStorageOptionsToURL("/api/v1/directories:batchGet", options)
+	if err != nil {
+		return nil, fmt.Errorf("error adding options to url: %w", err)
+	}
+
+	r, err := c.encode(&v1.BatchGetDirectoriesRequest{
+		Version: v1.APIVersion,
+		Ids:     ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.DoRaw(ctx, http.MethodPost, path, r)
+	if err != nil {
+		return nil, fmt.Errorf("error getting directories: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error getting directories: %s", resp.Status)
+	}
+
+	var dirs v1.BatchGetDirectoriesResponse
+	err = dirs.Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &dirs, nil
+}
+
+func (c *httpClient) GetSubtree(
+	ctx context.Context,
+	id v1.DirectoryID,
+	options ...storage.Option,
+) (*v1.DirectoryTreeFetch, error) {
+	path, err := url.JoinPath("/api/v1/directories", id.String(), "tree")
+	if err != nil {
+		return nil, fmt.Errorf("error getting directory tree: %w", err)
+	}
+
+	path, err = addStorageOptionsToURL(path, options)
+	if err != nil {
+		return nil, fmt.Errorf("error adding options to url: %w", err)
+	}
+
+	resp, err := c.DoRaw(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting directory tree: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest && responseError(resp) == storage.ErrSubtreeTooLarge.Error() {
+		return nil, fmt.Errorf("error getting directory tree: %w", storage.ErrSubtreeTooLarge)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error getting directory tree: %s", resp.Status)
+	}
+
+	var tree v1.DirectoryTreeFetch
+	err = tree.Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &tree, nil
+}
+
+func (c *httpClient) GetStats(
+	ctx context.Context,
+	id v1.DirectoryID,
+	options ...storage.Option,
+) (*v1.DirectoryStatsFetch, error) {
+	path, err := url.JoinPath("/api/v1/directories", id.String(), "stats")
+	if err != nil {
+		return nil, fmt.Errorf("error getting directory stats: %w", err)
+	}
+
+	path, err = addStorageOptionsToURL(path, options)
+	if err != nil {
+		return nil, fmt.Errorf("error adding options to url: %w", err)
+	}
+
+	resp, err := c.DoRaw(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting directory stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error getting directory stats: %s", resp.Status)
+	}
+
+	var stats v1.DirectoryStatsFetch
+	err = stats.Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &stats, nil
+}
+
+func (c *httpClient) SearchDirectories(
+	ctx context.Context,
+	id v1.DirectoryID,
+	q storage.NameQuery,
+	options ...storage.Option,
+) (*v1.DirectorySearchResults, error) {
+	path, err := url.JoinPath("/api/v1/directories", id.String(), "search")
+	if err != nil {
+		return nil, fmt.Errorf("error searching directories: %w", err)
+	}
+
+	path, err = addStorageOptionsToURL(path, options)
+	if err != nil {
+		return nil, fmt.Errorf("error adding options to url: %w", err)
+	}
+
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("error searching directories: %w", err)
+	}
+
+	values := u.Query()
+	values.Set("q", q.Name)
+
+	if q.Match != "" {
+		values.Set("match", string(q.Match))
+	}
+
+	u.RawQuery = values.Encode()
+
+	opts := storage.BuildOptions(options)
+	follow := opts.Page == 0 && opts.Cursor == nil
+
+	var results *v1.DirectorySearchResults
+
+	for path = u.String(); path != ""; {
+		page, err := c.searchDirectoriesPage(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("error searching directories: %w", err)
+		}
+
+		if results == nil {
+			results = page
+		} else {
+			results.Results = append(results.Results, page.Results...)
+			results.Links = page.Links
+		}
+
+		path = ""
+
+		if follow && page.Links.Next != nil {
+			path = page.Links.Next.HREF
+		}
+	}
+
+	return results, nil
+}
+
+func (c *httpClient) searchDirectoriesPage(ctx context.Context, path string) (*v1.DirectorySearchResults, error) {
+	resp, err := c.DoRaw(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest &&
+		strings.HasPrefix(responseError(resp), storage.ErrInvalidSearch.Error()) {
+		return nil, storage.ErrInvalidSearch
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+
+	var results v1.DirectorySearchResults
+	err = results.Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &results, nil
+}
+
+func (c *httpClient) GetHistory(
+	ctx context.Context,
+	id v1.DirectoryID,
+	options ...storage.Option,
+) (*v1.DirectoryHistory, error) {
+	path, err := url.JoinPath("/api/v1/directories", id.String(), "history")
+	if err != nil {
+		return nil, fmt.Errorf("error getting directory history: %w", err)
+	}
+
+	path, err = addStorageOptionsToURL(path, options)
+	if err != nil {
+		return nil, fmt.Errorf("error adding options to url: %w", err)
+	}
+
+	// Unless a specific page is requested, the next links are
+	// followed and all pages are merged into the returned history.
+	follow := storage.BuildOptions(options).Page == 0
+
+	var history *v1.DirectoryHistory
+
+	for path != "" {
+		page, err := c.getHistoryPage(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("error getting directory history: %w", err)
+		}
+
+		if history == nil {
+			history = page
+		} else {
+			history.Revisions = append(history.Revisions, page.Revisions...)
+			history.Links = page.Links
+		}
+
+		path = ""
+
+		if follow && page.Links.Next != nil {
+			path = page.Links.Next.HREF
+		}
+	}
+
+	return history, nil
+}
+
+func (c *httpClient) getHistoryPage(ctx context.Context, path string) (*v1.DirectoryHistory, error) {
+	resp, err := c.DoRaw(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+
+	var history v1.DirectoryHistory
+	err = history.Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &history, nil
+}
+
 func (c *httpClient) GetParents(
 	ctx context.Context,
 	id v1.DirectoryID,
@@ -275,23 +898,12 @@ func (c *httpClient) doGetParents(
 		}
 	}
 
-	resp, err := c.DoRaw(ctx, http.MethodGet, path, nil)
+	dirList, err := c.listDirectories(ctx, path, options)
 	if err != nil {
 		return nil, fmt.Errorf("error getting parents: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error getting parents: %s", resp.Status)
-	}
 
-	var dirList v1.DirectoryList
-	err = dirList.Parse(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing response: %w", err)
-	}
-
-	return &dirList, nil
+	return dirList, nil
 }
 
 func (c *httpClient) GetChildren(
@@ -309,14 +921,59 @@ func (c *httpClient) GetChildren(
 		return nil, fmt.Errorf("error adding options to url: %w", err)
 	}
 
-	resp, err := c.DoRaw(ctx, http.MethodGet, path, nil)
+	dirList, err := c.listDirectories(ctx, path, options)
 	if err != nil {
 		return nil, fmt.Errorf("error getting children: %w", err)
 	}
+
+	return dirList, nil
+}
+
+// listDirectories fetches the directory list at the provided path.
+// Unless a specific page or cursor is requested, the next links are
+// followed and all pages are merged into the returned list.
+func (c *httpClient) listDirectories(
+	ctx context.Context,
+	path string,
+	options []storage.Option,
+) (*v1.DirectoryList, error) {
+	opts := storage.BuildOptions(options)
+	follow := opts.Page == 0 && opts.Cursor == nil
+
+	var dirList *v1.DirectoryList
+
+	for path != "" {
+		page, err := c.getDirectoryList(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if dirList == nil {
+			dirList = page
+		} else {
+			dirList.Directories = append(dirList.Directories, page.Directories...)
+			dirList.Links = page.Links
+		}
+
+		path = ""
+
+		if follow && page.Links.Next != nil {
+			path = page.Links.Next.HREF
+		}
+	}
+
+	return dirList, nil
+}
+
+func (c *httpClient) getDirectoryList(ctx context.Context, path string) (*v1.DirectoryList, error) {
+	resp, err := c.DoRaw(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error getting children: %s", resp.Status)
+		return nil, fmt.Errorf("unexpected response: %s", resp.Status)
 	}
 
 	var dirList v1.DirectoryList
@@ -333,13 +990,25 @@ func (c *httpClient) DoRaw(
 	method string,
 	path string,
 	data io.Reader,
+) (*http.Response, error) {
+	return c.doRawWithHeader(ctx, method, path, data, nil)
+}
+
+// doRawWithHeader is like DoRaw, but also sets the provided headers on the request.
+func (c *httpClient) doRawWithHeader(
+	ctx context.Context,
+	method string,
+	path string,
+	data io.Reader,
+	header http.Header,
 ) (*http.Response, error) {
 	uPath, err := url.Parse(path)
 	if err != nil {
 		return nil, fmt.Errorf("error handling path: %w", err)
 	}
 
-	u := c.managerURL.JoinPath(uPath.Path)
+	// The path is joined escaped, so escaped slashes are kept as such.
+	u := c.managerURL.JoinPath(uPath.EscapedPath())
 
 	// Merge any query values managerURL and path may have.
 	values := u.Query()
@@ -355,6 +1024,10 @@ func (c *httpClient) DoRaw(
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
 	return c.c.Do(req)
 }
 
@@ -391,6 +1064,30 @@ func addStorageOptionsToURL(urlstr string, options []storage.Option) (string, er
 		values.Set("limit", strconv.Itoa(opts.PageSize))
 	}
 
+	if opts.Cursor != nil {
+		values.Set("cursor", opts.Cursor.String())
+	}
+
+	if len(opts.Selector) > 0 {
+		values.Set("selector", opts.Selector.String())
+	}
+
+	if opts.MaxDepth > 0 {
+		values.Set("depth", strconv.Itoa(opts.MaxDepth))
+	}
+
+	if opts.MaxNodes > 0 {
+		values.Set("max_nodes", strconv.Itoa(opts.MaxNodes))
+	}
+
+	if !opts.AsOf.IsZero() {
+		values.Set("as_of", opts.AsOf.Format(time.RFC3339Nano))
+	}
+
+	if opts.Total {
+		values.Set("total", "true")
+	}
+
 	u.RawQuery = values.Encode()
 
 	return u.String(), nil
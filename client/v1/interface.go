@@ -5,6 +5,9 @@ import (
 	"io"
 	"net/http"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
 	v1 "github.com/infratographer/fertilesoil/api/v1"
 	"github.com/infratographer/fertilesoil/storage"
 )
@@ -13,18 +16,63 @@ import (
 // with read-only access to the API.
 type ReadOnlyClient interface {
 	GetDirectory(c context.Context, id v1.DirectoryID, options ...storage.Option) (*v1.DirectoryFetch, error)
+	// GetDirectories returns the directories with the provided ids in a single request.
+	// The ids which don't match any directory are reported as missing.
+	GetDirectories(
+		c context.Context,
+		ids []v1.DirectoryID,
+		options ...storage.Option,
+	) (*v1.BatchGetDirectoriesResponse, error)
 	GetParents(c context.Context, id v1.DirectoryID, options ...storage.Option) (*v1.DirectoryList, error)
 	GetParentsUntil(c context.Context, id, until v1.DirectoryID, options ...storage.Option) (*v1.DirectoryList, error)
 	GetChildren(c context.Context, id v1.DirectoryID, options ...storage.Option) (*v1.DirectoryList, error)
+	// GetSubtree returns the directory along with its descendants as a nested tree.
+	// storage.ErrSubtreeTooLarge is returned if the tree has more directories than allowed.
+	GetSubtree(c context.Context, id v1.DirectoryID, options ...storage.Option) (*v1.DirectoryTreeFetch, error)
+	// SearchDirectories searches the descendants of the directory by name.
+	// Unless a specific page or cursor is requested, all pages are returned.
+	SearchDirectories(
+		c context.Context,
+		id v1.DirectoryID,
+		q storage.NameQuery,
+		options ...storage.Option,
+	) (*v1.DirectorySearchResults, error)
+	// GetStats returns the statistics of the subtree of the directory.
+	GetStats(c context.Context, id v1.DirectoryID, options ...storage.Option) (*v1.DirectoryStatsFetch, error)
+	// GetHistory returns the revisions recorded for the directory, newest first.
+	GetHistory(c context.Context, id v1.DirectoryID, options ...storage.Option) (*v1.DirectoryHistory, error)
+	// ResolvePath returns the directory at the provided path, e.g. /acme/emea/payments,
+	// see v1.ParsePath. storage.ErrAmbiguousPath is returned if several directories
+	// are at the path.
+	ResolvePath(c context.Context, path string, options ...storage.Option) (*v1.DirectoryFetch, error)
+	// GetSchema returns the JSON Schema the metadata of the directory is validated
+	// against, its own or the one of its closest ancestor, if any applies.
+	GetSchema(c context.Context, id v1.DirectoryID) (*v1.DirectorySchema, error)
 }
 
 // Client Allows for instantiating a client
 // with read/write access to the API.
+// Writes refused because a live sibling directory already
+// has the same name return a *DirectoryNameConflictError.
+// Writes refused because they would take a tree past one
+// of its quotas return a *storage.QuotaExceededError.
+// Writes refused because the metadata doesn't match the schema
+// applying to the directory return a *storage.InvalidMetadataError.
 type Client interface {
 	ReadOnlyClient
 	CreateDirectory(c context.Context, r *v1.CreateDirectoryRequest, parent v1.DirectoryID) (*v1.DirectoryFetch, error)
-	UpdateDirectory(c context.Context, id v1.DirectoryID, r *v1.UpdateDirectoryRequest) (*v1.DirectoryFetch, error)
+	// UpdateDirectory updates the directory. If expectedRevision is set, the update
+	// only happens if the directory is still at that revision, storage.ErrRevisionConflict
+	// is returned otherwise.
+	UpdateDirectory(
+		c context.Context,
+		id v1.DirectoryID,
+		r *v1.UpdateDirectoryRequest,
+		expectedRevision *int64,
+	) (*v1.DirectoryFetch, error)
 	DeleteDirectory(c context.Context, id v1.DirectoryID) (*v1.DirectoryList, error)
+	MoveDirectory(c context.Context, id v1.DirectoryID, r *v1.MoveDirectoryRequest) (*v1.DirectoryFetch, error)
+	RestoreDirectory(c context.Context, id v1.DirectoryID) (*v1.DirectoryList, error)
 }
 
 // RootClient allows for instantiating a client
@@ -33,6 +81,18 @@ type RootClient interface {
 	Client // Embed the Client interface
 	CreateRoot(c context.Context, r *v1.CreateDirectoryRequest) (*v1.DirectoryFetch, error)
 	ListRoots(c context.Context, options ...storage.Option) (*v1.DirectoryList, error)
+	PurgeDirectory(c context.Context, id v1.DirectoryID) (*v1.DirectoryList, error)
+	// GetQuota returns the quota of the tree of the root directory.
+	GetQuota(c context.Context, root v1.DirectoryID) (*v1.RootQuota, error)
+	// SetQuota replaces the limits overridden for the tree of the root directory.
+	SetQuota(c context.Context, root v1.DirectoryID, r *v1.SetQuotaRequest) (*v1.RootQuota, error)
+	// SetSchema replaces the JSON Schema of the directory, which applies to its subtree.
+	// storage.ErrInvalidSchema is returned if the schema isn't valid.
+	SetSchema(c context.Context, id v1.DirectoryID, r *v1.SetSchemaRequest) (*v1.DirectorySchema, error)
+	// DeleteSchema removes the JSON Schema of the directory, returning the one applying instead.
+	DeleteSchema(c context.Context, id v1.DirectoryID) (*v1.DirectorySchema, error)
+	// Batch applies the provided operations atomically.
+	Batch(c context.Context, r *v1.BatchRequest) (*v1.BatchResponse, error)
 }
 
 // RawHTTP allows for instantiating a client
@@ -66,3 +126,13 @@ type Watcher interface {
 	// It returns a channel for events and a channel for errors.
 	Watch(ctx context.Context) (<-chan *v1.DirectoryEvent, <-chan error)
 }
+
+// EventContext returns a copy of ctx carrying the trace context the event was
+// published with, if any, so that handling the event joins the publisher's trace.
+func EventContext(ctx context.Context, e *v1.DirectoryEvent) context.Context {
+	if len(e.TraceContext) == 0 {
+		return ctx
+	}
+
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(e.TraceContext))
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	natsgo "github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 
 	apiv1 "github.com/infratographer/fertilesoil/api/v1"
 	clientv1 "github.com/infratographer/fertilesoil/client/v1"
@@ -39,7 +41,27 @@ func (s *subscriber) Watch(ctx context.Context) (eventsChan <-chan *apiv1.Direct
 		defer close(events)
 		defer close(errs)
 
-		_, err := s.conn.Subscribe(s.subj, func(e *apiv1.DirectoryEvent) {
+		_, err := s.conn.Subscribe(s.subj, func(m *natsgo.Msg) {
+			e := &apiv1.DirectoryEvent{}
+
+			// Messages which aren't events are skipped.
+			if err := s.conn.Enc.Decode(m.Subject, m.Data, e); err != nil {
+				return
+			}
+
+			// The trace context travels in the headers, it's kept with the
+			// event for clientv1.EventContext.
+			headers := propagation.HeaderCarrier(m.Header)
+			for _, k := range otel.GetTextMapPropagator().Fields() {
+				if v := headers.Get(k); v != "" {
+					if e.TraceContext == nil {
+						e.TraceContext = map[string]string{}
+					}
+
+					e.TraceContext[k] = v
+				}
+			}
+
 			select {
 			case <-ctx.Done():
 				return
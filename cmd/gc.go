@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+	"go.uber.org/zap"
+
+	"github.com/infratographer/fertilesoil/notifier"
+	"github.com/infratographer/fertilesoil/notifier/nats"
+	natsutils "github.com/infratographer/fertilesoil/notifier/nats/utils"
+	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/gc"
+	sn "github.com/infratographer/fertilesoil/storage/notifier"
+)
+
+// leaseIntervals is the count of collection intervals the garbage collection
+// lease lasts for, so it's renewed before expiring.
+const leaseIntervals = 2
+
+var errInvalidGCInterval = errors.New("the garbage collection interval must be positive")
+
+// gcCmd represents the gc command.
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Purges the directories soft deleted for longer than the retention",
+	Long: `Permanently removes the directories which have been soft deleted for longer
+than the retention, along with their descendants, and publishes a hard
+deletion event for each of them. With --gc-dry-run, the directories which
+would be purged are only logged.`,
+	// The flags are shared with serve, whose bindings take precedence otherwise.
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindGCFlags(viper.GetViper(), cmd.Flags())
+	},
+	RunE: runGC,
+}
+
+//nolint:gochecknoinits // This is encouraged by cobra
+func init() {
+	rootCmd.AddCommand(gcCmd)
+
+	registerGCFlags(viper.GetViper(), gcCmd.Flags())
+}
+
+// registerGCFlags registers the flags setting how deleted directories are collected.
+func registerGCFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	flags.Duration("gc-retention", gc.DefaultRetention, "Time directories are kept for once soft deleted.")
+	flags.Int("gc-batch-size", gc.DefaultBatchSize, "Deleted directories to list at once, and purge before the next ones.")
+	flags.Bool("gc-dry-run", false, "Only log the deleted directories which would be purged.")
+
+	bindGCFlags(v, flags)
+}
+
+func bindGCFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	viperx.MustBindFlag(v, "gc.retention", flags.Lookup("gc-retention"))
+	viperx.MustBindFlag(v, "gc.batch_size", flags.Lookup("gc-batch-size"))
+	viperx.MustBindFlag(v, "gc.dry_run", flags.Lookup("gc-dry-run"))
+}
+
+// gcOptions returns the garbage collector options from the gc settings.
+func gcOptions(l *zap.Logger, v *viper.Viper) []gc.Option {
+	opts := []gc.Option{
+		gc.WithLogger(l),
+		gc.WithRetention(v.GetDuration("gc.retention")),
+		gc.WithBatchSize(v.GetInt("gc.batch_size")),
+		gc.WithInterval(v.GetDuration("gc.interval")),
+	}
+
+	if v.GetBool("gc.dry_run") {
+		opts = append(opts, gc.WithDryRun())
+	}
+
+	return opts
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	l := initLogger()
+	//nolint:errcheck // We don't care about the error here.
+	defer l.Sync()
+
+	v := viper.GetViper()
+
+	db, err := getDBConnection(v)
+	if err != nil {
+		return fmt.Errorf("failed to get db connection: %w", err)
+	}
+
+	defer db.Close()
+
+	store, err := newStorageDriver(v, db)
+	if err != nil {
+		return err
+	}
+
+	// Dry runs don't purge anything, so they don't publish events either.
+	if !v.GetBool("gc.dry_run") {
+		natconn, err := natsutils.BuildNATSConnFromArgs(v)
+		if err != nil {
+			return err
+		}
+
+		defer natconn.Close()
+
+		natjs, err := natconn.JetStream()
+		if err != nil {
+			return err
+		}
+
+		notif := nats.NewNotifier(natjs, natsutils.BuildNATSSubject(v), nats.WithLogger(l))
+		store = sn.StorageWithNotifier(store, notif, sn.WithNotifyRetrier())
+	}
+
+	if _, err := gc.NewCollector(store, gcOptions(l, v)...).RunOnce(cmd.Context()); err != nil {
+		return fmt.Errorf("garbage collection failed: %w", err)
+	}
+
+	return nil
+}
+
+// runGCLoop collects the deleted directories in the background, if enabled,
+// until the context is done. Replicas elect the one collecting through a lease
+// kept in NATS. Hard deletions are notified through the provided notifier.
+func runGCLoop(
+	ctx context.Context,
+	l *zap.Logger,
+	v *viper.Viper,
+	store storage.DirectoryAdmin,
+	notif notifier.Notifier,
+	natjs natsgo.JetStreamContext,
+) error {
+	if !v.GetBool("gc.enabled") {
+		return nil
+	}
+
+	interval := v.GetDuration("gc.interval")
+	if interval <= 0 {
+		return errInvalidGCInterval
+	}
+
+	lease, err := gc.NewNATSLease(natjs, gc.DefaultLeaseBucket, leaseIntervals*interval)
+	if err != nil {
+		return err
+	}
+
+	opts := append(gcOptions(l, v), gc.WithLease(lease))
+	collector := gc.NewCollector(sn.StorageWithNotifier(store, notif, sn.WithNotifyRetrier()), opts...)
+
+	if err := prometheus.Register(collector); err != nil {
+		return fmt.Errorf("failed to register garbage collector metrics: %w", err)
+	}
+
+	go collector.Run(ctx)
+
+	return nil
+}
@@ -6,9 +6,6 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.infratographer.com/x/crdbx"
-
-	"github.com/infratographer/fertilesoil/storage/crdb/migrations"
-	dbutils "github.com/infratographer/fertilesoil/storage/crdb/utils"
 )
 
 // migrateCmd represents the migrate command.
@@ -36,12 +33,12 @@ func migrate(cmd *cobra.Command, args []string) error {
 	l := initLogger()
 
 	// Initialize database connection
-	dbconn, err := dbutils.GetDBConnection(v, "directory", false)
+	dbconn, err := getDBConnection(v)
 	if err != nil {
 		return fmt.Errorf("failed to get db connection: %w", err)
 	}
 
 	l.Info("executing migrations")
 
-	return migrations.Migrate(dbconn)
+	return migrateStorage(v, dbconn)
 }
@@ -25,8 +25,9 @@ import (
 	"github.com/infratographer/fertilesoil/internal/httpsrv/treemanager"
 	"github.com/infratographer/fertilesoil/notifier/nats"
 	natsutils "github.com/infratographer/fertilesoil/notifier/nats/utils"
-	"github.com/infratographer/fertilesoil/storage/crdb/driver"
 	dbutils "github.com/infratographer/fertilesoil/storage/crdb/utils"
+	"github.com/infratographer/fertilesoil/storage/gc"
+	"github.com/infratographer/fertilesoil/storage/quota"
 )
 
 // serveCmd represents the treemanager command.
@@ -52,8 +53,8 @@ func init() {
 	ginjwt.RegisterViperOIDCFlags(v, serveCmd)
 	loggingx.MustViperFlags(v, serveCmd.Flags())
 	natsutils.RegisterNATSArgs(v, serveCmd.Flags())
+	registerTracingFlags(v, serveCmd.Flags())
 
-	// TODO(jaosorior): Add tracing
 	// TODO(jaosorior): Add metrics
 	// TODO(jaosorior): Add TLS flags
 
@@ -76,6 +77,35 @@ func init() {
 	flags.StringSlice("trusted-proxies", []string{}, "Proxy ips to trust X-Forwarded-* headers from")
 	viperx.MustBindFlag(v, "server.trusted-proxies", flags.Lookup("trusted-proxies"))
 
+	// server admin scopes
+	flags.StringSlice("admin-scopes", treemanager.DefaultTreeManagerAdminScopes,
+		"Scopes required to access admin-only endpoints")
+	viperx.MustBindFlag(v, "server.admin-scopes", flags.Lookup("admin-scopes"))
+
+	// storage cache size
+	flags.Int("storage-cache-size", 0,
+		"Directories and ancestor chains to cache, kept coherent through the NATS events. 0 disables the cache.")
+	viperx.MustBindFlag(v, "storage.cache.size", flags.Lookup("storage-cache-size"))
+
+	// default quotas of the trees
+	flags.Int("quota-max-depth", 0, "Levels of directories allowed below a root. 0 means no limit.")
+	viperx.MustBindFlag(v, "storage.quota.max-depth", flags.Lookup("quota-max-depth"))
+
+	flags.Int("quota-max-children", 0, "Direct children allowed in each directory. 0 means no limit.")
+	viperx.MustBindFlag(v, "storage.quota.max-children", flags.Lookup("quota-max-children"))
+
+	flags.Int("quota-max-descendants", 0, "Directories allowed below a root. 0 means no limit.")
+	viperx.MustBindFlag(v, "storage.quota.max-descendants", flags.Lookup("quota-max-descendants"))
+
+	// garbage collection of deleted directories
+	registerGCFlags(v, flags)
+
+	flags.Bool("gc", false, "Purge the directories soft deleted for longer than the retention in the background.")
+	viperx.MustBindFlag(v, "gc.enabled", flags.Lookup("gc"))
+
+	flags.Duration("gc-interval", gc.DefaultInterval, "Time between two collections of deleted directories.")
+	viperx.MustBindFlag(v, "gc.interval", flags.Lookup("gc-interval"))
+
 	// audit log path
 	flags.String("audit-log-path", "/app-audit/audit.log", "Path to the audit log file")
 	viperx.MustBindFlag(v, "audit.log.path", flags.Lookup("audit-log-path"))
@@ -96,13 +126,40 @@ func serverRunE(cmd *cobra.Command, args []string) error {
 		cancel()
 	}()
 
-	// TODO(jaosorior): Add tracing
-	db, dberr := dbutils.GetDBConnection(viper.GetViper(), "directory", false)
+	v := viper.GetViper()
+
+	// The tracer provider is set up before the server and the database
+	// connection, so they pick it up.
+	shutdownTracing, err := initTracing(v, l, "treemanager")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			l.Error("failed to shut down tracing", zap.Error(err))
+		}
+	}()
+
+	db, dberr := getDBConnection(v)
 	if dberr != nil {
 		return dberr
 	}
 
-	v := viper.GetViper()
+	store, err := newStorageDriver(v, db)
+	if err != nil {
+		return err
+	}
+
+	store, err = routeStorage(v, store)
+	if err != nil {
+		return err
+	}
+
+	store, err = cacheStorage(ctx, l, v, store)
+	if err != nil {
+		return err
+	}
 
 	auditLogPath := v.GetString("audit.log.path")
 	fd, err := helpers.OpenAuditLogFileUntilSuccessWithContext(ctx, auditLogPath)
@@ -133,9 +190,11 @@ func serverRunE(cmd *cobra.Command, args []string) error {
 
 	initNats(l, v, notif)
 
-	authConfig := buildAuthConfig(v)
+	if err := runGCLoop(ctx, l, v, store, notif, natjs); err != nil {
+		return err
+	}
 
-	store := driver.NewDirectoryDriver(db, dbutils.WithStorageOptions(v)...)
+	authConfig := buildAuthConfig(v)
 
 	s := treemanager.NewServer(
 		l,
@@ -149,6 +208,12 @@ func serverRunE(cmd *cobra.Command, args []string) error {
 		treemanager.WithStorageDriver(store),
 		treemanager.WithAuditMiddleware(mdw),
 		treemanager.WithAuthConfig(authConfig),
+		treemanager.WithAdminScopes(v.GetStringSlice("server.admin-scopes")),
+		treemanager.WithQuotaLimits(quota.Limits{
+			MaxDepth:       v.GetInt("storage.quota.max-depth"),
+			MaxChildren:    v.GetInt("storage.quota.max-children"),
+			MaxDescendants: v.GetInt("storage.quota.max-descendants"),
+		}),
 	)
 
 	go func() {
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+	"go.uber.org/zap"
+
+	clientv1nats "github.com/infratographer/fertilesoil/client/v1/nats"
+	natsutils "github.com/infratographer/fertilesoil/notifier/nats/utils"
+	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/cache"
+	crdbdriver "github.com/infratographer/fertilesoil/storage/crdb/driver"
+	crdbmigrations "github.com/infratographer/fertilesoil/storage/crdb/migrations"
+	crdbutils "github.com/infratographer/fertilesoil/storage/crdb/utils"
+	pgdriver "github.com/infratographer/fertilesoil/storage/postgres/driver"
+	pgmigrations "github.com/infratographer/fertilesoil/storage/postgres/migrations"
+	pgutils "github.com/infratographer/fertilesoil/storage/postgres/utils"
+	"github.com/infratographer/fertilesoil/storage/router"
+	sqlitedriver "github.com/infratographer/fertilesoil/storage/sqlite/driver"
+	sqlitemigrations "github.com/infratographer/fertilesoil/storage/sqlite/migrations"
+	sqliteutils "github.com/infratographer/fertilesoil/storage/sqlite/utils"
+)
+
+// Storage drivers which can be selected with --storage-driver.
+const (
+	storageDriverCRDB     = "crdb"
+	storageDriverPostgres = "postgres"
+	storageDriverSQLite   = "sqlite"
+)
+
+var (
+	errUnknownStorageDriver = errors.New("unknown storage driver")
+	errFastReadsUnsupported = errors.New("fast reads are only supported by the crdb storage driver")
+	errReplicaUnsupported   = errors.New("replicas are only supported by the crdb storage driver")
+	errReplicaWithCache     = errors.New("the storage cache can't be used with a replica, as it could keep stale reads")
+)
+
+//nolint:gochecknoinits // This is encouraged by cobra
+func init() {
+	v := viper.GetViper()
+	flags := rootCmd.PersistentFlags()
+
+	flags.String("storage-driver", storageDriverCRDB, "Storage driver to use, either crdb, postgres or sqlite.")
+	viperx.MustBindFlag(v, "storage.driver", flags.Lookup("storage-driver"))
+
+	flags.String("sqlite-dsn", sqliteutils.DefaultDSN, "SQLite database file or file: URI, used by the sqlite driver.")
+	viperx.MustBindFlag(v, "sqlite.dsn", flags.Lookup("sqlite-dsn"))
+
+	pgutils.MustViperEnv(v)
+}
+
+// getDBConnection opens a connection to the database of the selected storage driver.
+func getDBConnection(v *viper.Viper) (*sql.DB, error) {
+	switch driver := v.GetString("storage.driver"); driver {
+	case storageDriverCRDB:
+		return crdbutils.GetDBConnection(v, "directory", v.GetBool("tracing.enabled"))
+	case storageDriverPostgres:
+		return pgutils.GetDBConnection(v, "directory")
+	case storageDriverSQLite:
+		return sqliteutils.GetDBConnection(v)
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownStorageDriver, driver)
+	}
+}
+
+// newStorageDriver returns the selected storage driver, backed by the provided database.
+func newStorageDriver(v *viper.Viper, db *sql.DB) (storage.DirectoryAdmin, error) {
+	switch driver := v.GetString("storage.driver"); driver {
+	case storageDriverCRDB:
+		return crdbdriver.NewDirectoryDriver(db, crdbutils.WithStorageOptions(v)...), nil
+	case storageDriverPostgres:
+		if v.GetBool("storage.fast_reads") {
+			return nil, errFastReadsUnsupported
+		}
+
+		return pgdriver.NewDirectoryDriver(db, pgutils.WithStorageOptions(v)...), nil
+	case storageDriverSQLite:
+		if v.GetBool("storage.fast_reads") {
+			return nil, errFastReadsUnsupported
+		}
+
+		return sqlitedriver.NewDirectoryDriver(db, sqliteutils.WithStorageOptions(v)...), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownStorageDriver, driver)
+	}
+}
+
+// routeStorage sends the reads to the replica database, if one was configured,
+// while the writes go to the provided storage driver.
+func routeStorage(v *viper.Viper, store storage.DirectoryAdmin) (storage.DirectoryAdmin, error) {
+	if !crdbutils.HasReplica(v) {
+		return store, nil
+	}
+
+	if v.GetString("storage.driver") != storageDriverCRDB {
+		return nil, errReplicaUnsupported
+	}
+
+	if v.GetInt("storage.cache.size") > 0 {
+		return nil, errReplicaWithCache
+	}
+
+	db, err := crdbutils.GetReplicaDBConnection(v, "directory", v.GetBool("tracing.enabled"))
+	if err != nil {
+		return nil, err
+	}
+
+	replica := crdbdriver.NewDirectoryDriver(db, crdbutils.WithReplicaStorageOptions()...)
+
+	return router.StorageWithReplica(store, replica), nil
+}
+
+// migrateStorage runs the migrations of the selected storage driver.
+func migrateStorage(v *viper.Viper, db *sql.DB) error {
+	switch driver := v.GetString("storage.driver"); driver {
+	case storageDriverCRDB:
+		return crdbmigrations.Migrate(db)
+	case storageDriverPostgres:
+		return pgmigrations.Migrate(db)
+	case storageDriverSQLite:
+		return sqlitemigrations.Migrate(db)
+	default:
+		return fmt.Errorf("%w: %s", errUnknownStorageDriver, driver)
+	}
+}
+
+// cacheStorage wraps the storage driver with a cache, if enabled.
+// The cache is kept coherent with the other replicas by watching the
+// events they publish, until the context is done.
+func cacheStorage(
+	ctx context.Context,
+	l *zap.Logger,
+	v *viper.Viper,
+	store storage.DirectoryAdmin,
+) (storage.DirectoryAdmin, error) {
+	size := v.GetInt("storage.cache.size")
+	if size <= 0 {
+		return store, nil
+	}
+
+	// The watcher closes its connection once the context is done,
+	// so it doesn't share the one events are published with.
+	conn, err := natsutils.BuildNATSConnFromArgs(v)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := clientv1nats.NewSubscriber(conn, natsutils.BuildNATSSubject(v)+".*")
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	cached := cache.StorageWithCache(store, cache.WithSize(size))
+
+	if err := prometheus.Register(cached); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to register storage cache metrics: %w", err)
+	}
+
+	go func() {
+		if err := cached.Watch(ctx, w); err != nil {
+			l.Error("storage cache disabled, failed watching events", zap.Error(err))
+		}
+	}()
+
+	return cached, nil
+}
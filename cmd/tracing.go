@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/otelx"
+	"go.infratographer.com/x/viperx"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+)
+
+// registerTracingFlags registers the tracing flags. On top of the ones
+// provided by otelx, the OTLP endpoint may be set with flags as well as
+// with the TRACING_OTLP_* environment variables.
+func registerTracingFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	otelx.MustViperFlags(v, flags)
+
+	flags.String("tracing-otlp-endpoint", "", "OTLP collector endpoint, used by the otlphttp and otlpgrpc providers")
+	viperx.MustBindFlag(v, "tracing.otlp.endpoint", flags.Lookup("tracing-otlp-endpoint"))
+
+	flags.Bool("tracing-otlp-insecure", false, "Disable TLS when connecting to the OTLP collector")
+	viperx.MustBindFlag(v, "tracing.otlp.insecure", flags.Lookup("tracing-otlp-insecure"))
+}
+
+// initTracing sets up the global tracer provider from the tracing settings.
+// The returned function flushes the pending spans and shuts the provider down.
+func initTracing(v *viper.Viper, l *zap.Logger, appName string) (func(context.Context) error, error) {
+	var cfg struct {
+		Tracing otelx.Config `mapstructure:"tracing"`
+	}
+
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to read tracing settings: %w", err)
+	}
+
+	if err := otelx.InitTracer(cfg.Tracing, appName, l.Sugar()); err != nil {
+		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+
+	tp, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	if !ok {
+		// Tracing is disabled, there's nothing to shut down.
+		return func(context.Context) error { return nil }, nil
+	}
+
+	return tp.Shutdown, nil
+}
@@ -0,0 +1,254 @@
+package treemanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/internal/httpsrv/common"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+const (
+	// maxBatchOperations is the maximum amount of operations in a single batch.
+	maxBatchOperations = 100
+
+	// maxBatchGetIDs is the maximum amount of directories fetched in a single request.
+	maxBatchGetIDs = 100
+
+	batchGetDirectoriesPath = "/api/v1/directories:batchGet"
+)
+
+// Operations supported in a batch.
+const (
+	batchOpCreate = "create"
+	batchOpUpdate = "update"
+	batchOpDelete = "delete"
+)
+
+// errInvalidBatchOperation is returned when an operation of a batch can't be applied as requested.
+var errInvalidBatchOperation = errors.New("invalid batch operation")
+
+// batchOperationError is returned when an operation of a batch fails.
+type batchOperationError struct {
+	index int
+	err   error
+}
+
+func (e *batchOperationError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.index, e.err)
+}
+
+func (e *batchOperationError) Unwrap() error {
+	return e.err
+}
+
+// batchWrite applies an ordered list of operations within a single transaction.
+// Notifications are only sent once all the operations have been committed.
+func batchWrite(s *common.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req v1.BatchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if len(req.Operations) == 0 || len(req.Operations) > maxBatchOperations {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("a batch must have between 1 and %d operations", maxBatchOperations),
+			})
+			return
+		}
+
+		var results []v1.BatchResult
+
+		err := s.T.WithTx(c, func(tx storage.DirectoryAdmin) error {
+			results = make([]v1.BatchResult, len(req.Operations))
+
+			for i := range req.Operations {
+				res, err := applyBatchOperation(c, tx, &req.Operations[i], results[:i])
+				if err != nil {
+					return &batchOperationError{index: i, err: err}
+				}
+
+				results[i] = *res
+			}
+
+			return nil
+		})
+		if err != nil {
+			outputBatchError(s, c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, &v1.BatchResponse{
+			Version: v1.APIVersion,
+			Results: results,
+		})
+	}
+}
+
+// applyBatchOperation applies a single operation of a batch.
+// The results of the previous operations are used to resolve parent indexes.
+func applyBatchOperation(
+	ctx context.Context,
+	tx storage.DirectoryAdmin,
+	op *v1.BatchOperation,
+	previous []v1.BatchResult,
+) (*v1.BatchResult, error) {
+	switch op.Op {
+	case batchOpCreate:
+		return applyBatchCreate(ctx, tx, op, previous)
+	case batchOpUpdate:
+		return applyBatchUpdate(ctx, tx, op)
+	case batchOpDelete:
+		if op.Id == nil {
+			return nil, fmt.Errorf("%w: id is required", errInvalidBatchOperation)
+		}
+
+		affected, err := tx.DeleteDirectory(ctx, *op.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		affectedIDs := make([]v1.DirectoryID, len(affected))
+
+		for i, d := range affected {
+			affectedIDs[i] = d.Id
+		}
+
+		return &v1.BatchResult{
+			Op:       op.Op,
+			Affected: &affectedIDs,
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", errInvalidBatchOperation, op.Op)
+	}
+}
+
+func applyBatchCreate(
+	ctx context.Context,
+	tx storage.DirectoryAdmin,
+	op *v1.BatchOperation,
+	previous []v1.BatchResult,
+) (*v1.BatchResult, error) {
+	if op.Name == nil || *op.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", errInvalidBatchOperation)
+	}
+
+	parent := op.Parent
+
+	if op.ParentIndex != nil {
+		idx := *op.ParentIndex
+		if idx < 0 || idx >= len(previous) || previous[idx].Op != batchOpCreate {
+			return nil, fmt.Errorf("%w: parentIndex must refer to an earlier create operation", errInvalidBatchOperation)
+		}
+
+		parent = &previous[idx].Directory.Id
+	}
+
+	d := &v1.Directory{
+		Name:     *op.Name,
+		Metadata: op.Metadata,
+		Parent:   parent,
+	}
+
+	var (
+		created *v1.Directory
+		err     error
+	)
+
+	if parent == nil {
+		created, err = tx.CreateRoot(ctx, d)
+	} else {
+		if _, err = tx.GetDirectory(ctx, *parent); errors.Is(err, storage.ErrDirectoryNotFound) {
+			return nil, fmt.Errorf("%w: parent directory not found", errInvalidBatchOperation)
+		} else if err != nil {
+			return nil, err
+		}
+
+		created, err = tx.CreateDirectory(ctx, d)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.BatchResult{
+		Op:        op.Op,
+		Directory: created,
+	}, nil
+}
+
+func applyBatchUpdate(ctx context.Context, tx storage.DirectoryAdmin, op *v1.BatchOperation) (*v1.BatchResult, error) {
+	if op.Id == nil {
+		return nil, fmt.Errorf("%w: id is required", errInvalidBatchOperation)
+	}
+
+	current, err := tx.GetDirectory(ctx, *op.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Changes are made on a copy, so the directory returned
+	// by the storage is left untouched if the update fails.
+	d := *current
+
+	if op.Name != nil && *op.Name != "" {
+		d.Name = *op.Name
+	}
+
+	if op.Metadata != nil {
+		d.Metadata = op.Metadata
+	}
+
+	if op.Revision != nil {
+		err = tx.UpdateDirectoryIfRevision(ctx, &d, *op.Revision)
+	} else {
+		err = tx.UpdateDirectory(ctx, &d)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.BatchResult{
+		Op:        op.Op,
+		Directory: &d,
+	}, nil
+}
+
+func outputBatchError(s *common.Server, c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errInvalidBatchOperation):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, storage.ErrDirectoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, storage.ErrRevisionConflict):
+		c.JSON(http.StatusPreconditionFailed, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, storage.ErrDirectoryNameConflict):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		outputQuotaExceeded(c, err)
+	case errors.Is(err, storage.ErrInvalidMetadata):
+		outputInvalidMetadata(c, err)
+	default:
+		s.L.Error("error applying batch", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/infratographer/fertilesoil/notifier"
 	"github.com/infratographer/fertilesoil/notifier/noop"
 	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/quota"
 )
 
 type treeManagerConfig struct {
@@ -23,6 +24,8 @@ type treeManagerConfig struct {
 	auditMdw        *ginaudit.Middleware
 	authConfig      *ginjwt.AuthConfig
 	trustedProxies  []string
+	adminScopes     []string
+	quotaLimits     *quota.Limits
 }
 
 type Option func(*treeManagerConfig)
@@ -101,6 +104,22 @@ func WithTrustedProxies(proxies []string) Option {
 	}
 }
 
+// WithAdminScopes sets the scopes required to access admin-only endpoints.
+func WithAdminScopes(scopes []string) Option {
+	return func(c *treeManagerConfig) {
+		c.adminScopes = scopes
+	}
+}
+
+// WithQuotaLimits enforces quotas on the trees, with the provided limits as
+// defaults which may be overridden for each of them. It also serves the
+// admin endpoints to read and set the overrides.
+func WithQuotaLimits(l quota.Limits) Option {
+	return func(c *treeManagerConfig) {
+		c.quotaLimits = &l
+	}
+}
+
 func (c *treeManagerConfig) apply(opts ...Option) {
 	for _, opt := range opts {
 		opt(c)
@@ -17,5 +17,10 @@ const (
 	DefaultTreeManagerShutdownTimeout = 5 * time.Second
 )
 
-// DefaultTreeManagerNotifier is the default notifier for the TreeManager.
-var DefaultTreeManagerNotifier = noop.NewNotifier()
+var (
+	// DefaultTreeManagerNotifier is the default notifier for the TreeManager.
+	DefaultTreeManagerNotifier = noop.NewNotifier()
+	// DefaultTreeManagerAdminScopes are the default scopes required
+	// to access admin-only endpoints of the TreeManager.
+	DefaultTreeManagerAdminScopes = []string{"directories:admin"}
+)
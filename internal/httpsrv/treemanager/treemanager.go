@@ -3,10 +3,15 @@ package treemanager
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/metal-toolbox/auditevent/ginaudit"
@@ -17,6 +22,9 @@ import (
 	"github.com/infratographer/fertilesoil/internal/httpsrv/common"
 	"github.com/infratographer/fertilesoil/storage"
 	sn "github.com/infratographer/fertilesoil/storage/notifier"
+	"github.com/infratographer/fertilesoil/storage/quota"
+	"github.com/infratographer/fertilesoil/storage/router"
+	"github.com/infratographer/fertilesoil/storage/schema"
 )
 
 func NewServer(
@@ -30,10 +38,18 @@ func NewServer(
 		debug:           DefaultTreeManagerDebug,
 		shutdownTimeout: DefaultTreeManagerShutdownTimeout,
 		notif:           DefaultTreeManagerNotifier,
+		adminScopes:     DefaultTreeManagerAdminScopes,
 	}
 	cfg.apply(opts...)
 
-	store := sn.StorageWithNotifier(cfg.storageDriver, cfg.notif, sn.WithNotifyRetrier())
+	store := cfg.storageDriver
+	store = schema.StorageWithSchemas(store)
+
+	if cfg.quotaLimits != nil {
+		store = quota.StorageWithQuotas(store, quota.WithLimits(*cfg.quotaLimits))
+	}
+
+	store = sn.StorageWithNotifier(store, cfg.notif, sn.WithNotifyRetrier())
 
 	s := common.NewServer(
 		logger,
@@ -47,7 +63,7 @@ func NewServer(
 		cfg.trustedProxies,
 	)
 
-	s.SetHandler(newHandler(logger, s, cfg.auditMdw, cfg.authConfig))
+	s.SetHandler(newHandler(logger, s, cfg.auditMdw, cfg.authConfig, cfg.adminScopes, cfg.quotaLimits))
 
 	return s
 }
@@ -57,12 +73,26 @@ func newHandler(
 	s *common.Server,
 	auditMdw *ginaudit.Middleware,
 	authConfig *ginjwt.AuthConfig,
+	adminScopes []string,
+	quotaLimits *quota.Limits,
 ) *gin.Engine {
 	r, err := s.DefaultEngine(logger)
 	if err != nil {
 		logger.Fatal("failed to initialize route engine", zap.Error(err))
 	}
 
+	// Handlers pass the gin context to the storage, which then
+	// has access to the values set on the request context.
+	r.ContextWithFallback = true
+
+	// Paths may be used in place of ids, percent-encoded as a single segment,
+	// so routes are matched before the path is unescaped.
+	r.UseRawPath = true
+
+	// Reads made after a write within a request see the write,
+	// should they be sent to a replica otherwise.
+	r.Use(withStorageSession())
+
 	if auditMdw != nil {
 		r.Use(auditMdw.Audit())
 	}
@@ -80,20 +110,92 @@ func newHandler(
 	r.GET("/api/v1", apiVersionHandler)
 
 	r.GET("/api/v1/roots", authMW.AuthRequired(), listRoots(s))
-	r.POST("/api/v1/roots", authMW.AuthRequired(), createRootDirectory(s))
+	r.POST("/api/v1/roots", authMW.AuthRequired(), withActor(), createRootDirectory(s))
+
+	if quotaLimits != nil {
+		r.GET("/api/v1/roots/:id/quota",
+			authMW.AuthRequired(), authMW.RequiredScopes(adminScopes), getRootQuota(s, *quotaLimits))
+		r.PUT("/api/v1/roots/:id/quota",
+			authMW.AuthRequired(), authMW.RequiredScopes(adminScopes), withActor(), setRootQuota(s, *quotaLimits))
+	}
 
-	r.GET("/api/v1/directories/:id", authMW.AuthRequired(), getDirectory(s))
-	r.POST("/api/v1/directories/:id", authMW.AuthRequired(), createDirectory(s))
-	r.PATCH("/api/v1/directories/:id", authMW.AuthRequired(), updateDirectory(s))
-	r.DELETE("/api/v1/directories/:id", authMW.AuthRequired(), deleteDirectory(s))
+	r.GET("/api/v1/paths/*path", authMW.AuthRequired(), getDirectory(s, "path"))
+	r.GET("/api/v1/directories/:id", authMW.AuthRequired(), getDirectory(s, "id"))
+	r.POST("/api/v1/directories/:id", authMW.AuthRequired(), withActor(), createDirectory(s))
+	r.PATCH("/api/v1/directories/:id", authMW.AuthRequired(), withActor(), updateDirectory(s))
+	r.DELETE("/api/v1/directories/:id", authMW.AuthRequired(), withActor(), deleteDirectory(s))
+	r.POST("/api/v1/directories/:id/move", authMW.AuthRequired(), withActor(), moveDirectory(s))
+	r.POST("/api/v1/directories/:id/restore", authMW.AuthRequired(), withActor(), restoreDirectory(s))
+	r.POST("/api/v1/directories/:id/purge",
+		authMW.AuthRequired(), authMW.RequiredScopes(adminScopes), withActor(), purgeDirectory(s))
 
 	r.GET("/api/v1/directories/:id/children", authMW.AuthRequired(), listChildren(s))
+	r.GET("/api/v1/directories/:id/tree", authMW.AuthRequired(), getDirectoryTree(s))
+	r.GET("/api/v1/directories/:id/stats", authMW.AuthRequired(), getDirectoryStats(s))
+	r.GET("/api/v1/directories/:id/search", authMW.AuthRequired(), searchDirectories(s))
+	r.GET("/api/v1/directories/:id/history", authMW.AuthRequired(), listDirectoryHistory(s))
+	r.GET("/api/v1/directories/:id/schema", authMW.AuthRequired(), getDirectorySchema(s))
+	r.PUT("/api/v1/directories/:id/schema",
+		authMW.AuthRequired(), authMW.RequiredScopes(adminScopes), withActor(), setDirectorySchema(s))
+	r.DELETE("/api/v1/directories/:id/schema",
+		authMW.AuthRequired(), authMW.RequiredScopes(adminScopes), withActor(), deleteDirectorySchema(s))
 	r.GET("/api/v1/directories/:id/parents", authMW.AuthRequired(), listParents(s))
 	r.GET("/api/v1/directories/:id/parents/:until", authMW.AuthRequired(), listParentsUntil(s))
 
+	r.POST("/api/v1/batch", authMW.AuthRequired(), withActor(), batchWrite(s))
+
+	// The router can't tell custom methods like "directories:batchGet" apart
+	// from "directories/:id", so they are dispatched when no route matches.
+	r.NoRoute(customMethod(http.MethodPost, batchGetDirectoriesPath),
+		authMW.AuthRequired(), batchGetDirectories(s))
+
 	return r
 }
 
+// withActor sets the actor making the request on the request context,
+// so the storage records it in the history of the changed directories.
+// The subject of the token is used if authenticated, the User header otherwise.
+func withActor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := c.GetString("jwt.subject")
+		if actor == "" {
+			actor = c.GetString("current_actor")
+		}
+
+		if actor != "" {
+			c.Request = c.Request.WithContext(storage.WithActor(c.Request.Context(), actor))
+		}
+	}
+}
+
+// withStorageSession starts a storage session for the request, see router.WithSession.
+// Requests which may write read from the primary from the start, so the writes
+// are based on up to date directories.
+func withStorageSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		switch {
+		case c.Request.Method == http.MethodGet, c.Request.URL.Path == batchGetDirectoriesPath:
+			ctx = router.WithSession(ctx)
+		default:
+			ctx = router.WithPrimary(ctx)
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+	}
+}
+
+// customMethod only lets the request through if it is for the provided
+// method and path, responding as for any unknown route otherwise.
+func customMethod(method, path string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != method || c.Request.URL.Path != path {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "invalid request - route not found"})
+		}
+	}
+}
+
 func apiVersionHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		// NOTE(jaosorior): This is currently to v1.
@@ -114,7 +216,12 @@ func listRoots(s *common.Server) gin.HandlerFunc {
 		}
 
 		roots, err := s.T.ListRoots(c, options...)
-		if err != nil {
+		if errors.Is(err, storage.ErrAsOfOutOfRange) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		} else if err != nil {
 			s.L.Error("error listing roots", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "internal server error",
@@ -122,15 +229,35 @@ func listRoots(s *common.Server) gin.HandlerFunc {
 			return
 		}
 
-		pagination := paginationResponse(c, len(roots), options)
+		pagination, err := paginationResponse(c, s, roots, options, nil)
+		if err != nil {
+			s.L.Error("error building pagination", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
 
-		c.JSON(http.StatusOK, &v1.DirectoryList{
+		dirList := &v1.DirectoryList{
 			Version:     v1.APIVersion,
 			Page:        pagination.Page,
 			PageSize:    pagination.PageSize,
 			Links:       pagination.Links,
 			Directories: roots,
+		}
+
+		err = setTotal(c, dirList, options, func(options ...storage.Option) (int, error) {
+			return s.T.CountRoots(c, options...)
 		})
+		if err != nil {
+			s.L.Error("error counting roots", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, dirList)
 	}
 }
 
@@ -143,8 +270,9 @@ func createRootDirectory(s *common.Server) gin.HandlerFunc {
 		}
 
 		d := v1.Directory{
-			Name:     req.Name,
-			Metadata: req.Metadata,
+			Name:               req.Name,
+			Metadata:           req.Metadata,
+			UniqueSiblingNames: req.UniqueSiblingNames != nil && *req.UniqueSiblingNames,
 		}
 
 		rd, err := s.T.CreateRoot(c, &d)
@@ -154,14 +282,223 @@ func createRootDirectory(s *common.Server) gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusCreated, &v1.DirectoryFetch{
+		setETag(c, rd)
+		c.JSON(http.StatusCreated, directoryFetch(c, s, rd))
+	}
+}
+
+// getRootQuota returns the quota overrides of a root directory,
+// along with the limits in effect in its tree.
+func getRootQuota(s *common.Server, defaults quota.Limits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := directoryIDFromReference(c, s.T, c.Param("id"))
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
+
+		q, err := s.T.GetQuota(c, id)
+		if err != nil {
+			outputQuotaError(s, c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, rootQuotaResponse(id, q, defaults))
+	}
+}
+
+// setRootQuota replaces the quota overrides of a root directory.
+func setRootQuota(s *common.Server, defaults quota.Limits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := directoryIDFromReference(c, s.T, c.Param("id"))
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
+
+		var req v1.SetQuotaRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		q := &v1.DirectoryQuota{
+			MaxDepth:       req.MaxDepth,
+			MaxChildren:    req.MaxChildren,
+			MaxDescendants: req.MaxDescendants,
+		}
+
+		for _, limit := range []*int{q.MaxDepth, q.MaxChildren, q.MaxDescendants} {
+			if limit != nil && *limit < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": errNegativeLimit.Error(),
+				})
+				return
+			}
+		}
+
+		if err := s.T.SetQuota(c, id, q); err != nil {
+			outputQuotaError(s, c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, rootQuotaResponse(id, q, defaults))
+	}
+}
+
+func rootQuotaResponse(id v1.DirectoryID, q *v1.DirectoryQuota, defaults quota.Limits) *v1.RootQuota {
+	return &v1.RootQuota{
+		Version:   v1.APIVersion,
+		Root:      id,
+		Quota:     *q,
+		Effective: defaults.Override(q).Quota(),
+	}
+}
+
+func outputQuotaError(s *common.Server, c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrDirectoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "directory not found",
+		})
+	case errors.Is(err, storage.ErrNotRoot):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		s.L.Error("error accessing quota", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+	}
+}
+
+// getDirectorySchema returns the schema applying to the metadata of a directory.
+func getDirectorySchema(s *common.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := directoryIDFromReference(c, s.T, c.Param("id"))
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
+
+		outputDirectorySchema(s, c, id)
+	}
+}
+
+// setDirectorySchema replaces the schema of a directory.
+func setDirectorySchema(s *common.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := directoryIDFromReference(c, s.T, c.Param("id"))
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
+
+		var req v1.SetSchemaRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if req.Schema == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errMissingSchema.Error(),
+			})
+			return
+		}
+
+		doc, err := json.Marshal(req.Schema)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := s.T.SetSchema(c, id, doc); err != nil {
+			outputSchemaError(s, c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, &v1.DirectorySchema{
 			Version:   v1.APIVersion,
-			Directory: *rd,
+			Directory: id,
+			Source:    &id,
+			Schema:    &req.Schema,
+		})
+	}
+}
+
+// deleteDirectorySchema removes the schema of a directory,
+// and returns the one applying instead, if any.
+func deleteDirectorySchema(s *common.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := directoryIDFromReference(c, s.T, c.Param("id"))
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
+
+		if err := s.T.DeleteSchema(c, id); err != nil {
+			outputSchemaError(s, c, err)
+			return
+		}
+
+		outputDirectorySchema(s, c, id)
+	}
+}
+
+// outputDirectorySchema responds with the schema applying to the directory.
+// The schema and its source are left unset if none applies.
+func outputDirectorySchema(s *common.Server, c *gin.Context, id v1.DirectoryID) {
+	resp := &v1.DirectorySchema{
+		Version:   v1.APIVersion,
+		Directory: id,
+	}
+
+	ms, err := s.T.GetSchema(c, id)
+	if err != nil && !errors.Is(err, storage.ErrSchemaNotFound) {
+		outputSchemaError(s, c, err)
+		return
+	}
+
+	if ms != nil {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(ms.Schema, &doc); err != nil {
+			outputSchemaError(s, c, err)
+			return
+		}
+
+		resp.Source = &ms.Source
+		resp.Schema = &doc
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func outputSchemaError(s *common.Server, c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrDirectoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "directory not found",
+		})
+	case errors.Is(err, storage.ErrSchemaNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, storage.ErrInvalidSchema):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		s.L.Error("error accessing schema", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
 		})
 	}
 }
 
-func getDirectory(s *common.Server) gin.HandlerFunc {
+// getDirectory returns the directory referenced by the provided route parameter.
+func getDirectory(s *common.Server, param string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		options, err := storageOptionsFromGetQuery(c)
 		if err != nil {
@@ -172,33 +509,90 @@ func getDirectory(s *common.Server) gin.HandlerFunc {
 			return
 		}
 
-		idstr := c.Param("id")
-
-		dir, err := getDirectoryFromReference(s.T, idstr, options...)
+		dir, err := getDirectoryFromReference(c, s.T, c.Param(param), options...)
 		if err != nil {
 			outputGetDirectoryError(c, err)
 			return
 		}
 
-		c.JSON(http.StatusOK, &v1.DirectoryFetch{
-			Version:   v1.APIVersion,
-			Directory: *dir,
-		})
+		setETag(c, dir)
+		c.JSON(http.StatusOK, directoryFetch(c, s, dir, options...))
 	}
 }
 
-func createDirectory(s *common.Server) gin.HandlerFunc {
+// batchGetDirectories returns the requested directories in a single call.
+// Ids which don't match any directory are listed as missing.
+func batchGetDirectories(s *common.Server) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idstr := c.Param("id")
-
-		id, err := v1.ParseDirectoryID(idstr)
+		options, err := storageOptionsFromGetQuery(c)
 		if err != nil {
+			s.L.Error("error building storage.GetOptions from GetQuery", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "bad request",
+			})
+			return
+		}
+
+		var req v1.BatchGetDirectoriesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if len(req.Ids) == 0 || len(req.Ids) > maxBatchGetIDs {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("between 1 and %d ids must be requested", maxBatchGetIDs),
+			})
+			return
+		}
+
+		dirs, err := s.T.GetDirectories(c, req.Ids, options...)
+		if errors.Is(err, storage.ErrAsOfOutOfRange) {
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "invalid id",
+				"error": err.Error(),
+			})
+			return
+		} else if err != nil {
+			s.L.Error("error getting directories", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
 			})
 			return
 		}
 
+		resp := &v1.BatchGetDirectoriesResponse{
+			Version:     v1.APIVersion,
+			Directories: make([]v1.Directory, len(dirs)),
+			Missing:     []v1.DirectoryID{},
+		}
+
+		found := make(map[v1.DirectoryID]bool, len(dirs))
+
+		for i, d := range dirs {
+			resp.Directories[i] = *d
+			found[d.Id] = true
+		}
+
+		for _, id := range req.Ids {
+			if !found[id] {
+				resp.Missing = append(resp.Missing, id)
+				// Ids requested multiple times are only reported once.
+				found[id] = true
+			}
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+func createDirectory(s *common.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := directoryIDFromReference(c, s.T, c.Param("id"))
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
+
 		var req v1.CreateDirectoryRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -228,7 +622,16 @@ func createDirectory(s *common.Server) gin.HandlerFunc {
 		}
 
 		rd, err := s.T.CreateDirectory(c, &d)
-		if err != nil {
+		if errors.Is(err, storage.ErrDirectoryNameConflict) {
+			outputNameConflict(c)
+			return
+		} else if errors.Is(err, storage.ErrQuotaExceeded) {
+			outputQuotaExceeded(c, err)
+			return
+		} else if errors.Is(err, storage.ErrInvalidMetadata) {
+			outputInvalidMetadata(c, err)
+			return
+		} else if err != nil {
 			s.L.Error("error creating directory", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "internal server error",
@@ -236,22 +639,25 @@ func createDirectory(s *common.Server) gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusCreated, &v1.DirectoryFetch{
-			Version:   v1.APIVersion,
-			Directory: *rd,
-		})
+		setETag(c, rd)
+		c.JSON(http.StatusCreated, directoryFetch(c, s, rd))
 	}
 }
 
+// updateDirectory updates the name and metadata of a directory.
+// When the If-Match header is set, the update only happens
+// if the directory is still at the requested revision.
 func updateDirectory(s *common.Server) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idstr := c.Param("id")
+		id, err := directoryIDFromReference(c, s.T, c.Param("id"))
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
 
-		id, err := v1.ParseDirectoryID(idstr)
+		revision, conditional, err := revisionFromIfMatch(c)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "invalid id",
-			})
+			outputPreconditionFailed(c)
 			return
 		}
 
@@ -267,8 +673,24 @@ func updateDirectory(s *common.Server) gin.HandlerFunc {
 				"error": "directory not found",
 			})
 			return
+		} else if err != nil {
+			s.L.Error("error getting directory", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
 		}
 
+		if conditional && d.Revision != revision {
+			outputPreconditionFailed(c)
+			return
+		}
+
+		// Changes are made on a copy, so the directory returned
+		// by the storage is left untouched if the update fails.
+		updated := *d
+		d = &updated
+
 		if req.Name != nil && *req.Name != "" {
 			d.Name = *req.Name
 		}
@@ -277,62 +699,474 @@ func updateDirectory(s *common.Server) gin.HandlerFunc {
 			d.Metadata = req.Metadata
 		}
 
-		if err := s.T.UpdateDirectory(c, d); err != nil {
-			s.L.Error("error updating directory", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "failed to update directory",
-			})
+		if conditional {
+			err = s.T.UpdateDirectoryIfRevision(c, d, revision)
+		} else {
+			err = s.T.UpdateDirectory(c, d)
+		}
+
+		switch {
+		case errors.Is(err, storage.ErrRevisionConflict):
+			outputPreconditionFailed(c)
+			return
+		case errors.Is(err, storage.ErrDirectoryNameConflict):
+			outputNameConflict(c)
+			return
+		case errors.Is(err, storage.ErrInvalidMetadata):
+			outputInvalidMetadata(c, err)
+			return
+		case errors.Is(err, storage.ErrDirectoryNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "directory not found",
+			})
+			return
+		case err != nil:
+			s.L.Error("error updating directory", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to update directory",
+			})
 			return
 		}
 
-		c.JSON(http.StatusOK, &v1.DirectoryFetch{
-			Directory: *d,
-			Version:   v1.APIVersion,
-		})
+		setETag(c, d)
+		c.JSON(http.StatusOK, directoryFetch(c, s, d))
 	}
 }
 
 func deleteDirectory(s *common.Server) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		id, err := directoryIDFromReference(c, s.T, c.Param("id"))
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
+
+		revision, conditional, err := revisionFromIfMatch(c)
+		if err != nil {
+			outputPreconditionFailed(c)
+			return
+		}
+
+		var affected []*v1.Directory
+
+		if conditional {
+			affected, err = s.T.DeleteDirectoryIfRevision(c, id, revision)
+		} else {
+			affected, err = s.T.DeleteDirectory(c, id)
+		}
+
+		if errors.Is(err, storage.ErrRevisionConflict) {
+			outputPreconditionFailed(c)
+			return
+		} else if errors.Is(err, storage.ErrDirectoryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "directory not found",
+			})
+			return
+		} else if err != nil {
+			s.L.Error("error deleting directory", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
+
+		affectedIDs := make([]v1.DirectoryID, len(affected))
+
+		for i, d := range affected {
+			affectedIDs[i] = d.Id
+		}
+
+		c.JSON(http.StatusOK, &v1.DirectoryList{
+			Directories: affectedIDs,
+			Version:     v1.APIVersion,
+		})
+	}
+}
+
+// restoreDirectory restores a soft deleted directory and the children deleted with it.
+func restoreDirectory(s *common.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := directoryIDFromReference(c, s.T, c.Param("id"), storage.WithDeletedDirectories)
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
+
+		affected, err := s.T.RestoreDirectory(c, id)
+		if errors.Is(err, storage.ErrDirectoryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "directory not found",
+			})
+			return
+		} else if errors.Is(err, storage.ErrDirectoryNotDeleted) ||
+			errors.Is(err, storage.ErrParentDirectoryDeleted) ||
+			errors.Is(err, storage.ErrDirectoryNameConflict) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		} else if err != nil {
+			s.L.Error("error restoring directory", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
+
+		affectedIDs := make([]v1.DirectoryID, len(affected))
+
+		for i, d := range affected {
+			affectedIDs[i] = d.Id
+		}
+
+		c.JSON(http.StatusOK, &v1.DirectoryList{
+			Directories: affectedIDs,
+			Version:     v1.APIVersion,
+		})
+	}
+}
+
+// purgeDirectory permanently removes a soft deleted directory and its children.
+func purgeDirectory(s *common.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := directoryIDFromReference(c, s.T, c.Param("id"), storage.WithDeletedDirectories)
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
+
+		affected, err := s.T.PurgeDirectory(c, id)
+		if errors.Is(err, storage.ErrDirectoryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "directory not found",
+			})
+			return
+		} else if errors.Is(err, storage.ErrDirectoryNotDeleted) || errors.Is(err, storage.ErrDescendantNotDeleted) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		} else if err != nil {
+			s.L.Error("error purging directory", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
+
+		affectedIDs := make([]v1.DirectoryID, len(affected))
+
+		for i, d := range affected {
+			affectedIDs[i] = d.Id
+		}
+
+		c.JSON(http.StatusOK, &v1.DirectoryList{
+			Directories: affectedIDs,
+			Version:     v1.APIVersion,
+		})
+	}
+}
+
+// moveDirectory moves a directory under a new parent.
+// Promoting a directory to root, or demoting a root directory,
+// requires the request to explicitly set root to true.
+func moveDirectory(s *common.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := directoryIDFromReference(c, s.T, c.Param("id"))
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
+
+		revision, conditional, err := revisionFromIfMatch(c)
+		if err != nil {
+			outputPreconditionFailed(c)
+			return
+		}
+
+		var req v1.MoveDirectoryRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		root := req.Root != nil && *req.Root
+
+		if req.Parent == nil && !root {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "parent is required unless promoting the directory to root",
+			})
+			return
+		}
+
+		if req.Parent != nil {
+			_, err = s.T.GetDirectory(c, *req.Parent)
+			if errors.Is(err, storage.ErrDirectoryNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "parent directory not found",
+				})
+				return
+			} else if err != nil {
+				s.L.Error("error getting directory", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "internal server error",
+				})
+				return
+			}
+		}
+
+		var moved *v1.Directory
+
+		switch {
+		case req.Parent == nil && conditional:
+			moved, _, err = s.T.PromoteToRootIfRevision(c, id, revision)
+		case req.Parent == nil:
+			moved, _, err = s.T.PromoteToRoot(c, id)
+		case root && conditional:
+			moved, err = s.T.DemoteRootIfRevision(c, id, *req.Parent, revision)
+		case root:
+			moved, err = s.T.DemoteRoot(c, id, *req.Parent)
+		case conditional:
+			moved, _, err = s.T.MoveDirectoryIfRevision(c, id, *req.Parent, revision)
+		default:
+			moved, _, err = s.T.MoveDirectory(c, id, *req.Parent)
+		}
+
+		if err != nil {
+			outputMoveDirectoryError(s, c, err)
+			return
+		}
+
+		setETag(c, moved)
+		c.JSON(http.StatusOK, directoryFetch(c, s, moved))
+	}
+}
+
+//nolint:dupl // listChildren and listParents are very similar, but not the same.
+func listChildren(s *common.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		options, err := storageOptionsFromGetQuery(c)
+		if err != nil {
+			s.L.Error("error building storage.ListOptions from GetQuery", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "bad request",
+			})
+			return
+		}
+
 		idstr := c.Param("id")
 
-		id, err := v1.ParseDirectoryID(idstr)
+		dir, err := getDirectoryFromReference(c, s.T, idstr, options...)
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
+
+		children, err := s.T.GetChildren(c, dir.Id, options...)
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		} else if err != nil {
+			s.L.Error("error listing children", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
+
+		pagination, err := paginationResponse(c, s, children, options, nil)
+		if err != nil {
+			s.L.Error("error building pagination", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
+
+		dirList := &v1.DirectoryList{
+			Version:     v1.APIVersion,
+			Page:        pagination.Page,
+			PageSize:    pagination.PageSize,
+			Links:       pagination.Links,
+			Directories: children,
+		}
+
+		err = setTotal(c, dirList, options, func(options ...storage.Option) (int, error) {
+			return s.T.CountChildren(c, dir.Id, options...)
+		})
+		if err != nil {
+			s.L.Error("error counting children", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, dirList)
+	}
+}
+
+// getDirectoryTree returns a directory along with its descendants as a nested tree.
+func getDirectoryTree(s *common.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		options, err := storageOptionsFromGetQuery(c)
+		if err != nil {
+			s.L.Error("error building storage.ListOptions from GetQuery", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "bad request",
+			})
+			return
+		}
+
+		id, err := directoryIDFromReference(c, s.T, c.Param("id"), options...)
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
+
+		tree, err := s.T.GetSubtree(c, id, options...)
+		switch {
+		case errors.Is(err, storage.ErrSubtreeTooLarge):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		case errors.Is(err, storage.ErrDirectoryNotFound):
+			outputGetDirectoryError(c, err)
+			return
+		case err != nil:
+			s.L.Error("error getting directory tree", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, &v1.DirectoryTreeFetch{
+			Version: v1.APIVersion,
+			Tree:    *tree,
+		})
+	}
+}
+
+// getDirectoryStats returns the statistics of the subtree of a directory.
+func getDirectoryStats(s *common.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		options, err := storageOptionsFromGetQuery(c)
+		if err != nil {
+			s.L.Error("error building storage.ListOptions from GetQuery", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "bad request",
+			})
+			return
+		}
+
+		id, err := directoryIDFromReference(c, s.T, c.Param("id"), options...)
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
+
+		stats, err := s.T.GetStats(c, id, options...)
+		if errors.Is(err, storage.ErrDirectoryNotFound) {
+			outputGetDirectoryError(c, err)
+			return
+		} else if err != nil {
+			s.L.Error("error getting directory stats", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, &v1.DirectoryStatsFetch{
+			Version: v1.APIVersion,
+			Stats:   *stats,
+		})
+	}
+}
+
+// searchDirectories searches the descendants of a directory by name.
+func searchDirectories(s *common.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		options, err := storageOptionsFromGetQuery(c)
+		if err != nil {
+			s.L.Error("error building storage.ListOptions from GetQuery", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "bad request",
+			})
+			return
+		}
+
+		match, err := storage.ParseNameMatch(c.Query("match"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "invalid id",
+				"error": err.Error(),
 			})
 			return
 		}
 
-		affected, err := s.T.DeleteDirectory(c, id)
-		if errors.Is(err, storage.ErrDirectoryNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "directory not found",
+		q := storage.NameQuery{Name: c.Query("q"), Match: match}
+
+		if err := q.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
 			})
 			return
-		} else if err != nil {
-			s.L.Error("error deleting directory", zap.Error(err))
+		}
+
+		dir, err := getDirectoryFromReference(c, s.T, c.Param("id"), options...)
+		if err != nil {
+			outputGetDirectoryError(c, err)
+			return
+		}
+
+		results, err := s.T.SearchDirectories(c, dir.Id, q, options...)
+		if err != nil {
+			s.L.Error("error searching directories", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "internal server error",
 			})
 			return
 		}
 
-		affectedIDs := make([]v1.DirectoryID, len(affected))
+		ids := make([]v1.DirectoryID, len(results))
+		response := &v1.DirectorySearchResults{
+			Version: v1.APIVersion,
+			Results: make([]v1.DirectorySearchResult, len(results)),
+		}
 
-		for i, d := range affected {
-			affectedIDs[i] = d.Id
+		for i, r := range results {
+			ids[i] = r.Directory.Id
+			response.Results[i] = *r
 		}
 
-		c.JSON(http.StatusOK, &v1.DirectoryList{
-			Directories: affectedIDs,
-			Version:     v1.APIVersion,
+		pagination, err := paginationResponse(c, s, ids, options, url.Values{
+			"q":     {q.Name},
+			"match": {string(q.Match)},
 		})
+		if err != nil {
+			s.L.Error("error building pagination", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
+
+		response.Page = pagination.Page
+		response.PageSize = pagination.PageSize
+		response.Links = pagination.Links
+
+		c.JSON(http.StatusOK, response)
 	}
 }
 
-//nolint:dupl // listChildren and listParents are very similar, but not the same.
-func listChildren(s *common.Server) gin.HandlerFunc {
+// listDirectoryHistory lists the revisions of a directory, newest first.
+// Revisions are paginated by page only, cursors are refused.
+func listDirectoryHistory(s *common.Server) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		options, err := storageOptionsFromGetQuery(c)
 		if err != nil {
@@ -345,34 +1179,55 @@ func listChildren(s *common.Server) gin.HandlerFunc {
 
 		idstr := c.Param("id")
 
-		dir, err := getDirectoryFromReference(s.T, idstr, options...)
+		opts := storage.BuildOptions(options)
+
+		if opts.Cursor != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errHistoryCursor.Error(),
+			})
+			return
+		}
+
+		// The history of deleted directories is kept, so it can be looked at as well.
+		dir, err := getDirectoryFromReference(c, s.T, idstr, storage.WithDeletedDirectories, storage.WithAsOf(opts.AsOf))
 		if err != nil {
 			outputGetDirectoryError(c, err)
 			return
 		}
 
-		children, err := s.T.GetChildren(c, dir.Id, options...)
+		revisions, err := s.T.GetHistory(c, dir.Id, options...)
 		if err != nil {
-			s.L.Error("error listing children", zap.Error(err))
+			s.L.Error("error getting directory history", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "internal server error",
 			})
 			return
 		}
 
-		pagination := paginationResponse(c, len(children), options)
+		history := &v1.DirectoryHistory{
+			Version:   v1.APIVersion,
+			Page:      opts.GetPage(),
+			PageSize:  opts.GetPageSize(),
+			Revisions: make([]v1.DirectoryRevision, len(revisions)),
+		}
 
-		c.JSON(http.StatusOK, &v1.DirectoryList{
-			Version:     v1.APIVersion,
-			Page:        pagination.Page,
-			PageSize:    pagination.PageSize,
-			Links:       pagination.Links,
-			Directories: children,
-		})
+		for i, r := range revisions {
+			history.Revisions[i] = *r
+		}
+
+		// If the count is equal to the page size, then we'll assume there may be another page.
+		if len(revisions) == opts.GetPageSize() {
+			values := storageOptionsToURLValues(&storage.Options{AsOf: opts.AsOf}, nil)
+			values.Set("page", strconv.Itoa(opts.GetPage()+1))
+			values.Set("limit", strconv.Itoa(opts.GetPageSize()))
+
+			history.Links.Next = &v1.Link{HREF: buildURL(c, values).String()}
+		}
+
+		c.JSON(http.StatusOK, history)
 	}
 }
 
-//nolint:dupl // listChildren and listParents are very similar, but not the same.
 func listParents(s *common.Server) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		options, err := storageOptionsFromGetQuery(c)
@@ -386,14 +1241,19 @@ func listParents(s *common.Server) gin.HandlerFunc {
 
 		idstr := c.Param("id")
 
-		dir, err := getDirectoryFromReference(s.T, idstr, options...)
+		dir, err := getDirectoryFromReference(c, s.T, idstr, options...)
 		if err != nil {
 			outputGetDirectoryError(c, err)
 			return
 		}
 
 		parents, err := s.T.GetParents(c, dir.Id, options...)
-		if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		} else if err != nil {
 			s.L.Error("error listing parents", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "internal server error",
@@ -401,15 +1261,35 @@ func listParents(s *common.Server) gin.HandlerFunc {
 			return
 		}
 
-		pagination := paginationResponse(c, len(parents), options)
+		pagination, err := paginationResponse(c, s, parents, options, nil)
+		if err != nil {
+			s.L.Error("error building pagination", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
 
-		c.JSON(http.StatusOK, &v1.DirectoryList{
+		dirList := &v1.DirectoryList{
 			Version:     v1.APIVersion,
 			Page:        pagination.Page,
 			PageSize:    pagination.PageSize,
 			Links:       pagination.Links,
 			Directories: parents,
-		})
+		}
+
+		err = setTotal(c, dirList, options, countAll(func(options ...storage.Option) ([]v1.DirectoryID, error) {
+			return s.T.GetParents(c, dir.Id, options...)
+		}))
+		if err != nil {
+			s.L.Error("error counting parents", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, dirList)
 	}
 }
 
@@ -426,7 +1306,7 @@ func listParentsUntil(s *common.Server) gin.HandlerFunc {
 
 		idstr := c.Param("id")
 
-		dir, err := getDirectoryFromReference(s.T, idstr, options...)
+		dir, err := getDirectoryFromReference(c, s.T, idstr, options...)
 		if err != nil {
 			outputGetDirectoryError(c, err)
 			return
@@ -434,14 +1314,19 @@ func listParentsUntil(s *common.Server) gin.HandlerFunc {
 
 		untilstr := c.Param("until")
 
-		untildir, err := getDirectoryFromReference(s.T, untilstr, options...)
+		untildir, err := getDirectoryFromReference(c, s.T, untilstr, options...)
 		if err != nil {
 			outputGetDirectoryError(c, err)
 			return
 		}
 
 		parents, err := s.T.GetParentsUntilAncestor(c, dir.Id, untildir.Id, options...)
-		if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		} else if err != nil {
 			s.L.Error("error listing parents", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "internal server error",
@@ -449,29 +1334,61 @@ func listParentsUntil(s *common.Server) gin.HandlerFunc {
 			return
 		}
 
-		pagination := paginationResponse(c, len(parents), options)
+		pagination, err := paginationResponse(c, s, parents, options, nil)
+		if err != nil {
+			s.L.Error("error building pagination", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
 
-		c.JSON(http.StatusOK, &v1.DirectoryList{
+		dirList := &v1.DirectoryList{
 			Version:     v1.APIVersion,
 			Page:        pagination.Page,
 			PageSize:    pagination.PageSize,
 			Links:       pagination.Links,
 			Directories: parents,
-		})
+		}
+
+		err = setTotal(c, dirList, options, countAll(func(options ...storage.Option) ([]v1.DirectoryID, error) {
+			return s.T.GetParentsUntilAncestor(c, dir.Id, untildir.Id, options...)
+		}))
+		if err != nil {
+			s.L.Error("error counting parents", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, dirList)
 	}
 }
 
+// getDirectoryFromReference returns the directory the reference is to,
+// which is either its id or its path, see v1.ParsePath.
 func getDirectoryFromReference(
+	ctx context.Context,
 	drv storage.DirectoryAdmin,
-	idstr string,
+	ref string,
 	options ...storage.Option,
 ) (*v1.Directory, error) {
-	id, err := v1.ParseDirectoryID(idstr)
+	if strings.HasPrefix(ref, v1.PathSeparator) {
+		path, err := v1.ParsePath(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		return drv.ResolvePath(ctx, path, options...)
+	}
+
+	id, err := v1.ParseDirectoryID(ref)
 	if err != nil {
 		return nil, err
 	}
 
-	dir, err := drv.GetDirectory(context.Background(), id, options...)
+	dir, err := drv.GetDirectory(ctx, id, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -479,24 +1396,193 @@ func getDirectoryFromReference(
 	return dir, nil
 }
 
+// directoryIDFromReference returns the id of the directory the reference is to.
+// Unlike paths, which are resolved with the provided options, ids aren't looked up.
+func directoryIDFromReference(
+	ctx context.Context,
+	drv storage.DirectoryAdmin,
+	ref string,
+	options ...storage.Option,
+) (v1.DirectoryID, error) {
+	if !strings.HasPrefix(ref, v1.PathSeparator) {
+		return v1.ParseDirectoryID(ref)
+	}
+
+	dir, err := getDirectoryFromReference(ctx, drv, ref, options...)
+	if err != nil {
+		return v1.DirectoryID{}, err
+	}
+
+	return dir.Id, nil
+}
+
+// directoryFetch returns the response for the directory, along with its canonical path.
+// The path is left out should it fail to be read, e.g. as a parent has just been purged,
+// as the directory itself may have been written already.
+func directoryFetch(c *gin.Context, s *common.Server, d *v1.Directory, options ...storage.Option) *v1.DirectoryFetch {
+	fetch := &v1.DirectoryFetch{
+		Version:   v1.APIVersion,
+		Directory: *d,
+	}
+
+	names, err := storage.PathOf(c, s.T, d, options...)
+	if err != nil {
+		s.L.Error("error getting directory path", zap.Error(err))
+		return fetch
+	}
+
+	path := v1.FormatPath(names)
+	fetch.Path = &path
+
+	return fetch
+}
+
 func outputGetDirectoryError(c *gin.Context, err error) {
 	switch {
 	case errors.Is(err, v1.ErrParsingID):
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "invalid id",
 		})
+	case errors.Is(err, v1.ErrParsingPath):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid path",
+		})
+	case errors.Is(err, storage.ErrAmbiguousPath):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, storage.ErrAsOfOutOfRange):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+
+	case errors.Is(err, storage.ErrDirectoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "directory not found",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "internal server error",
+		})
+	}
+}
+
+// setETag sets the ETag header to the revision of the provided directory.
+func setETag(c *gin.Context, d *v1.Directory) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(d.Revision, 10)))
+}
+
+// revisionFromIfMatch returns the revision requested with the If-Match header.
+// ok is false when the header isn't set or matches any revision.
+func revisionFromIfMatch(c *gin.Context) (revision int64, ok bool, err error) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, false, nil
+	}
+
+	revision, err = strconv.ParseInt(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid If-Match header: %w", err)
+	}
+
+	return revision, true, nil
+}
+
+func outputPreconditionFailed(c *gin.Context) {
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error": storage.ErrRevisionConflict.Error(),
+	})
+}
+
+func outputNameConflict(c *gin.Context) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error": storage.ErrDirectoryNameConflict.Error(),
+	})
+}
+
+// outputQuotaExceeded responds with the quota the write would have exceeded.
+func outputQuotaExceeded(c *gin.Context, err error) {
+	resp := &v1.QuotaExceeded{
+		Error: err.Error(),
+	}
+
+	var qerr *storage.QuotaExceededError
+	if errors.As(err, &qerr) {
+		resp.Root = qerr.Root
+		resp.Quota = qerr.Quota
+		resp.Limit = qerr.Limit
+	}
+
+	c.JSON(http.StatusUnprocessableEntity, resp)
+}
+
+// outputInvalidMetadata responds with the metadata fields which don't match the schema.
+func outputInvalidMetadata(c *gin.Context, err error) {
+	resp := &v1.InvalidMetadata{
+		Error:  err.Error(),
+		Fields: []v1.MetadataFieldError{},
+	}
+
+	var merr *storage.InvalidMetadataError
+	if errors.As(err, &merr) {
+		resp.Source = merr.Source
+
+		for _, f := range merr.Fields {
+			resp.Fields = append(resp.Fields, v1.MetadataFieldError{
+				Field:   f.Field,
+				Message: f.Message,
+			})
+		}
+	}
 
+	c.JSON(http.StatusUnprocessableEntity, resp)
+}
+
+func outputMoveDirectoryError(s *common.Server, c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrRevisionConflict):
+		outputPreconditionFailed(c)
+	case errors.Is(err, storage.ErrDirectoryNameConflict):
+		outputNameConflict(c)
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		outputQuotaExceeded(c, err)
 	case errors.Is(err, storage.ErrDirectoryNotFound):
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "directory not found",
 		})
+	case errors.Is(err, storage.ErrMoveCycle),
+		errors.Is(err, storage.ErrRootDirectoryMove),
+		errors.Is(err, storage.ErrAlreadyRoot),
+		errors.Is(err, storage.ErrNotRoot):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
 	default:
+		s.L.Error("error moving directory", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "internal server error",
 		})
 	}
 }
 
+// errNegativeDepth is returned when a negative depth is requested.
+var errNegativeDepth = errors.New("depth must not be negative")
+
+// errNegativeLimit is returned when a negative quota limit is requested.
+var errNegativeLimit = errors.New("quota limits must not be negative")
+
+// errMissingSchema is returned when setting a schema without providing it.
+var errMissingSchema = errors.New("schema is required")
+
+// errInvalidMaxNodes is returned when a maximum count of nodes which isn't positive is requested.
+var errInvalidMaxNodes = errors.New("max_nodes must be positive")
+
+// errHistoryCursor is returned when the history is requested with a cursor.
+var errHistoryCursor = errors.New("cursor is not supported by the history, use page instead")
+
+// errFutureAsOf is returned when directories are requested as of a time in the future.
+var errFutureAsOf = errors.New("as_of must not be in the future")
+
 // storageOptionsFromGetQuery builds a new storage.GetOptions from gin query.
 //
 //nolint:cyclop,nolintlint // simple to follow.
@@ -542,48 +1628,192 @@ func storageOptionsFromGetQuery(c *gin.Context) ([]storage.Option, error) {
 		options = append(options, storage.Pagination(page, limit))
 	}
 
+	if value, ok := c.GetQuery("cursor"); ok {
+		cursor, err := storage.ParseCursor(value)
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, storage.WithCursor(cursor))
+	}
+
+	if value, ok := c.GetQuery("selector"); ok {
+		sel, err := storage.ParseSelector(value)
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, storage.WithSelector(sel))
+	}
+
+	if value, ok := c.GetQuery("depth"); ok {
+		depth, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if depth < 0 {
+			return nil, errNegativeDepth
+		}
+
+		options = append(options, storage.WithMaxDepth(depth))
+	}
+
+	if value, ok := c.GetQuery("max_nodes"); ok {
+		maxNodes, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if maxNodes < 1 {
+			return nil, errInvalidMaxNodes
+		}
+
+		// Larger trees are never returned, whatever the request.
+		if maxNodes > storage.DefaultMaxNodes {
+			maxNodes = storage.DefaultMaxNodes
+		}
+
+		options = append(options, storage.WithMaxNodes(maxNodes))
+	}
+
+	if value, ok := c.GetQuery("as_of"); ok {
+		asOf, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, err
+		}
+
+		if asOf.After(time.Now()) {
+			return nil, errFutureAsOf
+		}
+
+		options = append(options, storage.WithAsOf(asOf))
+	}
+
+	if value, ok := c.GetQuery("total"); ok {
+		total, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if total {
+			options = append(options, storage.WithTotal)
+		}
+	}
+
 	return options, nil
 }
 
-// storageOptionsToURLValues builds a new storage.GetOptions from gin query.
-//
-//nolint:cyclop,nolintlint // simple to follow.
-func storageOptionsToURLValues(opts *storage.Options, page int) url.Values {
+// storageOptionsToURLValues builds url values from the provided storage options.
+func storageOptionsToURLValues(opts *storage.Options, cursor *storage.Cursor) url.Values {
 	values := make(url.Values)
 
 	if opts.WithDeletedDirectories {
 		values.Set("with_deleted", "true")
 	}
 
-	// Only when page is provided, include pagination details.
-	if page > 0 {
-		if reqPage := opts.GetPage(); reqPage >= 1 {
-			values.Set("page", strconv.Itoa(page))
-		}
+	if len(opts.Selector) > 0 {
+		values.Set("selector", opts.Selector.String())
+	}
+
+	if opts.MaxDepth > 0 {
+		values.Set("depth", strconv.Itoa(opts.MaxDepth))
+	}
+
+	if !opts.AsOf.IsZero() {
+		values.Set("as_of", opts.AsOf.Format(time.RFC3339Nano))
+	}
 
+	if opts.Total {
+		values.Set("total", "true")
+	}
+
+	// Only when a cursor is provided, include pagination details.
+	if cursor != nil {
+		values.Set("cursor", cursor.String())
 		values.Set("limit", strconv.Itoa(opts.GetPageSize()))
 	}
 
 	return values
 }
 
-func paginationResponse(c *gin.Context, count int, options []storage.Option) v1.Pagination {
+// paginationResponse builds the pagination details for the returned directory ids.
+// The next link continues right after the last directory returned, keeping the
+// provided query values on top of the storage options, if any.
+func paginationResponse(
+	c *gin.Context,
+	s *common.Server,
+	ids []v1.DirectoryID,
+	options []storage.Option,
+	query url.Values,
+) (v1.Pagination, error) {
 	opts := storage.BuildOptions(options)
 
-	pagination := &v1.Pagination{
+	pagination := v1.Pagination{
 		Page:     opts.GetPage(),
 		PageSize: opts.GetPageSize(),
 	}
 
 	// If the count is equal to the max size, then we'll assume there may be another page.
 	// If the count is not equal, we'll assume we've reached the end and won't provide a next url.
-	if count == opts.GetPageSize() {
-		values := storageOptionsToURLValues(opts, opts.GetPage()+1)
+	if len(ids) == opts.GetPageSize() {
+		last, err := s.T.GetDirectory(c, ids[len(ids)-1], storage.WithDeletedDirectories, storage.WithAsOf(opts.AsOf))
+		if err != nil {
+			return pagination, fmt.Errorf("error getting last directory: %w", err)
+		}
+
+		values := storageOptionsToURLValues(opts, storage.NewCursor(last))
+
+		for key, vals := range query {
+			values[key] = vals
+		}
 
 		pagination.Links.Next = &v1.Link{HREF: buildURL(c, values).String()}
 	}
 
-	return *pagination
+	return pagination, nil
+}
+
+// setTotal sets the count of directories of the listing across all its pages,
+// along with the X-Total-Count header, if requested with the total query parameter.
+// count is called with the options of the listing.
+func setTotal(
+	c *gin.Context,
+	dirList *v1.DirectoryList,
+	options []storage.Option,
+	count func(options ...storage.Option) (int, error),
+) error {
+	if !storage.BuildOptions(options).Total {
+		return nil
+	}
+
+	total, err := count(options...)
+	if err != nil {
+		return fmt.Errorf("error counting directories: %w", err)
+	}
+
+	dirList.Total = &total
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
+
+	return nil
+}
+
+// countAll returns the count of directories listed across all pages.
+// It is meant for listings which are bounded, such as the parents
+// of a directory, and have no count of their own.
+func countAll(list func(options ...storage.Option) ([]v1.DirectoryID, error)) func(...storage.Option) (int, error) {
+	return func(options ...storage.Option) (int, error) {
+		all := make([]storage.Option, len(options), len(options)+2)
+		copy(all, options)
+
+		ids, err := list(append(all, storage.Pagination(1, math.MaxInt32), storage.WithCursor(nil))...)
+		if err != nil {
+			return 0, err
+		}
+
+		return len(ids), nil
+	}
 }
 
 // buildURL returns a new *url.URL for the current page being requested, overwriting the values with the ones provided.
@@ -594,6 +1824,7 @@ func buildURL(c *gin.Context, values url.Values) *url.URL {
 
 	if c != nil && c.Request != nil && c.Request.URL != nil {
 		outURL.Path = c.Request.URL.Path
+		outURL.RawPath = c.Request.URL.RawPath
 		outURL.RawQuery = values.Encode()
 
 		// gin doesn't expose an easy way to check if the request came from a trusted proxy.
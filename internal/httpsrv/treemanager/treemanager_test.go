@@ -22,10 +22,13 @@ import (
 	"go.uber.org/zap"
 
 	apiv1 "github.com/infratographer/fertilesoil/api/v1"
+	clientv1 "github.com/infratographer/fertilesoil/client/v1"
 	"github.com/infratographer/fertilesoil/internal/httpsrv/common"
 	"github.com/infratographer/fertilesoil/internal/httpsrv/treemanager"
 	"github.com/infratographer/fertilesoil/storage"
 	"github.com/infratographer/fertilesoil/storage/memory"
+	"github.com/infratographer/fertilesoil/storage/quota"
+	"github.com/infratographer/fertilesoil/storage/router"
 	integration "github.com/infratographer/fertilesoil/tests/integration"
 	testutils "github.com/infratographer/fertilesoil/tests/utils"
 )
@@ -267,7 +270,7 @@ func TestDirectoryOperations(t *testing.T) {
 		Metadata: &apiv1.DirectoryMetadata{
 			"item1": "value1",
 		},
-	})
+	}, nil)
 	assert.NoError(t, err, "expected no error updating directory")
 	assert.Equal(t, "test2", d2.Directory.Name, "expected name to be updated")
 	assert.Contains(t, map[string]string(*d2.Directory.Metadata), "item1", "expected metadata to be updated")
@@ -277,7 +280,7 @@ func TestDirectoryOperations(t *testing.T) {
 		Metadata: &apiv1.DirectoryMetadata{
 			"item2": "value2",
 		},
-	})
+	}, nil)
 	assert.NoError(t, err, "expected no error updating directory")
 	assert.Equal(t, "test2", d2.Directory.Name, "expected name to be not change")
 	assert.Contains(t, map[string]string(*d2.Directory.Metadata), "item2", "expected metadata to be updated")
@@ -287,14 +290,14 @@ func TestDirectoryOperations(t *testing.T) {
 		Metadata: &apiv1.DirectoryMetadata{
 			"item3": "value3",
 		},
-	})
+	}, nil)
 	assert.NoError(t, err, "expected no error updating directory")
 	assert.Equal(t, "test2", d2.Directory.Name, "expected name to be not change")
 	assert.Contains(t, map[string]string(*d2.Directory.Metadata), "item3", "expected metadata to be updated")
 
 	d2, err = cli.UpdateDirectory(context.Background(), d.Directory.Id, &apiv1.UpdateDirectoryRequest{
 		Name: ptr("test3"),
-	})
+	}, nil)
 	assert.NoError(t, err, "expected no error updating directory")
 	assert.Equal(t, "test3", d2.Directory.Name, "expected name to be updated")
 	assert.Contains(t, map[string]string(*d2.Directory.Metadata), "item3", "expected metadata to not be updated")
@@ -369,6 +372,7 @@ func TestDirectoryOperations(t *testing.T) {
 type mockDriver struct {
 	storage.DirectoryAdmin
 	UpdateError error
+	AsOfError   error
 }
 
 // UpdateDirectory returns UpdateError if not nil.
@@ -380,6 +384,28 @@ func (m *mockDriver) UpdateDirectory(ctx context.Context, d *apiv1.Directory) er
 	return m.DirectoryAdmin.UpdateDirectory(ctx, d)
 }
 
+// GetDirectory returns AsOfError if not nil and the directory is read at a point in time.
+func (m *mockDriver) GetDirectory(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	options ...storage.Option,
+) (*apiv1.Directory, error) {
+	if m.AsOfError != nil && !storage.BuildOptions(options).AsOf.IsZero() {
+		return nil, m.AsOfError
+	}
+
+	return m.DirectoryAdmin.GetDirectory(ctx, id, options...)
+}
+
+// ListRoots returns AsOfError if not nil and the roots are read at a point in time.
+func (m *mockDriver) ListRoots(ctx context.Context, options ...storage.Option) ([]apiv1.DirectoryID, error) {
+	if m.AsOfError != nil && !storage.BuildOptions(options).AsOf.IsZero() {
+		return nil, m.AsOfError
+	}
+
+	return m.DirectoryAdmin.ListRoots(ctx, options...)
+}
+
 func ptr[T any](v T) *T {
 	return &v
 }
@@ -436,7 +462,7 @@ func TestUpdateDirectoryError(t *testing.T) {
 		Metadata: &apiv1.DirectoryMetadata{
 			"item1": "value1",
 		},
-	})
+	}, nil)
 	assert.Error(t, err, "expected error updating directory")
 }
 
@@ -794,26 +820,23 @@ func TestDirectoryPagination(t *testing.T) {
 
 	// Listing Root directories
 
-	// Without pagination defined, should return first page with default page size
+	// Without pagination defined, the client should follow cursors and return everything
 	results, err := cli.ListRoots(context.Background())
 	assert.NoError(t, err, "listing roots should not return error")
 
-	assert.Len(t, results.Directories, storage.DefaultPageSize, "unexpected default page size directories returned")
-	assert.Equal(t, 1, results.Page, "expected page to be 1")
-	assert.Equal(t, storage.DefaultPageSize, results.PageSize, "expected limit to be default page size")
-	assert.NotNil(t, results.Links.Next, "next page expected")
-	assert.Contains(t, results.Links.Next.HREF, "page=2", "expected next page to be page 2")
+	assert.Len(t, results.Directories, 17, "expected all root directories to be returned")
+	assert.Nil(t, results.Links.Next, "next page unexpected")
 
 	// First page with default page size should match without
 	page1, err := cli.ListRoots(context.Background(), storage.Pagination(1, 0))
 	assert.NoError(t, err, "listing roots should not return error")
 
 	assert.Len(t, page1.Directories, storage.DefaultPageSize, "unexpected default page size directories returned")
-	assert.Equal(t, results.Directories, page1.Directories, "page 1 doesn't match default page results")
+	assert.Equal(t, results.Directories[:storage.DefaultPageSize], page1.Directories, "page 1 doesn't match default page results")
 	assert.Equal(t, 1, page1.Page, "expected page to be 1")
 	assert.Equal(t, storage.DefaultPageSize, page1.PageSize, "expected limit to be default page size")
 	assert.NotNil(t, page1.Links.Next, "next page expected")
-	assert.Contains(t, page1.Links.Next.HREF, "page=2", "expected next page to be page 2")
+	assert.Contains(t, page1.Links.Next.HREF, "cursor=", "expected next page to use a cursor")
 
 	// Second page with default page size should be a partial return
 	page2, err := cli.ListRoots(context.Background(), storage.Pagination(2, 0))
@@ -824,6 +847,13 @@ func TestDirectoryPagination(t *testing.T) {
 	assert.Equal(t, storage.DefaultPageSize, page2.PageSize, "expected limit to be default page size")
 	assert.Nil(t, page2.Links.Next, "next page unexpected")
 
+	// Following the next cursor should return the second page
+	next, err := cli.ListRoots(context.Background(), storage.WithCursor(cursorFromLink(t, page1.Links.Next)))
+	assert.NoError(t, err, "listing roots with cursor should not return error")
+
+	assert.Equal(t, page2.Directories, next.Directories, "cursor page doesn't match second page results")
+	assert.Nil(t, next.Links.Next, "next page unexpected")
+
 	// Third page with default page size should be empty
 	results, err = cli.ListRoots(context.Background(), storage.Pagination(3, 0))
 	assert.NoError(t, err, "listing roots should not return error")
@@ -849,25 +879,23 @@ func TestDirectoryPagination(t *testing.T) {
 
 	// Getting Children
 
-	// Without pagination defined, should return first page with default page size
+	// Without pagination defined, the client should follow cursors and return everything
 	results, err = cli.GetChildren(context.Background(), rootdir.Id)
 	assert.NoError(t, err, "listing children should not return error")
 
-	assert.Len(t, results.Directories, storage.DefaultPageSize, "unexpected default page size directories returned")
-	assert.Equal(t, storage.DefaultPageSize, results.PageSize, "expected limit to be default page size")
-	assert.NotNil(t, results.Links.Next, "next page expected")
-	assert.Contains(t, results.Links.Next.HREF, "page=2", "expected next page to be page 2")
+	assert.Len(t, results.Directories, 16, "expected all directories to be returned")
+	assert.Nil(t, results.Links.Next, "next page unexpected")
 
 	// First page with default page size should match without
 	page1, err = cli.GetChildren(context.Background(), rootdir.Id, storage.Pagination(1, 0))
 	assert.NoError(t, err, "listing children should not return error")
 
 	assert.Len(t, page1.Directories, storage.DefaultPageSize, "unexpected first page size directories returned")
-	assert.Equal(t, results.Directories, page1.Directories, "page 1 doesn't match default page results")
+	assert.Equal(t, results.Directories[:storage.DefaultPageSize], page1.Directories, "page 1 doesn't match default page results")
 	assert.Equal(t, 1, page1.Page, "expected page to be 1")
 	assert.Equal(t, storage.DefaultPageSize, page1.PageSize, "expected limit to be default page size")
 	assert.NotNil(t, page1.Links.Next, "next page expected")
-	assert.Contains(t, page1.Links.Next.HREF, "page=2", "expected next page to be page 2")
+	assert.Contains(t, page1.Links.Next.HREF, "cursor=", "expected next page to use a cursor")
 
 	// Second page with default page size should be a partial return
 	page2, err = cli.GetChildren(context.Background(), rootdir.Id, storage.Pagination(2, 0))
@@ -878,6 +906,13 @@ func TestDirectoryPagination(t *testing.T) {
 	assert.Equal(t, storage.DefaultPageSize, page2.PageSize, "expected limit to be default page size")
 	assert.Nil(t, page2.Links.Next, "next page unexpected")
 
+	// Following the next cursor should return the second page
+	next, err = cli.GetChildren(context.Background(), rootdir.Id, storage.WithCursor(cursorFromLink(t, page1.Links.Next)))
+	assert.NoError(t, err, "listing children with cursor should not return error")
+
+	assert.Equal(t, page2.Directories, next.Directories, "cursor page doesn't match second page results")
+	assert.Nil(t, next.Links.Next, "next page unexpected")
+
 	// Third page with default page size should be empty
 	results, err = cli.GetChildren(context.Background(), rootdir.Id, storage.Pagination(3, 0))
 	assert.NoError(t, err, "listing children should not return error")
@@ -903,25 +938,23 @@ func TestDirectoryPagination(t *testing.T) {
 
 	// Getting Parents
 
-	// Without pagination defined, should return first page with default page size
+	// Without pagination defined, the client should follow cursors and return everything
 	results, err = cli.GetParents(context.Background(), lastDir.Id)
 	assert.NoError(t, err, "listing parents should not return error")
 
-	assert.Len(t, results.Directories, storage.DefaultPageSize, "unexpected default page size directories returned")
-	assert.Equal(t, storage.DefaultPageSize, results.PageSize, "expected limit to be default page size")
-	assert.NotNil(t, results.Links.Next, "next page expected")
-	assert.Contains(t, results.Links.Next.HREF, "page=2", "expected next page to be page 2")
+	assert.Len(t, results.Directories, 16, "expected all directories to be returned")
+	assert.Nil(t, results.Links.Next, "next page unexpected")
 
 	// First page with default page size should match without
 	page1, err = cli.GetParents(context.Background(), lastDir.Id, storage.Pagination(1, 0))
 	assert.NoError(t, err, "listing parents should not return error")
 
 	assert.Len(t, page1.Directories, storage.DefaultPageSize, "unexpected first page size directories returned")
-	assert.Equal(t, results.Directories, page1.Directories, "page 1 doesn't match default page results")
+	assert.Equal(t, results.Directories[:storage.DefaultPageSize], page1.Directories, "page 1 doesn't match default page results")
 	assert.Equal(t, 1, page1.Page, "expected page to be 1")
 	assert.Equal(t, storage.DefaultPageSize, page1.PageSize, "expected limit to be default page size")
 	assert.NotNil(t, page1.Links.Next, "next page expected")
-	assert.Contains(t, page1.Links.Next.HREF, "page=2", "expected next page to be page 2")
+	assert.Contains(t, page1.Links.Next.HREF, "cursor=", "expected next page to use a cursor")
 
 	// Second page with default page size should be a partial return
 	page2, err = cli.GetParents(context.Background(), lastDir.Id, storage.Pagination(2, 0))
@@ -932,6 +965,23 @@ func TestDirectoryPagination(t *testing.T) {
 	assert.Equal(t, storage.DefaultPageSize, page2.PageSize, "expected limit to be default page size")
 	assert.Nil(t, page2.Links.Next, "next page unexpected")
 
+	// Following the next cursor should return the second page
+	next, err = cli.GetParents(context.Background(), lastDir.Id, storage.WithCursor(cursorFromLink(t, page1.Links.Next)))
+	assert.NoError(t, err, "listing parents with cursor should not return error")
+
+	assert.Equal(t, page2.Directories, next.Directories, "cursor page doesn't match second page results")
+	assert.Nil(t, next.Links.Next, "next page unexpected")
+
+	// Cursors of directories which aren't parents should be refused
+	outside, err := srv.T.CreateRoot(context.Background(), &apiv1.Directory{Name: "outside"})
+	assert.NoError(t, err, "error creating root directory")
+
+	resp, err := cli.DoRaw(context.Background(), http.MethodGet,
+		"/api/v1/directories/"+lastDir.Id.String()+"/parents?cursor="+storage.NewCursor(outside).String(), nil)
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "cursor outside of the parents should be refused")
+	resp.Body.Close()
+
 	// Third page with default page size should be empty
 	results, err = cli.GetParents(context.Background(), lastDir.Id, storage.Pagination(3, 0))
 	assert.NoError(t, err, "listing parents should not return error")
@@ -957,25 +1007,23 @@ func TestDirectoryPagination(t *testing.T) {
 
 	// Getting Parents Until
 
-	// Without pagination defined, should return first page with default page size
+	// Without pagination defined, the client should follow cursors and return everything
 	results, err = cli.GetParentsUntil(context.Background(), lastDir.Id, rootdir.Id)
 	assert.NoError(t, err, "listing parents until ancestor should not return error")
 
-	assert.Len(t, results.Directories, storage.DefaultPageSize, "unexpected default page size directories returned")
-	assert.Equal(t, storage.DefaultPageSize, results.PageSize, "expected limit to be default page size")
-	assert.NotNil(t, results.Links.Next, "next page expected")
-	assert.Contains(t, results.Links.Next.HREF, "page=2", "expected next page to be page 2")
+	assert.Len(t, results.Directories, 16, "expected all directories to be returned")
+	assert.Nil(t, results.Links.Next, "next page unexpected")
 
 	// First page with default page size should match without
 	page1, err = cli.GetParentsUntil(context.Background(), lastDir.Id, rootdir.Id, storage.Pagination(1, 0))
 	assert.NoError(t, err, "listing parents until ancestor should not return error")
 
 	assert.Len(t, page1.Directories, storage.DefaultPageSize, "unexpected first page size directories returned")
-	assert.Equal(t, results.Directories, page1.Directories, "page 1 doesn't match default page results")
+	assert.Equal(t, results.Directories[:storage.DefaultPageSize], page1.Directories, "page 1 doesn't match default page results")
 	assert.Equal(t, 1, page1.Page, "expected page to be 1")
 	assert.Equal(t, storage.DefaultPageSize, page1.PageSize, "expected limit to be default page size")
 	assert.NotNil(t, page1.Links.Next, "next page expected")
-	assert.Contains(t, page1.Links.Next.HREF, "page=2", "expected next page to be page 2")
+	assert.Contains(t, page1.Links.Next.HREF, "cursor=", "expected next page to use a cursor")
 
 	// Second page with default page size should be a partial return
 	page2, err = cli.GetParentsUntil(context.Background(), lastDir.Id, rootdir.Id, storage.Pagination(2, 0))
@@ -986,6 +1034,13 @@ func TestDirectoryPagination(t *testing.T) {
 	assert.Equal(t, storage.DefaultPageSize, page2.PageSize, "expected limit to be default page size")
 	assert.Nil(t, page2.Links.Next, "next page unexpected")
 
+	// Following the next cursor should return the second page
+	next, err = cli.GetParentsUntil(context.Background(), lastDir.Id, rootdir.Id, storage.WithCursor(cursorFromLink(t, page1.Links.Next)))
+	assert.NoError(t, err, "listing parents until ancestor with cursor should not return error")
+
+	assert.Equal(t, page2.Directories, next.Directories, "cursor page doesn't match second page results")
+	assert.Nil(t, next.Links.Next, "next page unexpected")
+
 	// Third page with default page size should be empty
 	results, err = cli.GetParentsUntil(context.Background(), lastDir.Id, rootdir.Id, storage.Pagination(3, 0))
 	assert.NoError(t, err, "listing parents until ancestor should not return error")
@@ -1010,6 +1065,23 @@ func TestDirectoryPagination(t *testing.T) {
 	}
 }
 
+// cursorFromLink parses the cursor out of a pagination link.
+func cursorFromLink(t *testing.T, link *apiv1.Link) *storage.Cursor {
+	t.Helper()
+
+	if !assert.NotNil(t, link, "link expected") {
+		return nil
+	}
+
+	u, err := url.Parse(link.HREF)
+	assert.NoError(t, err, "parsing link should not return error")
+
+	cursor, err := storage.ParseCursor(u.Query().Get("cursor"))
+	assert.NoError(t, err, "parsing cursor should not return error")
+
+	return cursor
+}
+
 // httpClientFetch similar to clientv1.DoRaw except allows us to add http headers to pretend to be a proxy.
 func httpClientFetch(
 	client *http.Client,
@@ -1179,3 +1251,713 @@ func createDirectoryHierarchy(store storage.DirectoryAdmin, depth int) (root, la
 
 	return root, last, nil
 }
+
+func TestMoveDirectory(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.MoveDirectoryTest(t, cli)
+}
+
+func TestPurgeDirectory(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.PurgeDirectoryTest(t, cli)
+}
+
+func TestRestoreDirectory(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.RestoreDirectoryTest(t, cli)
+}
+
+func TestUpdateDirectoryRevision(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.UpdateDirectoryRevisionTest(t, cli)
+}
+
+func TestIfMatchPreconditions(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	rd, err := cli.CreateRoot(context.Background(), &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	d, err := cli.CreateDirectory(context.Background(), &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "child",
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "error creating directory")
+
+	fetch := func(method, path, ifMatch, body string) int {
+		resp, err := httpClientFetch(clientv1.UnixClient(skt), method, srvAddr, path,
+			http.Header{"If-Match": []string{ifMatch}}, strings.NewReader(body), nil)
+		assert.NoError(t, err, "error sending request")
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	dirPath := "/api/v1/directories/" + d.Directory.Id.String()
+	moveBody := `{"version":"` + apiv1.APIVersion + `","root":true}`
+
+	// Stale and malformed revisions are refused
+	assert.Equal(t, http.StatusPreconditionFailed, fetch(http.MethodDelete, dirPath, `"2"`, ""),
+		"expected delete to fail with a stale revision")
+	assert.Equal(t, http.StatusPreconditionFailed, fetch(http.MethodPost, dirPath+"/move", `"2"`, moveBody),
+		"expected move to fail with a stale revision")
+	assert.Equal(t, http.StatusPreconditionFailed, fetch(http.MethodPatch, dirPath, `"2"`, `{"name":"other"}`),
+		"expected update to fail with a stale revision")
+	assert.Equal(t, http.StatusPreconditionFailed, fetch(http.MethodDelete, dirPath, "invalid", ""),
+		"expected delete to fail with a malformed revision")
+
+	// Matching revisions go through
+	assert.Equal(t, http.StatusOK, fetch(http.MethodPost, dirPath+"/move", `"1"`, moveBody),
+		"expected move to succeed with the current revision")
+	assert.Equal(t, http.StatusOK, fetch(http.MethodPatch, dirPath, `W/"2"`, `{"name":"other"}`),
+		"expected update to succeed with the current revision")
+	assert.Equal(t, http.StatusOK, fetch(http.MethodPatch, dirPath, "*", `{"name":"another"}`),
+		"expected update to succeed with a wildcard")
+
+	got, err := cli.GetDirectory(context.Background(), d.Directory.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, int64(4), got.Directory.Revision, "unexpected revision")
+
+	d2, err := cli.CreateDirectory(context.Background(), &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "child2",
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "error creating directory")
+
+	assert.Equal(t, http.StatusOK, fetch(http.MethodDelete, "/api/v1/directories/"+d2.Directory.Id.String(), `"1"`, ""),
+		"expected delete to succeed with the current revision")
+}
+
+func TestBatch(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.BatchTest(t, cli)
+}
+
+// recordingNotifier records the type of every notification sent.
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []apiv1.EventType
+}
+
+func (r *recordingNotifier) record(t apiv1.EventType) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, t)
+
+	return nil
+}
+
+func (r *recordingNotifier) recorded() []apiv1.EventType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]apiv1.EventType(nil), r.events...)
+}
+
+func (r *recordingNotifier) NotifyCreate(_ context.Context, _ *apiv1.Directory) error {
+	return r.record(apiv1.EventTypeCreate)
+}
+
+func (r *recordingNotifier) NotifyUpdate(_ context.Context, _ *apiv1.Directory) error {
+	return r.record(apiv1.EventTypeUpdate)
+}
+
+func (r *recordingNotifier) NotifyDelete(_ context.Context, _ *apiv1.Directory) error {
+	return r.record(apiv1.EventTypeDelete)
+}
+
+func (r *recordingNotifier) NotifyDeleteHard(_ context.Context, _ *apiv1.Directory) error {
+	return r.record(apiv1.EventTypeDeleteHard)
+}
+
+func (r *recordingNotifier) NotifyMove(_ context.Context, _ *apiv1.Directory, _ *apiv1.DirectoryID) error {
+	return r.record(apiv1.EventTypeMove)
+}
+
+func (r *recordingNotifier) NotifyRestore(_ context.Context, _ *apiv1.Directory) error {
+	return r.record(apiv1.EventTypeRestore)
+}
+
+func TestBatchNotifiesAfterCommit(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+	notif := &recordingNotifier{}
+
+	srv := newTestServerWithOptions(t, nil, nil, auditBuf,
+		treemanager.WithListen(srvhost),
+		treemanager.WithUnix(skt),
+		treemanager.WithNotifier(notif),
+	)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, getStubServerAddress(t, skt), nil)
+
+	testutils.WaitForServer(t, cli)
+
+	rootIdx := 0
+	name := "dir"
+	unknown := apiv1.DirectoryID(uuid.New())
+
+	// Nothing is notified when the batch fails
+	_, err := cli.Batch(context.Background(), &apiv1.BatchRequest{
+		Version: apiv1.APIVersion,
+		Operations: []apiv1.BatchOperation{
+			{Op: "create", Name: &name},
+			{Op: "create", Name: &name, ParentIndex: &rootIdx},
+			{Op: "delete", Id: &unknown},
+		},
+	})
+	assert.Error(t, err, "expected batch to fail")
+	assert.Empty(t, notif.recorded(), "failed batch should not notify")
+
+	_, err = cli.Batch(context.Background(), &apiv1.BatchRequest{
+		Version: apiv1.APIVersion,
+		Operations: []apiv1.BatchOperation{
+			{Op: "create", Name: &name},
+			{Op: "create", Name: &name, ParentIndex: &rootIdx},
+		},
+	})
+	assert.NoError(t, err, "error applying batch")
+	assert.Equal(t, []apiv1.EventType{apiv1.EventTypeCreate, apiv1.EventTypeCreate}, notif.recorded(),
+		"committed batch should notify every operation")
+}
+
+func TestUniqueSiblingNames(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.UniqueSiblingNamesTest(t, cli)
+}
+
+func TestMetadataSelector(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.MetadataSelectorTest(t, cli)
+}
+
+func TestChildrenDepth(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.ChildrenDepthTest(t, cli)
+}
+
+func TestSubtree(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.SubtreeTest(t, cli)
+}
+
+func TestBatchGetDirectories(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.BatchGetDirectoriesTest(t, cli)
+}
+
+func TestDirectoryHistory(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.DirectoryHistoryTest(t, cli)
+}
+
+func TestDirectoryHistoryActor(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	rd, err := cli.CreateRoot(context.Background(), &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	dirPath := "/api/v1/directories/" + rd.Directory.Id.String()
+
+	resp, err := httpClientFetch(clientv1.UnixClient(skt), http.MethodPatch, srvAddr, dirPath,
+		http.Header{"User": []string{"jane"}}, strings.NewReader(`{"name":"renamed"}`), nil)
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "unexpected status code")
+	resp.Body.Close()
+
+	history, err := cli.GetHistory(context.Background(), rd.Directory.Id)
+	assert.NoError(t, err, "error getting history")
+	assert.Len(t, history.Revisions, 2, "unexpected revisions")
+	assert.Equal(t, "jane", history.Revisions[0].Actor, "the actor of the update should be recorded")
+	assert.Equal(t, "renamed", history.Revisions[0].After.Name, "unexpected directory")
+	assert.Empty(t, history.Revisions[1].Actor, "the root was created without an actor")
+}
+
+func TestAsOf(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.AsOfTest(t, cli)
+}
+
+func TestAsOfOutOfRange(t *testing.T) {
+	t.Parallel()
+
+	memDriver, _ := newMemoryStorage(t)
+
+	// The memory driver keeps its whole history, so the error of the databases which don't is forced.
+	store := &mockDriver{
+		DirectoryAdmin: memDriver,
+		AsOfError:      storage.ErrAsOfOutOfRange,
+	}
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, store, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, getStubServerAddress(t, skt), nil)
+
+	testutils.WaitForServer(t, cli)
+
+	ctx := context.Background()
+
+	rd, err := store.CreateRoot(ctx, &apiv1.Directory{Name: "root"})
+	assert.NoError(t, err, "error creating root directory")
+
+	asOf := url.QueryEscape(time.Now().Add(-time.Hour).Format(time.RFC3339Nano))
+
+	for _, path := range []string{
+		"/api/v1/roots?as_of=" + asOf,
+		"/api/v1/directories/" + rd.Id.String() + "?as_of=" + asOf,
+		"/api/v1/directories/" + rd.Id.String() + "/children?as_of=" + asOf,
+	} {
+		resp, err := cli.DoRaw(ctx, http.MethodGet, path, nil)
+		assert.NoError(t, err, "error sending request")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "as_of out of range should be refused for %s", path)
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err, "error reading response")
+		assert.Contains(t, string(body), storage.ErrAsOfOutOfRange.Error(), "the error should tell as_of is out of range")
+
+		resp.Body.Close()
+	}
+}
+
+func TestReplicaRouting(t *testing.T) {
+	t.Parallel()
+
+	// The replica never catches up with the primary, so the
+	// driver reads are made from can be told apart.
+	primary, _ := newMemoryStorage(t)
+	replica, _ := newMemoryStorage(t)
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, router.StorageWithReplica(primary, replica), nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	ctx := context.Background()
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root",
+	})
+	assert.NoError(t, err, "error creating root directory")
+
+	// Writes read the parent from the primary.
+	cd, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "child",
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "error creating directory")
+
+	_, err = primary.GetDirectory(ctx, cd.Directory.Id)
+	assert.NoError(t, err, "directory should have been created in the primary")
+
+	// Reads go to the replica.
+	_, err = cli.GetDirectory(ctx, rd.Directory.Id)
+	assert.Error(t, err, "read should have gone to the replica")
+}
+
+func TestQuota(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServerWithOptions(t, nil, nil, auditBuf,
+		treemanager.WithListen(srvhost),
+		treemanager.WithUnix(skt),
+		treemanager.WithQuotaLimits(quota.Limits{}),
+	)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.QuotaTest(t, cli)
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.StatsTest(t, cli)
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.SearchTest(t, cli)
+}
+
+func TestPath(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.PathTest(t, cli)
+}
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+
+	auditBuf := &strings.Builder{}
+	skt := testutils.NewUnixsocketPath(t)
+
+	srv := newTestServer(t, skt, nil, nil, auditBuf)
+
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	srvAddr := getStubServerAddress(t, skt)
+	cli := testutils.NewTestClient(t, skt, srvAddr, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.SchemaTest(t, cli)
+}
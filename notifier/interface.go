@@ -11,4 +11,6 @@ type Notifier interface {
 	NotifyUpdate(ctx context.Context, d *apiv1.Directory) error
 	NotifyDelete(ctx context.Context, d *apiv1.Directory) error
 	NotifyDeleteHard(ctx context.Context, d *apiv1.Directory) error
+	NotifyMove(ctx context.Context, d *apiv1.Directory, oldParent *apiv1.DirectoryID) error
+	NotifyRestore(ctx context.Context, d *apiv1.Directory) error
 }
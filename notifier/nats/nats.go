@@ -9,11 +9,19 @@ import (
 	"time"
 
 	nats "github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	apiv1 "github.com/infratographer/fertilesoil/api/v1"
 )
 
+var tracer = otel.Tracer("github.com/infratographer/fertilesoil/notifier/nats")
+
 // NewNotifier creates a new NATS notifier using JetStream.
 // The subject is the NATS subject to publish events to.
 func NewNotifier(js nats.JetStreamContext, subjectPrefix string, options ...Option) *Notifier {
@@ -79,25 +87,42 @@ func (n *Notifier) AddStream(stream *nats.StreamConfig) (*nats.StreamInfo, error
 
 // NotifyCreate publishes a create event for the provided directory.
 func (n *Notifier) NotifyCreate(ctx context.Context, d *apiv1.Directory) error {
-	return n.publish(getEvent(apiv1.EventTypeCreate, d))
+	return n.publish(ctx, getEvent(apiv1.EventTypeCreate, d))
 }
 
 // NotifyUpdate publishes an update event for the provided directory.
 func (n *Notifier) NotifyUpdate(ctx context.Context, d *apiv1.Directory) error {
-	return n.publish(getEvent(apiv1.EventTypeUpdate, d))
+	return n.publish(ctx, getEvent(apiv1.EventTypeUpdate, d))
 }
 
 // NotifyDelete publishes a delete event for the provided directory.
 func (n *Notifier) NotifyDelete(ctx context.Context, d *apiv1.Directory) error {
-	return n.publish(getEvent(apiv1.EventTypeDelete, d))
+	return n.publish(ctx, getEvent(apiv1.EventTypeDelete, d))
 }
 
 // NotifyDeleteHard publishes a hard delete event for the provided directory.
 func (n *Notifier) NotifyDeleteHard(ctx context.Context, d *apiv1.Directory) error {
-	return n.publish(getEvent(apiv1.EventTypeDeleteHard, d))
+	return n.publish(ctx, getEvent(apiv1.EventTypeDeleteHard, d))
+}
+
+// NotifyMove publishes a move event for the provided directory.
+// The directory's current parent is published as the new parent.
+func (n *Notifier) NotifyMove(ctx context.Context, d *apiv1.Directory, oldParent *apiv1.DirectoryID) error {
+	evt := getEvent(apiv1.EventTypeMove, d)
+	evt.OldParent = oldParent
+	evt.NewParent = d.Parent
+
+	return n.publish(ctx, evt)
+}
+
+// NotifyRestore publishes a restore event for the provided directory.
+func (n *Notifier) NotifyRestore(ctx context.Context, d *apiv1.Directory) error {
+	return n.publish(ctx, getEvent(apiv1.EventTypeRestore, d))
 }
 
-func (n *Notifier) publish(evt *apiv1.DirectoryEvent) error {
+// publish sends the event to its subject, within a span of the context's trace.
+// The trace context is propagated to the subscribers in the message headers.
+func (n *Notifier) publish(ctx context.Context, evt *apiv1.DirectoryEvent) error {
 	var buff bytes.Buffer
 
 	if err := json.NewEncoder(&buff).Encode(evt); err != nil {
@@ -106,15 +131,34 @@ func (n *Notifier) publish(evt *apiv1.DirectoryEvent) error {
 
 	subject := n.subjectPrefix + "." + string(evt.Type)
 
+	ctx, span := tracer.Start(ctx, subject+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("nats"),
+			semconv.MessagingOperationPublish,
+			semconv.MessagingDestinationNameKey.String(subject),
+			attribute.String("directory.id", evt.Directory.Id.String()),
+		),
+	)
+	defer span.End()
+
+	msg := nats.NewMsg(subject)
+	msg.Data = buff.Bytes()
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(msg.Header))
+
 	n.logger.Debug("Sending event", zap.String("nats.publish.subject", subject), zap.Any("nats.publish.body", evt))
 
-	if _, err := n.js.Publish(subject, buff.Bytes(), n.publishOptions...); err != nil {
+	if _, err := n.js.PublishMsg(msg, n.publishOptions...); err != nil {
 		n.logger.Debug("Failed to send event",
 			zap.String("nats.publish.subject", subject),
 			zap.Any("nats.publish.body", evt),
 			zap.Error(err),
 		)
 
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
 		return fmt.Errorf("failed to publish event to: %w", err)
 	}
 
@@ -10,9 +10,14 @@ import (
 	natssrv "github.com/nats-io/nats-server/v2/server"
 	natsgo "github.com/nats-io/nats.go"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap/zaptest"
 
 	apiv1 "github.com/infratographer/fertilesoil/api/v1"
+	clientv1 "github.com/infratographer/fertilesoil/client/v1"
+	clientv1nats "github.com/infratographer/fertilesoil/client/v1/nats"
 	"github.com/infratographer/fertilesoil/notifier/nats"
 	natsutils "github.com/infratographer/fertilesoil/notifier/nats/utils"
 )
@@ -175,6 +180,57 @@ func TestBasicNotifications(t *testing.T) {
 		assert.NoError(t, err, "unmarshalling nats message")
 		assert.Equal(t, apiv1.EventTypeDeleteHard, unmarshalled.Type)
 	})
+
+	t.Run("send move", func(t *testing.T) {
+		oldParent := apiv1.DirectoryID(uuid.New())
+		newParent := apiv1.DirectoryID(uuid.New())
+		dir.Parent = &newParent
+
+		err = ntf.NotifyMove(context.Background(), dir, &oldParent)
+		assert.NoError(t, err, "notifying move")
+
+		var msg *natsgo.Msg
+
+		// Receive move
+		select {
+		case msg = <-msgChan:
+		case <-time.After(natsMsgSubTimeout):
+			t.Error("failed to receive nats message")
+		}
+
+		unmarshalled := &apiv1.DirectoryEvent{}
+		err = json.Unmarshal(msg.Data, unmarshalled)
+
+		assert.NoError(t, err, "unmarshalling nats message")
+		assert.Equal(t, apiv1.EventTypeMove, unmarshalled.Type)
+		assert.Equal(t, dir.Id, unmarshalled.Directory.Id)
+		assert.Equal(t, &oldParent, unmarshalled.OldParent)
+		assert.Equal(t, &newParent, unmarshalled.NewParent)
+	})
+
+	t.Run("send restore", func(t *testing.T) {
+		dir.DeletedAt = nil
+
+		err = ntf.NotifyRestore(context.Background(), dir)
+		assert.NoError(t, err, "notifying restore")
+
+		var msg *natsgo.Msg
+
+		// Receive restore
+		select {
+		case msg = <-msgChan:
+		case <-time.After(natsMsgSubTimeout):
+			t.Error("failed to receive nats message")
+		}
+
+		unmarshalled := &apiv1.DirectoryEvent{}
+		err = json.Unmarshal(msg.Data, unmarshalled)
+
+		assert.NoError(t, err, "unmarshalling nats message")
+		assert.Equal(t, apiv1.EventTypeRestore, unmarshalled.Type)
+		assert.Equal(t, dir.Id, unmarshalled.Directory.Id)
+		assert.Nil(t, unmarshalled.Directory.DeletedAt)
+	})
 }
 
 func TestNotifyCreateFailsOnBadConnection(t *testing.T) {
@@ -274,3 +330,69 @@ func TestAddStream(t *testing.T) {
 	assert.Error(t, err, "expected error to be returned for bad name")
 	assert.Nil(t, stream, "expected stream to be nil")
 }
+
+//nolint:paralleltest // The test sets the global propagator.
+func TestNotifyPropagatesTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	subject := t.Name()
+
+	conn, err := natsgo.Connect(natss.ClientURL())
+	assert.NoError(t, err, "connecting to nats server")
+
+	js, err := conn.JetStream()
+	assert.NoError(t, err, "creating JetStream connection")
+
+	clientconn, err := natsgo.Connect(natss.ClientURL())
+	assert.NoError(t, err, "connecting to nats server")
+
+	natsutils.WaitConnected(t, conn)
+	natsutils.WaitConnected(t, clientconn)
+
+	ntf := nats.NewNotifier(js, subject, nats.WithLogger(zaptest.NewLogger(t)))
+
+	_, err = ntf.AddStream(&natsgo.StreamConfig{
+		Name:    subject,
+		Storage: natsgo.MemoryStorage,
+	})
+	assert.NoError(t, err, "creating JetStream stream")
+
+	w, err := clientv1nats.NewSubscriber(clientconn, subject+".*")
+	assert.NoError(t, err, "creating subscriber")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	evCh, errCh := w.Watch(ctx)
+
+	// Wait for the subscription to be set up before publishing.
+	assert.NoError(t, clientconn.Flush(), "flushing subscription")
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01},
+		SpanID:     trace.SpanID{0x01},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	dir := &apiv1.Directory{
+		Id:   apiv1.DirectoryID(uuid.New()),
+		Name: "test",
+	}
+
+	err = ntf.NotifyCreate(trace.ContextWithSpanContext(context.Background(), sc), dir)
+	assert.NoError(t, err, "notifying create")
+
+	select {
+	case ev := <-evCh:
+		assert.Equal(t, dir.Id, ev.Directory.Id, "unexpected event")
+		assert.NotEmpty(t, ev.TraceContext, "trace context should be propagated")
+
+		got := trace.SpanContextFromContext(clientv1.EventContext(context.Background(), ev))
+		assert.Equal(t, sc.TraceID(), got.TraceID(), "event should be part of the publisher's trace")
+		assert.True(t, got.IsRemote(), "span context should be remote")
+	case err := <-errCh:
+		t.Errorf("unexpected error watching events: %v", err)
+	case <-time.After(natsMsgSubTimeout):
+		t.Error("failed to receive event")
+	}
+}
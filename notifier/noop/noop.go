@@ -31,3 +31,11 @@ func (n *noopNotifier) NotifyDelete(ctx context.Context, d *apiv1.Directory) err
 func (n *noopNotifier) NotifyDeleteHard(ctx context.Context, d *apiv1.Directory) error {
 	return nil
 }
+
+func (n *noopNotifier) NotifyMove(ctx context.Context, d *apiv1.Directory, oldParent *apiv1.DirectoryID) error {
+	return nil
+}
+
+func (n *noopNotifier) NotifyRestore(ctx context.Context, d *apiv1.Directory) error {
+	return nil
+}
@@ -0,0 +1,19 @@
+package storage
+
+import "context"
+
+type actorContextKey struct{}
+
+// WithActor returns a copy of the context carrying the actor making the changes.
+// Drivers record it in the revision history of the directories they change.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by the context,
+// or an empty string if there's none.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorContextKey{}).(string)
+
+	return actor
+}
@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"sync"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+)
+
+// Kinds of cached entries, used to label the metrics.
+const (
+	kindDirectory = "directory"
+	kindParents   = "parents"
+)
+
+// parentsKey identifies a cached ancestor chain.
+// Only the first page of a chain is cached.
+type parentsKey struct {
+	id v1.DirectoryID
+	// ancestor is where the walk stops, or the zero id
+	// if it goes up to the root directory.
+	ancestor    v1.DirectoryID
+	withDeleted bool
+	pageSize    int
+}
+
+// cache holds the cached entries, shared by a storage and its transactions.
+type cache struct {
+	mu          sync.Mutex
+	directories *lru[v1.DirectoryID, *v1.Directory]
+	parents     *lru[parentsKey, []v1.DirectoryID]
+	// dependents holds the ancestor chains each directory takes part in,
+	// either as the child the walk started from or as one of its ancestors.
+	dependents map[v1.DirectoryID]map[parentsKey]struct{}
+	// generation is bumped on every invalidation. Values read from the
+	// storage before an invalidation aren't cached after it, as they
+	// may predate the change.
+	generation uint64
+	// disabled is set once the cache can't be kept coherent anymore.
+	disabled bool
+	stats    Stats
+}
+
+func newCache(size int) *cache {
+	c := &cache{
+		directories: newLRU[v1.DirectoryID, *v1.Directory](size, nil),
+		dependents:  map[v1.DirectoryID]map[parentsKey]struct{}{},
+	}
+
+	c.parents = newLRU(size, c.removeDependents)
+
+	return c
+}
+
+// currentGeneration returns the generation to provide when caching values
+// read from the storage from now on.
+func (c *cache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+func (c *cache) getDirectory(id v1.DirectoryID) (*v1.Directory, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.disabled {
+		return nil, false
+	}
+
+	d, ok := c.directories.get(id)
+	c.record(kindDirectory, ok)
+
+	if !ok {
+		return nil, false
+	}
+
+	return copyDirectory(d), true
+}
+
+func (c *cache) addDirectory(generation uint64, d *v1.Directory) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.disabled || generation != c.generation {
+		return
+	}
+
+	c.directories.add(d.Id, copyDirectory(d))
+}
+
+func (c *cache) getParents(key parentsKey) ([]v1.DirectoryID, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.disabled {
+		return nil, false
+	}
+
+	parents, ok := c.parents.get(key)
+	c.record(kindParents, ok)
+
+	if !ok {
+		return nil, false
+	}
+
+	return append([]v1.DirectoryID{}, parents...), true
+}
+
+func (c *cache) addParents(generation uint64, key parentsKey, parents []v1.DirectoryID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.disabled || generation != c.generation {
+		return
+	}
+
+	if previous, ok := c.parents.get(key); ok {
+		c.removeDependents(key, previous)
+	}
+
+	parents = append([]v1.DirectoryID{}, parents...)
+	c.parents.add(key, parents)
+
+	for _, id := range append([]v1.DirectoryID{key.id}, parents...) {
+		if c.dependents[id] == nil {
+			c.dependents[id] = map[parentsKey]struct{}{}
+		}
+
+		c.dependents[id][key] = struct{}{}
+	}
+}
+
+// removeDependents removes the ancestor chain from the dependents of its directories.
+func (c *cache) removeDependents(key parentsKey, parents []v1.DirectoryID) {
+	for _, id := range append([]v1.DirectoryID{key.id}, parents...) {
+		delete(c.dependents[id], key)
+
+		if len(c.dependents[id]) == 0 {
+			delete(c.dependents, id)
+		}
+	}
+}
+
+// invalidate removes the provided directories from the cache, along with
+// the ancestor chains they take part in. Moving a directory changes the
+// chains of all its descendants, which all have it as an ancestor.
+func (c *cache) invalidate(ids ...v1.DirectoryID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.stats.Invalidations += uint64(len(ids))
+
+	for _, id := range ids {
+		c.directories.remove(id)
+
+		for key := range c.dependents[id] {
+			if parents, ok := c.parents.get(key); ok {
+				c.parents.remove(key)
+				c.removeDependents(key, parents)
+			}
+		}
+	}
+}
+
+// disable drops all the entries and stops caching new ones.
+func (c *cache) disable() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.disabled = true
+	c.directories.purge()
+	c.parents.purge()
+	c.dependents = map[v1.DirectoryID]map[parentsKey]struct{}{}
+}
+
+// record counts a lookup of the provided kind of entry.
+// The lock must be held.
+func (c *cache) record(kind string, hit bool) {
+	switch {
+	case kind == kindDirectory && hit:
+		c.stats.DirectoryHits++
+	case kind == kindDirectory:
+		c.stats.DirectoryMisses++
+	case hit:
+		c.stats.ParentsHits++
+	default:
+		c.stats.ParentsMisses++
+	}
+}
+
+// copyDirectory returns a copy of the directory, so neither the cached
+// directories nor the ones returned can be changed by the callers.
+func copyDirectory(d *v1.Directory) *v1.Directory {
+	dc := *d
+
+	if d.Metadata != nil {
+		md := make(v1.DirectoryMetadata, len(*d.Metadata))
+
+		for k, v := range *d.Metadata {
+			md[k] = v
+		}
+
+		dc.Metadata = &md
+	}
+
+	return &dc
+}
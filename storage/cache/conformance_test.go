@@ -0,0 +1,18 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/cache"
+	"github.com/infratographer/fertilesoil/storage/memory"
+	"github.com/infratographer/fertilesoil/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RunConformance(t, func(t *testing.T) storage.DirectoryAdmin {
+		return cache.StorageWithCache(memory.NewDirectoryDriver())
+	})
+}
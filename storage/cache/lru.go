@@ -0,0 +1,85 @@
+package cache
+
+import "container/list"
+
+// lru is a map holding up to a fixed count of entries.
+// Once full, the least recently used entry is evicted to make room.
+// It isn't safe for concurrent use.
+type lru[K comparable, V any] struct {
+	size    int
+	order   *list.List
+	entries map[K]*list.Element
+	// onEvict is called with the entries evicted to make room,
+	// not with the ones removed explicitly.
+	onEvict func(K, V)
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func newLRU[K comparable, V any](size int, onEvict func(K, V)) *lru[K, V] {
+	return &lru[K, V]{
+		size:    size,
+		order:   list.New(),
+		entries: map[K]*list.Element{},
+		onEvict: onEvict,
+	}
+}
+
+// get returns the value of the key, marking it as the most recently used.
+func (l *lru[K, V]) get(key K) (V, bool) {
+	e, ok := l.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	l.order.MoveToFront(e)
+
+	return e.Value.(*lruEntry[K, V]).value, true
+}
+
+// add sets the value of the key, evicting the least recently used entry if full.
+func (l *lru[K, V]) add(key K, value V) {
+	if e, ok := l.entries[key]; ok {
+		e.Value.(*lruEntry[K, V]).value = value
+		l.order.MoveToFront(e)
+
+		return
+	}
+
+	l.entries[key] = l.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+
+	if l.order.Len() > l.size {
+		oldest := l.order.Back()
+		evicted := oldest.Value.(*lruEntry[K, V])
+
+		l.order.Remove(oldest)
+		delete(l.entries, evicted.key)
+
+		if l.onEvict != nil {
+			l.onEvict(evicted.key, evicted.value)
+		}
+	}
+}
+
+// remove removes the key, if present.
+func (l *lru[K, V]) remove(key K) {
+	if e, ok := l.entries[key]; ok {
+		l.order.Remove(e)
+		delete(l.entries, key)
+	}
+}
+
+// purge removes all the entries.
+func (l *lru[K, V]) purge() {
+	l.order.Init()
+	l.entries = map[K]*list.Element{}
+}
+
+// len returns the count of entries.
+func (l *lru[K, V]) len() int {
+	return l.order.Len()
+}
@@ -0,0 +1,69 @@
+package cache
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Stats holds the counts of cache lookups and invalidations.
+type Stats struct {
+	DirectoryHits   uint64
+	DirectoryMisses uint64
+	ParentsHits     uint64
+	ParentsMisses   uint64
+	// Invalidations is the count of directories invalidated,
+	// either by local writes or by incoming events.
+	Invalidations uint64
+}
+
+var (
+	lookupsDesc = prometheus.NewDesc(
+		"fertilesoil_storage_cache_lookups_total",
+		"Count of storage cache lookups, by kind of entry and result.",
+		[]string{"kind", "result"}, nil,
+	)
+	invalidationsDesc = prometheus.NewDesc(
+		"fertilesoil_storage_cache_invalidations_total",
+		"Count of directories invalidated in the storage cache.",
+		nil, nil,
+	)
+	entriesDesc = prometheus.NewDesc(
+		"fertilesoil_storage_cache_entries",
+		"Count of entries in the storage cache, by kind of entry.",
+		[]string{"kind"}, nil,
+	)
+)
+
+// ensure Storage implements prometheus.Collector, so it can be registered as is.
+var _ prometheus.Collector = &Storage{}
+
+// Describe implements prometheus.Collector.
+func (s *Storage) Describe(ch chan<- *prometheus.Desc) {
+	ch <- lookupsDesc
+	ch <- invalidationsDesc
+	ch <- entriesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (s *Storage) Collect(ch chan<- prometheus.Metric) {
+	s.cache.mu.Lock()
+	stats := s.cache.stats
+	directories := s.cache.directories.len()
+	parents := s.cache.parents.len()
+	s.cache.mu.Unlock()
+
+	lookups := []struct {
+		kind, result string
+		count        uint64
+	}{
+		{kindDirectory, "hit", stats.DirectoryHits},
+		{kindDirectory, "miss", stats.DirectoryMisses},
+		{kindParents, "hit", stats.ParentsHits},
+		{kindParents, "miss", stats.ParentsMisses},
+	}
+
+	for _, l := range lookups {
+		ch <- prometheus.MustNewConstMetric(lookupsDesc, prometheus.CounterValue, float64(l.count), l.kind, l.result)
+	}
+
+	ch <- prometheus.MustNewConstMetric(invalidationsDesc, prometheus.CounterValue, float64(stats.Invalidations))
+	ch <- prometheus.MustNewConstMetric(entriesDesc, prometheus.GaugeValue, float64(directories), kindDirectory)
+	ch <- prometheus.MustNewConstMetric(entriesDesc, prometheus.GaugeValue, float64(parents), kindParents)
+}
@@ -0,0 +1,460 @@
+// Package cache provides a storage driver which caches the directories
+// and ancestor chains read from another storage driver.
+package cache
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	apiv1 "github.com/infratographer/fertilesoil/api/v1"
+	clientv1 "github.com/infratographer/fertilesoil/client/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// DefaultSize is the default count of directories, and of ancestor chains, kept in the cache.
+const DefaultSize = 10000
+
+type Option func(*Storage)
+
+// WithSize sets the count of directories, and of ancestor chains, kept in the cache.
+// Once full, the least recently used entries are evicted.
+func WithSize(size int) Option {
+	return func(s *Storage) {
+		s.size = size
+	}
+}
+
+// Storage is a meta-storage driver that wraps a storage.DirectoryAdmin and
+// caches the directories and the ancestor chains read from it.
+// Entries are invalidated on the writes made through the driver, and on
+// the events of a watcher passed to Watch, so the changes made by other
+// replicas are seen as well. Reads at a point in time aren't cached.
+type Storage struct {
+	storage.DirectoryAdmin
+	size int
+	// cache is nil within a transaction, where reads go to the wrapped storage
+	// so the uncommitted changes are seen.
+	cache *cache
+	// written holds the directories written within a transaction, which are
+	// invalidated once it ends.
+	written []apiv1.DirectoryID
+	// moved holds the directories moved within a transaction, whose
+	// descendants are invalidated once it ends.
+	moved []apiv1.DirectoryID
+}
+
+// ensure Storage implements storage.DirectoryAdmin.
+var _ storage.DirectoryAdmin = &Storage{}
+
+// StorageWithCache wraps the provided storage with a cache.
+func StorageWithCache(s storage.DirectoryAdmin, opts ...Option) *Storage {
+	cws := &Storage{
+		DirectoryAdmin: s,
+		size:           DefaultSize,
+	}
+
+	for _, opt := range opts {
+		opt(cws)
+	}
+
+	cws.cache = newCache(cws.size)
+
+	return cws
+}
+
+// Watch invalidates the directories changed by the events of the watcher,
+// until the context is done. It should run for as long as the storage is used.
+// Events may be missed once the watcher fails, so the cache is dropped and
+// disabled, and the error of the watcher is returned.
+func (s *Storage) Watch(ctx context.Context, w clientv1.Watcher) error {
+	events, errs := w.Watch(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				s.cache.disable()
+				return nil
+			}
+
+			if ev.Type == apiv1.EventTypeMove {
+				s.invalidateMoved(ctx, ev.Directory.Id, nil)
+			} else {
+				s.cache.invalidate(ev.Directory.Id)
+			}
+		case err, ok := <-errs:
+			if !ok {
+				s.cache.disable()
+				return nil
+			}
+
+			s.cache.disable()
+
+			return err
+		}
+	}
+}
+
+// Stats returns the cache statistics.
+func (s *Storage) Stats() Stats {
+	s.cache.mu.Lock()
+	defer s.cache.mu.Unlock()
+
+	return s.cache.stats
+}
+
+// invalidate removes the provided directories from the cache.
+// Within a transaction, they are only removed once it ends.
+func (s *Storage) invalidate(ids ...apiv1.DirectoryID) {
+	if s.cache == nil {
+		s.written = append(s.written, ids...)
+		return
+	}
+
+	s.cache.invalidate(ids...)
+}
+
+// invalidateMoved removes the directory a move was attempted on from the cache.
+// Once moved, its descendants are removed as well, as they take the unique
+// sibling names setting of their new tree without any event being sent for them.
+// They are listed from the wrapped storage, along with the soft deleted ones
+// which are cached too. Within a transaction, they are only listed once it ends.
+// The cache is dropped and disabled if they can't be listed, as it can't be
+// kept coherent anymore.
+func (s *Storage) invalidateMoved(ctx context.Context, id apiv1.DirectoryID, moveErr error) {
+	if moveErr != nil {
+		s.invalidate(id)
+		return
+	}
+
+	if s.cache == nil {
+		s.written = append(s.written, id)
+		s.moved = append(s.moved, id)
+
+		return
+	}
+
+	s.cache.invalidate(id)
+
+	descendants, err := s.DirectoryAdmin.GetChildren(ctx, id,
+		storage.WithDeletedDirectories, storage.Pagination(1, math.MaxInt32))
+	if errors.Is(err, storage.ErrDirectoryNotFound) {
+		// The directory was purged since, along with its descendants.
+		return
+	} else if err != nil {
+		s.cache.disable()
+		return
+	}
+
+	s.cache.invalidate(descendants...)
+}
+
+// WithTx runs fn within a transaction of the wrapped storage.
+// The directories written in fn are invalidated once the transaction ends,
+// along with the descendants of the moved ones.
+func (s *Storage) WithTx(ctx context.Context, fn func(storage.DirectoryAdmin) error) error {
+	var written, moved []apiv1.DirectoryID
+
+	err := s.DirectoryAdmin.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		txs := &Storage{DirectoryAdmin: tx}
+
+		// The transaction may be retried, so the directories written
+		// by all the attempts are invalidated.
+		defer func() {
+			written = append(written, txs.written...)
+			moved = append(moved, txs.moved...)
+		}()
+
+		return fn(txs)
+	})
+
+	s.invalidate(written...)
+
+	for _, id := range moved {
+		s.invalidateMoved(ctx, id, nil)
+	}
+
+	return err
+}
+
+func (s *Storage) GetDirectory(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	options ...storage.Option,
+) (*apiv1.Directory, error) {
+	opts := storage.BuildOptions(options)
+
+	if s.cache == nil || !opts.AsOf.IsZero() {
+		return s.DirectoryAdmin.GetDirectory(ctx, id, options...)
+	}
+
+	d, ok := s.cache.getDirectory(id)
+	if !ok {
+		generation := s.cache.currentGeneration()
+
+		// Deleted directories are cached as well, and filtered out as requested.
+		var err error
+
+		d, err = s.DirectoryAdmin.GetDirectory(ctx, id, storage.WithDeletedDirectories)
+		if err != nil {
+			return nil, err
+		}
+
+		s.cache.addDirectory(generation, d)
+	}
+
+	if d.DeletedAt != nil && !opts.WithDeletedDirectories {
+		return nil, storage.ErrDirectoryNotFound
+	}
+
+	return d, nil
+}
+
+// GetDirectories returns the cached directories, and reads the missing
+// ones from the wrapped storage in a single call.
+func (s *Storage) GetDirectories(
+	ctx context.Context,
+	ids []apiv1.DirectoryID,
+	options ...storage.Option,
+) ([]*apiv1.Directory, error) {
+	opts := storage.BuildOptions(options)
+
+	if s.cache == nil || !opts.AsOf.IsZero() {
+		return s.DirectoryAdmin.GetDirectories(ctx, ids, options...)
+	}
+
+	found := make(map[apiv1.DirectoryID]*apiv1.Directory, len(ids))
+
+	var missing []apiv1.DirectoryID
+
+	for _, id := range ids {
+		if _, ok := found[id]; ok {
+			continue
+		}
+
+		if d, ok := s.cache.getDirectory(id); ok {
+			found[id] = d
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		generation := s.cache.currentGeneration()
+
+		dirs, err := s.DirectoryAdmin.GetDirectories(ctx, missing, storage.WithDeletedDirectories)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range dirs {
+			s.cache.addDirectory(generation, d)
+			found[d.Id] = d
+		}
+	}
+
+	dirs := make([]*apiv1.Directory, 0, len(found))
+
+	for _, id := range ids {
+		d, ok := found[id]
+		if !ok || (d.DeletedAt != nil && !opts.WithDeletedDirectories) {
+			continue
+		}
+
+		dirs = append(dirs, d)
+		// Each directory is only returned once, even if requested multiple times.
+		delete(found, id)
+	}
+
+	return dirs, nil
+}
+
+func (s *Storage) GetParents(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	options ...storage.Option,
+) ([]apiv1.DirectoryID, error) {
+	return s.getParents(ctx, id, nil, options, func() ([]apiv1.DirectoryID, error) {
+		return s.DirectoryAdmin.GetParents(ctx, id, options...)
+	})
+}
+
+func (s *Storage) GetParentsUntilAncestor(ctx context.Context,
+	child apiv1.DirectoryID,
+	ancestor apiv1.DirectoryID,
+	options ...storage.Option,
+) ([]apiv1.DirectoryID, error) {
+	return s.getParents(ctx, child, &ancestor, options, func() ([]apiv1.DirectoryID, error) {
+		return s.DirectoryAdmin.GetParentsUntilAncestor(ctx, child, ancestor, options...)
+	})
+}
+
+// getParents returns the cached ancestor chain of the directory, or reads it
+// with the provided function and caches it. Only the first page is cached.
+func (s *Storage) getParents(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	ancestor *apiv1.DirectoryID,
+	options []storage.Option,
+	read func() ([]apiv1.DirectoryID, error),
+) ([]apiv1.DirectoryID, error) {
+	opts := storage.BuildOptions(options)
+
+	if s.cache == nil || !opts.AsOf.IsZero() || opts.Cursor != nil || opts.GetPageOffset() != 0 {
+		return read()
+	}
+
+	key := parentsKey{
+		id:          id,
+		withDeleted: opts.WithDeletedDirectories,
+		pageSize:    opts.GetPageSize(),
+	}
+
+	if ancestor != nil {
+		key.ancestor = *ancestor
+	}
+
+	if parents, ok := s.cache.getParents(key); ok {
+		return parents, nil
+	}
+
+	generation := s.cache.currentGeneration()
+
+	parents, err := read()
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.addParents(generation, key, parents)
+
+	return parents, nil
+}
+
+func (s *Storage) UpdateDirectory(ctx context.Context, d *apiv1.Directory) error {
+	defer s.invalidate(d.Id)
+
+	return s.DirectoryAdmin.UpdateDirectory(ctx, d)
+}
+
+func (s *Storage) UpdateDirectoryIfRevision(ctx context.Context, d *apiv1.Directory, revision int64) error {
+	defer s.invalidate(d.Id)
+
+	return s.DirectoryAdmin.UpdateDirectoryIfRevision(ctx, d, revision)
+}
+
+func (s *Storage) DeleteDirectory(ctx context.Context, id apiv1.DirectoryID) ([]*apiv1.Directory, error) {
+	affected, err := s.DirectoryAdmin.DeleteDirectory(ctx, id)
+
+	s.invalidate(affectedIDs(id, affected)...)
+
+	return affected, err
+}
+
+func (s *Storage) RestoreDirectory(ctx context.Context, id apiv1.DirectoryID) ([]*apiv1.Directory, error) {
+	affected, err := s.DirectoryAdmin.RestoreDirectory(ctx, id)
+
+	s.invalidate(affectedIDs(id, affected)...)
+
+	return affected, err
+}
+
+func (s *Storage) PurgeDirectory(ctx context.Context, id apiv1.DirectoryID) ([]*apiv1.Directory, error) {
+	affected, err := s.DirectoryAdmin.PurgeDirectory(ctx, id)
+
+	s.invalidate(affectedIDs(id, affected)...)
+
+	return affected, err
+}
+
+func (s *Storage) MoveDirectory(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	moved, oldParent, err := s.DirectoryAdmin.MoveDirectory(ctx, id, parent)
+
+	s.invalidateMoved(ctx, id, err)
+
+	return moved, oldParent, err
+}
+
+func (s *Storage) PromoteToRoot(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	promoted, oldParent, err := s.DirectoryAdmin.PromoteToRoot(ctx, id)
+
+	s.invalidateMoved(ctx, id, err)
+
+	return promoted, oldParent, err
+}
+
+func (s *Storage) DemoteRoot(ctx context.Context, id, parent apiv1.DirectoryID) (*apiv1.Directory, error) {
+	demoted, err := s.DirectoryAdmin.DemoteRoot(ctx, id, parent)
+
+	s.invalidateMoved(ctx, id, err)
+
+	return demoted, err
+}
+
+func (s *Storage) DeleteDirectoryIfRevision(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	revision int64,
+) ([]*apiv1.Directory, error) {
+	affected, err := s.DirectoryAdmin.DeleteDirectoryIfRevision(ctx, id, revision)
+
+	s.invalidate(affectedIDs(id, affected)...)
+
+	return affected, err
+}
+
+func (s *Storage) MoveDirectoryIfRevision(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+	revision int64,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	moved, oldParent, err := s.DirectoryAdmin.MoveDirectoryIfRevision(ctx, id, parent, revision)
+
+	s.invalidateMoved(ctx, id, err)
+
+	return moved, oldParent, err
+}
+
+func (s *Storage) PromoteToRootIfRevision(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	revision int64,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	promoted, oldParent, err := s.DirectoryAdmin.PromoteToRootIfRevision(ctx, id, revision)
+
+	s.invalidateMoved(ctx, id, err)
+
+	return promoted, oldParent, err
+}
+
+func (s *Storage) DemoteRootIfRevision(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+	revision int64,
+) (*apiv1.Directory, error) {
+	demoted, err := s.DirectoryAdmin.DemoteRootIfRevision(ctx, id, parent, revision)
+
+	s.invalidateMoved(ctx, id, err)
+
+	return demoted, err
+}
+
+// affectedIDs returns the id of the directory along with the ids of the
+// directories affected by a write on it.
+func affectedIDs(id apiv1.DirectoryID, affected []*apiv1.Directory) []apiv1.DirectoryID {
+	ids := []apiv1.DirectoryID{id}
+
+	for _, d := range affected {
+		ids = append(ids, d.Id)
+	}
+
+	return ids
+}
@@ -0,0 +1,313 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/cache"
+	"github.com/infratographer/fertilesoil/storage/memory"
+)
+
+// chanWatcher is a clientv1.Watcher sending the events and errors sent to its channels.
+type chanWatcher struct {
+	events chan *v1.DirectoryEvent
+	errs   chan error
+}
+
+func newChanWatcher() *chanWatcher {
+	return &chanWatcher{
+		events: make(chan *v1.DirectoryEvent),
+		errs:   make(chan error),
+	}
+}
+
+func (w *chanWatcher) Watch(ctx context.Context) (<-chan *v1.DirectoryEvent, <-chan error) {
+	return w.events, w.errs
+}
+
+// withTree creates a root directory with a child, which has a child of its own.
+func withTree(t *testing.T, store storage.DirectoryAdmin) (root, child, grandchild *v1.Directory) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	root, err := store.CreateRoot(ctx, &v1.Directory{Name: "root"})
+	assert.NoError(t, err, "error creating root directory")
+
+	child, err = store.CreateDirectory(ctx, &v1.Directory{Name: "child", Parent: &root.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	grandchild, err = store.CreateDirectory(ctx, &v1.Directory{Name: "grandchild", Parent: &child.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	return root, child, grandchild
+}
+
+func TestGetDirectoryIsCached(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := cache.StorageWithCache(memory.NewDirectoryDriver())
+	_, child, _ := withTree(t, store)
+
+	d, err := store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, "child", d.Name, "name should match")
+
+	// Changes to the returned directory don't reach the cache.
+	d.Name = "changed"
+	(*d.Metadata)["key"] = "value"
+
+	d, err = store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, "child", d.Name, "name should match")
+	assert.Empty(t, *d.Metadata, "metadata should be empty")
+
+	stats := store.Stats()
+	assert.Equal(t, uint64(1), stats.DirectoryHits, "should have hit the cache")
+	assert.Equal(t, uint64(1), stats.DirectoryMisses, "should have missed the cache once")
+}
+
+func TestUpdateInvalidatesDirectory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := cache.StorageWithCache(memory.NewDirectoryDriver())
+	_, child, _ := withTree(t, store)
+
+	d, err := store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error getting directory")
+
+	d.Name = "renamed"
+	assert.NoError(t, store.UpdateDirectory(ctx, d), "error updating directory")
+
+	d, err = store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, "renamed", d.Name, "name should have been updated")
+}
+
+func TestDeletedDirectoriesAreFiltered(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := cache.StorageWithCache(memory.NewDirectoryDriver())
+	_, child, grandchild := withTree(t, store)
+
+	_, err := store.GetDirectory(ctx, grandchild.Id)
+	assert.NoError(t, err, "error getting directory")
+
+	_, err = store.DeleteDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	_, err = store.GetDirectory(ctx, grandchild.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "deleted directory should not be found")
+
+	d, err := store.GetDirectory(ctx, grandchild.Id, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting deleted directory")
+	assert.NotNil(t, d.DeletedAt, "directory should be deleted")
+
+	dirs, err := store.GetDirectories(ctx, []v1.DirectoryID{child.Id, grandchild.Id})
+	assert.NoError(t, err, "error getting directories")
+	assert.Empty(t, dirs, "deleted directories should not be returned")
+
+	dirs, err = store.GetDirectories(ctx, []v1.DirectoryID{grandchild.Id, child.Id, grandchild.Id},
+		storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting directories")
+	assert.Len(t, dirs, 2, "each directory should be returned once")
+	assert.Equal(t, grandchild.Id, dirs[0].Id, "directories should be in the requested order")
+}
+
+func TestMoveInvalidatesDescendantParents(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := cache.StorageWithCache(memory.NewDirectoryDriver())
+	root, child, grandchild := withTree(t, store)
+
+	other, err := store.CreateRoot(ctx, &v1.Directory{Name: "other"})
+	assert.NoError(t, err, "error creating root directory")
+
+	for i := 0; i < 2; i++ {
+		parents, err := store.GetParents(ctx, grandchild.Id)
+		assert.NoError(t, err, "error getting parents")
+		assert.Equal(t, []v1.DirectoryID{child.Id, root.Id}, parents, "parents should match")
+	}
+
+	parents, err := store.GetParentsUntilAncestor(ctx, grandchild.Id, root.Id)
+	assert.NoError(t, err, "error getting parents")
+	assert.Equal(t, []v1.DirectoryID{child.Id, root.Id}, parents, "parents should match")
+
+	_, _, err = store.MoveDirectory(ctx, child.Id, other.Id)
+	assert.NoError(t, err, "error moving directory")
+
+	parents, err = store.GetParents(ctx, grandchild.Id)
+	assert.NoError(t, err, "error getting parents")
+	assert.Equal(t, []v1.DirectoryID{child.Id, other.Id}, parents, "parents should have been updated")
+
+	parents, err = store.GetParentsUntilAncestor(ctx, grandchild.Id, root.Id)
+	assert.NoError(t, err, "error getting parents")
+	assert.Equal(t, []v1.DirectoryID{child.Id, other.Id}, parents, "root should no longer be an ancestor")
+
+	stats := store.Stats()
+	assert.Equal(t, uint64(1), stats.ParentsHits, "should have hit the cache once")
+	assert.Equal(t, uint64(4), stats.ParentsMisses, "should have missed the cache")
+}
+
+func TestMoveInvalidatesDescendants(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := cache.StorageWithCache(memory.NewDirectoryDriver())
+	root, child, grandchild := withTree(t, store)
+
+	unique, err := store.CreateRoot(ctx, &v1.Directory{Name: "unique", UniqueSiblingNames: true})
+	assert.NoError(t, err, "error creating root directory")
+
+	d, err := store.GetDirectory(ctx, grandchild.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.False(t, d.UniqueSiblingNames, "directory should not enforce unique sibling names")
+
+	_, _, err = store.MoveDirectory(ctx, child.Id, unique.Id)
+	assert.NoError(t, err, "error moving directory")
+
+	d, err = store.GetDirectory(ctx, grandchild.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.True(t, d.UniqueSiblingNames, "descendants should take the setting of their new tree")
+
+	// Within a transaction, the descendants are invalidated once it ends.
+	err = store.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		_, _, err := tx.MoveDirectory(ctx, child.Id, root.Id)
+		return err
+	})
+	assert.NoError(t, err, "error running transaction")
+
+	d, err = store.GetDirectory(ctx, grandchild.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.False(t, d.UniqueSiblingNames, "descendants should take the setting of their new tree")
+}
+
+func TestTransactionInvalidatesOnceCommitted(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := cache.StorageWithCache(memory.NewDirectoryDriver())
+	_, child, _ := withTree(t, store)
+
+	_, err := store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error getting directory")
+
+	err = store.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		d, err := tx.GetDirectory(ctx, child.Id)
+		if err != nil {
+			return err
+		}
+
+		d.Name = "renamed"
+
+		if err := tx.UpdateDirectory(ctx, d); err != nil {
+			return err
+		}
+
+		// The uncommitted change is seen within the transaction.
+		d, err = tx.GetDirectory(ctx, child.Id)
+		assert.NoError(t, err, "error getting directory")
+		assert.Equal(t, "renamed", d.Name, "name should have been updated")
+
+		return nil
+	})
+	assert.NoError(t, err, "error running transaction")
+
+	d, err := store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, "renamed", d.Name, "name should have been updated")
+}
+
+func TestWatchInvalidatesOnEvents(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Changes made to the wrapped storage stand for the ones made by other replicas.
+	backend := memory.NewDirectoryDriver()
+	store := cache.StorageWithCache(backend)
+	_, child, _ := withTree(t, store)
+
+	w := newChanWatcher()
+	done := make(chan error)
+
+	go func() {
+		done <- store.Watch(ctx, w)
+	}()
+
+	_, err := store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error getting directory")
+
+	d, err := backend.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error getting directory")
+
+	d.Name = "renamed"
+	assert.NoError(t, backend.UpdateDirectory(ctx, d), "error updating directory")
+
+	cached, err := store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, "child", cached.Name, "the change should not be seen before the event")
+
+	w.events <- &v1.DirectoryEvent{Type: v1.EventTypeUpdate, Directory: *d}
+
+	cached, err = store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, "renamed", cached.Name, "the change should be seen after the event")
+
+	cancel()
+	assert.NoError(t, <-done, "watch should stop once the context is done")
+}
+
+func TestWatchFailureDisablesCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := cache.StorageWithCache(memory.NewDirectoryDriver())
+	_, child, _ := withTree(t, store)
+
+	w := newChanWatcher()
+	done := make(chan error)
+	errWatch := errors.New("watch failed")
+
+	go func() {
+		done <- store.Watch(ctx, w)
+	}()
+
+	w.errs <- errWatch
+	assert.ErrorIs(t, <-done, errWatch, "watch should return the error of the watcher")
+
+	for i := 0; i < 2; i++ {
+		_, err := store.GetDirectory(ctx, child.Id)
+		assert.NoError(t, err, "error getting directory")
+	}
+
+	stats := store.Stats()
+	assert.Zero(t, stats.DirectoryHits, "the cache should be disabled")
+}
+
+func TestCacheIsBounded(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := cache.StorageWithCache(memory.NewDirectoryDriver(), cache.WithSize(1))
+	root, child, _ := withTree(t, store)
+
+	for _, id := range []v1.DirectoryID{root.Id, child.Id, root.Id} {
+		_, err := store.GetDirectory(ctx, id)
+		assert.NoError(t, err, "error getting directory")
+	}
+
+	stats := store.Stats()
+	assert.Zero(t, stats.DirectoryHits, "evicted directories should not be hit")
+	assert.Equal(t, uint64(3), stats.DirectoryMisses, "should have missed the cache")
+}
@@ -0,0 +1,18 @@
+package driver_test
+
+import (
+	"testing"
+
+	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/crdb/driver"
+	"github.com/infratographer/fertilesoil/storage/crdb/utils"
+	"github.com/infratographer/fertilesoil/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RunConformance(t, func(t *testing.T) storage.DirectoryAdmin {
+		return driver.NewDirectoryDriver(utils.GetNewTestDB(t, baseDBURL))
+	})
+}
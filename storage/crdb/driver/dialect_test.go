@@ -0,0 +1,24 @@
+package driver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsAsOfOutOfRange(t *testing.T) {
+	t.Parallel()
+
+	gcErr := &pq.Error{
+		Code:    "XXUUU",
+		Message: "batch timestamp 1690000000.000000000,0 must be after replica GC threshold 1690000100.000000000,0",
+	}
+
+	assert.True(t, dialect{}.IsAsOfOutOfRange(fmt.Errorf("error querying directory: %w", gcErr)),
+		"reads older than the GC threshold should be out of range")
+	assert.False(t, dialect{}.IsAsOfOutOfRange(&pq.Error{Code: "42P01", Message: `relation "directories" does not exist`}),
+		"other errors should not be out of range")
+	assert.False(t, dialect{}.IsAsOfOutOfRange(nil), "no error should not be out of range")
+}
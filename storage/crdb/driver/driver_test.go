@@ -342,6 +342,84 @@ func TestDirectoryPagination(t *testing.T) {
 	}
 }
 
+func TestDirectoryCursorPagination(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	rootdir, lastDir, err := createDirectoryHierarchy(store, 17)
+	assert.NoError(t, err, "creating testing directory hierarchy should not return an error")
+
+	for i := 1; i < 17; i++ {
+		d := &v1.Directory{
+			Name: "root" + strconv.Itoa(i),
+		}
+		_, err := store.CreateRoot(context.Background(), d)
+		assert.NoError(t, err, "error creating root directory")
+	}
+
+	cursorAfter := func(ids []v1.DirectoryID) storage.Option {
+		d, err := store.GetDirectory(context.Background(), ids[len(ids)-1])
+		assert.NoError(t, err, "getting last directory of page should not return error")
+
+		return storage.WithCursor(storage.NewCursor(d))
+	}
+
+	// Listing Root directories
+	roots, err := store.ListRoots(context.Background(), storage.Pagination(1, 20))
+	assert.NoError(t, err, "listing roots should not return error")
+
+	page1, err := store.ListRoots(context.Background())
+	assert.NoError(t, err, "listing roots should not return error")
+	assert.Equal(t, roots[:storage.DefaultPageSize], page1, "first page doesn't match full results")
+
+	page2, err := store.ListRoots(context.Background(), cursorAfter(page1))
+	assert.NoError(t, err, "listing roots with cursor should not return error")
+	assert.Equal(t, roots[storage.DefaultPageSize:], page2, "cursor page doesn't match remaining results")
+
+	// A cursor after the last root returns an empty page
+	results, err := store.ListRoots(context.Background(), cursorAfter(roots))
+	assert.NoError(t, err, "listing roots with cursor should not return error")
+	assert.Len(t, results, 0, "unexpected results after the last root")
+
+	// Getting Children
+	all, err := store.GetChildren(context.Background(), rootdir.Id, storage.Pagination(1, 20))
+	assert.NoError(t, err, "listing children should not return error")
+
+	page1, err = store.GetChildren(context.Background(), rootdir.Id)
+	assert.NoError(t, err, "listing children should not return error")
+	assert.Equal(t, all[:storage.DefaultPageSize], page1, "first page doesn't match full results")
+
+	page2, err = store.GetChildren(context.Background(), rootdir.Id, cursorAfter(page1))
+	assert.NoError(t, err, "listing children with cursor should not return error")
+	assert.Equal(t, all[storage.DefaultPageSize:], page2, "cursor page doesn't match remaining results")
+
+	// Getting Parents
+	all, err = store.GetParents(context.Background(), lastDir.Id, storage.Pagination(1, 20))
+	assert.NoError(t, err, "listing parents should not return error")
+
+	page1, err = store.GetParents(context.Background(), lastDir.Id)
+	assert.NoError(t, err, "listing parents should not return error")
+	assert.Equal(t, all[:storage.DefaultPageSize], page1, "first page doesn't match full results")
+
+	page2, err = store.GetParents(context.Background(), lastDir.Id, cursorAfter(page1))
+	assert.NoError(t, err, "listing parents with cursor should not return error")
+	assert.Equal(t, all[storage.DefaultPageSize:], page2, "cursor page doesn't match remaining results")
+
+	// Getting Parents Until
+	all, err = store.GetParentsUntilAncestor(context.Background(), lastDir.Id, rootdir.Id, storage.Pagination(1, 20))
+	assert.NoError(t, err, "listing parents until ancestor should not return error")
+
+	page1, err = store.GetParentsUntilAncestor(context.Background(), lastDir.Id, rootdir.Id)
+	assert.NoError(t, err, "listing parents until ancestor should not return error")
+	assert.Equal(t, all[:storage.DefaultPageSize], page1, "first page doesn't match full results")
+
+	page2, err = store.GetParentsUntilAncestor(context.Background(), lastDir.Id, rootdir.Id, cursorAfter(page1))
+	assert.NoError(t, err, "listing parents until ancestor with cursor should not return error")
+	assert.Equal(t, all[storage.DefaultPageSize:], page2, "cursor page doesn't match remaining results")
+}
+
 func TestCreateAndUpdateDirectory(t *testing.T) {
 	t.Parallel()
 
@@ -391,6 +469,140 @@ func TestCreateAndUpdateDirectory(t *testing.T) {
 	assert.Error(t, err, "read only error should've been returned")
 }
 
+func TestUpdateDirectoryIfRevision(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	rootdir := withRootDir(t, store)
+	assert.Equal(t, int64(1), rootdir.Revision, "new directories start at revision 1")
+
+	d, err := store.CreateDirectory(context.Background(), &v1.Directory{
+		Name:   "testdir",
+		Parent: &rootdir.Id,
+	})
+	assert.NoError(t, err, "error creating directory")
+	assert.Equal(t, int64(1), d.Revision, "new directories start at revision 1")
+
+	d.Name = "newtestdir"
+
+	err = store.UpdateDirectoryIfRevision(context.Background(), d, 1)
+	assert.NoError(t, err, "error updating directory at the current revision")
+	assert.Equal(t, int64(2), d.Revision, "update should bump the revision")
+
+	stale := *d
+	stale.Name = "staletestdir"
+
+	err = store.UpdateDirectoryIfRevision(context.Background(), &stale, 1)
+	assert.ErrorIs(t, err, storage.ErrRevisionConflict, "stale revision should conflict")
+
+	got, err := store.GetDirectory(context.Background(), d.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, "newtestdir", got.Name, "conflicting update should not be applied")
+	assert.Equal(t, int64(2), got.Revision, "conflicting update should not bump the revision")
+
+	err = store.UpdateDirectory(context.Background(), got)
+	assert.NoError(t, err, "error updating directory")
+	assert.Equal(t, int64(3), got.Revision, "unconditional update should bump the revision")
+
+	moved, _, err := store.PromoteToRoot(context.Background(), d.Id)
+	assert.NoError(t, err, "error promoting directory")
+	assert.Equal(t, int64(4), moved.Revision, "promotion should bump the revision")
+
+	err = store.UpdateDirectoryIfRevision(context.Background(), &v1.Directory{Id: v1.DirectoryID(uuid.New())}, 1)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "unknown directory should not be found")
+}
+
+func TestUniqueSiblingNames(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	rootdir, err := store.CreateRoot(ctx, &v1.Directory{Name: "root", UniqueSiblingNames: true})
+	assert.NoError(t, err, "error creating root directory")
+	assert.True(t, rootdir.UniqueSiblingNames, "root should enforce unique sibling names")
+
+	d, err := store.CreateDirectory(ctx, &v1.Directory{Name: "testdir", Parent: &rootdir.Id})
+	assert.NoError(t, err, "error creating directory")
+	assert.True(t, d.UniqueSiblingNames, "children should inherit unique sibling names")
+
+	_, err = store.CreateDirectory(ctx, &v1.Directory{Name: "testdir", Parent: &rootdir.Id})
+	assert.ErrorIs(t, err, storage.ErrDirectoryNameConflict, "expected name conflict")
+
+	other, err := store.CreateDirectory(ctx, &v1.Directory{Name: "other", Parent: &rootdir.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	other.Name = "testdir"
+	err = store.UpdateDirectory(ctx, other)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNameConflict, "expected name conflict")
+
+	// Deleted directories don't take part in the rule
+	_, err = store.DeleteDirectory(ctx, d.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	_, err = store.CreateDirectory(ctx, &v1.Directory{Name: "testdir", Parent: &rootdir.Id})
+	assert.NoError(t, err, "deleted siblings should not conflict")
+
+	_, err = store.RestoreDirectory(ctx, d.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNameConflict, "expected name conflict")
+
+	// Directories moved into the tree take its setting along with their descendants
+	plain := withRootDir(t, store)
+	assert.False(t, plain.UniqueSiblingNames, "roots should not enforce unique sibling names by default")
+
+	sub, err := store.CreateDirectory(ctx, &v1.Directory{Name: "sub", Parent: &plain.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	for i := 0; i < 2; i++ {
+		_, err = store.CreateDirectory(ctx, &v1.Directory{Name: "dup", Parent: &sub.Id})
+		assert.NoError(t, err, "duplicate names should be allowed")
+	}
+
+	_, _, err = store.MoveDirectory(ctx, sub.Id, rootdir.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNameConflict, "descendants should conflict once moved")
+
+	got, err := store.GetDirectory(ctx, sub.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, plain.Id, *got.Parent, "conflicting move should not be applied")
+
+	moved, err := store.CreateDirectory(ctx, &v1.Directory{Name: "moved", Parent: &plain.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	movedChild, err := store.CreateDirectory(ctx, &v1.Directory{Name: "child", Parent: &moved.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	_, _, err = store.MoveDirectory(ctx, moved.Id, rootdir.Id)
+	assert.NoError(t, err, "error moving directory")
+
+	got, err = store.GetDirectory(ctx, movedChild.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.True(t, got.UniqueSiblingNames, "descendants should take the setting of the new parent")
+}
+
+func TestUniqueSiblingNamesForAllDirectories(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db, driver.WithUniqueSiblingNames())
+
+	rootdir := withRootDir(t, store)
+	assert.True(t, rootdir.UniqueSiblingNames, "roots should enforce unique sibling names")
+
+	_, err := store.CreateDirectory(ctx, &v1.Directory{Name: "testdir", Parent: &rootdir.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	_, err = store.CreateDirectory(ctx, &v1.Directory{Name: "testdir", Parent: &rootdir.Id})
+	assert.ErrorIs(t, err, storage.ErrDirectoryNameConflict, "expected name conflict")
+
+	// Directories created without the option are left as they are
+	plain := withRootDir(t, driver.NewDirectoryDriver(db))
+	assert.False(t, plain.UniqueSiblingNames, "roots should not enforce unique sibling names by default")
+}
+
 func TestCreateDirectoryWithParentThatDoesntExist(t *testing.T) {
 	t.Parallel()
 
@@ -799,6 +1011,188 @@ func TestGetChildrenMultiple(t *testing.T) {
 	assert.Contains(t, children, rd4.Id, "should contain id")
 }
 
+func TestListBySelector(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	prodRoot, err := store.CreateRoot(ctx, &v1.Directory{
+		Name:     "prod",
+		Metadata: &v1.DirectoryMetadata{"billing_account": "123"},
+	})
+	assert.NoError(t, err, "error creating root directory")
+
+	_, err = store.CreateRoot(ctx, &v1.Directory{
+		Name:     "other",
+		Metadata: &v1.DirectoryMetadata{"billing_account": "456"},
+	})
+	assert.NoError(t, err, "error creating root directory")
+
+	sel, err := storage.ParseSelector("billing_account=123")
+	assert.NoError(t, err, "error parsing selector")
+
+	roots, err := store.ListRoots(ctx, storage.WithSelector(sel))
+	assert.NoError(t, err, "error listing roots")
+	assert.Equal(t, []v1.DirectoryID{prodRoot.Id}, roots, "unexpected roots")
+
+	byEnv := map[string]v1.DirectoryID{}
+
+	for _, env := range []string{"prod", "dev", "qa"} {
+		d, err := store.CreateDirectory(ctx, &v1.Directory{
+			Name:     env,
+			Parent:   &prodRoot.Id,
+			Metadata: &v1.DirectoryMetadata{"env": env},
+		})
+		assert.NoError(t, err, "error creating directory")
+
+		byEnv[env] = d.Id
+	}
+
+	unset, err := store.CreateDirectory(ctx, &v1.Directory{Name: "unset", Parent: &prodRoot.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	tests := map[string][]v1.DirectoryID{
+		"env=prod":                {byEnv["prod"]},
+		"env!=prod":               {byEnv["dev"], byEnv["qa"], unset.Id},
+		"env in (dev,qa)":         {byEnv["dev"], byEnv["qa"]},
+		"env notin (prod,dev,qa)": {unset.Id},
+		"env":                     {byEnv["prod"], byEnv["dev"], byEnv["qa"]},
+		"!env":                    {unset.Id},
+		"env=prod,!env":           nil,
+	}
+
+	for selector, expected := range tests {
+		sel, err := storage.ParseSelector(selector)
+		assert.NoError(t, err, "error parsing selector")
+
+		children, err := store.GetChildren(ctx, prodRoot.Id, storage.WithSelector(sel))
+		assert.NoError(t, err, "error getting children")
+		assert.ElementsMatch(t, expected, children, "unexpected children for %q", selector)
+	}
+}
+
+func TestGetChildrenMaxDepth(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	rootdir := withRootDir(t, store)
+
+	child, err := store.CreateDirectory(ctx, &v1.Directory{Name: "child", Parent: &rootdir.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	grandchild, err := store.CreateDirectory(ctx, &v1.Directory{Name: "grandchild", Parent: &child.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	greatgrandchild, err := store.CreateDirectory(ctx, &v1.Directory{Name: "greatgrandchild", Parent: &grandchild.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	children, err := store.GetChildren(ctx, rootdir.Id, storage.WithMaxDepth(1))
+	assert.NoError(t, err, "error getting children")
+	assert.Equal(t, []v1.DirectoryID{child.Id}, children, "only immediate children should be returned")
+
+	children, err = store.GetChildren(ctx, rootdir.Id, storage.WithMaxDepth(2))
+	assert.NoError(t, err, "error getting children")
+	assert.Equal(t, []v1.DirectoryID{child.Id, grandchild.Id}, children, "unexpected children")
+
+	children, err = store.GetChildren(ctx, rootdir.Id)
+	assert.NoError(t, err, "error getting children")
+	assert.Equal(t, []v1.DirectoryID{child.Id, grandchild.Id, greatgrandchild.Id}, children, "unexpected children")
+
+	children, err = store.GetChildren(ctx, greatgrandchild.Id, storage.WithMaxDepth(1))
+	assert.NoError(t, err, "error getting children")
+	assert.Empty(t, children, "leaf directories have no children")
+}
+
+func TestGetSubtree(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	rootdir := withRootDir(t, store)
+
+	child, err := store.CreateDirectory(ctx, &v1.Directory{Name: "child", Parent: &rootdir.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	grandchild, err := store.CreateDirectory(ctx, &v1.Directory{Name: "grandchild", Parent: &child.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	sibling, err := store.CreateDirectory(ctx, &v1.Directory{Name: "sibling", Parent: &rootdir.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	_, err = store.DeleteDirectory(ctx, sibling.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	tree, err := store.GetSubtree(ctx, rootdir.Id)
+	assert.NoError(t, err, "error getting subtree")
+	assert.Equal(t, rootdir.Id, tree.Directory.Id, "unexpected tree root")
+	assert.Len(t, tree.Children, 1, "deleted directories should be left out")
+	assert.Equal(t, child.Id, tree.Children[0].Directory.Id, "unexpected child")
+	assert.Equal(t, "child", tree.Children[0].Directory.Name, "full directories should be returned")
+	assert.Len(t, tree.Children[0].Children, 1, "unexpected grandchildren")
+	assert.Equal(t, grandchild.Id, tree.Children[0].Children[0].Directory.Id, "unexpected grandchild")
+
+	tree, err = store.GetSubtree(ctx, rootdir.Id, storage.WithMaxDepth(1), storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting subtree")
+	assert.Len(t, tree.Children, 2, "deleted directories should be included")
+	assert.Empty(t, tree.Children[0].Children, "directories below the depth should be left out")
+
+	_, err = store.GetSubtree(ctx, rootdir.Id, storage.WithMaxNodes(2))
+	assert.ErrorIs(t, err, storage.ErrSubtreeTooLarge, "expected the subtree to be too large")
+
+	_, err = store.GetSubtree(ctx, rootdir.Id, storage.WithMaxNodes(3))
+	assert.NoError(t, err, "the subtree should fit")
+
+	_, err = store.GetSubtree(ctx, v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "unknown directories should not be found")
+}
+
+func TestGetDirectories(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	rootdir := withRootDir(t, store)
+
+	first, err := store.CreateDirectory(ctx, &v1.Directory{Name: "first", Parent: &rootdir.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	second, err := store.CreateDirectory(ctx, &v1.Directory{Name: "second", Parent: &rootdir.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	deleted, err := store.CreateDirectory(ctx, &v1.Directory{Name: "deleted", Parent: &rootdir.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	_, err = store.DeleteDirectory(ctx, deleted.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	ids := []v1.DirectoryID{second.Id, v1.DirectoryID(uuid.New()), first.Id, deleted.Id, second.Id}
+
+	dirs, err := store.GetDirectories(ctx, ids)
+	assert.NoError(t, err, "error getting directories")
+	assert.Len(t, dirs, 2, "unknown and deleted directories should be skipped")
+	assert.Equal(t, second.Id, dirs[0].Id, "directories should be in the requested order")
+	assert.Equal(t, first.Id, dirs[1].Id, "directories should be in the requested order")
+	assert.Equal(t, "first", dirs[1].Name, "full directories should be returned")
+
+	dirs, err = store.GetDirectories(ctx, ids, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting directories")
+	assert.Len(t, dirs, 3, "deleted directories should be included")
+	assert.Equal(t, deleted.Id, dirs[2].Id, "unexpected directory")
+
+	dirs, err = store.GetDirectories(ctx, nil)
+	assert.NoError(t, err, "error getting directories")
+	assert.Empty(t, dirs, "no directory should be returned")
+}
+
 func TestGetChildrenMayReturnEmptyAppropriately(t *testing.T) {
 	t.Parallel()
 
@@ -896,3 +1290,408 @@ func TestOperationsFailWithBadDatabaseConnection(t *testing.T) {
 	_, err = store.GetParentsUntilAncestor(context.Background(), someID, otherID)
 	assert.Error(t, err, "should have errored")
 }
+
+func TestMoveDirectory(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	root, last, err := createDirectoryHierarchy(store, 3)
+	assert.NoError(t, err, "error creating directory hierarchy")
+
+	oldParent := *last.Parent
+
+	moved, prev, err := store.MoveDirectory(context.Background(), last.Id, root.Id)
+	assert.NoError(t, err, "error moving directory")
+	assert.Equal(t, root.Id, *moved.Parent, "parent should be updated")
+	assert.Equal(t, oldParent, *prev, "old parent should be returned")
+	assert.True(t, moved.UpdatedAt.After(last.UpdatedAt), "updated at should be bumped")
+
+	parents, err := store.GetParents(context.Background(), last.Id)
+	assert.NoError(t, err, "error getting parents")
+	assert.Equal(t, []v1.DirectoryID{root.Id}, parents, "unexpected parents")
+}
+
+func TestMoveDirectoryRejectsCycles(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	root, last, err := createDirectoryHierarchy(store, 4)
+	assert.NoError(t, err, "error creating directory hierarchy")
+
+	children, err := store.GetChildren(context.Background(), root.Id)
+	assert.NoError(t, err, "error getting children")
+
+	// Move a directory under itself
+	_, _, err = store.MoveDirectory(context.Background(), last.Id, last.Id)
+	assert.ErrorIs(t, err, storage.ErrMoveCycle, "should have rejected moving under itself")
+
+	// Move a directory under one of its descendants
+	for _, child := range children {
+		if child == last.Id {
+			continue
+		}
+
+		_, _, err = store.MoveDirectory(context.Background(), child, last.Id)
+		assert.ErrorIs(t, err, storage.ErrMoveCycle, "should have rejected moving under a descendant")
+	}
+
+	// Demote a root under one of its descendants
+	_, err = store.DemoteRoot(context.Background(), root.Id, last.Id)
+	assert.ErrorIs(t, err, storage.ErrMoveCycle, "should have rejected demoting under a descendant")
+}
+
+func TestMoveDirectoryRootRules(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	root := withRootDir(t, store)
+	other := withRootDir(t, store)
+
+	child, err := store.CreateDirectory(context.Background(), &v1.Directory{
+		Name:   "child",
+		Parent: &root.Id,
+	})
+	assert.NoError(t, err, "error creating child directory")
+
+	// Roots can't be moved without demotion
+	_, _, err = store.MoveDirectory(context.Background(), other.Id, root.Id)
+	assert.ErrorIs(t, err, storage.ErrRootDirectoryMove, "should have rejected moving a root")
+
+	// Only roots can be demoted
+	_, err = store.DemoteRoot(context.Background(), child.Id, other.Id)
+	assert.ErrorIs(t, err, storage.ErrNotRoot, "should have rejected demoting a non-root")
+
+	// Only non-roots can be promoted
+	_, _, err = store.PromoteToRoot(context.Background(), root.Id)
+	assert.ErrorIs(t, err, storage.ErrAlreadyRoot, "should have rejected promoting a root")
+
+	// Unknown parents are rejected
+	_, _, err = store.MoveDirectory(context.Background(), child.Id, v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "should have rejected unknown parent")
+
+	// Unknown directories are rejected
+	_, _, err = store.MoveDirectory(context.Background(), v1.DirectoryID(uuid.New()), root.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "should have rejected unknown directory")
+}
+
+func TestPromoteAndDemoteDirectory(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	root := withRootDir(t, store)
+	other := withRootDir(t, store)
+
+	child, err := store.CreateDirectory(context.Background(), &v1.Directory{
+		Name:   "child",
+		Parent: &root.Id,
+	})
+	assert.NoError(t, err, "error creating child directory")
+
+	promoted, oldParent, err := store.PromoteToRoot(context.Background(), child.Id)
+	assert.NoError(t, err, "error promoting directory")
+	assert.True(t, promoted.IsRoot(), "directory should be a root")
+	assert.Equal(t, root.Id, *oldParent, "old parent should be returned")
+
+	roots, err := store.ListRoots(context.Background())
+	assert.NoError(t, err, "error listing roots")
+	assert.Contains(t, roots, child.Id, "promoted directory should be listed as root")
+
+	demoted, err := store.DemoteRoot(context.Background(), other.Id, child.Id)
+	assert.NoError(t, err, "error demoting directory")
+	assert.False(t, demoted.IsRoot(), "directory should not be a root")
+	assert.Equal(t, child.Id, *demoted.Parent, "parent should be updated")
+
+	roots, err = store.ListRoots(context.Background())
+	assert.NoError(t, err, "error listing roots")
+	assert.NotContains(t, roots, other.Id, "demoted directory should not be listed as root")
+}
+
+func TestPurgeDirectory(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	root, last, err := createDirectoryHierarchy(store, 4)
+	assert.NoError(t, err, "error creating directory hierarchy")
+
+	children, err := store.GetChildren(context.Background(), root.Id)
+	assert.NoError(t, err, "error getting children")
+	assert.Len(t, children, 3, "should have 3 children")
+
+	deleted, err := store.DeleteDirectory(context.Background(), children[0])
+	assert.NoError(t, err, "error deleting directory")
+
+	purged, err := store.PurgeDirectory(context.Background(), children[0])
+	assert.NoError(t, err, "error purging directory")
+	assert.Len(t, purged, len(deleted), "should have purged all deleted directories")
+
+	_, err = store.GetDirectory(context.Background(), last.Id, storage.WithDeletedDirectories)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "purged directory should not be found")
+
+	rootdir, err := store.GetDirectory(context.Background(), root.Id)
+	assert.NoError(t, err, "root should still exist")
+	assert.Equal(t, root.Id, rootdir.Id, "id should match")
+}
+
+func TestPurgeDirectoryNotDeleted(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	root, last, err := createDirectoryHierarchy(store, 2)
+	assert.NoError(t, err, "error creating directory hierarchy")
+
+	purged, err := store.PurgeDirectory(context.Background(), last.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotDeleted, "should have refused purging")
+	assert.Nil(t, purged, "should not have purged anything")
+
+	purged, err = store.PurgeDirectory(context.Background(), root.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotDeleted, "should have refused purging root")
+	assert.Nil(t, purged, "should not have purged anything")
+
+	purged, err = store.PurgeDirectory(context.Background(), v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "should have errored with unknown directory")
+	assert.Nil(t, purged, "should not have purged anything")
+}
+
+func TestRestoreDirectory(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	root, last, err := createDirectoryHierarchy(store, 4)
+	assert.NoError(t, err, "error creating directory hierarchy")
+
+	children, err := store.GetChildren(context.Background(), root.Id)
+	assert.NoError(t, err, "error getting children")
+
+	deleted, err := store.DeleteDirectory(context.Background(), children[0])
+	assert.NoError(t, err, "error deleting directory")
+
+	// the parent of the deepest directory is still deleted
+	_, err = store.RestoreDirectory(context.Background(), last.Id)
+	assert.ErrorIs(t, err, storage.ErrParentDirectoryDeleted, "should have refused restoring")
+
+	restored, err := store.RestoreDirectory(context.Background(), children[0])
+	assert.NoError(t, err, "error restoring directory")
+	assert.Len(t, restored, len(deleted), "should have restored all deleted directories")
+
+	for _, d := range restored {
+		assert.Nil(t, d.DeletedAt, "restored directory should not be deleted")
+	}
+
+	lastdir, err := store.GetDirectory(context.Background(), last.Id)
+	assert.NoError(t, err, "restored directory should be found")
+	assert.Equal(t, last.Id, lastdir.Id, "id should match")
+
+	_, err = store.RestoreDirectory(context.Background(), children[0])
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotDeleted, "should have refused restoring a live directory")
+
+	_, err = store.RestoreDirectory(context.Background(), v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "should have errored with unknown directory")
+}
+
+func TestRestoreDirectoryKeepsSeparatelyDeletedChildren(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	root, last, err := createDirectoryHierarchy(store, 3)
+	assert.NoError(t, err, "error creating directory hierarchy")
+
+	_, err = store.DeleteDirectory(context.Background(), last.Id)
+	assert.NoError(t, err, "error deleting deepest directory")
+
+	_, err = store.DeleteDirectory(context.Background(), *last.Parent)
+	assert.NoError(t, err, "error deleting parent directory")
+
+	restored, err := store.RestoreDirectory(context.Background(), *last.Parent)
+	assert.NoError(t, err, "error restoring directory")
+	assert.Len(t, restored, 1, "should only restore the parent directory")
+
+	_, err = store.GetDirectory(context.Background(), last.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "separately deleted directory should stay deleted")
+
+	children, err := store.GetChildren(context.Background(), root.Id)
+	assert.NoError(t, err, "error getting children")
+	assert.Equal(t, []v1.DirectoryID{*last.Parent}, children, "only the restored directory should be listed")
+}
+
+func TestWithTxCommits(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	var root, child *v1.Directory
+
+	err := store.WithTx(context.Background(), func(tx storage.DirectoryAdmin) error {
+		var err error
+
+		root, err = tx.CreateRoot(context.Background(), &v1.Directory{Name: "root"})
+		if err != nil {
+			return err
+		}
+
+		child, err = tx.CreateDirectory(context.Background(), &v1.Directory{
+			Name:   "child",
+			Parent: &root.Id,
+		})
+
+		return err
+	})
+	assert.NoError(t, err, "error running transaction")
+
+	children, err := store.GetChildren(context.Background(), root.Id)
+	assert.NoError(t, err, "error getting children")
+	assert.Equal(t, []v1.DirectoryID{child.Id}, children, "committed directories should be visible")
+}
+
+func TestWithTxRollsBack(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	rootdir := withRootDir(t, store)
+
+	var child *v1.Directory
+
+	err := store.WithTx(context.Background(), func(tx storage.DirectoryAdmin) error {
+		var err error
+
+		child, err = tx.CreateDirectory(context.Background(), &v1.Directory{
+			Name:   "child",
+			Parent: &rootdir.Id,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.DeleteDirectory(context.Background(), v1.DirectoryID(uuid.New()))
+
+		return err
+	})
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "transaction should fail")
+
+	_, err = store.GetDirectory(context.Background(), child.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "rolled back directory should not exist")
+}
+
+func TestGetHistory(t *testing.T) {
+	t.Parallel()
+
+	ctx := storage.WithActor(context.Background(), "tester")
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	rootdir := withRootDir(t, store)
+
+	d, err := store.CreateDirectory(ctx, &v1.Directory{
+		Name:     "child",
+		Parent:   &rootdir.Id,
+		Metadata: &v1.DirectoryMetadata{"env": "dev"},
+	})
+	assert.NoError(t, err, "error creating directory")
+
+	d.Metadata = &v1.DirectoryMetadata{"env": "prod"}
+
+	err = store.UpdateDirectory(ctx, d)
+	assert.NoError(t, err, "error updating directory")
+
+	_, err = store.DeleteDirectory(ctx, d.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	_, err = store.RestoreDirectory(ctx, d.Id)
+	assert.NoError(t, err, "error restoring directory")
+
+	history, err := store.GetHistory(ctx, d.Id)
+	assert.NoError(t, err, "error getting history")
+	assert.Len(t, history, 4, "every change should be recorded")
+
+	expected := []v1.EventType{v1.EventTypeRestore, v1.EventTypeDelete, v1.EventTypeUpdate, v1.EventTypeCreate}
+
+	for i, r := range history {
+		assert.Equal(t, expected[i], r.Operation, "revisions should be newest first")
+		assert.Equal(t, int64(len(expected)-i), r.Revision, "unexpected revision")
+		assert.Equal(t, "tester", r.Actor, "the actor should be recorded")
+	}
+
+	assert.Nil(t, history[3].Before, "created directories have no previous state")
+	assert.Equal(t, "dev", (*history[2].Before.Metadata)["env"], "the previous metadata should be recorded")
+	assert.Equal(t, "prod", (*history[2].After.Metadata)["env"], "the new metadata should be recorded")
+	assert.NotNil(t, history[1].After.DeletedAt, "the directory should be deleted after")
+
+	history, err = store.GetHistory(ctx, d.Id, storage.Pagination(2, 3))
+	assert.NoError(t, err, "error getting history")
+	assert.Len(t, history, 1, "unexpected page size")
+	assert.Equal(t, v1.EventTypeCreate, history[0].Operation, "unexpected revision")
+
+	// A failed transaction doesn't record anything
+	err = store.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		if _, err := tx.DeleteDirectory(ctx, d.Id); err != nil {
+			return err
+		}
+
+		_, err := tx.DeleteDirectory(ctx, v1.DirectoryID(uuid.New()))
+
+		return err
+	})
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "transaction should fail")
+
+	history, err = store.GetHistory(ctx, d.Id)
+	assert.NoError(t, err, "error getting history")
+	assert.Len(t, history, 4, "rolled back changes should not be recorded")
+}
+
+func TestGetDirectoryAsOf(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+
+	rootdir := withRootDir(t, store)
+
+	d, err := store.CreateDirectory(ctx, &v1.Directory{
+		Name:   "original",
+		Parent: &rootdir.Id,
+	})
+	assert.NoError(t, err, "error creating directory")
+
+	time.Sleep(100 * time.Millisecond)
+	asOf := time.Now()
+	time.Sleep(100 * time.Millisecond)
+
+	d.Name = "renamed"
+
+	err = store.UpdateDirectory(ctx, d)
+	assert.NoError(t, err, "error updating directory")
+
+	_, err = store.DeleteDirectory(ctx, d.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	past, err := store.GetDirectory(ctx, d.Id, storage.WithAsOf(asOf))
+	assert.NoError(t, err, "error getting directory as of a past time")
+	assert.Equal(t, "original", past.Name, "the directory should be returned as it was")
+
+	children, err := store.GetChildren(ctx, rootdir.Id, storage.WithAsOf(asOf))
+	assert.NoError(t, err, "error getting children as of a past time")
+	assert.Len(t, children, 1, "children deleted since should be listed")
+
+	history, err := store.GetHistory(ctx, d.Id, storage.WithAsOf(asOf))
+	assert.NoError(t, err, "error getting history as of a past time")
+	assert.Len(t, history, 1, "later revisions should not be returned")
+}
@@ -1,13 +1,13 @@
 package driver
 
+import "github.com/infratographer/fertilesoil/storage/sqldriver"
+
 // Options defines ways to configure the CRDB driver.
-type Options func(*Driver)
+type Options = sqldriver.Option
 
 // WithReadOnly configures the driver to be read-only.
 func WithReadOnly() Options {
-	return func(d *Driver) {
-		d.readOnly = true
-	}
+	return sqldriver.WithReadOnly()
 }
 
 // WithFastReads configures the driver to use fast reads.
@@ -17,7 +17,12 @@ func WithReadOnly() Options {
 // query will not return the latest data, but it will
 // return data that is consistent with the latest data.
 func WithFastReads() Options {
-	return func(d *Driver) {
-		d.fastReads = true
-	}
+	return sqldriver.WithFastReads()
+}
+
+// WithUniqueSiblingNames configures the driver to enforce unique
+// names among live siblings in every directory it creates.
+// Directories created before are left as they are.
+func WithUniqueSiblingNames() Options {
+	return sqldriver.WithUniqueSiblingNames()
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 
 	v1 "github.com/infratographer/fertilesoil/api/v1"
@@ -155,3 +156,78 @@ func TestReaderCannotCreateDirectory(t *testing.T) {
 	assert.ErrorIs(t, err, storage.ErrReadOnly, "error should be read only")
 	assert.Nil(t, rd, "directory should be nil")
 }
+
+func TestReaderCannotMoveDirectory(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	store := driver.NewDirectoryDriver(db)
+	rostore := driver.NewDirectoryDriver(db, driver.WithReadOnly())
+
+	root, last, err := createDirectoryHierarchy(store, 3)
+	assert.NoError(t, err, "error creating directory hierarchy")
+
+	_, _, err = rostore.MoveDirectory(context.Background(), last.Id, root.Id)
+	assert.ErrorIs(t, err, storage.ErrReadOnly, "error should be read only")
+
+	_, _, err = rostore.PromoteToRoot(context.Background(), last.Id)
+	assert.ErrorIs(t, err, storage.ErrReadOnly, "error should be read only")
+
+	_, err = rostore.DemoteRoot(context.Background(), root.Id, last.Id)
+	assert.ErrorIs(t, err, storage.ErrReadOnly, "error should be read only")
+}
+
+func TestReaderCannotDeleteDirectory(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	rostore := driver.NewDirectoryDriver(db, driver.WithReadOnly())
+
+	deleted, err := rostore.DeleteDirectory(context.Background(), v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrReadOnly, "error should be read only")
+	assert.Nil(t, deleted, "nothing should be deleted")
+}
+
+func TestReaderCannotPurgeDirectory(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	rostore := driver.NewDirectoryDriver(db, driver.WithReadOnly())
+
+	purged, err := rostore.PurgeDirectory(context.Background(), v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrReadOnly, "error should be read only")
+	assert.Nil(t, purged, "nothing should be purged")
+}
+
+func TestReaderCannotRestoreDirectory(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	rostore := driver.NewDirectoryDriver(db, driver.WithReadOnly())
+
+	restored, err := rostore.RestoreDirectory(context.Background(), v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrReadOnly, "error should be read only")
+	assert.Nil(t, restored, "nothing should be restored")
+}
+
+func TestReaderCannotUpdateDirectoryIfRevision(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	rostore := driver.NewDirectoryDriver(db, driver.WithReadOnly())
+
+	err := rostore.UpdateDirectoryIfRevision(context.Background(), &v1.Directory{Id: v1.DirectoryID(uuid.New())}, 1)
+	assert.ErrorIs(t, err, storage.ErrReadOnly, "error should be read only")
+}
+
+func TestReaderCannotWithTx(t *testing.T) {
+	t.Parallel()
+
+	db := utils.GetNewTestDB(t, baseDBURL)
+	rostore := driver.NewDirectoryDriver(db, driver.WithReadOnly())
+
+	err := rostore.WithTx(context.Background(), func(storage.DirectoryAdmin) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, storage.ErrReadOnly, "error should be read only")
+}
@@ -0,0 +1,12 @@
+-- This adds a revision to directories, which is incremented on every write.
+-- It allows clients to detect concurrent modifications of a directory.
+
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE directories ADD COLUMN IF NOT EXISTS revision INT8 NOT NULL DEFAULT 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE directories DROP COLUMN IF EXISTS revision;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- This allows enforcing unique names among live sibling directories.
+-- The rule is opt-in per directory, and child directories inherit it
+-- from their parent. Soft deleted directories don't take part in it.
+-- The column and the index are added outside of a transaction, as
+-- CockroachDB can't index a column added within the same transaction.
+
+-- +goose NO TRANSACTION
+
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE directories ADD COLUMN IF NOT EXISTS unique_sibling_names BOOL NOT NULL DEFAULT false;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE UNIQUE INDEX IF NOT EXISTS directories_unique_sibling_names ON directories (parent_id, name)
+    WHERE deleted_at IS NULL AND unique_sibling_names;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS directories@directories_unique_sibling_names CASCADE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE directories DROP COLUMN IF EXISTS unique_sibling_names;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- This indexes directory metadata, so directories can be efficiently
+-- listed by metadata selectors using JSONB containment.
+
+-- +goose Up
+-- +goose StatementBegin
+CREATE INVERTED INDEX IF NOT EXISTS directories_metadata ON directories (metadata);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS directories@directories_metadata;
+-- +goose StatementEnd
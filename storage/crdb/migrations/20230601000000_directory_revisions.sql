@@ -0,0 +1,24 @@
+-- This records the revision history of directories.
+-- A row is added by every write, in the same transaction, holding the
+-- directory as it was before and after the change. The history of a
+-- directory is removed along with it when it is purged.
+
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS directory_revisions (
+    id UUID NOT NULL DEFAULT gen_random_uuid() PRIMARY KEY,
+    directory_id UUID NOT NULL REFERENCES directories(id) ON DELETE CASCADE,
+    revision INT8 NOT NULL,
+    operation TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    before JSONB,
+    after JSONB NOT NULL,
+    INDEX directory_revisions_directory_id_revision (directory_id, revision DESC)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS directory_revisions;
+-- +goose StatementEnd
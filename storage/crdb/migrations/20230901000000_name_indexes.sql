@@ -0,0 +1,21 @@
+-- This indexes directory names, so directories can be efficiently searched
+-- by name. Exact and prefix matches use the plain index, while substring
+-- matches use the trigram one.
+
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS directories_name ON directories (name);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS directories_name_trigram ON directories USING GIN (name gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS directories@directories_name_trigram;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS directories@directories_name;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- This indexes the soft deleted directories by deletion time, so the ones
+-- past their retention can be efficiently listed for garbage collection.
+
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS directories_deleted_at ON directories (deleted_at, id) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS directories@directories_deleted_at;
+-- +goose StatementEnd
@@ -22,6 +22,19 @@ func RegisterDBArgs(v *viper.Viper, flags *pflag.FlagSet) {
 	// fast reads
 	flags.Bool("fast-reads", false, "Run the server in fast reads mode.")
 	viperx.MustBindFlag(v, "storage.fast_reads", flags.Lookup("fast-reads"))
+
+	// primary and replica connections
+	flags.String("primary-db-uri", "", "URI of the primary database, where writes are made. "+
+		"Takes precedence over the FERTILESOIL_CRDB_* environment variables.")
+	viperx.MustBindFlag(v, "crdb.uri", flags.Lookup("primary-db-uri"))
+
+	flags.String("replica-db-uri", "", "URI of a database to send reads to with fast reads, "+
+		"while writes go to the primary. Defaults to sending reads to the primary as well.")
+	viperx.MustBindFlag(v, "crdb.replica.uri", flags.Lookup("replica-db-uri"))
+
+	// unique sibling names
+	flags.Bool("unique-sibling-names", false, "Enforce unique names among sibling directories in every new directory.")
+	viperx.MustBindFlag(v, "storage.unique_sibling_names", flags.Lookup("unique-sibling-names"))
 }
 
 func GetDBConnection(v *viper.Viper, dbName string, tracing bool) (*sql.DB, error) {
@@ -29,6 +42,29 @@ func GetDBConnection(v *viper.Viper, dbName string, tracing bool) (*sql.DB, erro
 	return crdbx.NewDB(cfg, tracing)
 }
 
+// HasReplica returns whether a replica database was configured.
+func HasReplica(v *viper.Viper) bool {
+	return v.GetString("crdb.replica.uri") != ""
+}
+
+// GetReplicaDBConnection opens a connection to the replica database.
+// The connection settings other than the URI are shared with the primary.
+func GetReplicaDBConnection(v *viper.Viper, dbName string, tracing bool) (*sql.DB, error) {
+	cfg := crdbx.ConfigFromArgs(v, dbName)
+	cfg.URI = v.GetString("crdb.replica.uri")
+
+	return crdbx.NewDB(cfg, tracing)
+}
+
+// WithReplicaStorageOptions returns the storage options for the driver of the
+// replica, which is read-only and does fast reads.
+func WithReplicaStorageOptions() []driver.Options {
+	return []driver.Options{
+		driver.WithReadOnly(),
+		driver.WithFastReads(),
+	}
+}
+
 // WithStorageOptions returns the storage options for the driver.
 func WithStorageOptions(v *viper.Viper) []driver.Options {
 	var opts []driver.Options
@@ -40,5 +76,9 @@ func WithStorageOptions(v *viper.Viper) []driver.Options {
 		opts = append(opts, driver.WithFastReads())
 	}
 
+	if v.GetBool("storage.unique_sibling_names") {
+		opts = append(opts, driver.WithUniqueSiblingNames())
+	}
+
 	return opts
 }
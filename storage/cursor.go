@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+)
+
+// Cursor is the position of a directory in a keyset paginated listing.
+// Listings continue right after the directory the cursor points to.
+type Cursor struct {
+	// CreatedAt is the creation time of the directory.
+	CreatedAt time.Time
+
+	// ID is the id of the directory.
+	ID v1.DirectoryID
+}
+
+// NewCursor returns a cursor positioned at the provided directory.
+func NewCursor(d *v1.Directory) *Cursor {
+	return &Cursor{
+		CreatedAt: d.CreatedAt,
+		ID:        d.Id,
+	}
+}
+
+// String encodes the cursor as an opaque string.
+func (c *Cursor) String() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + c.ID.String()
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a cursor previously encoded with Cursor.String.
+func ParseCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &Cursor{}
+
+	c.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	c.ID, err = v1.ParseDirectoryID(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	return c, nil
+}
+
+// After reports whether the provided directory is ordered after the cursor.
+// Directories are ordered by creation time, then by id.
+func (c *Cursor) After(d *v1.Directory) bool {
+	if !d.CreatedAt.Equal(c.CreatedAt) {
+		return d.CreatedAt.After(c.CreatedAt)
+	}
+
+	return d.Id.String() > c.ID.String()
+}
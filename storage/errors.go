@@ -23,4 +23,69 @@ var (
 	// ErrNoRootAccess is returned when a root directory is attempted to be accessed
 	// without root access.
 	ErrNoRootAccess = errors.New("attempted to access root directory without root access")
+
+	// ErrMoveCycle is returned when a directory is moved under itself or one of its descendants.
+	ErrMoveCycle = errors.New("directory cannot be moved under itself or one of its descendants")
+
+	// ErrRootDirectoryMove is returned when a root directory is moved without explicitly demoting it.
+	ErrRootDirectoryMove = errors.New("root directory must be explicitly demoted to be moved")
+
+	// ErrAlreadyRoot is returned when promoting a directory which is already a root directory.
+	ErrAlreadyRoot = errors.New("directory is already a root directory")
+
+	// ErrNotRoot is returned when demoting a directory which is not a root directory.
+	ErrNotRoot = errors.New("directory is not a root directory")
+
+	// ErrDirectoryNotDeleted is returned when purging or restoring a directory
+	// which hasn't been soft deleted.
+	ErrDirectoryNotDeleted = errors.New("directory is not deleted")
+
+	// ErrDescendantNotDeleted is returned when purging a directory
+	// which has descendants that aren't soft deleted.
+	ErrDescendantNotDeleted = errors.New("directory has descendants which are not deleted")
+
+	// ErrParentDirectoryDeleted is returned when restoring a directory whose parent is still deleted.
+	ErrParentDirectoryDeleted = errors.New("parent directory is deleted")
+
+	// ErrInvalidCursor is returned when a pagination cursor can't be decoded.
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrAsOfOutOfRange is returned when reading directories as of a time
+	// older than the data kept by the database.
+	ErrAsOfOutOfRange = errors.New("as_of is older than the history kept by the database")
+
+	// ErrRevisionConflict is returned when a conditional write is attempted
+	// on a directory which is no longer at the expected revision.
+	ErrRevisionConflict = errors.New("directory revision does not match the expected revision")
+
+	// ErrDirectoryNameConflict is returned when a directory would share its name
+	// with a live sibling while unique sibling names are enforced.
+	ErrDirectoryNameConflict = errors.New("directory name conflicts with a sibling directory")
+
+	// ErrInvalidSelector is returned when a metadata selector can't be parsed.
+	ErrInvalidSelector = errors.New("invalid selector")
+
+	// ErrInvalidSearch is returned when a name search has no name or an unknown match.
+	ErrInvalidSearch = errors.New("invalid search")
+
+	// ErrAmbiguousPath is returned when several directories are at the same path,
+	// which may happen unless sibling names are unique.
+	ErrAmbiguousPath = errors.New("several directories are at the path")
+
+	// ErrSubtreeTooLarge is returned when a subtree has more directories than requested.
+	ErrSubtreeTooLarge = errors.New("subtree has more directories than allowed")
+
+	// ErrQuotaExceeded is returned when a write would take a tree past one of its quotas.
+	// It is wrapped by QuotaExceededError, which tells the exceeded limit.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+
+	// ErrSchemaNotFound is returned when no metadata schema applies to a directory.
+	ErrSchemaNotFound = errors.New("metadata schema not found")
+
+	// ErrInvalidSchema is returned when setting a metadata schema which isn't a valid schema.
+	ErrInvalidSchema = errors.New("invalid metadata schema")
+
+	// ErrInvalidMetadata is returned when the metadata of a directory doesn't match its schema.
+	// It is wrapped by InvalidMetadataError, which tells the invalid fields.
+	ErrInvalidMetadata = errors.New("metadata doesn't match the schema")
 )
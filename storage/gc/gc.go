@@ -0,0 +1,271 @@
+// Package gc provides a garbage collector which permanently removes the
+// directories that have been soft deleted for longer than a retention.
+package gc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+const (
+	// DefaultRetention is the default time directories are kept for once soft deleted.
+	DefaultRetention = 30 * 24 * time.Hour
+
+	// DefaultInterval is the default time between two collections.
+	DefaultInterval = time.Hour
+
+	// DefaultBatchSize is the default count of deleted directories listed at once.
+	DefaultBatchSize = 100
+)
+
+type Option func(*Collector)
+
+// WithRetention sets the time directories are kept for once soft deleted.
+func WithRetention(d time.Duration) Option {
+	return func(c *Collector) {
+		c.retention = d
+	}
+}
+
+// WithInterval sets the time between two collections made by Run.
+func WithInterval(d time.Duration) Option {
+	return func(c *Collector) {
+		c.interval = d
+	}
+}
+
+// WithBatchSize sets the count of deleted directories listed at once,
+// and purged before the next ones are listed.
+func WithBatchSize(n int) Option {
+	return func(c *Collector) {
+		c.batchSize = n
+	}
+}
+
+// WithDryRun only reports the directories which would be purged,
+// rather than purging them.
+func WithDryRun() Option {
+	return func(c *Collector) {
+		c.dryRun = true
+	}
+}
+
+// WithLease only runs the collections of Run while the lease is held,
+// so a single replica collects at a time.
+func WithLease(l Lease) Option {
+	return func(c *Collector) {
+		c.lease = l
+	}
+}
+
+// WithLogger sets the logger of the collector.
+func WithLogger(l *zap.Logger) Option {
+	return func(c *Collector) {
+		c.logger = l
+	}
+}
+
+// Lease elects the replica running the collections.
+type Lease interface {
+	// Acquire acquires the lease, or renews it if already held,
+	// and returns whether it is held.
+	Acquire(ctx context.Context) (bool, error)
+}
+
+// Report tells what a collection purged, or would have purged in dry-run mode.
+type Report struct {
+	// Before is the time the collected directories were deleted before.
+	Before time.Time
+	// Purged is the count of directories purged, or which would have been.
+	Purged int
+	// Directories holds the directories which would have been purged.
+	// It is only set in dry-run mode.
+	Directories []*v1.Directory
+}
+
+// Collector permanently removes the directories which have been soft deleted
+// for longer than the retention, along with their descendants. They're purged
+// through the storage driver it's given, so wrapping it with a notifier sends
+// an event for each purged directory.
+type Collector struct {
+	store     storage.DirectoryAdmin
+	retention time.Duration
+	interval  time.Duration
+	batchSize int
+	dryRun    bool
+	lease     Lease
+	logger    *zap.Logger
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// NewCollector returns a collector purging the directories of the storage.
+func NewCollector(store storage.DirectoryAdmin, opts ...Option) *Collector {
+	c := &Collector{
+		store:     store,
+		retention: DefaultRetention,
+		interval:  DefaultInterval,
+		batchSize: DefaultBatchSize,
+		logger:    zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.batchSize < 1 {
+		c.batchSize = DefaultBatchSize
+	}
+
+	if c.interval <= 0 {
+		c.interval = DefaultInterval
+	}
+
+	return c
+}
+
+// Run collects the deleted directories every interval, until the context
+// is done. With a lease, the collections only happen while it is held.
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick runs a collection if the lease, if any, is held.
+func (c *Collector) tick(ctx context.Context) {
+	if c.lease != nil {
+		held, err := c.lease.Acquire(ctx)
+		if err != nil {
+			c.logger.Error("failed to acquire garbage collection lease", zap.Error(err))
+			return
+		}
+
+		if !held {
+			c.logger.Debug("garbage collection lease held by another replica")
+			return
+		}
+	}
+
+	if report, err := c.RunOnce(ctx); err != nil {
+		c.logger.Error("garbage collection failed", zap.Error(err), zap.Int("purged", report.Purged))
+	}
+}
+
+// RunOnce purges the directories soft deleted for longer than the retention,
+// in batches, until none is left. In dry-run mode, the directories which would
+// be purged are listed in the report instead. The report is returned along
+// with errors as well, telling what was purged before the failure, and is
+// logged otherwise.
+func (c *Collector) RunOnce(ctx context.Context) (*Report, error) {
+	started := time.Now()
+	report := &Report{Before: started.Add(-c.retention)}
+
+	var err error
+
+	if c.dryRun {
+		err = c.list(ctx, report)
+	} else {
+		err = c.purge(ctx, report)
+	}
+
+	c.record(report, started, err)
+
+	if err != nil {
+		return report, err
+	}
+
+	for _, d := range report.Directories {
+		c.logger.Info("directory would be purged",
+			zap.String("id", d.Id.String()), zap.String("name", d.Name), zap.Timep("deleted_at", d.DeletedAt))
+	}
+
+	c.logger.Info("garbage collection done",
+		zap.Time("before", report.Before), zap.Int("purged", report.Purged), zap.Bool("dry_run", c.dryRun))
+
+	return report, nil
+}
+
+// purge purges the deleted directories a batch at a time. Purged directories
+// aren't listed anymore, so the first page is always listed. The directories
+// which can't be purged are still listed, so the page is made larger by as
+// many, and they're skipped.
+func (c *Collector) purge(ctx context.Context, report *Report) error {
+	skipped := make(map[v1.DirectoryID]struct{})
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		size := c.batchSize + len(skipped)
+
+		batch, err := c.store.ListDeleted(ctx, report.Before, storage.Pagination(1, size))
+		if err != nil {
+			return fmt.Errorf("error listing deleted directories: %w", err)
+		}
+
+		for _, d := range batch {
+			if _, ok := skipped[d.Id]; ok {
+				continue
+			}
+
+			purged, err := c.store.PurgeDirectory(ctx, d.Id)
+
+			// Directories may be purged even if notifying it failed.
+			report.Purged += len(purged)
+
+			switch {
+			case err == nil:
+			case errors.Is(err, storage.ErrDirectoryNotFound), errors.Is(err, storage.ErrDirectoryNotDeleted):
+				// It was purged along with a deleted parent, or restored since it was listed.
+			case errors.Is(err, storage.ErrDescendantNotDeleted):
+				// Purging it would remove live directories along with it.
+				c.logger.Warn("skipping deleted directory with live descendants", zap.String("id", d.Id.String()))
+
+				skipped[d.Id] = struct{}{}
+			default:
+				return fmt.Errorf("error purging directory %s: %w", d.Id, err)
+			}
+		}
+
+		if len(batch) < size {
+			return nil
+		}
+	}
+}
+
+// list lists the deleted directories a batch at a time.
+func (c *Collector) list(ctx context.Context, report *Report) error {
+	for page := 1; ; page++ {
+		batch, err := c.store.ListDeleted(ctx, report.Before, storage.Pagination(page, c.batchSize))
+		if err != nil {
+			return fmt.Errorf("error listing deleted directories: %w", err)
+		}
+
+		report.Directories = append(report.Directories, batch...)
+		report.Purged += len(batch)
+
+		if len(batch) < c.batchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package gc_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/notifier"
+	natsutils "github.com/infratographer/fertilesoil/notifier/nats/utils"
+	"github.com/infratographer/fertilesoil/notifier/noop"
+	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/gc"
+	"github.com/infratographer/fertilesoil/storage/memory"
+	sn "github.com/infratographer/fertilesoil/storage/notifier"
+)
+
+// purgeRecorder records the directories hard deletions are notified for.
+type purgeRecorder struct {
+	notifier.Notifier
+	mu     sync.Mutex
+	purged []v1.DirectoryID
+}
+
+func (r *purgeRecorder) NotifyDeleteHard(_ context.Context, d *v1.Directory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.purged = append(r.purged, d.Id)
+
+	return nil
+}
+
+func newStore() (storage.DirectoryAdmin, *purgeRecorder) {
+	rec := &purgeRecorder{Notifier: noop.NewNotifier()}
+
+	return sn.StorageWithNotifier(memory.NewDirectoryDriver(), rec), rec
+}
+
+func create(t *testing.T, store storage.DirectoryAdmin, parent *v1.Directory) *v1.Directory {
+	t.Helper()
+
+	d := &v1.Directory{Name: "dir"}
+
+	var err error
+
+	if parent == nil {
+		d, err = store.CreateRoot(context.Background(), d)
+	} else {
+		d.Parent = &parent.Id
+		d, err = store.CreateDirectory(context.Background(), d)
+	}
+
+	if !assert.NoError(t, err, "error creating directory") {
+		t.FailNow()
+	}
+
+	return d
+}
+
+func deleteDirectory(t *testing.T, store storage.DirectoryAdmin, d *v1.Directory) []v1.DirectoryID {
+	t.Helper()
+
+	deleted, err := store.DeleteDirectory(context.Background(), d.Id)
+	if !assert.NoError(t, err, "error deleting directory") {
+		t.FailNow()
+	}
+
+	ids := make([]v1.DirectoryID, len(deleted))
+
+	for i, d := range deleted {
+		ids[i] = d.Id
+	}
+
+	return ids
+}
+
+func TestRunOnce(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, rec := newStore()
+	root := create(t, store, nil)
+	child := create(t, store, root)
+	create(t, store, child)
+	live := create(t, store, root)
+
+	// Several batches are needed to purge every deleted directory.
+	var deleted []v1.DirectoryID
+
+	deleted = append(deleted, deleteDirectory(t, store, child)...)
+
+	for i := 0; i < 3; i++ {
+		deleted = append(deleted, deleteDirectory(t, store, create(t, store, root))...)
+	}
+
+	collector := gc.NewCollector(store, gc.WithRetention(0), gc.WithBatchSize(2))
+
+	report, err := collector.RunOnce(ctx)
+	assert.NoError(t, err, "error collecting directories")
+	assert.Equal(t, len(deleted), report.Purged, "every deleted directory should be purged")
+	assert.ElementsMatch(t, deleted, rec.purged, "hard deletions should be notified once per directory")
+
+	for _, id := range deleted {
+		_, err = store.GetDirectory(ctx, id, storage.WithDeletedDirectories)
+		assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "deleted directory should be purged")
+	}
+
+	_, err = store.GetDirectory(ctx, live.Id)
+	assert.NoError(t, err, "live directory should be kept")
+
+	stats := collector.Stats()
+	assert.Equal(t, uint64(1), stats.Runs, "unexpected count of runs")
+	assert.Equal(t, uint64(len(deleted)), stats.Purged, "unexpected count of purged directories")
+}
+
+// purgeRefuser refuses to purge one of the directories, as if it had live descendants.
+type purgeRefuser struct {
+	storage.DirectoryAdmin
+	refused v1.DirectoryID
+}
+
+func (r *purgeRefuser) PurgeDirectory(ctx context.Context, id v1.DirectoryID) ([]*v1.Directory, error) {
+	if id == r.refused {
+		return nil, storage.ErrDescendantNotDeleted
+	}
+
+	return r.DirectoryAdmin.PurgeDirectory(ctx, id)
+}
+
+func TestSkipLiveDescendants(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, _ := newStore()
+	root := create(t, store, nil)
+
+	refused := deleteDirectory(t, store, create(t, store, root))
+
+	var deleted []v1.DirectoryID
+
+	for i := 0; i < 3; i++ {
+		deleted = append(deleted, deleteDirectory(t, store, create(t, store, root))...)
+	}
+
+	refuser := &purgeRefuser{DirectoryAdmin: store, refused: refused[0]}
+	collector := gc.NewCollector(refuser, gc.WithRetention(0), gc.WithBatchSize(1))
+
+	report, err := collector.RunOnce(ctx)
+	assert.NoError(t, err, "error collecting directories")
+	assert.Equal(t, len(deleted), report.Purged, "the directories after the refused one should be purged")
+
+	for _, id := range deleted {
+		_, err = store.GetDirectory(ctx, id, storage.WithDeletedDirectories)
+		assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "deleted directory should be purged")
+	}
+
+	_, err = store.GetDirectory(ctx, refused[0], storage.WithDeletedDirectories)
+	assert.NoError(t, err, "refused directory should be kept")
+}
+
+func TestRetention(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, rec := newStore()
+	deleted := deleteDirectory(t, store, create(t, store, create(t, store, nil)))
+
+	report, err := gc.NewCollector(store, gc.WithRetention(time.Hour)).RunOnce(ctx)
+	assert.NoError(t, err, "error collecting directories")
+	assert.Zero(t, report.Purged, "directories within the retention should be kept")
+	assert.Empty(t, rec.purged, "no hard deletion should be notified")
+
+	_, err = store.GetDirectory(ctx, deleted[0], storage.WithDeletedDirectories)
+	assert.NoError(t, err, "deleted directory should be kept")
+}
+
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, rec := newStore()
+	root := create(t, store, nil)
+	deleted := deleteDirectory(t, store, create(t, store, root))
+
+	for i := 0; i < 2; i++ {
+		deleted = append(deleted, deleteDirectory(t, store, create(t, store, root))...)
+	}
+
+	collector := gc.NewCollector(store, gc.WithRetention(0), gc.WithBatchSize(2), gc.WithDryRun())
+
+	report, err := collector.RunOnce(ctx)
+	assert.NoError(t, err, "error collecting directories")
+	assert.Equal(t, len(deleted), report.Purged, "every deleted directory should be reported")
+	assert.Len(t, report.Directories, len(deleted), "every deleted directory should be listed")
+	assert.Empty(t, rec.purged, "no hard deletion should be notified")
+
+	for _, id := range deleted {
+		_, err = store.GetDirectory(ctx, id, storage.WithDeletedDirectories)
+		assert.NoError(t, err, "deleted directory should be kept")
+	}
+
+	stats := collector.Stats()
+	assert.Equal(t, len(deleted), stats.Pending, "unexpected count of pending directories")
+	assert.Zero(t, stats.Purged, "no directory should be purged")
+}
+
+func TestNATSLease(t *testing.T) {
+	t.Parallel()
+
+	srv, err := natsutils.StartNatsServer()
+	if !assert.NoError(t, err, "error starting nats server") {
+		return
+	}
+
+	defer srv.Shutdown()
+
+	conn, err := natsgo.Connect(srv.ClientURL())
+	if !assert.NoError(t, err, "error connecting to nats") {
+		return
+	}
+
+	defer conn.Close()
+
+	js, err := conn.JetStream()
+	if !assert.NoError(t, err, "error getting jetstream context") {
+		return
+	}
+
+	ctx := context.Background()
+
+	// JetStream keeps its files in the temporary directory, which other runs share.
+	bucket := gc.DefaultLeaseBucket + "-" + uuid.NewString()
+
+	//nolint:errcheck // The bucket is only removed to spare the disk space.
+	defer js.DeleteKeyValue(bucket)
+
+	first, err := gc.NewNATSLease(js, bucket, time.Minute)
+	assert.NoError(t, err, "error creating lease")
+
+	second, err := gc.NewNATSLease(js, bucket, time.Minute)
+	assert.NoError(t, err, "error creating lease")
+
+	held, err := first.Acquire(ctx)
+	assert.NoError(t, err, "error acquiring lease")
+	assert.True(t, held, "lease should be acquired")
+
+	held, err = second.Acquire(ctx)
+	assert.NoError(t, err, "error acquiring lease")
+	assert.False(t, held, "lease should be held by the other replica")
+
+	held, err = first.Acquire(ctx)
+	assert.NoError(t, err, "error renewing lease")
+	assert.True(t, held, "lease should be renewed")
+}
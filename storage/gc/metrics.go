@@ -0,0 +1,96 @@
+package gc
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Stats holds the counts of collections and purged directories.
+type Stats struct {
+	Runs       uint64
+	FailedRuns uint64
+	// Purged is the count of directories purged. Dry runs don't purge any.
+	Purged uint64
+	// Pending is the count of directories the last dry run would have purged.
+	Pending int
+	// LastSuccess is the time the last successful collection started at.
+	LastSuccess time.Time
+}
+
+var (
+	runsDesc = prometheus.NewDesc(
+		"fertilesoil_gc_runs_total",
+		"Count of garbage collections of deleted directories, by result.",
+		[]string{"result"}, nil,
+	)
+	purgedDesc = prometheus.NewDesc(
+		"fertilesoil_gc_purged_directories_total",
+		"Count of deleted directories purged by the garbage collector.",
+		nil, nil,
+	)
+	pendingDesc = prometheus.NewDesc(
+		"fertilesoil_gc_pending_directories",
+		"Count of deleted directories the last dry run would have purged.",
+		nil, nil,
+	)
+	lastSuccessDesc = prometheus.NewDesc(
+		"fertilesoil_gc_last_success_timestamp_seconds",
+		"Time the last successful garbage collection started at.",
+		nil, nil,
+	)
+)
+
+// ensure Collector implements prometheus.Collector, so it can be registered as is.
+var _ prometheus.Collector = &Collector{}
+
+// Stats returns the collector statistics.
+func (c *Collector) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stats
+}
+
+// record records the outcome of a collection in the statistics.
+func (c *Collector) record(report *Report, started time.Time, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dryRun {
+		c.stats.Pending = report.Purged
+	} else {
+		c.stats.Purged += uint64(report.Purged)
+	}
+
+	if err != nil {
+		c.stats.FailedRuns++
+		return
+	}
+
+	c.stats.Runs++
+	c.stats.LastSuccess = started
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- runsDesc
+	ch <- purgedDesc
+	ch <- pendingDesc
+	ch <- lastSuccessDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.Stats()
+
+	ch <- prometheus.MustNewConstMetric(runsDesc, prometheus.CounterValue, float64(stats.Runs), "success")
+	ch <- prometheus.MustNewConstMetric(runsDesc, prometheus.CounterValue, float64(stats.FailedRuns), "failure")
+	ch <- prometheus.MustNewConstMetric(purgedDesc, prometheus.CounterValue, float64(stats.Purged))
+	ch <- prometheus.MustNewConstMetric(pendingDesc, prometheus.GaugeValue, float64(stats.Pending))
+
+	if !stats.LastSuccess.IsZero() {
+		ch <- prometheus.MustNewConstMetric(lastSuccessDesc, prometheus.GaugeValue,
+			float64(stats.LastSuccess.UnixNano())/float64(time.Second))
+	}
+}
@@ -0,0 +1,91 @@
+package gc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	natsgo "github.com/nats-io/nats.go"
+)
+
+// DefaultLeaseBucket is the default NATS key-value bucket the lease is kept in.
+const DefaultLeaseBucket = "fertilesoil-gc"
+
+// leaseKey is the key holding the id of the replica holding the lease.
+const leaseKey = "leader"
+
+// NATSLease is a lease kept in a NATS JetStream key-value bucket, whose values
+// expire after a TTL. The replica holding the lease renews it by updating the
+// value at the revision it last wrote, and others only acquire it once it
+// expired, so a single replica holds it at a time.
+type NATSLease struct {
+	kv     natsgo.KeyValue
+	holder string
+}
+
+// ensure NATSLease implements Lease.
+var _ Lease = &NATSLease{}
+
+// NewNATSLease returns a lease kept in the provided bucket, which is created
+// with the provided TTL if it doesn't exist yet. The lease is lost unless it's
+// renewed within the TTL, which should be longer than the collection interval.
+func NewNATSLease(js natsgo.JetStreamContext, bucket string, ttl time.Duration) (*NATSLease, error) {
+	kv, err := js.KeyValue(bucket)
+	if errors.Is(err, natsgo.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&natsgo.KeyValueConfig{
+			Bucket:      bucket,
+			Description: "Lease of the fertilesoil garbage collector",
+			TTL:         ttl,
+			History:     1,
+		})
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error opening lease bucket: %w", err)
+	}
+
+	return &NATSLease{
+		kv:     kv,
+		holder: uuid.New().String(),
+	}, nil
+}
+
+// Acquire implements Lease.
+func (l *NATSLease) Acquire(_ context.Context) (bool, error) {
+	_, err := l.kv.Create(leaseKey, []byte(l.holder))
+	if err == nil {
+		return true, nil
+	}
+
+	if !errors.Is(err, natsgo.ErrKeyExists) {
+		return false, fmt.Errorf("error acquiring lease: %w", err)
+	}
+
+	entry, err := l.kv.Get(leaseKey)
+	if errors.Is(err, natsgo.ErrKeyNotFound) {
+		// It expired in the meantime, and is acquired on the next attempt.
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("error reading lease: %w", err)
+	}
+
+	if string(entry.Value()) != l.holder {
+		return false, nil
+	}
+
+	_, err = l.kv.Update(leaseKey, []byte(l.holder), entry.Revision())
+	if errors.Is(err, natsgo.ErrKeyExists) {
+		// Another replica acquired it since it was read.
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("error renewing lease: %w", err)
+	}
+
+	return true, nil
+}
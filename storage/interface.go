@@ -2,6 +2,8 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	v1 "github.com/infratographer/fertilesoil/api/v1"
 )
@@ -10,13 +12,53 @@ import (
 // on the directory tree.
 type Reader interface {
 	GetDirectory(ctx context.Context, id v1.DirectoryID, options ...Option) (*v1.Directory, error)
+	// GetDirectories returns the directories with the provided ids in a single call.
+	// Directories are returned in the order of the ids, each one only once,
+	// and the ids which don't match any directory are skipped.
+	GetDirectories(ctx context.Context, ids []v1.DirectoryID, options ...Option) ([]*v1.Directory, error)
 	GetParents(ctx context.Context, id v1.DirectoryID, options ...Option) ([]v1.DirectoryID, error)
 	GetParentsUntilAncestor(
 		ctx context.Context,
 		child, ancestor v1.DirectoryID,
 		options ...Option,
 	) ([]v1.DirectoryID, error)
+	// GetChildren returns the descendants of the directory breadth first,
+	// each level ordered by creation. A Cursor must point to one of them,
+	// or ErrInvalidCursor is returned.
 	GetChildren(ctx context.Context, id v1.DirectoryID, options ...Option) ([]v1.DirectoryID, error)
+	// CountChildren returns the count of the descendants listed by GetChildren
+	// across all pages. The MaxDepth, Selector, WithDeletedDirectories and
+	// AsOf options are respected.
+	CountChildren(ctx context.Context, id v1.DirectoryID, options ...Option) (int, error)
+	// GetSubtree returns the directory along with its descendants as a nested tree.
+	// The MaxDepth and WithDeletedDirectories options are respected, and
+	// ErrSubtreeTooLarge is returned if it has more directories than MaxNodes.
+	GetSubtree(ctx context.Context, id v1.DirectoryID, options ...Option) (*v1.DirectoryTree, error)
+	// GetStats returns the statistics of the subtree of the directory.
+	// Soft deleted descendants are always counted apart from the live ones.
+	// Only the WithDeletedDirectories and AsOf options are respected,
+	// the former applying to the directory itself.
+	GetStats(ctx context.Context, id v1.DirectoryID, options ...Option) (*v1.DirectoryStats, error)
+	// SearchDirectories returns the descendants of the directory whose name matches
+	// the query, ordered by creation, along with their parents up to the directory.
+	// ErrInvalidSearch is returned if the query isn't valid. Only the Page, PageSize,
+	// Cursor, WithDeletedDirectories and AsOf options are respected.
+	SearchDirectories(
+		ctx context.Context,
+		id v1.DirectoryID,
+		q NameQuery,
+		options ...Option,
+	) ([]*v1.DirectorySearchResult, error)
+	// ResolvePath returns the directory at the provided path, made of the name of
+	// its root followed by the names of its parents and its own, see PathOf.
+	// ErrAmbiguousPath is returned if several directories are at the path.
+	// Only the WithDeletedDirectories and AsOf options are respected,
+	// the former applying to every directory of the path.
+	ResolvePath(ctx context.Context, path []string, options ...Option) (*v1.Directory, error)
+	// GetHistory returns the revisions recorded for the directory, newest first.
+	// Nothing is returned once the directory is purged.
+	// Only the Page, PageSize and AsOf options are respected.
+	GetHistory(ctx context.Context, id v1.DirectoryID, options ...Option) ([]*v1.DirectoryRevision, error)
 }
 
 // RootReader is the interface that allows doing all read operations
@@ -24,19 +66,103 @@ type Reader interface {
 type RootReader interface {
 	Reader
 	ListRoots(ctx context.Context, options ...Option) ([]v1.DirectoryID, error)
+	// CountRoots returns the count of the roots listed by ListRoots across all pages.
+	// The Selector, WithDeletedDirectories and AsOf options are respected.
+	CountRoots(ctx context.Context, options ...Option) (int, error)
 }
 
 // Writer is the interface that allows doing basic write operations.
+// Changes are recorded in the revision history of the directories,
+// along with the actor carried by the context, see WithActor.
 type Writer interface {
 	CreateDirectory(ctx context.Context, d *v1.Directory) (*v1.Directory, error)
 	UpdateDirectory(ctx context.Context, d *v1.Directory) error
+	// UpdateDirectoryIfRevision updates the directory only if it is still
+	// at the provided revision, returning ErrRevisionConflict otherwise.
+	UpdateDirectoryIfRevision(ctx context.Context, d *v1.Directory, revision int64) error
 	DeleteDirectory(ctx context.Context, id v1.DirectoryID) ([]*v1.Directory, error)
+	// MoveDirectory moves a non-root directory under the provided parent.
+	// The moved directory and its previous parent are returned.
+	MoveDirectory(
+		ctx context.Context,
+		id, parent v1.DirectoryID,
+	) (moved *v1.Directory, oldParent *v1.DirectoryID, err error)
+	// RestoreDirectory restores a soft deleted directory along with
+	// the descendants which were deleted with it.
+	// The restored directories are returned.
+	RestoreDirectory(ctx context.Context, id v1.DirectoryID) ([]*v1.Directory, error)
 }
 
 // RootWriter is the interface that allows doing all write operations.
 type RootWriter interface {
 	Writer
 	CreateRoot(ctx context.Context, d *v1.Directory) (*v1.Directory, error)
+	// PromoteToRoot detaches a directory from its parent, making it a root directory.
+	// The promoted directory and its previous parent are returned.
+	PromoteToRoot(
+		ctx context.Context,
+		id v1.DirectoryID,
+	) (promoted *v1.Directory, oldParent *v1.DirectoryID, err error)
+	// DemoteRoot moves a root directory under the provided parent.
+	DemoteRoot(ctx context.Context, id, parent v1.DirectoryID) (*v1.Directory, error)
+}
+
+// ConditionalWriter is the interface that allows doing write operations
+// only if the directory is still at the provided revision. The revision is
+// compared within the write, so no change can slip in between, and
+// ErrRevisionConflict is returned if it doesn't match.
+type ConditionalWriter interface {
+	DeleteDirectoryIfRevision(ctx context.Context, id v1.DirectoryID, revision int64) ([]*v1.Directory, error)
+	MoveDirectoryIfRevision(
+		ctx context.Context,
+		id, parent v1.DirectoryID,
+		revision int64,
+	) (moved *v1.Directory, oldParent *v1.DirectoryID, err error)
+	PromoteToRootIfRevision(
+		ctx context.Context,
+		id v1.DirectoryID,
+		revision int64,
+	) (promoted *v1.Directory, oldParent *v1.DirectoryID, err error)
+	DemoteRootIfRevision(ctx context.Context, id, parent v1.DirectoryID, revision int64) (*v1.Directory, error)
+}
+
+// Transactor is the interface that allows applying several
+// operations on the directory tree atomically.
+type Transactor interface {
+	// WithTx calls fn with a DirectoryAdmin whose operations all happen
+	// within a single transaction. The transaction is committed if fn
+	// returns nil and rolled back otherwise.
+	// fn may be called more than once if the transaction is retried.
+	WithTx(ctx context.Context, fn func(DirectoryAdmin) error) error
+	// LockDirectories locks the provided directories, in order, until the end
+	// of the transaction, so concurrent transactions locking them wait for it
+	// to end. Outside of a transaction, they're only locked for the call.
+	LockDirectories(ctx context.Context, ids ...v1.DirectoryID) error
+}
+
+// QuotaAdmin is the interface that allows reading and setting the quota
+// overrides of root directories, which apply to their whole tree.
+type QuotaAdmin interface {
+	// GetQuota returns the quota overrides of the root directory.
+	// Limits which aren't overridden are unset.
+	GetQuota(ctx context.Context, root v1.DirectoryID) (*v1.DirectoryQuota, error)
+	// SetQuota replaces the quota overrides of the root directory.
+	// ErrNotRoot is returned for directories which aren't roots.
+	SetQuota(ctx context.Context, root v1.DirectoryID, q *v1.DirectoryQuota) error
+}
+
+// SchemaAdmin is the interface that allows reading and setting the metadata
+// schemas of directories, which apply to their whole subtree.
+type SchemaAdmin interface {
+	// GetSchema returns the schema applying to the directory: its own,
+	// or the one of its closest ancestor having one.
+	// ErrSchemaNotFound is returned if none applies.
+	GetSchema(ctx context.Context, id v1.DirectoryID) (*MetadataSchema, error)
+	// SetSchema replaces the schema of the directory.
+	SetSchema(ctx context.Context, id v1.DirectoryID, schema json.RawMessage) error
+	// DeleteSchema removes the schema of the directory, so the one of its
+	// ancestors applies again. ErrSchemaNotFound is returned if it has none.
+	DeleteSchema(ctx context.Context, id v1.DirectoryID) error
 }
 
 // DirectoryAdmin is the interface that allows doing all operations
@@ -44,4 +170,17 @@ type RootWriter interface {
 type DirectoryAdmin interface {
 	RootReader
 	RootWriter
+	ConditionalWriter
+	Transactor
+	QuotaAdmin
+	SchemaAdmin
+	// PurgeDirectory permanently removes a soft deleted directory
+	// and all of its descendants, along with their history.
+	// The removed directories are returned. Nothing is removed if
+	// any of the descendants isn't soft deleted.
+	PurgeDirectory(ctx context.Context, id v1.DirectoryID) ([]*v1.Directory, error)
+	// ListDeleted returns the directories soft deleted before the provided time,
+	// oldest deletion first, e.g. to purge them once they're past a retention.
+	// Only the Page and PageSize options are respected.
+	ListDeleted(ctx context.Context, before time.Time, options ...Option) ([]*v1.Directory, error)
 }
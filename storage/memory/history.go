@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// revisionLog holds the revision history of the directories.
+type revisionLog struct {
+	mu sync.Mutex
+	// base is the log of the driver a transaction was started from.
+	// Its revisions come before the ones recorded within the transaction.
+	base      *revisionLog
+	revisions map[v1.DirectoryID][]*v1.DirectoryRevision
+	// purged holds the directories purged within a transaction,
+	// whose revisions are removed from the base once committed.
+	purged map[v1.DirectoryID]struct{}
+}
+
+func newRevisionLog(base *revisionLog) *revisionLog {
+	return &revisionLog{
+		base:      base,
+		revisions: map[v1.DirectoryID][]*v1.DirectoryRevision{},
+		purged:    map[v1.DirectoryID]struct{}{},
+	}
+}
+
+func (l *revisionLog) add(r *v1.DirectoryRevision) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.revisions[r.DirectoryId] = append(l.revisions[r.DirectoryId], r)
+}
+
+// purge removes the history of the directory, as the SQL drivers
+// do when the directory is deleted.
+func (l *revisionLog) purge(id v1.DirectoryID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.revisions, id)
+
+	if l.base != nil {
+		l.purged[id] = struct{}{}
+	}
+}
+
+// entries returns the revisions of all the directories, oldest first.
+func (l *revisionLog) entries() map[v1.DirectoryID][]*v1.DirectoryRevision {
+	revisions := map[v1.DirectoryID][]*v1.DirectoryRevision{}
+
+	if l.base != nil {
+		revisions = l.base.entries()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for id := range l.purged {
+		delete(revisions, id)
+	}
+
+	for id, r := range l.revisions {
+		revisions[id] = append(revisions[id], r...)
+	}
+
+	return revisions
+}
+
+// list returns the history of the directory as it was at the provided
+// point in time, oldest first. The current history is returned if ts is zero.
+func (l *revisionLog) list(id v1.DirectoryID, ts time.Time) []*v1.DirectoryRevision {
+	return visibleRevisions(l.entries()[id], ts)
+}
+
+// visibleRevisions filters the revisions of a directory down to the ones
+// visible at the provided point in time, or currently if ts is zero.
+func visibleRevisions(revisions []*v1.DirectoryRevision, ts time.Time) []*v1.DirectoryRevision {
+	var visible []*v1.DirectoryRevision
+
+	for _, r := range revisions {
+		if ts.IsZero() || !r.Time.After(ts) {
+			visible = append(visible, r)
+		}
+	}
+
+	return visible
+}
+
+// commit applies the changes made to the log within a transaction to its base.
+func (l *revisionLog) commit() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for id := range l.purged {
+		l.base.purge(id)
+	}
+
+	for _, revisions := range l.revisions {
+		for _, r := range revisions {
+			l.base.add(r)
+		}
+	}
+}
+
+// recordRevision records a change of the directory in its history.
+// before is the directory as it was before the change, nil if it was created.
+func (t *Driver) recordRevision(ctx context.Context, op v1.EventType, before, after *v1.Directory) {
+	t.wrote(after.Id)
+	t.history.add(&v1.DirectoryRevision{
+		DirectoryId: after.Id,
+		Revision:    after.Revision,
+		Operation:   op,
+		Actor:       storage.ActorFromContext(ctx),
+		Time:        time.Now(),
+		Before:      before,
+		After:       copyDirectory(after),
+	})
+}
+
+// GetHistory gets the revisions recorded for a directory, newest first.
+func (t *Driver) GetHistory(
+	ctx context.Context,
+	id v1.DirectoryID,
+	options ...storage.Option,
+) ([]*v1.DirectoryRevision, error) {
+	opts := storage.BuildOptions(options)
+
+	revisions := t.history.list(id, opts.AsOf)
+
+	start := len(revisions) - opts.GetPageOffset()
+	if start <= 0 {
+		return nil, nil
+	}
+
+	end := start - opts.GetPageSize()
+	if end < 0 {
+		end = 0
+	}
+
+	page := make([]*v1.DirectoryRevision, 0, start-end)
+
+	for i := start - 1; i >= end; i-- {
+		page = append(page, revisions[i])
+	}
+
+	return page, nil
+}
+
+// asOf returns a read-only driver holding the directories as they were at
+// the requested point in time, rebuilt from their revisions. Directories
+// without recorded revisions are taken as they are, if created by then.
+// nil is returned if the current directories are to be read.
+func (t *Driver) asOf(opts *storage.Options) *Driver {
+	if opts.AsOf.IsZero() || t.pointInTime {
+		return nil
+	}
+
+	revisions := t.history.entries()
+	dirMap := &sync.Map{}
+
+	for id := range revisions {
+		if history := visibleRevisions(revisions[id], opts.AsOf); len(history) > 0 {
+			dirMap.Store(id, copyDirectory(latestRevision(history).After))
+		}
+	}
+
+	t.dirMap.Range(func(key, value interface{}) bool {
+		d, ok := value.(*v1.Directory)
+		if ok && len(revisions[d.Id]) == 0 && !d.CreatedAt.After(opts.AsOf) {
+			dirMap.Store(d.Id, copyDirectory(d))
+		}
+
+		return true
+	})
+
+	return &Driver{
+		dirMap:      dirMap,
+		history:     t.history,
+		pointInTime: true,
+	}
+}
+
+// latestRevision returns the most recent of the provided revisions.
+// Revisions committed by transactions may be recorded out of order.
+func latestRevision(revisions []*v1.DirectoryRevision) *v1.DirectoryRevision {
+	latest := revisions[0]
+
+	for _, r := range revisions[1:] {
+		if !r.Time.Before(latest.Time) {
+			latest = r
+		}
+	}
+
+	return latest
+}
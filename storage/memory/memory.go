@@ -7,7 +7,9 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"sort"
 	"sync"
 	"time"
@@ -23,6 +25,24 @@ type Options func(*Driver)
 type Driver struct {
 	// dirMap is a thread-safe map of directories.
 	dirMap *sync.Map
+	// mu guards the stored directories, which writes modify in place.
+	mu sync.RWMutex
+	// txMu serializes the writes, transactions included.
+	txMu sync.Mutex
+	// written holds the ids of the directories written within a transaction,
+	// on the drivers bound to one.
+	written map[v1.DirectoryID]struct{}
+	// uniqueSiblingNames enforces unique sibling names in every new directory.
+	uniqueSiblingNames bool
+	// history holds the revision history of the directories.
+	history *revisionLog
+	// quotas holds the quota overrides of the root directories.
+	quotas *quotaMap
+	// schemas holds the metadata schemas of the directories.
+	schemas *schemaMap
+	// pointInTime is set on the read-only drivers holding the directories
+	// as they were at a point in time, see asOf.
+	pointInTime bool
 }
 
 // WithDirectoryMap allows to set a custom directory map.
@@ -34,9 +54,20 @@ func WithDirectoryMap(dirMap *sync.Map) Options {
 	}
 }
 
+// WithUniqueSiblingNames enforces unique names among live siblings
+// in every directory created by the driver.
+func WithUniqueSiblingNames() Options {
+	return func(d *Driver) {
+		d.uniqueSiblingNames = true
+	}
+}
+
 func NewDirectoryDriver(opts ...Options) *Driver {
 	d := &Driver{
-		dirMap: &sync.Map{},
+		dirMap:  &sync.Map{},
+		history: newRevisionLog(nil),
+		quotas:  newQuotaMap(),
+		schemas: newSchemaMap(),
 	}
 
 	for _, opt := range opts {
@@ -48,10 +79,170 @@ func NewDirectoryDriver(opts ...Options) *Driver {
 
 var _ storage.DirectoryAdmin = (*Driver)(nil)
 
+// WithTx calls fn with a driver working on a copy of the directories.
+// The directories written through the copy are applied to the stored
+// directories only if fn succeeds. Transactions are serialized along
+// with the writes made outside of them.
+func (t *Driver) WithTx(ctx context.Context, fn func(storage.DirectoryAdmin) error) error {
+	t.txMu.Lock()
+	defer t.txMu.Unlock()
+
+	snapshot, txMap, err := t.snapshot()
+	if err != nil {
+		return err
+	}
+
+	txHistory := newRevisionLog(t.history)
+	txQuotas := t.quotas.clone()
+	txSchemas := t.schemas.clone()
+
+	txDriver := &Driver{
+		dirMap:             txMap,
+		written:            map[v1.DirectoryID]struct{}{},
+		uniqueSiblingNames: t.uniqueSiblingNames,
+		history:            txHistory,
+		quotas:             txQuotas,
+		schemas:            txSchemas,
+	}
+
+	if err := fn(txDriver); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	txHistory.commit()
+	t.quotas.replace(txQuotas)
+	t.schemas.replace(txSchemas)
+
+	for id := range txDriver.written {
+		value, ok := txMap.Load(id)
+		if !ok {
+			t.dirMap.Delete(id)
+			continue
+		}
+
+		d, ok := value.(*v1.Directory)
+		if !ok {
+			return fmt.Errorf("directory %s is not of type *v1.Directory", id)
+		}
+
+		// Existing directories are updated in place, as they may be referenced.
+		if orig, ok := snapshot[id]; ok {
+			*orig = *d
+		} else {
+			t.dirMap.Store(id, d)
+		}
+	}
+
+	return nil
+}
+
+// snapshot returns the stored directories, along with a map holding copies of them.
+func (t *Driver) snapshot() (map[v1.DirectoryID]*v1.Directory, *sync.Map, error) {
+	defer t.read()()
+
+	snapshot := map[v1.DirectoryID]*v1.Directory{}
+	copies := &sync.Map{}
+
+	var iterationErr error
+
+	t.dirMap.Range(func(key, value interface{}) bool {
+		d, ok := value.(*v1.Directory)
+		if !ok {
+			iterationErr = fmt.Errorf("found directory that is not of type *v1.Directory")
+			return false
+		}
+
+		snapshot[d.Id] = d
+		copies.Store(d.Id, copyDirectory(d))
+
+		return true
+	})
+
+	if iterationErr != nil {
+		return nil, nil, iterationErr
+	}
+
+	return snapshot, copies, nil
+}
+
+// read locks the stored directories for reading, returning the function unlocking them.
+func (t *Driver) read() func() {
+	t.mu.RLock()
+
+	return t.mu.RUnlock
+}
+
+// write serializes a write with the other ones and the transactions, and locks
+// the stored directories for it, returning the function unlocking them.
+func (t *Driver) write() func() {
+	t.txMu.Lock()
+	t.mu.Lock()
+
+	return func() {
+		t.mu.Unlock()
+		t.txMu.Unlock()
+	}
+}
+
+// wrote records that the directory was written, if the driver is bound to a transaction.
+func (t *Driver) wrote(id v1.DirectoryID) {
+	if t.written != nil {
+		t.written[id] = struct{}{}
+	}
+}
+
+// LockDirectories waits for the running writes and transactions to end.
+// Within a transaction, nothing else may write until it ends, so there's
+// nothing to wait for.
+func (t *Driver) LockDirectories(ctx context.Context, ids ...v1.DirectoryID) error {
+	if t.written == nil {
+		unlock := t.write()
+		unlock()
+	}
+
+	return nil
+}
+
+// copyDirectory returns a copy of the directory which can be
+// modified without affecting the original one.
+func copyDirectory(d *v1.Directory) *v1.Directory {
+	c := *d
+
+	if d.Metadata != nil {
+		md := make(v1.DirectoryMetadata, len(*d.Metadata))
+
+		for k, v := range *d.Metadata {
+			md[k] = v
+		}
+
+		c.Metadata = &md
+	}
+
+	return &c
+}
+
+// copyDirectories returns copies of the directories.
+func copyDirectories(dirs []*v1.Directory) []*v1.Directory {
+	copies := make([]*v1.Directory, len(dirs))
+
+	for i, d := range dirs {
+		copies[i] = copyDirectory(d)
+	}
+
+	return copies
+}
+
 // CreateRoot creates a root directory.
 // Root directories are directories that have no parent directory.
 // ID is generated by the database, it will be ignored if given.
+// Unique sibling names are enforced in the new tree if requested by
+// the directory or by the driver.
 func (t *Driver) CreateRoot(ctx context.Context, d *v1.Directory) (*v1.Directory, error) {
+	defer t.write()()
+
 	if d.Parent != nil {
 		return nil, storage.ErrRootWithParentDirectory
 	}
@@ -60,22 +251,28 @@ func (t *Driver) CreateRoot(ctx context.Context, d *v1.Directory) (*v1.Directory
 		d.Metadata = &v1.DirectoryMetadata{}
 	}
 
+	d.UniqueSiblingNames = d.UniqueSiblingNames || t.uniqueSiblingNames
+
 	d.Id = v1.DirectoryID(uuid.New())
 	d.CreatedAt = time.Now()
 	d.UpdatedAt = time.Now()
+	d.Revision = 1
 
-	rawdir, _ := t.dirMap.LoadOrStore(d.Id, d)
+	t.dirMap.Store(d.Id, copyDirectory(d))
 
-	dir, ok := rawdir.(*v1.Directory)
-	if !ok {
-		return nil, fmt.Errorf("directory %s is not of type *v1.Directory", d.Id)
-	}
+	t.recordRevision(ctx, v1.EventTypeCreate, nil, d)
 
-	return dir, nil
+	return d, nil
 }
 
 // ListRoots lists all root directories.
 func (t *Driver) ListRoots(ctx context.Context, options ...storage.Option) ([]v1.DirectoryID, error) {
+	defer t.read()()
+
+	if view := t.asOf(storage.BuildOptions(options)); view != nil {
+		return view.ListRoots(ctx, options...)
+	}
+
 	var roots []*v1.Directory
 
 	opts := storage.BuildOptions(options)
@@ -89,7 +286,7 @@ func (t *Driver) ListRoots(ctx context.Context, options ...storage.Option) ([]v1
 			return false
 		}
 
-		if (dir.DeletedAt == nil || opts.WithDeletedDirectories) && dir.Parent == nil {
+		if (dir.DeletedAt == nil || opts.WithDeletedDirectories) && dir.Parent == nil && opts.Selector.Matches(dir.Metadata) {
 			roots = append(roots, dir)
 		}
 
@@ -100,15 +297,12 @@ func (t *Driver) ListRoots(ctx context.Context, options ...storage.Option) ([]v1
 		return nil, iterationErr
 	}
 
+	roots = sortAfterCursor(roots, opts.Cursor)
+
 	if opts.GetPageOffset() > len(roots) {
 		return nil, nil
 	}
 
-	// Sort the slice to ensure consistency.
-	sort.Slice(roots, func(i, j int) bool {
-		return roots[i].CreatedAt.Before(roots[j].CreatedAt)
-	})
-
 	limit := opts.GetPageOffset() + opts.GetPageSize()
 
 	if limit > len(roots) {
@@ -124,9 +318,22 @@ func (t *Driver) ListRoots(ctx context.Context, options ...storage.Option) ([]v1
 	return rootIDs, nil
 }
 
+// CountRoots counts the root directories listed across all pages.
+func (t *Driver) CountRoots(ctx context.Context, options ...storage.Option) (int, error) {
+	roots, err := t.ListRoots(ctx, allPages(options)...)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(roots), nil
+}
+
 // CreateDirectory creates a directory.
 // ID is generated by the database, it will be ignored if given.
+// The directory enforces unique sibling names if its parent or the driver do.
 func (t *Driver) CreateDirectory(ctx context.Context, d *v1.Directory) (*v1.Directory, error) {
+	defer t.write()()
+
 	if d.Parent == nil {
 		return nil, storage.ErrDirectoryWithoutParent
 	}
@@ -135,36 +342,93 @@ func (t *Driver) CreateDirectory(ctx context.Context, d *v1.Directory) (*v1.Dire
 		d.Metadata = &v1.DirectoryMetadata{}
 	}
 
+	parent, err := t.getDirectory(*d.Parent, false)
+	if err != nil {
+		return nil, err
+	}
+
 	d.Id = v1.DirectoryID(uuid.New())
+	d.UniqueSiblingNames = parent.UniqueSiblingNames || t.uniqueSiblingNames
+
+	if err := t.checkSiblingName(d, *d.Parent); err != nil {
+		return nil, err
+	}
+
 	d.CreatedAt = time.Now()
 	d.UpdatedAt = time.Now()
+	d.Revision = 1
 
-	rawdir, _ := t.dirMap.LoadOrStore(d.Id, d)
+	t.dirMap.Store(d.Id, copyDirectory(d))
 
-	dir, ok := rawdir.(*v1.Directory)
-	if !ok {
-		return nil, fmt.Errorf("directory %s is not of type *v1.Directory", d.Id)
-	}
+	t.recordRevision(ctx, v1.EventTypeCreate, nil, d)
 
-	return dir, nil
+	return d, nil
 }
 
-// UpdateDirectory updates the directory provided.
+// UpdateDirectory updates the name and metadata of the directory provided.
 func (t *Driver) UpdateDirectory(ctx context.Context, d *v1.Directory) error {
+	defer t.write()()
+
+	return t.updateDirectory(ctx, d)
+}
+
+// updateDirectory is UpdateDirectory for the callers already holding the write lock.
+func (t *Driver) updateDirectory(ctx context.Context, d *v1.Directory) error {
 	if d.Metadata == nil {
 		d.Metadata = &v1.DirectoryMetadata{}
 	}
 
-	d.UpdatedAt = time.Now()
+	current, err := t.getDirectory(d.Id, true)
+	if err != nil {
+		return err
+	}
+
+	if current.DeletedAt == nil && current.Parent != nil {
+		renamed := *current
+		renamed.Name = d.Name
+
+		if err := t.checkSiblingName(&renamed, *current.Parent); err != nil {
+			return err
+		}
+	}
+
+	before := copyDirectory(current)
+
+	current.Name = d.Name
+	current.Metadata = d.Metadata
+	current.UpdatedAt = time.Now()
+	current.Revision++
+
+	d.UpdatedAt = current.UpdatedAt
+	d.Revision = current.Revision
 
-	t.dirMap.Store(d.Id, d)
+	t.recordRevision(ctx, v1.EventTypeUpdate, before, current)
 
 	return nil
 }
 
-// DeleteDirectory deletes a directory.
+// UpdateDirectoryIfRevision updates the directory provided
+// only if it is still at the provided revision.
+func (t *Driver) UpdateDirectoryIfRevision(ctx context.Context, d *v1.Directory, revision int64) error {
+	defer t.write()()
+
+	if err := t.checkRevision(d.Id, revision); err != nil {
+		return err
+	}
+
+	return t.updateDirectory(ctx, d)
+}
+
+// DeleteDirectory soft deletes a directory along with all of its descendants.
 func (t *Driver) DeleteDirectory(ctx context.Context, id v1.DirectoryID) ([]*v1.Directory, error) {
-	dir, err := t.GetDirectory(ctx, id)
+	defer t.write()()
+
+	return t.deleteDirectory(ctx, id)
+}
+
+// deleteDirectory is DeleteDirectory for the callers already holding the write lock.
+func (t *Driver) deleteDirectory(ctx context.Context, id v1.DirectoryID) ([]*v1.Directory, error) {
+	dir, err := t.getDirectory(id, false)
 	if err != nil {
 		return nil, err
 	}
@@ -173,198 +437,194 @@ func (t *Driver) DeleteDirectory(ctx context.Context, id v1.DirectoryID) ([]*v1.
 		return nil, storage.ErrDirectoryNotFound
 	}
 
-	children, err := t.GetChildren(ctx, id)
+	children, err := t.getChildren(id, storage.BuildOptions(nil))
 	if err != nil {
 		return nil, fmt.Errorf("error getting children: %w", err)
 	}
 
 	deletedTime := time.Now()
 
-	// length is the requested directory plus the count of children
-	affected := make([]*v1.Directory, 1+len(children))
+	affected := append([]*v1.Directory{dir}, children...)
 
-	dir.DeletedAt = &deletedTime
+	for _, d := range affected {
+		before := copyDirectory(d)
 
-	affected[0] = dir
+		d.DeletedAt = &deletedTime
+		d.Revision++
 
-	for i, childID := range children {
-		child, err := t.GetDirectory(ctx, childID)
-		if err != nil {
-			return nil, fmt.Errorf("error getting child: %s: %w", childID, err)
-		}
+		t.recordRevision(ctx, v1.EventTypeDelete, before, d)
+	}
+
+	return copyDirectories(affected), nil
+}
 
-		child.DeletedAt = &deletedTime
+// PurgeDirectory permanently removes a soft deleted directory
+// and all of its descendants, which must all be soft deleted.
+func (t *Driver) PurgeDirectory(ctx context.Context, id v1.DirectoryID) ([]*v1.Directory, error) {
+	defer t.write()()
 
-		affected[i+1] = child
+	dir, err := t.getDirectory(id, true)
+	if err != nil {
+		return nil, err
 	}
 
-	return affected, nil
-}
+	if dir.DeletedAt == nil {
+		return nil, storage.ErrDirectoryNotDeleted
+	}
 
-// GetDirectory gets a directory by ID.
-func (t *Driver) GetDirectory(
-	ctx context.Context,
-	id v1.DirectoryID,
-	options ...storage.Option,
-) (*v1.Directory, error) {
-	rawdir, ok := t.dirMap.Load(id)
-	if !ok {
-		return nil, storage.ErrDirectoryNotFound
+	byParent, err := t.childrenByParent()
+	if err != nil {
+		return nil, err
 	}
 
-	dir, ok := rawdir.(*v1.Directory)
-	if !ok {
-		return nil, fmt.Errorf("directory %s is not of type *v1.Directory", id)
+	affected := []*v1.Directory{dir}
+
+	for i := 0; i < len(affected); i++ {
+		affected = append(affected, byParent[affected[i].Id]...)
 	}
 
-	opts := storage.BuildOptions(options)
+	for _, d := range affected {
+		if d.DeletedAt == nil {
+			return nil, storage.ErrDescendantNotDeleted
+		}
+	}
 
-	if dir.DeletedAt != nil && !opts.WithDeletedDirectories {
-		return nil, storage.ErrDirectoryNotFound
+	for _, d := range affected {
+		t.dirMap.Delete(d.Id)
+		t.quotas.delete(d.Id)
+		t.schemas.delete(d.Id)
+		t.history.purge(d.Id)
+		t.wrote(d.Id)
 	}
 
-	return dir, nil
+	return copyDirectories(affected), nil
 }
 
-// GetParents gets all parent directories of a directory.
-func (t *Driver) GetParents(
+// ListDeleted lists the directories soft deleted before the provided time,
+// ordered by deletion time and id.
+func (t *Driver) ListDeleted(
 	ctx context.Context,
-	id v1.DirectoryID,
+	before time.Time,
 	options ...storage.Option,
-) ([]v1.DirectoryID, error) {
-	var (
-		parentIDs []v1.DirectoryID
-		parents   []*v1.Directory
-	)
+) ([]*v1.Directory, error) {
+	defer t.read()()
 
-	opts := storage.BuildOptions(options)
+	var deleted []*v1.Directory
 
-	for {
-		dir, err := t.GetDirectory(ctx, id, options...)
-		if err != nil {
-			return nil, err
-		}
+	var iterationErr error
 
-		// skips the first child
-		if len(parentIDs) != 0 {
-			parents = append(parents, dir)
+	t.dirMap.Range(func(key, value interface{}) bool {
+		dir, ok := value.(*v1.Directory)
+		if !ok {
+			iterationErr = fmt.Errorf("found directory that is not of type *v1.Directory")
+			return false
 		}
 
-		if dir.Parent == nil {
-			break
+		if dir.DeletedAt != nil && dir.DeletedAt.Before(before) {
+			deleted = append(deleted, dir)
 		}
 
-		parentIDs = append(parentIDs, *dir.Parent)
-		id = *dir.Parent
-	}
+		return true
+	})
 
-	if opts.GetPageOffset() > len(parents) {
-		return nil, nil
+	if iterationErr != nil {
+		return nil, iterationErr
 	}
 
-	// Sort the slice to ensure consistency.
-	sort.Slice(parentIDs, func(i, j int) bool {
-		return parents[i].CreatedAt.Before(parents[j].CreatedAt)
-	})
-
-	limit := opts.GetPageOffset() + opts.GetPageSize()
+	sort.Slice(deleted, func(i, j int) bool {
+		if !deleted[i].DeletedAt.Equal(*deleted[j].DeletedAt) {
+			return deleted[i].DeletedAt.Before(*deleted[j].DeletedAt)
+		}
 
-	if limit > len(parentIDs) {
-		limit = len(parentIDs)
-	}
+		return deleted[i].Id.String() < deleted[j].Id.String()
+	})
 
-	return parentIDs[opts.GetPageOffset():limit], nil
+	return copyDirectories(page(deleted, storage.BuildOptions(options))), nil
 }
 
-// GetParentsUntilAncestor gets all parent directories of a directory
-// until the ancestor directory is reached.
-func (t *Driver) GetParentsUntilAncestor(
-	ctx context.Context,
-	child,
-	ancestor v1.DirectoryID,
-	options ...storage.Option,
-) ([]v1.DirectoryID, error) {
-	var (
-		parentIDs []v1.DirectoryID
-		parents   []*v1.Directory
-	)
-
-	opts := storage.BuildOptions(options)
+// RestoreDirectory restores a soft deleted directory along with
+// the descendants which were deleted with it.
+func (t *Driver) RestoreDirectory(ctx context.Context, id v1.DirectoryID) ([]*v1.Directory, error) {
+	defer t.write()()
 
-	// verify that ancestor indeed exists
-	ancestorDir, err := t.GetDirectory(ctx, ancestor, options...)
+	dir, err := t.getDirectory(id, true)
 	if err != nil {
 		return nil, err
 	}
 
-	for {
-		dir, err := t.GetDirectory(ctx, child, options...)
+	if dir.DeletedAt == nil {
+		return nil, storage.ErrDirectoryNotDeleted
+	}
+
+	if dir.Parent != nil {
+		parent, err := t.getDirectory(*dir.Parent, true)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("error getting parent: %w", err)
 		}
 
-		// skips the first child
-		if len(parentIDs) != 0 {
-			parents = append(parents, dir)
+		if parent.DeletedAt != nil {
+			return nil, storage.ErrParentDirectoryDeleted
 		}
+	}
 
-		if dir.Parent == nil && dir.Id != ancestor {
-			return nil, storage.ErrDirectoryNotFound
-		}
+	byParent, err := t.childrenByParent()
+	if err != nil {
+		return nil, err
+	}
 
-		parentIDs = append(parentIDs, *dir.Parent)
-		child = *dir.Parent
+	deletedAt := *dir.DeletedAt
+	affected := []*v1.Directory{dir}
 
-		if child == ancestor {
-			parents = append(parents, ancestorDir)
-			break
+	for i := 0; i < len(affected); i++ {
+		for _, child := range byParent[affected[i].Id] {
+			if child.DeletedAt != nil && child.DeletedAt.Equal(deletedAt) {
+				affected = append(affected, child)
+			}
 		}
 	}
 
-	if opts.GetPageOffset() > len(parents) {
-		return nil, nil
+	for _, d := range affected {
+		if d.Parent == nil {
+			continue
+		}
+
+		if err := t.checkSiblingName(d, *d.Parent); err != nil {
+			return nil, err
+		}
 	}
 
-	// Sort the slice to ensure consistency.
-	sort.Slice(parentIDs, func(i, j int) bool {
-		return parents[i].CreatedAt.Before(parents[j].CreatedAt)
-	})
+	now := time.Now()
 
-	limit := opts.GetPageOffset() + opts.GetPageSize()
+	for _, d := range affected {
+		before := copyDirectory(d)
+
+		d.DeletedAt = nil
+		d.UpdatedAt = now
+		d.Revision++
 
-	if limit > len(parentIDs) {
-		limit = len(parentIDs)
+		t.recordRevision(ctx, v1.EventTypeRestore, before, d)
 	}
 
-	return parentIDs[opts.GetPageOffset():limit], nil
+	return copyDirectories(affected), nil
 }
 
-// getChildren retreives all child descendants for the provided parent.
-func (t *Driver) getChildren(
-	id v1.DirectoryID,
-	opts *storage.Options,
-) ([]*v1.Directory, error) {
-	var iterationErr error
+// childrenByParent indexes all directories, including deleted ones, by their parent.
+func (t *Driver) childrenByParent() (map[v1.DirectoryID][]*v1.Directory, error) {
+	byParent := map[v1.DirectoryID][]*v1.Directory{}
 
-	childrenByParents := map[string][]*v1.Directory{}
+	var iterationErr error
 
 	t.dirMap.Range(func(key, value interface{}) bool {
-		dir, ok := value.(*v1.Directory)
+		d, ok := value.(*v1.Directory)
 		if !ok {
 			iterationErr = fmt.Errorf("found directory that is not of type *v1.Directory")
 			return false
 		}
 
-		// If item is deleted and WithDeletedDirectories is not enabled, skip.
-		// We can also ignore root directory parents as there will never be a request from its parent.
-		if dir.DeletedAt != nil && !opts.WithDeletedDirectories || dir.Parent == nil {
-			return true
+		if d.Parent != nil {
+			byParent[*d.Parent] = append(byParent[*d.Parent], d)
 		}
 
-		parent := dir.Parent.String()
-
-		childrenByParents[parent] = append(childrenByParents[parent], dir)
-
 		return true
 	})
 
@@ -372,49 +632,954 @@ func (t *Driver) getChildren(
 		return nil, iterationErr
 	}
 
-	children := append([]*v1.Directory{}, childrenByParents[id.String()]...)
+	return byParent, nil
+}
 
-	// Continuously look through all children and get their children.
-	for i := 0; i < len(children); i++ {
-		children = append(children, childrenByParents[children[i].Id.String()]...)
-	}
+// MoveDirectory moves a non-root directory under the provided parent.
+func (t *Driver) MoveDirectory(
+	ctx context.Context,
+	id, parent v1.DirectoryID,
+) (*v1.Directory, *v1.DirectoryID, error) {
+	defer t.write()()
 
-	return children, nil
+	return t.moveDirectory(ctx, id, parent)
 }
 
-// GetChildren gets all child directories of a directory.
-func (t *Driver) GetChildren(
+// moveDirectory is MoveDirectory for the callers already holding the write lock.
+func (t *Driver) moveDirectory(
 	ctx context.Context,
-	id v1.DirectoryID,
-	options ...storage.Option,
-) ([]v1.DirectoryID, error) {
-	opts := storage.BuildOptions(options)
-
-	children, err := t.getChildren(id, opts)
+	id, parent v1.DirectoryID,
+) (*v1.Directory, *v1.DirectoryID, error) {
+	dir, err := t.getDirectory(id, false)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if opts.GetPageOffset() > len(children) {
-		return nil, nil
+	if dir.Parent == nil {
+		return nil, nil, storage.ErrRootDirectoryMove
 	}
 
-	// Sort the slice to ensure consistency.
-	sort.Slice(children, func(i, j int) bool {
-		return children[i].CreatedAt.Before(children[j].CreatedAt)
-	})
+	if err := t.checkMoveDestination(ctx, id, parent); err != nil {
+		return nil, nil, err
+	}
 
-	limit := opts.GetPageOffset() + opts.GetPageSize()
+	before := copyDirectory(dir)
 
-	if limit > len(children) {
-		limit = len(children)
+	if err := t.inheritSiblingNamesRule(ctx, dir, parent); err != nil {
+		return nil, nil, err
 	}
 
-	childIDs := make([]v1.DirectoryID, limit-opts.GetPageOffset())
+	oldParent := dir.Parent
 
-	for i := range childIDs {
-		childIDs[i] = children[opts.GetPageOffset()+i].Id
-	}
+	dir.Parent = &parent
+	dir.UpdatedAt = time.Now()
+	dir.Revision++
 
-	return childIDs, nil
+	t.recordRevision(ctx, v1.EventTypeMove, before, dir)
+
+	return copyDirectory(dir), oldParent, nil
+}
+
+// PromoteToRoot detaches a directory from its parent, making it a root directory.
+func (t *Driver) PromoteToRoot(ctx context.Context, id v1.DirectoryID) (*v1.Directory, *v1.DirectoryID, error) {
+	defer t.write()()
+
+	return t.promoteToRoot(ctx, id)
+}
+
+// promoteToRoot is PromoteToRoot for the callers already holding the write lock.
+func (t *Driver) promoteToRoot(ctx context.Context, id v1.DirectoryID) (*v1.Directory, *v1.DirectoryID, error) {
+	dir, err := t.getDirectory(id, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if dir.Parent == nil {
+		return nil, nil, storage.ErrAlreadyRoot
+	}
+
+	before := copyDirectory(dir)
+	oldParent := dir.Parent
+
+	dir.Parent = nil
+	dir.UpdatedAt = time.Now()
+	dir.Revision++
+
+	t.recordRevision(ctx, v1.EventTypeMove, before, dir)
+
+	return copyDirectory(dir), oldParent, nil
+}
+
+// DemoteRoot moves a root directory under the provided parent.
+// Its quota overrides are dropped, as it stops being a root.
+func (t *Driver) DemoteRoot(ctx context.Context, id, parent v1.DirectoryID) (*v1.Directory, error) {
+	defer t.write()()
+
+	return t.demoteRoot(ctx, id, parent)
+}
+
+// demoteRoot is DemoteRoot for the callers already holding the write lock.
+func (t *Driver) demoteRoot(ctx context.Context, id, parent v1.DirectoryID) (*v1.Directory, error) {
+	dir, err := t.getDirectory(id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir.Parent != nil {
+		return nil, storage.ErrNotRoot
+	}
+
+	if err := t.checkMoveDestination(ctx, id, parent); err != nil {
+		return nil, err
+	}
+
+	before := copyDirectory(dir)
+
+	if err := t.inheritSiblingNamesRule(ctx, dir, parent); err != nil {
+		return nil, err
+	}
+
+	dir.Parent = &parent
+	dir.UpdatedAt = time.Now()
+	dir.Revision++
+
+	t.quotas.delete(id)
+	t.recordRevision(ctx, v1.EventTypeMove, before, dir)
+
+	return copyDirectory(dir), nil
+}
+
+// DeleteDirectoryIfRevision soft deletes a directory along with all of its
+// descendants, only if it is still at the provided revision.
+func (t *Driver) DeleteDirectoryIfRevision(
+	ctx context.Context,
+	id v1.DirectoryID,
+	revision int64,
+) ([]*v1.Directory, error) {
+	defer t.write()()
+
+	if err := t.checkRevision(id, revision); err != nil {
+		return nil, err
+	}
+
+	return t.deleteDirectory(ctx, id)
+}
+
+// MoveDirectoryIfRevision moves a non-root directory under the provided parent,
+// only if it is still at the provided revision.
+func (t *Driver) MoveDirectoryIfRevision(
+	ctx context.Context,
+	id, parent v1.DirectoryID,
+	revision int64,
+) (*v1.Directory, *v1.DirectoryID, error) {
+	defer t.write()()
+
+	if err := t.checkRevision(id, revision); err != nil {
+		return nil, nil, err
+	}
+
+	return t.moveDirectory(ctx, id, parent)
+}
+
+// PromoteToRootIfRevision detaches a directory from its parent,
+// only if it is still at the provided revision.
+func (t *Driver) PromoteToRootIfRevision(
+	ctx context.Context,
+	id v1.DirectoryID,
+	revision int64,
+) (*v1.Directory, *v1.DirectoryID, error) {
+	defer t.write()()
+
+	if err := t.checkRevision(id, revision); err != nil {
+		return nil, nil, err
+	}
+
+	return t.promoteToRoot(ctx, id)
+}
+
+// DemoteRootIfRevision moves a root directory under the provided parent,
+// only if it is still at the provided revision.
+func (t *Driver) DemoteRootIfRevision(
+	ctx context.Context,
+	id, parent v1.DirectoryID,
+	revision int64,
+) (*v1.Directory, error) {
+	defer t.write()()
+
+	if err := t.checkRevision(id, revision); err != nil {
+		return nil, err
+	}
+
+	return t.demoteRoot(ctx, id, parent)
+}
+
+// checkRevision ensures the provided live directory is still at the provided revision.
+func (t *Driver) checkRevision(id v1.DirectoryID, revision int64) error {
+	dir, err := t.getDirectory(id, false)
+	if err != nil {
+		return err
+	}
+
+	if dir.Revision != revision {
+		return storage.ErrRevisionConflict
+	}
+
+	return nil
+}
+
+// checkMoveDestination ensures the destination parent exists and is not
+// the moved directory itself or one of its descendants.
+func (t *Driver) checkMoveDestination(ctx context.Context, id, parent v1.DirectoryID) error {
+	dest, err := t.getDirectory(parent, false)
+	if err != nil {
+		return err
+	}
+
+	for {
+		if dest.Id == id {
+			return storage.ErrMoveCycle
+		}
+
+		if dest.Parent == nil {
+			return nil
+		}
+
+		dest, err = t.getDirectory(*dest.Parent, true)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// inheritSiblingNamesRule applies the unique sibling names setting of the
+// provided parent to the directory being moved under it and its descendants.
+// Nothing is changed if the directory or one of its descendants would then
+// conflict with a sibling.
+func (t *Driver) inheritSiblingNamesRule(ctx context.Context, dir *v1.Directory, parent v1.DirectoryID) error {
+	dest, err := t.getDirectory(parent, false)
+	if err != nil {
+		return err
+	}
+
+	descendants, err := t.getChildren(dir.Id, storage.BuildOptions([]storage.Option{storage.WithDeletedDirectories}))
+	if err != nil {
+		return err
+	}
+
+	moved := *dir
+	moved.UniqueSiblingNames = dest.UniqueSiblingNames
+
+	if err := t.checkSiblingName(&moved, parent); err != nil {
+		return err
+	}
+
+	// Siblings within the subtree only conflict once the rule is turned on.
+	if dest.UniqueSiblingNames {
+		seen := map[string]bool{}
+
+		for _, d := range descendants {
+			if d.DeletedAt != nil {
+				continue
+			}
+
+			key := d.Parent.String() + "/" + d.Name
+			if seen[key] {
+				return storage.ErrDirectoryNameConflict
+			}
+
+			seen[key] = true
+		}
+	}
+
+	dir.UniqueSiblingNames = dest.UniqueSiblingNames
+
+	for _, d := range descendants {
+		d.UniqueSiblingNames = dest.UniqueSiblingNames
+		t.wrote(d.Id)
+	}
+
+	return nil
+}
+
+// checkSiblingName ensures no live sibling under the provided parent shares
+// the name of the directory, if both enforce unique sibling names.
+func (t *Driver) checkSiblingName(d *v1.Directory, parent v1.DirectoryID) error {
+	if !d.UniqueSiblingNames {
+		return nil
+	}
+
+	var conflict bool
+
+	t.dirMap.Range(func(key, value interface{}) bool {
+		sibling, ok := value.(*v1.Directory)
+		if !ok || sibling.Id == d.Id || sibling.DeletedAt != nil || sibling.Parent == nil {
+			return true
+		}
+
+		conflict = *sibling.Parent == parent && sibling.Name == d.Name && sibling.UniqueSiblingNames
+
+		return !conflict
+	})
+
+	if conflict {
+		return storage.ErrDirectoryNameConflict
+	}
+
+	return nil
+}
+
+// GetDirectory gets a directory by ID.
+// Changes made to the returned directory don't affect the stored one.
+func (t *Driver) GetDirectory(
+	ctx context.Context,
+	id v1.DirectoryID,
+	options ...storage.Option,
+) (*v1.Directory, error) {
+	defer t.read()()
+
+	if view := t.asOf(storage.BuildOptions(options)); view != nil {
+		return view.GetDirectory(ctx, id, options...)
+	}
+
+	dir, err := t.getDirectory(id, storage.BuildOptions(options).WithDeletedDirectories)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyDirectory(dir), nil
+}
+
+// getDirectory gets the stored directory, which is modified in place by writes.
+func (t *Driver) getDirectory(id v1.DirectoryID, withDeleted bool) (*v1.Directory, error) {
+	rawdir, ok := t.dirMap.Load(id)
+	if !ok {
+		return nil, storage.ErrDirectoryNotFound
+	}
+
+	dir, ok := rawdir.(*v1.Directory)
+	if !ok {
+		return nil, fmt.Errorf("directory %s is not of type *v1.Directory", id)
+	}
+
+	if dir.DeletedAt != nil && !withDeleted {
+		return nil, storage.ErrDirectoryNotFound
+	}
+
+	return dir, nil
+}
+
+// GetDirectories gets the directories with the provided IDs, in the same order.
+// IDs which don't match any directory are skipped.
+func (t *Driver) GetDirectories(
+	ctx context.Context,
+	ids []v1.DirectoryID,
+	options ...storage.Option,
+) ([]*v1.Directory, error) {
+	defer t.read()()
+
+	if view := t.asOf(storage.BuildOptions(options)); view != nil {
+		return view.GetDirectories(ctx, ids, options...)
+	}
+
+	opts := storage.BuildOptions(options)
+
+	var dirs []*v1.Directory
+
+	seen := make(map[v1.DirectoryID]bool, len(ids))
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+
+		dir, err := t.getDirectory(id, opts.WithDeletedDirectories)
+		if errors.Is(err, storage.ErrDirectoryNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		dirs = append(dirs, copyDirectory(dir))
+	}
+
+	return dirs, nil
+}
+
+// GetParents gets all parent directories of a directory.
+// Parents are returned from the closest to the furthest ancestor.
+func (t *Driver) GetParents(
+	ctx context.Context,
+	id v1.DirectoryID,
+	options ...storage.Option,
+) ([]v1.DirectoryID, error) {
+	defer t.read()()
+
+	if view := t.asOf(storage.BuildOptions(options)); view != nil {
+		return view.GetParents(ctx, id, options...)
+	}
+
+	var parentIDs []v1.DirectoryID
+
+	opts := storage.BuildOptions(options)
+
+	// Continue walking up from the last directory returned.
+	if opts.Cursor != nil {
+		if err := t.checkParentsCursor(id, nil, opts.Cursor); err != nil {
+			return nil, err
+		}
+
+		id = opts.Cursor.ID
+	}
+
+	for {
+		dir, err := t.getDirectory(id, opts.WithDeletedDirectories)
+		if err != nil {
+			return nil, err
+		}
+
+		if dir.Parent == nil {
+			break
+		}
+
+		parentIDs = append(parentIDs, *dir.Parent)
+		id = *dir.Parent
+	}
+
+	return paginateIDs(parentIDs, opts), nil
+}
+
+// GetParentsUntilAncestor gets all parent directories of a directory
+// until the ancestor directory is reached, the ancestor included.
+// All parents are returned if the ancestor isn't one of them.
+func (t *Driver) GetParentsUntilAncestor(
+	ctx context.Context,
+	child,
+	ancestor v1.DirectoryID,
+	options ...storage.Option,
+) ([]v1.DirectoryID, error) {
+	defer t.read()()
+
+	if view := t.asOf(storage.BuildOptions(options)); view != nil {
+		return view.GetParentsUntilAncestor(ctx, child, ancestor, options...)
+	}
+
+	if child == ancestor {
+		return []v1.DirectoryID{}, nil
+	}
+
+	var parentIDs []v1.DirectoryID
+
+	opts := storage.BuildOptions(options)
+
+	// Continue walking up from the last directory returned.
+	if opts.Cursor != nil {
+		if err := t.checkParentsCursor(child, &ancestor, opts.Cursor); err != nil {
+			return nil, err
+		}
+
+		child = opts.Cursor.ID
+	}
+
+	for child != ancestor {
+		dir, err := t.getDirectory(child, opts.WithDeletedDirectories)
+		if err != nil {
+			return nil, err
+		}
+
+		if dir.Parent == nil {
+			break
+		}
+
+		parentIDs = append(parentIDs, *dir.Parent)
+		child = *dir.Parent
+	}
+
+	return paginateIDs(parentIDs, opts), nil
+}
+
+// checkParentsCursor ensures the cursor's directory is one of the directories
+// walked through up from the child, stopping at the ancestor if provided.
+// Deleted directories are walked through, as the cursor's directory may have
+// been deleted since it was listed.
+func (t *Driver) checkParentsCursor(child v1.DirectoryID, ancestor *v1.DirectoryID, cursor *storage.Cursor) error {
+	for current := child; current != cursor.ID; {
+		if ancestor != nil && current == *ancestor {
+			return storage.ErrInvalidCursor
+		}
+
+		dir, err := t.getDirectory(current, true)
+		if err != nil || dir.Parent == nil {
+			return storage.ErrInvalidCursor
+		}
+
+		current = *dir.Parent
+	}
+
+	return nil
+}
+
+// paginateIDs returns the requested page of the provided ids.
+func paginateIDs(ids []v1.DirectoryID, opts *storage.Options) []v1.DirectoryID {
+	if opts.GetPageOffset() > len(ids) {
+		return nil
+	}
+
+	limit := opts.GetPageOffset() + opts.GetPageSize()
+
+	if limit > len(ids) {
+		limit = len(ids)
+	}
+
+	return ids[opts.GetPageOffset():limit]
+}
+
+// getChildren retreives all child descendants for the provided parent.
+func (t *Driver) getChildren(
+	id v1.DirectoryID,
+	opts *storage.Options,
+) ([]*v1.Directory, error) {
+	var iterationErr error
+
+	childrenByParents := map[string][]*v1.Directory{}
+
+	t.dirMap.Range(func(key, value interface{}) bool {
+		dir, ok := value.(*v1.Directory)
+		if !ok {
+			iterationErr = fmt.Errorf("found directory that is not of type *v1.Directory")
+			return false
+		}
+
+		// If item is deleted and WithDeletedDirectories is not enabled, skip.
+		// We can also ignore root directory parents as there will never be a request from its parent.
+		if dir.DeletedAt != nil && !opts.WithDeletedDirectories || dir.Parent == nil {
+			return true
+		}
+
+		parent := dir.Parent.String()
+
+		childrenByParents[parent] = append(childrenByParents[parent], dir)
+
+		return true
+	})
+
+	if iterationErr != nil {
+		return nil, iterationErr
+	}
+
+	children := append([]*v1.Directory{}, childrenByParents[id.String()]...)
+
+	// Continuously look through all children and get their children,
+	// one level at a time, until the maximum depth is reached.
+	for depth, levelStart := 1, 0; opts.MaxDepth == 0 || depth < opts.MaxDepth; depth++ {
+		levelEnd := len(children)
+		if levelStart == levelEnd {
+			break
+		}
+
+		for i := levelStart; i < levelEnd; i++ {
+			children = append(children, childrenByParents[children[i].Id.String()]...)
+		}
+
+		levelStart = levelEnd
+	}
+
+	return children, nil
+}
+
+// GetChildren gets all child directories of a directory.
+func (t *Driver) GetChildren(
+	ctx context.Context,
+	id v1.DirectoryID,
+	options ...storage.Option,
+) ([]v1.DirectoryID, error) {
+	defer t.read()()
+
+	if view := t.asOf(storage.BuildOptions(options)); view != nil {
+		return view.GetChildren(ctx, id, options...)
+	}
+
+	opts := storage.BuildOptions(options)
+
+	if _, err := t.getDirectory(id, opts.WithDeletedDirectories); err != nil {
+		return nil, err
+	}
+
+	children, err := t.getChildren(id, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Children are listed breadth first, each level ordered by creation.
+	// Parents come before their children, so their depth is already known.
+	depths := map[v1.DirectoryID]int{id: 0}
+
+	for _, d := range children {
+		depths[d.Id] = depths[*d.Parent] + 1
+	}
+
+	children = sortAfterCursor(filterBySelector(children, opts.Selector), nil)
+
+	sort.SliceStable(children, func(i, j int) bool {
+		return depths[children[i].Id] < depths[children[j].Id]
+	})
+
+	if opts.Cursor != nil {
+		cursorDepth, err := t.cursorDepth(id, opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		for len(children) > 0 {
+			depth := depths[children[0].Id]
+			if depth > cursorDepth || depth == cursorDepth && opts.Cursor.After(children[0]) {
+				break
+			}
+
+			children = children[1:]
+		}
+	}
+
+	children = page(children, opts)
+
+	if len(children) == 0 {
+		return nil, nil
+	}
+
+	childIDs := make([]v1.DirectoryID, len(children))
+
+	for i, d := range children {
+		childIDs[i] = d.Id
+	}
+
+	return childIDs, nil
+}
+
+// CountChildren counts the child directories listed across all pages.
+func (t *Driver) CountChildren(ctx context.Context, id v1.DirectoryID, options ...storage.Option) (int, error) {
+	children, err := t.GetChildren(ctx, id, allPages(options)...)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(children), nil
+}
+
+// allPages returns the options listing all the directories in a single page.
+func allPages(options []storage.Option) []storage.Option {
+	all := make([]storage.Option, len(options), len(options)+2)
+	copy(all, options)
+
+	return append(all, storage.Pagination(1, math.MaxInt32), storage.WithCursor(nil))
+}
+
+// cursorDepth returns the depth of the cursor's directory below the provided one.
+// ErrInvalidCursor is returned if the directory isn't one of its descendants.
+func (t *Driver) cursorDepth(id v1.DirectoryID, cursor *storage.Cursor) (int, error) {
+	depth := 0
+
+	for current := cursor.ID; current != id; depth++ {
+		dir, err := t.getDirectory(current, true)
+		if err != nil || dir.Parent == nil {
+			return 0, storage.ErrInvalidCursor
+		}
+
+		current = *dir.Parent
+	}
+
+	return depth, nil
+}
+
+// SearchDirectories searches the descendants of a directory by name.
+func (t *Driver) SearchDirectories(
+	ctx context.Context,
+	id v1.DirectoryID,
+	q storage.NameQuery,
+	options ...storage.Option,
+) ([]*v1.DirectorySearchResult, error) {
+	defer t.read()()
+
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	if view := t.asOf(storage.BuildOptions(options)); view != nil {
+		return view.SearchDirectories(ctx, id, q, options...)
+	}
+
+	opts := storage.BuildOptions(options)
+
+	if _, err := t.getDirectory(id, opts.WithDeletedDirectories); err != nil {
+		return nil, err
+	}
+
+	// All descendants are searched, whatever the depth requested.
+	descendants, err := t.getChildren(id, &storage.Options{WithDeletedDirectories: opts.WithDeletedDirectories})
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[v1.DirectoryID]*v1.Directory, len(descendants))
+
+	var matches []*v1.Directory
+
+	for _, d := range descendants {
+		byID[d.Id] = d
+
+		if q.Matches(d.Name) {
+			matches = append(matches, d)
+		}
+	}
+
+	matches = page(sortAfterCursor(matches, opts.Cursor), opts)
+
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	results := make([]*v1.DirectorySearchResult, len(matches))
+
+	for i, d := range matches {
+		// Descendants always have a parent, which is either the directory or another descendant.
+		var parents []v1.DirectoryID
+
+		for parent := *d.Parent; parent != id; parent = *byID[parent].Parent {
+			parents = append(parents, parent)
+		}
+
+		results[i] = &v1.DirectorySearchResult{
+			Directory: *copyDirectory(d),
+			Parents:   append(parents, id),
+		}
+	}
+
+	return results, nil
+}
+
+// ResolvePath gets the directory at the provided path, walking down
+// from the roots one name at a time.
+func (t *Driver) ResolvePath(
+	ctx context.Context,
+	path []string,
+	options ...storage.Option,
+) (*v1.Directory, error) {
+	defer t.read()()
+
+	if view := t.asOf(storage.BuildOptions(options)); view != nil {
+		return view.ResolvePath(ctx, path, options...)
+	}
+
+	if len(path) == 0 {
+		return nil, storage.ErrDirectoryNotFound
+	}
+
+	opts := storage.BuildOptions(options)
+
+	byParent, err := t.childrenByParent()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		matches      []*v1.Directory
+		iterationErr error
+	)
+
+	t.dirMap.Range(func(key, value interface{}) bool {
+		d, ok := value.(*v1.Directory)
+		if !ok {
+			iterationErr = fmt.Errorf("found directory that is not of type *v1.Directory")
+			return false
+		}
+
+		if d.Parent == nil && d.Name == path[0] && (d.DeletedAt == nil || opts.WithDeletedDirectories) {
+			matches = append(matches, d)
+		}
+
+		return true
+	})
+
+	if iterationErr != nil {
+		return nil, iterationErr
+	}
+
+	for _, name := range path[1:] {
+		var children []*v1.Directory
+
+		for _, parent := range matches {
+			for _, d := range byParent[parent.Id] {
+				if d.Name == name && (d.DeletedAt == nil || opts.WithDeletedDirectories) {
+					children = append(children, d)
+				}
+			}
+		}
+
+		matches = children
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, storage.ErrDirectoryNotFound
+	case 1:
+		return copyDirectory(matches[0]), nil
+	default:
+		return nil, storage.AmbiguousPathError(path)
+	}
+}
+
+// page returns the directories of the requested page.
+func page(dirs []*v1.Directory, opts *storage.Options) []*v1.Directory {
+	if opts.GetPageOffset() > len(dirs) {
+		return nil
+	}
+
+	limit := opts.GetPageOffset() + opts.GetPageSize()
+
+	if limit > len(dirs) {
+		limit = len(dirs)
+	}
+
+	return dirs[opts.GetPageOffset():limit]
+}
+
+// GetSubtree gets a directory along with its descendants as a nested tree.
+func (t *Driver) GetSubtree(
+	ctx context.Context,
+	id v1.DirectoryID,
+	options ...storage.Option,
+) (*v1.DirectoryTree, error) {
+	defer t.read()()
+
+	if view := t.asOf(storage.BuildOptions(options)); view != nil {
+		return view.GetSubtree(ctx, id, options...)
+	}
+
+	opts := storage.BuildOptions(options)
+
+	dir, err := t.getDirectory(id, opts.WithDeletedDirectories)
+	if err != nil {
+		return nil, err
+	}
+
+	descendants, err := t.getChildren(id, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if 1+len(descendants) > opts.GetMaxNodes() {
+		return nil, storage.ErrSubtreeTooLarge
+	}
+
+	return v1.NewDirectoryTree(dir, sortAfterCursor(descendants, nil)), nil
+}
+
+// GetStats gets the statistics of the subtree of a directory.
+func (t *Driver) GetStats(
+	ctx context.Context,
+	id v1.DirectoryID,
+	options ...storage.Option,
+) (*v1.DirectoryStats, error) {
+	defer t.read()()
+
+	if view := t.asOf(storage.BuildOptions(options)); view != nil {
+		return view.GetStats(ctx, id, options...)
+	}
+
+	dir, err := t.getDirectory(id, storage.BuildOptions(options).WithDeletedDirectories)
+	if err != nil {
+		return nil, err
+	}
+
+	byParent, err := t.childrenByParent()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &v1.DirectoryStats{
+		Id:           id,
+		LastModified: lastModified(dir),
+	}
+
+	// Walk the subtree level by level, the directory being at depth 0.
+	level := byParent[id]
+
+	for depth := 1; len(level) > 0; depth++ {
+		var next []*v1.Directory
+
+		for _, d := range level {
+			stats.Descendants++
+
+			if d.DeletedAt == nil {
+				stats.LiveDescendants++
+				stats.MaxDepth = depth
+
+				if depth == 1 {
+					stats.Children++
+				}
+			} else {
+				stats.DeletedDescendants++
+			}
+
+			if modified := lastModified(d); modified.After(stats.LastModified) {
+				stats.LastModified = modified
+			}
+
+			next = append(next, byParent[d.Id]...)
+		}
+
+		level = next
+	}
+
+	return stats, nil
+}
+
+// lastModified returns the last time the directory was updated or deleted.
+func lastModified(d *v1.Directory) time.Time {
+	if d.DeletedAt != nil && d.DeletedAt.After(d.UpdatedAt) {
+		return *d.DeletedAt
+	}
+
+	return d.UpdatedAt
+}
+
+// filterBySelector only keeps the directories whose metadata matches the selector.
+func filterBySelector(dirs []*v1.Directory, sel storage.Selector) []*v1.Directory {
+	if len(sel) == 0 {
+		return dirs
+	}
+
+	var matching []*v1.Directory
+
+	for _, d := range dirs {
+		if sel.Matches(d.Metadata) {
+			matching = append(matching, d)
+		}
+	}
+
+	return matching
+}
+
+// sortAfterCursor sorts the directories by creation time and id,
+// only keeping the ones after the provided cursor, if any.
+func sortAfterCursor(dirs []*v1.Directory, cursor *storage.Cursor) []*v1.Directory {
+	sort.Slice(dirs, func(i, j int) bool {
+		if !dirs[i].CreatedAt.Equal(dirs[j].CreatedAt) {
+			return dirs[i].CreatedAt.Before(dirs[j].CreatedAt)
+		}
+
+		return dirs[i].Id.String() < dirs[j].Id.String()
+	})
+
+	if cursor == nil {
+		return dirs
+	}
+
+	for i, d := range dirs {
+		if cursor.After(d) {
+			return dirs[i:]
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,65 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/memory"
+	"github.com/infratographer/fertilesoil/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RunConformance(t, func(t *testing.T) storage.DirectoryAdmin {
+		return memory.NewDirectoryDriver()
+	})
+}
+
+func TestWithTxKeepsConcurrentWrites(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := memory.NewDirectoryDriver()
+
+	root, err := store.CreateRoot(ctx, &v1.Directory{Name: "root"})
+	assert.NoError(t, err, "error creating root")
+
+	other, err := store.CreateRoot(ctx, &v1.Directory{Name: "other"})
+	assert.NoError(t, err, "error creating root")
+
+	started := make(chan struct{})
+	done := make(chan error)
+
+	go func() {
+		done <- store.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+			close(started)
+
+			// Give the write below the time to be attempted while the transaction runs.
+			time.Sleep(50 * time.Millisecond) //nolint:gomnd // see above
+
+			_, err := tx.CreateDirectory(ctx, &v1.Directory{Name: "child", Parent: &root.Id})
+
+			return err
+		})
+	}()
+
+	<-started
+
+	other.Name = "renamed"
+	assert.NoError(t, store.UpdateDirectory(ctx, other), "error updating directory")
+	assert.NoError(t, <-done, "error running transaction")
+
+	updated, err := store.GetDirectory(ctx, other.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, "renamed", updated.Name, "writes outside of the transaction should be kept")
+
+	children, err := store.GetChildren(ctx, root.Id)
+	assert.NoError(t, err, "error getting children")
+	assert.Len(t, children, 1, "writes of the transaction should be applied")
+}
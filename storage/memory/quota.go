@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// quotaMap holds the quota overrides of the root directories.
+type quotaMap struct {
+	mu     sync.Mutex
+	quotas map[v1.DirectoryID]v1.DirectoryQuota
+}
+
+func newQuotaMap() *quotaMap {
+	return &quotaMap{quotas: map[v1.DirectoryID]v1.DirectoryQuota{}}
+}
+
+func (m *quotaMap) get(id v1.DirectoryID) v1.DirectoryQuota {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.quotas[id]
+}
+
+func (m *quotaMap) set(id v1.DirectoryID, q v1.DirectoryQuota) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.quotas[id] = q
+}
+
+func (m *quotaMap) delete(id v1.DirectoryID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.quotas, id)
+}
+
+// clone returns a copy of the map, e.g. for a transaction.
+func (m *quotaMap) clone() *quotaMap {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := newQuotaMap()
+
+	for id, q := range m.quotas {
+		c.quotas[id] = q
+	}
+
+	return c
+}
+
+// replace replaces the quotas with the ones of the provided map,
+// e.g. as a transaction commits.
+func (m *quotaMap) replace(other *quotaMap) {
+	c := other.clone()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.quotas = c.quotas
+}
+
+// GetQuota returns the quota overrides of the provided root directory.
+func (t *Driver) GetQuota(ctx context.Context, root v1.DirectoryID) (*v1.DirectoryQuota, error) {
+	defer t.read()()
+
+	dir, err := t.getDirectory(root, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir.Parent != nil {
+		return nil, storage.ErrNotRoot
+	}
+
+	q := t.quotas.get(root)
+
+	return &q, nil
+}
+
+// SetQuota replaces the quota overrides of the provided root directory.
+func (t *Driver) SetQuota(ctx context.Context, root v1.DirectoryID, q *v1.DirectoryQuota) error {
+	defer t.write()()
+
+	dir, err := t.getDirectory(root, false)
+	if err != nil {
+		return err
+	}
+
+	if dir.Parent != nil {
+		return storage.ErrNotRoot
+	}
+
+	t.quotas.set(root, *q)
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// schemaMap holds the metadata schemas of the directories.
+type schemaMap struct {
+	mu      sync.Mutex
+	schemas map[v1.DirectoryID]json.RawMessage
+}
+
+func newSchemaMap() *schemaMap {
+	return &schemaMap{schemas: map[v1.DirectoryID]json.RawMessage{}}
+}
+
+func (m *schemaMap) get(id v1.DirectoryID) (json.RawMessage, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.schemas[id]
+
+	return s, ok
+}
+
+func (m *schemaMap) set(id v1.DirectoryID, s json.RawMessage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.schemas[id] = append(json.RawMessage(nil), s...)
+}
+
+// empty returns whether no schema is set.
+func (m *schemaMap) empty() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.schemas) == 0
+}
+
+// delete removes the schema of the directory, returning whether it had one.
+func (m *schemaMap) delete(id v1.DirectoryID) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.schemas[id]
+	delete(m.schemas, id)
+
+	return ok
+}
+
+// clone returns a copy of the map, e.g. for a transaction.
+// Schemas are never modified in place, so they're shared.
+func (m *schemaMap) clone() *schemaMap {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := newSchemaMap()
+
+	for id, s := range m.schemas {
+		c.schemas[id] = s
+	}
+
+	return c
+}
+
+// replace replaces the schemas with the ones of the provided map,
+// e.g. as a transaction commits.
+func (m *schemaMap) replace(other *schemaMap) {
+	c := other.clone()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.schemas = c.schemas
+}
+
+// GetSchema returns the schema applying to the provided directory.
+func (t *Driver) GetSchema(ctx context.Context, id v1.DirectoryID) (*storage.MetadataSchema, error) {
+	defer t.read()()
+
+	dir, err := t.getDirectory(id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Walking up the parents is spared while no schema is set.
+	if t.schemas.empty() {
+		return nil, storage.ErrSchemaNotFound
+	}
+
+	for {
+		if s, ok := t.schemas.get(dir.Id); ok {
+			return &storage.MetadataSchema{
+				Source: dir.Id,
+				Schema: append(json.RawMessage(nil), s...),
+			}, nil
+		}
+
+		if dir.Parent == nil {
+			return nil, storage.ErrSchemaNotFound
+		}
+
+		dir, err = t.getDirectory(*dir.Parent, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// SetSchema replaces the schema of the provided directory.
+func (t *Driver) SetSchema(ctx context.Context, id v1.DirectoryID, schema json.RawMessage) error {
+	defer t.write()()
+
+	if _, err := t.getDirectory(id, false); err != nil {
+		return err
+	}
+
+	t.schemas.set(id, schema)
+
+	return nil
+}
+
+// DeleteSchema removes the schema of the provided directory.
+func (t *Driver) DeleteSchema(ctx context.Context, id v1.DirectoryID) error {
+	defer t.write()()
+
+	if _, err := t.getDirectory(id, false); err != nil {
+		return err
+	}
+
+	if !t.schemas.delete(id) {
+		return storage.ErrSchemaNotFound
+	}
+
+	return nil
+}
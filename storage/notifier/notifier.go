@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/cenkalti/backoff/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 
 	apiv1 "github.com/infratographer/fertilesoil/api/v1"
 	nif "github.com/infratographer/fertilesoil/notifier"
@@ -14,17 +17,22 @@ import (
 
 var ErrNotifyFailed = errors.New("notification failed")
 
+var tracer = otel.Tracer("github.com/infratographer/fertilesoil/storage/notifier")
+
 type Option func(*notifierWithStorage)
 
 // StorageWithNotifier is a meta-storage driver that wraps a storage.DirectoryAdmin
 // and notifies a notifier.Notifier on every operation.
 // The notifier is called after the operation has been completed.
+// Each notification is traced, including the retries of WithNotifyRetrier.
 func StorageWithNotifier(s storage.DirectoryAdmin, n nif.Notifier, opts ...Option) storage.DirectoryAdmin {
 	nws := &notifierWithStorage{
 		DirectoryAdmin: s,
 		notifier:       n,
 	}
 
+	nws.addWrapper(traceNotify)
+
 	for _, opt := range opts {
 		opt(nws)
 	}
@@ -58,6 +66,21 @@ type handler func(context.Context) error
 // wraps a handler.
 type wrapper func(context.Context, handler) error
 
+// traceNotify runs the handler within a span, which records its error.
+func traceNotify(ctx context.Context, h handler) error {
+	ctx, span := tracer.Start(ctx, "notify")
+	defer span.End()
+
+	if err := h(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	return nil
+}
+
 func (n *notifierWithStorage) CreateDirectory(ctx context.Context, d *apiv1.Directory) (*apiv1.Directory, error) {
 	d, err := n.DirectoryAdmin.CreateDirectory(ctx, d)
 	if err != nil {
@@ -94,20 +117,93 @@ func (n *notifierWithStorage) UpdateDirectory(ctx context.Context, d *apiv1.Dire
 	return nil
 }
 
+func (n *notifierWithStorage) UpdateDirectoryIfRevision(
+	ctx context.Context,
+	d *apiv1.Directory,
+	revision int64,
+) error {
+	if err := n.DirectoryAdmin.UpdateDirectoryIfRevision(ctx, d, revision); err != nil {
+		return err
+	}
+
+	err := n.notifyWrapper(ctx, func(ctx context.Context) error {
+		return n.notifier.NotifyUpdate(ctx, d)
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotifyFailed, err)
+	}
+	return nil
+}
+
 func (n *notifierWithStorage) DeleteDirectory(ctx context.Context, id apiv1.DirectoryID) ([]*apiv1.Directory, error) {
 	affected, err := n.DirectoryAdmin.DeleteDirectory(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
+	return affected, n.notifyDelete(ctx, affected)
+}
+
+func (n *notifierWithStorage) DeleteDirectoryIfRevision(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	revision int64,
+) ([]*apiv1.Directory, error) {
+	affected, err := n.DirectoryAdmin.DeleteDirectoryIfRevision(ctx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return affected, n.notifyDelete(ctx, affected)
+}
+
+func (n *notifierWithStorage) notifyDelete(ctx context.Context, affected []*apiv1.Directory) error {
 	for _, d := range affected {
-		err = n.notifyWrapper(ctx, func(ctx context.Context) error {
+		err := n.notifyWrapper(ctx, func(ctx context.Context) error {
 			if err := n.notifier.NotifyDelete(ctx, d); err != nil {
 				return err
 			}
 			return nil
 		})
 
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrNotifyFailed, err)
+		}
+	}
+
+	return nil
+}
+
+func (n *notifierWithStorage) RestoreDirectory(ctx context.Context, id apiv1.DirectoryID) ([]*apiv1.Directory, error) {
+	affected, err := n.DirectoryAdmin.RestoreDirectory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range affected {
+		err = n.notifyWrapper(ctx, func(ctx context.Context) error {
+			return n.notifier.NotifyRestore(ctx, d)
+		})
+
+		if err != nil {
+			return affected, fmt.Errorf("%w: %v", ErrNotifyFailed, err)
+		}
+	}
+
+	return affected, nil
+}
+
+func (n *notifierWithStorage) PurgeDirectory(ctx context.Context, id apiv1.DirectoryID) ([]*apiv1.Directory, error) {
+	affected, err := n.DirectoryAdmin.PurgeDirectory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range affected {
+		err = n.notifyWrapper(ctx, func(ctx context.Context) error {
+			return n.notifier.NotifyDeleteHard(ctx, d)
+		})
+
 		if err != nil {
 			return affected, fmt.Errorf("%w: %v", ErrNotifyFailed, err)
 		}
@@ -135,6 +231,120 @@ func (n *notifierWithStorage) CreateRoot(ctx context.Context, d *apiv1.Directory
 	return d, nil
 }
 
+func (n *notifierWithStorage) MoveDirectory(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	d, oldParent, err := n.DirectoryAdmin.MoveDirectory(ctx, id, parent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := n.notifyMove(ctx, d, oldParent); err != nil {
+		return d, oldParent, err
+	}
+
+	return d, oldParent, nil
+}
+
+func (n *notifierWithStorage) PromoteToRoot(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	d, oldParent, err := n.DirectoryAdmin.PromoteToRoot(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := n.notifyMove(ctx, d, oldParent); err != nil {
+		return d, oldParent, err
+	}
+
+	return d, oldParent, nil
+}
+
+func (n *notifierWithStorage) DemoteRoot(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+) (*apiv1.Directory, error) {
+	d, err := n.DirectoryAdmin.DemoteRoot(ctx, id, parent)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := n.notifyMove(ctx, d, nil); err != nil {
+		return d, err
+	}
+
+	return d, nil
+}
+
+func (n *notifierWithStorage) MoveDirectoryIfRevision(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+	revision int64,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	d, oldParent, err := n.DirectoryAdmin.MoveDirectoryIfRevision(ctx, id, parent, revision)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := n.notifyMove(ctx, d, oldParent); err != nil {
+		return d, oldParent, err
+	}
+
+	return d, oldParent, nil
+}
+
+func (n *notifierWithStorage) PromoteToRootIfRevision(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	revision int64,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	d, oldParent, err := n.DirectoryAdmin.PromoteToRootIfRevision(ctx, id, revision)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := n.notifyMove(ctx, d, oldParent); err != nil {
+		return d, oldParent, err
+	}
+
+	return d, oldParent, nil
+}
+
+func (n *notifierWithStorage) DemoteRootIfRevision(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+	revision int64,
+) (*apiv1.Directory, error) {
+	d, err := n.DirectoryAdmin.DemoteRootIfRevision(ctx, id, parent, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := n.notifyMove(ctx, d, nil); err != nil {
+		return d, err
+	}
+
+	return d, nil
+}
+
+func (n *notifierWithStorage) notifyMove(
+	ctx context.Context,
+	d *apiv1.Directory,
+	oldParent *apiv1.DirectoryID,
+) error {
+	err := n.notifyWrapper(ctx, func(ctx context.Context) error {
+		return n.notifier.NotifyMove(ctx, d, oldParent)
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotifyFailed, err)
+	}
+
+	return nil
+}
+
 // passthrough functions.
 func (n *notifierWithStorage) GetDirectory(
 	ctx context.Context,
@@ -144,6 +354,14 @@ func (n *notifierWithStorage) GetDirectory(
 	return n.DirectoryAdmin.GetDirectory(ctx, id, options...)
 }
 
+func (n *notifierWithStorage) GetDirectories(
+	ctx context.Context,
+	ids []apiv1.DirectoryID,
+	options ...storage.Option,
+) ([]*apiv1.Directory, error) {
+	return n.DirectoryAdmin.GetDirectories(ctx, ids, options...)
+}
+
 func (n *notifierWithStorage) GetParents(
 	ctx context.Context,
 	id apiv1.DirectoryID,
@@ -168,6 +386,63 @@ func (n *notifierWithStorage) GetChildren(
 	return n.DirectoryAdmin.GetChildren(ctx, id, options...)
 }
 
+func (n *notifierWithStorage) CountChildren(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	options ...storage.Option,
+) (int, error) {
+	return n.DirectoryAdmin.CountChildren(ctx, id, options...)
+}
+
+func (n *notifierWithStorage) GetSubtree(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	options ...storage.Option,
+) (*apiv1.DirectoryTree, error) {
+	return n.DirectoryAdmin.GetSubtree(ctx, id, options...)
+}
+
+func (n *notifierWithStorage) GetStats(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	options ...storage.Option,
+) (*apiv1.DirectoryStats, error) {
+	return n.DirectoryAdmin.GetStats(ctx, id, options...)
+}
+
+func (n *notifierWithStorage) SearchDirectories(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	q storage.NameQuery,
+	options ...storage.Option,
+) ([]*apiv1.DirectorySearchResult, error) {
+	return n.DirectoryAdmin.SearchDirectories(ctx, id, q, options...)
+}
+
+func (n *notifierWithStorage) ResolvePath(
+	ctx context.Context,
+	path []string,
+	options ...storage.Option,
+) (*apiv1.Directory, error) {
+	return n.DirectoryAdmin.ResolvePath(ctx, path, options...)
+}
+
+func (n *notifierWithStorage) GetHistory(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	options ...storage.Option,
+) ([]*apiv1.DirectoryRevision, error) {
+	return n.DirectoryAdmin.GetHistory(ctx, id, options...)
+}
+
+func (n *notifierWithStorage) ListDeleted(
+	ctx context.Context,
+	before time.Time,
+	options ...storage.Option,
+) ([]*apiv1.Directory, error) {
+	return n.DirectoryAdmin.ListDeleted(ctx, before, options...)
+}
+
 func (n *notifierWithStorage) addWrapper(w wrapper) {
 	wrap := n.notifyWrapper
 	if wrap == nil {
@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+
+	apiv1 "github.com/infratographer/fertilesoil/api/v1"
+	nif "github.com/infratographer/fertilesoil/notifier"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// WithTx runs fn within a transaction of the wrapped storage.
+// Notifications for the operations done in fn are queued, and only
+// sent once the transaction has been committed.
+func (n *notifierWithStorage) WithTx(ctx context.Context, fn func(storage.DirectoryAdmin) error) error {
+	var queue *queueNotifier
+
+	err := n.DirectoryAdmin.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		// The transaction may be retried, so only the
+		// notifications from the last attempt are kept.
+		queue = &queueNotifier{}
+
+		return fn(&notifierWithStorage{
+			DirectoryAdmin: tx,
+			notifier:       queue,
+			notifyWrapper: func(ctx context.Context, h handler) error {
+				return h(ctx)
+			},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, notify := range queue.pending {
+		notify := notify
+
+		err := n.notifyWrapper(ctx, func(ctx context.Context) error {
+			return notify(ctx, n.notifier)
+		})
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrNotifyFailed, err)
+		}
+	}
+
+	return nil
+}
+
+// queueNotifier is a notifier.Notifier which queues notifications
+// so they can be sent later on.
+type queueNotifier struct {
+	pending []func(context.Context, nif.Notifier) error
+}
+
+var _ nif.Notifier = &queueNotifier{}
+
+func (q *queueNotifier) NotifyCreate(_ context.Context, d *apiv1.Directory) error {
+	q.pending = append(q.pending, func(ctx context.Context, n nif.Notifier) error {
+		return n.NotifyCreate(ctx, d)
+	})
+
+	return nil
+}
+
+func (q *queueNotifier) NotifyUpdate(_ context.Context, d *apiv1.Directory) error {
+	q.pending = append(q.pending, func(ctx context.Context, n nif.Notifier) error {
+		return n.NotifyUpdate(ctx, d)
+	})
+
+	return nil
+}
+
+func (q *queueNotifier) NotifyDelete(_ context.Context, d *apiv1.Directory) error {
+	q.pending = append(q.pending, func(ctx context.Context, n nif.Notifier) error {
+		return n.NotifyDelete(ctx, d)
+	})
+
+	return nil
+}
+
+func (q *queueNotifier) NotifyDeleteHard(_ context.Context, d *apiv1.Directory) error {
+	q.pending = append(q.pending, func(ctx context.Context, n nif.Notifier) error {
+		return n.NotifyDeleteHard(ctx, d)
+	})
+
+	return nil
+}
+
+func (q *queueNotifier) NotifyMove(_ context.Context, d *apiv1.Directory, oldParent *apiv1.DirectoryID) error {
+	q.pending = append(q.pending, func(ctx context.Context, n nif.Notifier) error {
+		return n.NotifyMove(ctx, d, oldParent)
+	})
+
+	return nil
+}
+
+func (q *queueNotifier) NotifyRestore(_ context.Context, d *apiv1.Directory) error {
+	q.pending = append(q.pending, func(ctx context.Context, n nif.Notifier) error {
+		return n.NotifyRestore(ctx, d)
+	})
+
+	return nil
+}
@@ -1,8 +1,13 @@
 package storage
 
+import "time"
+
 const (
 	// DefaultPageSize is the default count of items returned in a list when no limit is provided.
 	DefaultPageSize = 10
+
+	// DefaultMaxNodes is the default count of directories a subtree may have when no limit is provided.
+	DefaultMaxNodes = 1000
 )
 
 // Options contains all possible storage options.
@@ -15,6 +20,30 @@ type Options struct {
 
 	// PageSize sets the limit per page.
 	PageSize int
+
+	// Cursor continues the listing right after the provided position.
+	// When set, Page is ignored.
+	Cursor *Cursor
+
+	// Selector only lists the directories whose metadata matches it.
+	// It applies to root and child directory listings.
+	Selector Selector
+
+	// MaxDepth limits child directory listings to the descendants up to
+	// that many levels below the directory, 1 meaning its immediate children.
+	// When 0, all descendants are listed.
+	MaxDepth int
+
+	// MaxNodes limits the count of directories a subtree may have.
+	MaxNodes int
+
+	// AsOf reads the directories as they were at that point in time.
+	// When zero, the current directories are read.
+	AsOf time.Time
+
+	// Total requests the count of listed directories across all pages
+	// along with an API listing. Drivers ignore it.
+	Total bool
 }
 
 // GetPage returns the page if defined.
@@ -37,8 +66,23 @@ func (o *Options) GetPageSize() int {
 	return o.PageSize
 }
 
+// GetMaxNodes returns the maximum count of directories of a subtree if defined.
+// If MaxNodes is less than 1, the default of 1000 is used.
+func (o *Options) GetMaxNodes() int {
+	if o.MaxNodes < 1 {
+		return DefaultMaxNodes
+	}
+
+	return o.MaxNodes
+}
+
 // GetPageOffset returns the offset calculated by PageSize * (Page - 1).
+// If a Cursor is defined, the offset is always 0.
 func (o *Options) GetPageOffset() int {
+	if o.Cursor != nil {
+		return 0
+	}
+
 	return o.GetPageSize() * (o.GetPage() - 1)
 }
 
@@ -58,6 +102,49 @@ func Pagination(page, size int) Option {
 	}
 }
 
+// WithCursor continues the listing right after the provided cursor.
+func WithCursor(c *Cursor) Option {
+	return func(opts *Options) {
+		opts.Cursor = c
+	}
+}
+
+// WithSelector only lists the directories whose metadata matches the selector.
+func WithSelector(sel Selector) Option {
+	return func(opts *Options) {
+		opts.Selector = sel
+	}
+}
+
+// WithMaxDepth limits child directory listings to the descendants
+// up to depth levels below the directory, 1 meaning its immediate children.
+func WithMaxDepth(depth int) Option {
+	return func(opts *Options) {
+		opts.MaxDepth = depth
+	}
+}
+
+// WithMaxNodes limits the count of directories a subtree may have.
+func WithMaxNodes(n int) Option {
+	return func(opts *Options) {
+		opts.MaxNodes = n
+	}
+}
+
+// WithAsOf reads the directories as they were at the provided point in time.
+// Drivers may not support it within transactions.
+func WithAsOf(t time.Time) Option {
+	return func(opts *Options) {
+		opts.AsOf = t
+	}
+}
+
+// WithTotal requests the count of listed directories across all pages
+// along with an API listing.
+var WithTotal Option = func(opts *Options) {
+	opts.Total = true
+}
+
 // BuildOptions applies all options to a new Options instance.
 func BuildOptions(opts []Option) *Options {
 	options := new(Options)
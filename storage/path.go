@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+)
+
+// AmbiguousPathError returns the error telling several directories are at the path.
+func AmbiguousPathError(path []string) error {
+	return fmt.Errorf("%w: %s", ErrAmbiguousPath, v1.FormatPath(path))
+}
+
+// PathOf returns the path of the directory, i.e. the names of its root,
+// its parents and its own, which it is resolved with by Reader.ResolvePath.
+// Only the WithDeletedDirectories and AsOf options are respected.
+func PathOf(ctx context.Context, r Reader, d *v1.Directory, options ...Option) ([]string, error) {
+	if d.IsRoot() {
+		return []string{d.Name}, nil
+	}
+
+	opts := BuildOptions(options)
+	read := []Option{WithAsOf(opts.AsOf)}
+
+	if opts.WithDeletedDirectories {
+		read = append(read, WithDeletedDirectories)
+	}
+
+	parents, err := r.GetParents(ctx, d.Id, append(read, Pagination(1, math.MaxInt32))...)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := r.GetDirectories(ctx, parents, read...)
+	if err != nil {
+		return nil, err
+	}
+
+	// A parent may have been purged since.
+	if len(dirs) != len(parents) {
+		return nil, ErrDirectoryNotFound
+	}
+
+	// Parents are ordered from the closest, while paths start from the root.
+	names := make([]string, len(dirs)+1)
+
+	for i, p := range dirs {
+		names[len(dirs)-1-i] = p.Name
+	}
+
+	names[len(dirs)] = d.Name
+
+	return names, nil
+}
@@ -0,0 +1,20 @@
+package driver_test
+
+import (
+	"testing"
+
+	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/postgres/driver"
+	"github.com/infratographer/fertilesoil/storage/postgres/utils"
+	"github.com/infratographer/fertilesoil/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	utils.RequireTestDBServer(t, serverErr)
+
+	storagetest.RunConformance(t, func(t *testing.T) storage.DirectoryAdmin {
+		return driver.NewDirectoryDriver(utils.GetNewTestDB(t, baseDBURL))
+	})
+}
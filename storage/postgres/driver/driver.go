@@ -0,0 +1,15 @@
+// Package driver implements the storage interface on plain PostgreSQL.
+package driver
+
+import (
+	"database/sql"
+
+	"github.com/infratographer/fertilesoil/storage/sqldriver"
+)
+
+// Driver is the PostgreSQL driver.
+type Driver = sqldriver.Driver
+
+func NewDirectoryDriver(db *sql.DB, opts ...Options) *Driver {
+	return sqldriver.New(db, sqldriver.PostgresDialect{}, opts...)
+}
@@ -0,0 +1,26 @@
+package driver_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/infratographer/fertilesoil/storage/postgres/utils"
+)
+
+var (
+	baseDBURL *url.URL
+	// serverErr is the error returned when starting the test server, if any.
+	// Tests check it with utils.RequireTestDBServer, so they're reported as skipped.
+	serverErr error
+)
+
+func TestMain(m *testing.M) {
+	var stop utils.StopServerFunc
+
+	baseDBURL, stop, serverErr = utils.NewTestDBServer()
+	if serverErr == nil {
+		defer stop()
+	}
+
+	m.Run()
+}
@@ -0,0 +1,62 @@
+-- This contains the database model for the directory tree on PostgreSQL.
+-- It matches the CockroachDB model as of its latest migration.
+
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS directories (
+    id UUID NOT NULL DEFAULT gen_random_uuid() PRIMARY KEY,
+    name TEXT NOT NULL,
+    metadata JSONB NOT NULL,
+    parent_id UUID REFERENCES directories(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ,
+    revision INT8 NOT NULL DEFAULT 1,
+    unique_sibling_names BOOL NOT NULL DEFAULT false,
+    CONSTRAINT parent_child_not_equal CHECK (id != parent_id)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS directories_parent_id ON directories (parent_id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE UNIQUE INDEX IF NOT EXISTS directories_unique_sibling_names ON directories (parent_id, name)
+    WHERE deleted_at IS NULL AND unique_sibling_names;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS directories_metadata ON directories USING GIN (metadata);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS directory_revisions (
+    id UUID NOT NULL DEFAULT gen_random_uuid() PRIMARY KEY,
+    directory_id UUID NOT NULL REFERENCES directories(id) ON DELETE CASCADE,
+    revision INT8 NOT NULL,
+    operation TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    before JSONB,
+    after JSONB NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS directory_revisions_directory_id_revision
+    ON directory_revisions (directory_id, revision DESC);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS directory_revisions_created_at ON directory_revisions (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS directory_revisions;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS directories;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- This holds the quota overrides of root directories, which apply to their
+-- whole tree. Limits left NULL fall back to the defaults of the server.
+-- The overrides are removed along with the root when it is purged.
+
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS root_quotas (
+    root_id UUID NOT NULL PRIMARY KEY REFERENCES directories(id) ON DELETE CASCADE,
+    max_depth INT8 CHECK (max_depth >= 0),
+    max_children INT8 CHECK (max_children >= 0),
+    max_descendants INT8 CHECK (max_descendants >= 0)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS root_quotas;
+-- +goose StatementEnd
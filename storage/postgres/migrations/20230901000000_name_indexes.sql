@@ -0,0 +1,13 @@
+-- This indexes directory names, so directories can be efficiently searched
+-- by exact or prefix matches on their name. Substring matches would need the
+-- pg_trgm extension, which isn't always available, so they aren't indexed.
+
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS directories_name ON directories (name text_pattern_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS directories_name;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+package migrations_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infratographer/fertilesoil/storage/postgres/migrations"
+	"github.com/infratographer/fertilesoil/storage/postgres/utils"
+)
+
+func TestMigrations(t *testing.T) {
+	t.Parallel()
+
+	dbURL, stop, err := utils.NewTestDBServer()
+	utils.RequireTestDBServer(t, err)
+
+	defer stop()
+
+	dbdialect := "postgres"
+	dbConn, dbopenerr := sql.Open(dbdialect, dbURL.String())
+	assert.NoError(t, dbopenerr, "failed to open db connection")
+
+	assert.NoError(t, migrations.Migrate(dbConn), "failed to run migrations")
+
+	goose.SetBaseFS(migrations.Migrations)
+	assert.NoError(t, goose.SetDialect(dbdialect), "failed to set dialect")
+	assert.NoError(t, goose.Down(dbConn, "."), "failed to roll back migrations")
+}
@@ -0,0 +1,188 @@
+//go:build testtools
+// +build testtools
+
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/infratographer/fertilesoil/storage/postgres/migrations"
+)
+
+const (
+	// TestDBURLEnv is the environment variable holding the URL of a PostgreSQL
+	// server to run the tests against, e.g. one running in a container.
+	TestDBURLEnv = "FERTILESOIL_POSTGRES_TEST_URL"
+
+	// storageDriverEnv selects the storage driver the tests run against.
+	// When it is set to "postgres", the tests fail without a server.
+	storageDriverEnv = "FERTILESOIL_TEST_STORAGE_DRIVER"
+)
+
+// ErrNoTestDBServer is returned when there's no PostgreSQL server to test against.
+var ErrNoTestDBServer = errors.New("no postgres test server: set " + TestDBURLEnv +
+	" or install the postgres binaries (initdb and pg_ctl)")
+
+type StopServerFunc func()
+
+var gooseMutex sync.Mutex
+
+// NewTestDBServer returns the URL of the server set in TestDBURLEnv if any.
+// Otherwise, a throwaway server is started from the local postgres binaries.
+func NewTestDBServer() (*url.URL, StopServerFunc, error) {
+	if dbURL := os.Getenv(TestDBURLEnv); dbURL != "" {
+		u, err := url.Parse(dbURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %w", TestDBURLEnv, err)
+		}
+
+		return u, func() {}, nil
+	}
+
+	initdb, err := exec.LookPath("initdb")
+	if err != nil {
+		return nil, nil, ErrNoTestDBServer
+	}
+
+	pgctl, err := exec.LookPath("pg_ctl")
+	if err != nil {
+		return nil, nil, ErrNoTestDBServer
+	}
+
+	dir, err := os.MkdirTemp("", "fertilesoil-postgres-")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dataDir := filepath.Join(dir, "data")
+
+	//nolint:gosec // The binaries are looked up in the PATH on purpose.
+	if out, err := exec.Command(initdb, "-D", dataDir, "-U", "postgres", "--auth=trust").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, fmt.Errorf("error initializing database: %w: %s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+
+	opts := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1", port, dir)
+
+	//nolint:gosec // The binaries are looked up in the PATH on purpose.
+	if out, err := exec.Command(pgctl, "-D", dataDir, "-o", opts, "-w", "start").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, fmt.Errorf("error starting database: %w: %s", err, out)
+	}
+
+	dbURL := &url.URL{
+		Scheme:   "postgresql",
+		User:     url.User("postgres"),
+		Host:     net.JoinHostPort("127.0.0.1", strconv.Itoa(port)),
+		Path:     "/",
+		RawQuery: "sslmode=disable",
+	}
+
+	return dbURL, func() {
+		//nolint:errcheck,gosec // The server is thrown away anyway.
+		exec.Command(pgctl, "-D", dataDir, "-m", "immediate", "stop").Run()
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// RequireTestDBServer checks the error returned by NewTestDBServer.
+// The test is skipped if there's no server to test against, unless the
+// tests were asked to run against PostgreSQL, in which case it fails.
+func RequireTestDBServer(t *testing.T, err error) {
+	t.Helper()
+
+	if err == nil {
+		return
+	}
+
+	if errors.Is(err, ErrNoTestDBServer) && os.Getenv(storageDriverEnv) != "postgres" {
+		t.Skip(err)
+	}
+
+	t.Fatalf("error creating test database server: %v", err)
+}
+
+// freePort returns a TCP port which is free to listen on.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Returns a new test database for an application.
+// The database is not migrated.
+func GetNewTestDBForApp(t *testing.T, baseDBURL *url.URL) *sql.DB {
+	t.Helper()
+
+	return getNewTestDB(t, baseDBURL, "app")
+}
+
+// Returns a new test database for the tree manager
+// with the migrations applied.
+func GetNewTestDB(t *testing.T, baseDBURL *url.URL) *sql.DB {
+	t.Helper()
+
+	gooseMutex.Lock()
+	defer gooseMutex.Unlock()
+
+	dbConn := getNewTestDB(t, baseDBURL, "")
+
+	goose.SetBaseFS(migrations.Migrations)
+
+	if err := goose.SetDialect("postgres"); err != nil {
+		t.Fatalf("error setting dialect: %v", err)
+	}
+
+	if err := goose.Up(dbConn, "."); err != nil {
+		t.Fatalf("error running migrations: %v", err)
+	}
+	return dbConn
+}
+
+func getNewTestDB(t *testing.T, baseDBURL *url.URL, suffix string) *sql.DB {
+	t.Helper()
+
+	dbName := strings.ToLower(strings.ReplaceAll(t.Name(), "/", "_"))
+	dbName += suffix
+
+	baseDB, err := sql.Open("postgres", baseDBURL.String())
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+	defer baseDB.Close()
+
+	if _, err := baseDB.Exec("CREATE DATABASE " + dbName); err != nil {
+		t.Fatalf("error creating database: %v", err)
+	}
+
+	dbURL := baseDBURL.JoinPath(dbName)
+	dbConn, err := sql.Open("postgres", dbURL.String())
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+
+	return dbConn
+}
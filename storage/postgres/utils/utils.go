@@ -0,0 +1,89 @@
+// Package utils provides helpers to set up the PostgreSQL storage driver.
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	_ "github.com/lib/pq" // Register the Postgres driver.
+	"github.com/spf13/viper"
+
+	"github.com/infratographer/fertilesoil/storage/postgres/driver"
+)
+
+const (
+	defaultMaxOpenConns    = 25
+	defaultMaxIdleConns    = 25
+	defaultMaxConnLifetime = 5 * time.Minute
+)
+
+// MustViperEnv binds the settings of the database connection to the
+// environment, e.g. FERTILESOIL_POSTGRES_URI for postgres.uri.
+func MustViperEnv(v *viper.Viper) {
+	v.MustBindEnv("postgres.host")
+	v.MustBindEnv("postgres.params")
+	v.MustBindEnv("postgres.user")
+	v.MustBindEnv("postgres.password")
+	v.MustBindEnv("postgres.uri")
+	v.MustBindEnv("postgres.connections.max_open")
+	v.MustBindEnv("postgres.connections.max_idle")
+	v.MustBindEnv("postgres.connections.max_lifetime")
+
+	v.SetDefault("postgres.host", "localhost:5432")
+	v.SetDefault("postgres.connections.max_open", defaultMaxOpenConns)
+	v.SetDefault("postgres.connections.max_idle", defaultMaxIdleConns)
+	v.SetDefault("postgres.connections.max_lifetime", defaultMaxConnLifetime)
+}
+
+// GetURI returns the connection URI of the database. The configured URI
+// is returned if any, otherwise it is built from the other settings.
+func GetURI(v *viper.Viper, dbName string) string {
+	if uri := v.GetString("postgres.uri"); uri != "" {
+		return uri
+	}
+
+	u := url.URL{
+		Scheme:   "postgresql",
+		User:     url.UserPassword(v.GetString("postgres.user"), v.GetString("postgres.password")),
+		Host:     v.GetString("postgres.host"),
+		Path:     dbName,
+		RawQuery: v.GetString("postgres.params"),
+	}
+
+	return u.String()
+}
+
+// GetDBConnection opens a connection to the database and verifies it.
+func GetDBConnection(v *viper.Viper, dbName string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", GetURI(v, dbName))
+	if err != nil {
+		return nil, fmt.Errorf("failed connecting to database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed verifying database connection: %w", err)
+	}
+
+	db.SetMaxOpenConns(v.GetInt("postgres.connections.max_open"))
+	db.SetMaxIdleConns(v.GetInt("postgres.connections.max_idle"))
+	db.SetConnMaxLifetime(v.GetDuration("postgres.connections.max_lifetime"))
+
+	return db, nil
+}
+
+// WithStorageOptions returns the storage options for the driver.
+// Fast reads rely on CockroachDB follower reads, so they aren't supported.
+func WithStorageOptions(v *viper.Viper) []driver.Options {
+	var opts []driver.Options
+	if v.GetBool("storage.read_only") {
+		opts = append(opts, driver.WithReadOnly())
+	}
+
+	if v.GetBool("storage.unique_sibling_names") {
+		opts = append(opts, driver.WithUniqueSiblingNames())
+	}
+
+	return opts
+}
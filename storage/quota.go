@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"fmt"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+)
+
+// Quota limits which may be exceeded, as named in the API.
+const (
+	// QuotaMaxDepth limits the levels of directories below a root.
+	QuotaMaxDepth = "maxDepth"
+	// QuotaMaxChildren limits the direct children of each directory of a tree.
+	QuotaMaxChildren = "maxChildren"
+	// QuotaMaxDescendants limits the directories below a root.
+	QuotaMaxDescendants = "maxDescendants"
+)
+
+// QuotaExceededError is returned when a write would take a tree past one of its quotas.
+// It wraps ErrQuotaExceeded.
+type QuotaExceededError struct {
+	// Root is the root directory of the tree.
+	Root v1.DirectoryID
+	// Quota is the exceeded limit, e.g. QuotaMaxDepth.
+	Quota string
+	// Limit is the value of the exceeded limit.
+	Limit int
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%v: %s of %d reached in tree %s", ErrQuotaExceeded, e.Quota, e.Limit, e.Root)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
@@ -0,0 +1,359 @@
+// Package quota provides a storage driver which enforces quotas on the
+// directory trees of another storage driver.
+package quota
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	apiv1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// all lists every directory requested, as large as a page may be.
+var all = storage.Pagination(1, math.MaxInt32)
+
+// Limits are the quotas of a directory tree. A limit of 0 means there's none.
+type Limits struct {
+	// MaxDepth limits the levels of directories below the root.
+	MaxDepth int
+	// MaxChildren limits the direct children of each directory of the tree.
+	MaxChildren int
+	// MaxDescendants limits the directories below the root.
+	MaxDescendants int
+}
+
+// Override returns the limits with the ones set in the quota overriding them.
+func (l Limits) Override(q *apiv1.DirectoryQuota) Limits {
+	if q == nil {
+		return l
+	}
+
+	if q.MaxDepth != nil {
+		l.MaxDepth = *q.MaxDepth
+	}
+
+	if q.MaxChildren != nil {
+		l.MaxChildren = *q.MaxChildren
+	}
+
+	if q.MaxDescendants != nil {
+		l.MaxDescendants = *q.MaxDescendants
+	}
+
+	return l
+}
+
+// Quota returns the limits as a quota where every limit is set.
+func (l Limits) Quota() apiv1.DirectoryQuota {
+	return apiv1.DirectoryQuota{
+		MaxDepth:       &l.MaxDepth,
+		MaxChildren:    &l.MaxChildren,
+		MaxDescendants: &l.MaxDescendants,
+	}
+}
+
+type Option func(*Storage)
+
+// WithLimits sets the default limits of the trees, which apply unless
+// overridden by the quota of their root.
+func WithLimits(l Limits) Option {
+	return func(s *Storage) {
+		s.limits = l
+	}
+}
+
+// Storage is a meta-storage driver that wraps a storage.DirectoryAdmin and
+// refuses the writes which would take a tree past its limits with a
+// *storage.QuotaExceededError. Directories are created and moved within
+// a transaction of the wrapped storage, along with the checks, which lock
+// the root of the tree and the parent so concurrent writes wait for them.
+//
+// Restoring directories isn't checked, as they were already counted
+// once, nor are roots, which start new trees.
+type Storage struct {
+	storage.DirectoryAdmin
+	limits Limits
+	// inTx is set within a transaction, where the checks run directly.
+	inTx bool
+}
+
+// ensure Storage implements storage.DirectoryAdmin.
+var _ storage.DirectoryAdmin = &Storage{}
+
+// StorageWithQuotas wraps the provided storage, enforcing quotas on its trees.
+func StorageWithQuotas(s storage.DirectoryAdmin, opts ...Option) *Storage {
+	qs := &Storage{
+		DirectoryAdmin: s,
+	}
+
+	for _, opt := range opts {
+		opt(qs)
+	}
+
+	return qs
+}
+
+// Limits returns the limits in effect in the tree of the provided root,
+// i.e. the default ones overridden by the quota of the root.
+func (s *Storage) Limits(ctx context.Context, root apiv1.DirectoryID) (Limits, error) {
+	q, err := s.GetQuota(ctx, root)
+	if err != nil {
+		return Limits{}, err
+	}
+
+	return s.limits.Override(q), nil
+}
+
+// WithTx runs fn within a transaction of the wrapped storage,
+// where the quotas are enforced as well.
+func (s *Storage) WithTx(ctx context.Context, fn func(storage.DirectoryAdmin) error) error {
+	return s.DirectoryAdmin.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		return fn(&Storage{DirectoryAdmin: tx, limits: s.limits, inTx: true})
+	})
+}
+
+// withinTx runs fn within the transaction of the storage,
+// or within a new transaction otherwise.
+func (s *Storage) withinTx(ctx context.Context, fn func(*Storage) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	return s.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		//nolint:forcetypeassert // WithTx always passes a *Storage.
+		return fn(tx.(*Storage))
+	})
+}
+
+func (s *Storage) CreateDirectory(ctx context.Context, d *apiv1.Directory) (*apiv1.Directory, error) {
+	if d.Parent == nil {
+		return s.DirectoryAdmin.CreateDirectory(ctx, d)
+	}
+
+	var created *apiv1.Directory
+
+	err := s.withinTx(ctx, func(tx *Storage) error {
+		if err := tx.checkAdd(ctx, *d.Parent, &subtree{height: 1, size: 1}); err != nil {
+			return err
+		}
+
+		var err error
+
+		created, err = tx.DirectoryAdmin.CreateDirectory(ctx, d)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (s *Storage) MoveDirectory(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	return s.moveDirectory(ctx, id, parent, func(tx *Storage) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+		return tx.DirectoryAdmin.MoveDirectory(ctx, id, parent)
+	})
+}
+
+func (s *Storage) MoveDirectoryIfRevision(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+	revision int64,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	return s.moveDirectory(ctx, id, parent, func(tx *Storage) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+		return tx.DirectoryAdmin.MoveDirectoryIfRevision(ctx, id, parent, revision)
+	})
+}
+
+func (s *Storage) DemoteRoot(ctx context.Context, id, parent apiv1.DirectoryID) (*apiv1.Directory, error) {
+	moved, _, err := s.moveDirectory(ctx, id, parent, func(tx *Storage) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+		demoted, err := tx.DirectoryAdmin.DemoteRoot(ctx, id, parent)
+
+		return demoted, nil, err
+	})
+
+	return moved, err
+}
+
+func (s *Storage) DemoteRootIfRevision(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+	revision int64,
+) (*apiv1.Directory, error) {
+	moved, _, err := s.moveDirectory(ctx, id, parent, func(tx *Storage) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+		demoted, err := tx.DirectoryAdmin.DemoteRootIfRevision(ctx, id, parent, revision)
+
+		return demoted, nil, err
+	})
+
+	return moved, err
+}
+
+// moveDirectory checks the quotas of the destination tree before moving
+// the directory under the parent with move, within a single transaction.
+func (s *Storage) moveDirectory(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+	move func(tx *Storage) (*apiv1.Directory, *apiv1.DirectoryID, error),
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	var (
+		moved     *apiv1.Directory
+		oldParent *apiv1.DirectoryID
+	)
+
+	err := s.withinTx(ctx, func(tx *Storage) error {
+		if err := tx.checkMove(ctx, id, parent); err != nil {
+			return err
+		}
+
+		var err error
+
+		moved, oldParent, err = move(tx)
+
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return moved, oldParent, nil
+}
+
+// subtree describes the directories added to a tree.
+type subtree struct {
+	// height is the count of levels the directories span.
+	height int
+	// size is the count of directories.
+	size int
+	// root is the root of the tree the directories come from, if any.
+	root *apiv1.DirectoryID
+}
+
+// checkMove checks that the provided directory, along with its descendants,
+// may be moved under the parent.
+// Directories which aren't found are left to the wrapped storage to refuse.
+func (s *Storage) checkMove(ctx context.Context, id, parent apiv1.DirectoryID) error {
+	dir, err := s.GetDirectory(ctx, id)
+	if errors.Is(err, storage.ErrDirectoryNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if dir.Parent != nil && *dir.Parent == parent {
+		// The directory stays where it is.
+		return nil
+	}
+
+	parents, err := s.GetParents(ctx, id, all)
+	if err != nil {
+		return err
+	}
+
+	root := id
+	if len(parents) > 0 {
+		root = parents[len(parents)-1]
+	}
+
+	tree, err := s.GetSubtree(ctx, id, storage.WithMaxNodes(math.MaxInt32))
+	if err != nil {
+		return err
+	}
+
+	added := &subtree{root: &root}
+	added.height, added.size = measure(tree)
+
+	return s.checkAdd(ctx, parent, added)
+}
+
+// measure returns the count of levels and of directories of the tree.
+func measure(tree *apiv1.DirectoryTree) (height, size int) {
+	size = 1
+
+	for i := range tree.Children {
+		h, n := measure(&tree.Children[i])
+		size += n
+
+		if h > height {
+			height = h
+		}
+	}
+
+	return height + 1, size
+}
+
+// checkAdd checks that the directories may be added under the parent.
+// Parents which aren't found are left to the wrapped storage to refuse.
+func (s *Storage) checkAdd(ctx context.Context, parent apiv1.DirectoryID, added *subtree) error {
+	parents, err := s.GetParents(ctx, parent, all)
+	if errors.Is(err, storage.ErrDirectoryNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	root := parent
+	if len(parents) > 0 {
+		root = parents[len(parents)-1]
+	}
+
+	// Concurrent additions to the tree wait for the counts below to be
+	// checked and the directories added, so they can't both fit in the
+	// last spot left. The root is always locked first, so they can't deadlock.
+	if err := s.LockDirectories(ctx, root, parent); err != nil {
+		return err
+	}
+
+	limits, err := s.Limits(ctx, root)
+	if err != nil {
+		return err
+	}
+
+	// The parent is as deep as it has parents, the root being at depth 0.
+	if limits.MaxDepth > 0 && len(parents)+added.height > limits.MaxDepth {
+		return exceeded(root, storage.QuotaMaxDepth, limits.MaxDepth)
+	}
+
+	if limits.MaxChildren > 0 {
+		children, err := s.GetChildren(ctx, parent, storage.WithMaxDepth(1), storage.Pagination(1, limits.MaxChildren))
+		if err != nil {
+			return err
+		}
+
+		if len(children) >= limits.MaxChildren {
+			return exceeded(root, storage.QuotaMaxChildren, limits.MaxChildren)
+		}
+	}
+
+	// Directories moved within their tree don't change its count of descendants.
+	if limits.MaxDescendants > 0 && (added.root == nil || *added.root != root) {
+		descendants, err := s.GetChildren(ctx, root, storage.Pagination(1, limits.MaxDescendants))
+		if err != nil {
+			return err
+		}
+
+		if len(descendants)+added.size > limits.MaxDescendants {
+			return exceeded(root, storage.QuotaMaxDescendants, limits.MaxDescendants)
+		}
+	}
+
+	return nil
+}
+
+func exceeded(root apiv1.DirectoryID, quota string, limit int) error {
+	return &storage.QuotaExceededError{
+		Root:  root,
+		Quota: quota,
+		Limit: limit,
+	}
+}
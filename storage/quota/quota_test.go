@@ -0,0 +1,185 @@
+package quota_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/memory"
+	"github.com/infratographer/fertilesoil/storage/quota"
+	"github.com/infratographer/fertilesoil/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RunConformance(t, func(t *testing.T) storage.DirectoryAdmin {
+		return quota.StorageWithQuotas(memory.NewDirectoryDriver())
+	})
+}
+
+func createRoot(t *testing.T, store storage.DirectoryAdmin) *v1.Directory {
+	t.Helper()
+
+	d, err := store.CreateRoot(context.Background(), &v1.Directory{Name: "root"})
+	if !assert.NoError(t, err, "error creating root directory") {
+		t.FailNow()
+	}
+
+	return d
+}
+
+func createChild(t *testing.T, store storage.DirectoryAdmin, parent *v1.Directory) *v1.Directory {
+	t.Helper()
+
+	d, err := store.CreateDirectory(context.Background(), &v1.Directory{Name: "dir", Parent: &parent.Id})
+	if !assert.NoError(t, err, "error creating directory") {
+		t.FailNow()
+	}
+
+	return d
+}
+
+// assertExceeded asserts that err tells the quota of the root was exceeded.
+func assertExceeded(t *testing.T, err error, root v1.DirectoryID, name string, limit int) {
+	t.Helper()
+
+	assert.ErrorIs(t, err, storage.ErrQuotaExceeded, "quota should be exceeded")
+	assert.Equal(t, &storage.QuotaExceededError{Root: root, Quota: name, Limit: limit}, err,
+		"error should tell the exceeded quota")
+}
+
+func TestMaxDepth(t *testing.T) {
+	t.Parallel()
+
+	store := quota.StorageWithQuotas(memory.NewDirectoryDriver(), quota.WithLimits(quota.Limits{MaxDepth: 2}))
+	root := createRoot(t, store)
+	child := createChild(t, store, root)
+	grandchild := createChild(t, store, child)
+
+	_, err := store.CreateDirectory(context.Background(), &v1.Directory{Name: "dir", Parent: &grandchild.Id})
+	assertExceeded(t, err, root.Id, storage.QuotaMaxDepth, 2)
+
+	// The directory and its child would be one level too deep.
+	other := createChild(t, store, root)
+
+	_, _, err = store.MoveDirectory(context.Background(), child.Id, other.Id)
+	assertExceeded(t, err, root.Id, storage.QuotaMaxDepth, 2)
+
+	_, _, err = store.MoveDirectory(context.Background(), grandchild.Id, other.Id)
+	assert.NoError(t, err, "error moving directory")
+}
+
+func TestMaxChildren(t *testing.T) {
+	t.Parallel()
+
+	store := quota.StorageWithQuotas(memory.NewDirectoryDriver(), quota.WithLimits(quota.Limits{MaxChildren: 2}))
+	root := createRoot(t, store)
+	first := createChild(t, store, root)
+	createChild(t, store, root)
+
+	_, err := store.CreateDirectory(context.Background(), &v1.Directory{Name: "dir", Parent: &root.Id})
+	assertExceeded(t, err, root.Id, storage.QuotaMaxChildren, 2)
+
+	// Directories may be moved under their own parent.
+	_, _, err = store.MoveDirectory(context.Background(), first.Id, root.Id)
+	assert.NoError(t, err, "error moving directory under its parent")
+
+	other := createRoot(t, store)
+	moved := createChild(t, store, other)
+
+	_, _, err = store.MoveDirectory(context.Background(), moved.Id, root.Id)
+	assertExceeded(t, err, root.Id, storage.QuotaMaxChildren, 2)
+
+	_, err = store.DemoteRoot(context.Background(), other.Id, root.Id)
+	assertExceeded(t, err, root.Id, storage.QuotaMaxChildren, 2)
+}
+
+func TestMaxDescendants(t *testing.T) {
+	t.Parallel()
+
+	store := quota.StorageWithQuotas(memory.NewDirectoryDriver(), quota.WithLimits(quota.Limits{MaxDescendants: 3}))
+	root := createRoot(t, store)
+	child := createChild(t, store, root)
+	createChild(t, store, child)
+
+	other := createRoot(t, store)
+	moved := createChild(t, store, other)
+	createChild(t, store, moved)
+
+	// The directory and its child don't fit in the tree.
+	_, _, err := store.MoveDirectory(context.Background(), moved.Id, root.Id)
+	assertExceeded(t, err, root.Id, storage.QuotaMaxDescendants, 3)
+
+	last := createChild(t, store, root)
+
+	_, err = store.CreateDirectory(context.Background(), &v1.Directory{Name: "dir", Parent: &root.Id})
+	assertExceeded(t, err, root.Id, storage.QuotaMaxDescendants, 3)
+
+	// Moves within the tree don't add descendants.
+	_, _, err = store.MoveDirectory(context.Background(), last.Id, child.Id)
+	assert.NoError(t, err, "error moving directory within its tree")
+
+	// Deleted directories don't count.
+	_, err = store.DeleteDirectory(context.Background(), last.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	createChild(t, store, root)
+}
+
+func TestQuotaOverrides(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := quota.StorageWithQuotas(memory.NewDirectoryDriver(), quota.WithLimits(quota.Limits{MaxChildren: 1}))
+	root := createRoot(t, store)
+	other := createRoot(t, store)
+
+	unlimited, depth := 0, 1
+
+	err := store.SetQuota(ctx, root.Id, &v1.DirectoryQuota{MaxChildren: &unlimited, MaxDepth: &depth})
+	assert.NoError(t, err, "error setting quota")
+
+	limits, err := store.Limits(ctx, root.Id)
+	assert.NoError(t, err, "error getting limits")
+	assert.Equal(t, quota.Limits{MaxDepth: 1}, limits, "limits should be overridden")
+
+	child := createChild(t, store, root)
+	createChild(t, store, root)
+
+	_, err = store.CreateDirectory(ctx, &v1.Directory{Name: "dir", Parent: &child.Id})
+	assertExceeded(t, err, root.Id, storage.QuotaMaxDepth, 1)
+
+	// The defaults still apply to the other trees.
+	createChild(t, store, other)
+
+	_, err = store.CreateDirectory(ctx, &v1.Directory{Name: "dir", Parent: &other.Id})
+	assertExceeded(t, err, other.Id, storage.QuotaMaxChildren, 1)
+}
+
+func TestQuotaWithTx(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := quota.StorageWithQuotas(memory.NewDirectoryDriver(), quota.WithLimits(quota.Limits{MaxChildren: 1}))
+	root := createRoot(t, store)
+
+	err := store.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		if _, err := tx.CreateDirectory(ctx, &v1.Directory{Name: "dir", Parent: &root.Id}); err != nil {
+			return err
+		}
+
+		// The directory created within the transaction counts.
+		_, err := tx.CreateDirectory(ctx, &v1.Directory{Name: "dir", Parent: &root.Id})
+
+		return err
+	})
+	assertExceeded(t, err, root.Id, storage.QuotaMaxChildren, 1)
+
+	children, err := store.GetChildren(ctx, root.Id)
+	assert.NoError(t, err, "error getting children")
+	assert.Empty(t, children, "transaction should be rolled back")
+}
@@ -0,0 +1,298 @@
+// Package router provides a storage driver which sends reads to a replica
+// and writes to a primary storage driver.
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	apiv1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+type sessionContextKey struct{}
+
+// session tracks whether a write was made within it.
+type session struct {
+	wrote atomic.Bool
+}
+
+// WithSession returns a copy of the context starting a session, e.g. for the
+// lifetime of a request. Once a write is made within the session, its reads
+// go to the primary, so they see the write.
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, &session{})
+}
+
+// WithPrimary returns a copy of the context starting a session whose reads all
+// go to the primary, e.g. for a request reading the directories it then writes.
+func WithPrimary(ctx context.Context) context.Context {
+	s := &session{}
+	s.wrote.Store(true)
+
+	return context.WithValue(ctx, sessionContextKey{}, s)
+}
+
+func sessionFromContext(ctx context.Context) *session {
+	s, _ := ctx.Value(sessionContextKey{}).(*session)
+
+	return s
+}
+
+// Storage is a meta-storage driver that sends reads to a replica driver,
+// e.g. one doing fast reads, and writes to a primary driver.
+// Reads made after a write within the same session go to the primary,
+// see WithSession. Transactions always run on the primary.
+type Storage struct {
+	primary storage.DirectoryAdmin
+	replica storage.DirectoryAdmin
+}
+
+// ensure Storage implements storage.DirectoryAdmin.
+var _ storage.DirectoryAdmin = &Storage{}
+
+// StorageWithReplica returns a storage driver which sends writes to the primary
+// and reads to the replica.
+func StorageWithReplica(primary, replica storage.DirectoryAdmin) *Storage {
+	return &Storage{
+		primary: primary,
+		replica: replica,
+	}
+}
+
+// reader returns the driver reads made with the context go to.
+func (s *Storage) reader(ctx context.Context) storage.DirectoryAdmin {
+	if sess := sessionFromContext(ctx); sess != nil && sess.wrote.Load() {
+		return s.primary
+	}
+
+	return s.replica
+}
+
+// writer returns the primary, recording the write in the session of the context.
+// Writes are recorded before they're made, as even failed ones may have been applied.
+func (s *Storage) writer(ctx context.Context) storage.DirectoryAdmin {
+	if sess := sessionFromContext(ctx); sess != nil {
+		sess.wrote.Store(true)
+	}
+
+	return s.primary
+}
+
+func (s *Storage) GetDirectory(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	options ...storage.Option,
+) (*apiv1.Directory, error) {
+	return s.reader(ctx).GetDirectory(ctx, id, options...)
+}
+
+func (s *Storage) GetDirectories(
+	ctx context.Context,
+	ids []apiv1.DirectoryID,
+	options ...storage.Option,
+) ([]*apiv1.Directory, error) {
+	return s.reader(ctx).GetDirectories(ctx, ids, options...)
+}
+
+func (s *Storage) GetParents(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	options ...storage.Option,
+) ([]apiv1.DirectoryID, error) {
+	return s.reader(ctx).GetParents(ctx, id, options...)
+}
+
+func (s *Storage) GetParentsUntilAncestor(
+	ctx context.Context,
+	child, ancestor apiv1.DirectoryID,
+	options ...storage.Option,
+) ([]apiv1.DirectoryID, error) {
+	return s.reader(ctx).GetParentsUntilAncestor(ctx, child, ancestor, options...)
+}
+
+func (s *Storage) GetChildren(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	options ...storage.Option,
+) ([]apiv1.DirectoryID, error) {
+	return s.reader(ctx).GetChildren(ctx, id, options...)
+}
+
+func (s *Storage) GetSubtree(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	options ...storage.Option,
+) (*apiv1.DirectoryTree, error) {
+	return s.reader(ctx).GetSubtree(ctx, id, options...)
+}
+
+func (s *Storage) CountChildren(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	options ...storage.Option,
+) (int, error) {
+	return s.reader(ctx).CountChildren(ctx, id, options...)
+}
+
+func (s *Storage) GetStats(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	options ...storage.Option,
+) (*apiv1.DirectoryStats, error) {
+	return s.reader(ctx).GetStats(ctx, id, options...)
+}
+
+func (s *Storage) SearchDirectories(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	q storage.NameQuery,
+	options ...storage.Option,
+) ([]*apiv1.DirectorySearchResult, error) {
+	return s.reader(ctx).SearchDirectories(ctx, id, q, options...)
+}
+
+func (s *Storage) ResolvePath(
+	ctx context.Context,
+	path []string,
+	options ...storage.Option,
+) (*apiv1.Directory, error) {
+	return s.reader(ctx).ResolvePath(ctx, path, options...)
+}
+
+func (s *Storage) GetHistory(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	options ...storage.Option,
+) ([]*apiv1.DirectoryRevision, error) {
+	return s.reader(ctx).GetHistory(ctx, id, options...)
+}
+
+func (s *Storage) ListRoots(ctx context.Context, options ...storage.Option) ([]apiv1.DirectoryID, error) {
+	return s.reader(ctx).ListRoots(ctx, options...)
+}
+
+func (s *Storage) CountRoots(ctx context.Context, options ...storage.Option) (int, error) {
+	return s.reader(ctx).CountRoots(ctx, options...)
+}
+
+func (s *Storage) CreateDirectory(ctx context.Context, d *apiv1.Directory) (*apiv1.Directory, error) {
+	return s.writer(ctx).CreateDirectory(ctx, d)
+}
+
+func (s *Storage) UpdateDirectory(ctx context.Context, d *apiv1.Directory) error {
+	return s.writer(ctx).UpdateDirectory(ctx, d)
+}
+
+func (s *Storage) UpdateDirectoryIfRevision(ctx context.Context, d *apiv1.Directory, revision int64) error {
+	return s.writer(ctx).UpdateDirectoryIfRevision(ctx, d, revision)
+}
+
+func (s *Storage) DeleteDirectory(ctx context.Context, id apiv1.DirectoryID) ([]*apiv1.Directory, error) {
+	return s.writer(ctx).DeleteDirectory(ctx, id)
+}
+
+func (s *Storage) MoveDirectory(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	return s.writer(ctx).MoveDirectory(ctx, id, parent)
+}
+
+func (s *Storage) RestoreDirectory(ctx context.Context, id apiv1.DirectoryID) ([]*apiv1.Directory, error) {
+	return s.writer(ctx).RestoreDirectory(ctx, id)
+}
+
+func (s *Storage) CreateRoot(ctx context.Context, d *apiv1.Directory) (*apiv1.Directory, error) {
+	return s.writer(ctx).CreateRoot(ctx, d)
+}
+
+func (s *Storage) PromoteToRoot(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	return s.writer(ctx).PromoteToRoot(ctx, id)
+}
+
+func (s *Storage) DemoteRoot(ctx context.Context, id, parent apiv1.DirectoryID) (*apiv1.Directory, error) {
+	return s.writer(ctx).DemoteRoot(ctx, id, parent)
+}
+
+func (s *Storage) DeleteDirectoryIfRevision(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	revision int64,
+) ([]*apiv1.Directory, error) {
+	return s.writer(ctx).DeleteDirectoryIfRevision(ctx, id, revision)
+}
+
+func (s *Storage) MoveDirectoryIfRevision(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+	revision int64,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	return s.writer(ctx).MoveDirectoryIfRevision(ctx, id, parent, revision)
+}
+
+func (s *Storage) PromoteToRootIfRevision(
+	ctx context.Context,
+	id apiv1.DirectoryID,
+	revision int64,
+) (*apiv1.Directory, *apiv1.DirectoryID, error) {
+	return s.writer(ctx).PromoteToRootIfRevision(ctx, id, revision)
+}
+
+func (s *Storage) DemoteRootIfRevision(
+	ctx context.Context,
+	id, parent apiv1.DirectoryID,
+	revision int64,
+) (*apiv1.Directory, error) {
+	return s.writer(ctx).DemoteRootIfRevision(ctx, id, parent, revision)
+}
+
+func (s *Storage) PurgeDirectory(ctx context.Context, id apiv1.DirectoryID) ([]*apiv1.Directory, error) {
+	return s.writer(ctx).PurgeDirectory(ctx, id)
+}
+
+// ListDeleted always reads from the primary, as the listed directories
+// are usually purged right after, and a lagging replica would list the
+// ones already purged again.
+func (s *Storage) ListDeleted(
+	ctx context.Context,
+	before time.Time,
+	options ...storage.Option,
+) ([]*apiv1.Directory, error) {
+	return s.primary.ListDeleted(ctx, before, options...)
+}
+
+func (s *Storage) GetQuota(ctx context.Context, root apiv1.DirectoryID) (*apiv1.DirectoryQuota, error) {
+	return s.reader(ctx).GetQuota(ctx, root)
+}
+
+func (s *Storage) SetQuota(ctx context.Context, root apiv1.DirectoryID, q *apiv1.DirectoryQuota) error {
+	return s.writer(ctx).SetQuota(ctx, root, q)
+}
+
+func (s *Storage) GetSchema(ctx context.Context, id apiv1.DirectoryID) (*storage.MetadataSchema, error) {
+	return s.reader(ctx).GetSchema(ctx, id)
+}
+
+func (s *Storage) SetSchema(ctx context.Context, id apiv1.DirectoryID, schema json.RawMessage) error {
+	return s.writer(ctx).SetSchema(ctx, id, schema)
+}
+
+func (s *Storage) DeleteSchema(ctx context.Context, id apiv1.DirectoryID) error {
+	return s.writer(ctx).DeleteSchema(ctx, id)
+}
+
+// WithTx runs fn within a transaction of the primary, where all the
+// operations, reads included, happen.
+func (s *Storage) WithTx(ctx context.Context, fn func(storage.DirectoryAdmin) error) error {
+	return s.writer(ctx).WithTx(ctx, fn)
+}
+
+func (s *Storage) LockDirectories(ctx context.Context, ids ...apiv1.DirectoryID) error {
+	return s.writer(ctx).LockDirectories(ctx, ids...)
+}
@@ -0,0 +1,114 @@
+package router_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/memory"
+	"github.com/infratographer/fertilesoil/storage/router"
+	"github.com/infratographer/fertilesoil/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RunConformance(t, func(t *testing.T) storage.DirectoryAdmin {
+		store := memory.NewDirectoryDriver()
+
+		return router.StorageWithReplica(store, store)
+	})
+}
+
+// The replicas in these tests are separate stores, which never catch up
+// with the primary, so the driver reads are made from can be told apart.
+
+func TestReadsGoToReplica(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	primary := memory.NewDirectoryDriver()
+	replica := memory.NewDirectoryDriver()
+	store := router.StorageWithReplica(primary, replica)
+
+	rd, err := store.CreateRoot(ctx, &v1.Directory{Name: "root"})
+	assert.NoError(t, err, "error creating root directory")
+
+	_, err = primary.GetDirectory(ctx, rd.Id)
+	assert.NoError(t, err, "write should have gone to the primary")
+
+	_, err = store.GetDirectory(ctx, rd.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "read should have gone to the replica")
+
+	roots, err := store.ListRoots(ctx)
+	assert.NoError(t, err, "error listing roots")
+	assert.Empty(t, roots, "read should have gone to the replica")
+}
+
+func TestReadsAfterWriteInSessionGoToPrimary(t *testing.T) {
+	t.Parallel()
+
+	primary := memory.NewDirectoryDriver()
+	replica := memory.NewDirectoryDriver()
+	store := router.StorageWithReplica(primary, replica)
+
+	rd, err := primary.CreateRoot(context.Background(), &v1.Directory{Name: "root"})
+	assert.NoError(t, err, "error creating root directory")
+
+	ctx := router.WithSession(context.Background())
+
+	_, err = store.GetDirectory(ctx, rd.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "read before a write should have gone to the replica")
+
+	cd, err := store.CreateDirectory(ctx, &v1.Directory{Name: "child", Parent: &rd.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	d, err := store.GetDirectory(ctx, cd.Id)
+	assert.NoError(t, err, "read after a write should have gone to the primary")
+	assert.Equal(t, "child", d.Name, "name should match")
+
+	children, err := store.GetChildren(ctx, rd.Id)
+	assert.NoError(t, err, "error getting children")
+	assert.Equal(t, []v1.DirectoryID{cd.Id}, children, "read after a write should have gone to the primary")
+
+	// Other sessions aren't affected.
+	_, err = store.GetDirectory(router.WithSession(context.Background()), cd.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "read of another session should have gone to the replica")
+}
+
+func TestTransactionsGoToPrimary(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	primary := memory.NewDirectoryDriver()
+	replica := memory.NewDirectoryDriver()
+	store := router.StorageWithReplica(primary, replica)
+
+	rd, err := primary.CreateRoot(ctx, &v1.Directory{Name: "root"})
+	assert.NoError(t, err, "error creating root directory")
+
+	err = store.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		_, err := tx.GetDirectory(ctx, rd.Id)
+		assert.NoError(t, err, "read within a transaction should have gone to the primary")
+
+		return err
+	})
+	assert.NoError(t, err, "error running transaction")
+}
+
+func TestWithPrimary(t *testing.T) {
+	t.Parallel()
+
+	primary := memory.NewDirectoryDriver()
+	replica := memory.NewDirectoryDriver()
+	store := router.StorageWithReplica(primary, replica)
+
+	rd, err := primary.CreateRoot(context.Background(), &v1.Directory{Name: "root"})
+	assert.NoError(t, err, "error creating root directory")
+
+	_, err = store.GetDirectory(router.WithPrimary(context.Background()), rd.Id)
+	assert.NoError(t, err, "read should have gone to the primary")
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+)
+
+// MetadataSchema is the JSON Schema the metadata of the directories of a
+// subtree is validated against.
+type MetadataSchema struct {
+	// Source is the directory the schema is set on, which may be
+	// an ancestor of the directory it applies to.
+	Source v1.DirectoryID
+	// Schema is the JSON document of the schema.
+	Schema json.RawMessage
+}
+
+// FieldError tells why a metadata field doesn't match its schema.
+type FieldError struct {
+	// Field is the metadata key, empty if the error is about the metadata as a whole.
+	Field string
+	// Message tells the rule the field breaks.
+	Message string
+}
+
+// InvalidMetadataError is returned when the metadata of a directory doesn't
+// match the schema applying to it. It wraps ErrInvalidMetadata.
+type InvalidMetadataError struct {
+	// Source is the directory the schema is set on.
+	Source v1.DirectoryID
+	// Fields holds the errors of the invalid fields.
+	Fields []FieldError
+}
+
+func (e *InvalidMetadataError) Error() string {
+	fields := make([]string, len(e.Fields))
+
+	for i, f := range e.Fields {
+		if f.Field == "" {
+			fields[i] = f.Message
+		} else {
+			fields[i] = f.Field + ": " + f.Message
+		}
+	}
+
+	return fmt.Sprintf("%v of directory %s: %s", ErrInvalidMetadata, e.Source, strings.Join(fields, "; "))
+}
+
+func (e *InvalidMetadataError) Unwrap() error {
+	return ErrInvalidMetadata
+}
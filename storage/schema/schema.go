@@ -0,0 +1,208 @@
+// Package schema provides a storage driver which validates the metadata of
+// the directories of another storage driver against JSON Schemas.
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	apiv1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// Storage is a meta-storage driver that wraps a storage.DirectoryAdmin and
+// refuses the writes leaving the metadata of a directory at odds with the
+// schema applying to it with a *storage.InvalidMetadataError.
+//
+// Schemas are understood as OpenAPI 3.0 schema objects, the subset of JSON
+// Schema supported by kin-openapi. Setting a schema which can't be parsed is
+// refused with storage.ErrInvalidSchema.
+//
+// Directories keep their metadata as it is when a schema is set, so the
+// metadata is validated against the schema read before the write, rather
+// than within a transaction. Moved and restored directories keep their
+// metadata as well, so they aren't checked. Nor are roots, as no schema may
+// apply to them before they're created.
+type Storage struct {
+	storage.DirectoryAdmin
+}
+
+// ensure Storage implements storage.DirectoryAdmin.
+var _ storage.DirectoryAdmin = &Storage{}
+
+// StorageWithSchemas wraps the provided storage, validating the metadata of its directories.
+func StorageWithSchemas(s storage.DirectoryAdmin) *Storage {
+	return &Storage{
+		DirectoryAdmin: s,
+	}
+}
+
+// WithTx runs fn within a transaction of the wrapped storage,
+// where the metadata is validated as well.
+func (s *Storage) WithTx(ctx context.Context, fn func(storage.DirectoryAdmin) error) error {
+	return s.DirectoryAdmin.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		return fn(&Storage{DirectoryAdmin: tx})
+	})
+}
+
+// SetSchema replaces the schema of the directory, once checked to be valid.
+func (s *Storage) SetSchema(ctx context.Context, id apiv1.DirectoryID, schema json.RawMessage) error {
+	if _, err := Parse(ctx, schema); err != nil {
+		return err
+	}
+
+	return s.DirectoryAdmin.SetSchema(ctx, id, schema)
+}
+
+func (s *Storage) CreateDirectory(ctx context.Context, d *apiv1.Directory) (*apiv1.Directory, error) {
+	// The directory takes the schema of its parent.
+	if d.Parent != nil {
+		if err := s.check(ctx, *d.Parent, d.Metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.DirectoryAdmin.CreateDirectory(ctx, d)
+}
+
+func (s *Storage) UpdateDirectory(ctx context.Context, d *apiv1.Directory) error {
+	if err := s.check(ctx, d.Id, d.Metadata); err != nil {
+		return err
+	}
+
+	return s.DirectoryAdmin.UpdateDirectory(ctx, d)
+}
+
+func (s *Storage) UpdateDirectoryIfRevision(ctx context.Context, d *apiv1.Directory, revision int64) error {
+	if err := s.check(ctx, d.Id, d.Metadata); err != nil {
+		return err
+	}
+
+	return s.DirectoryAdmin.UpdateDirectoryIfRevision(ctx, d, revision)
+}
+
+// check validates the metadata against the schema applying to the directory.
+// Directories which aren't found are left to the wrapped storage to refuse.
+func (s *Storage) check(ctx context.Context, id apiv1.DirectoryID, md *apiv1.DirectoryMetadata) error {
+	ms, err := s.GetSchema(ctx, id)
+	if errors.Is(err, storage.ErrSchemaNotFound) || errors.Is(err, storage.ErrDirectoryNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	schema, err := Parse(ctx, ms.Schema)
+	if err != nil {
+		return fmt.Errorf("schema of directory %s: %w", ms.Source, err)
+	}
+
+	fields := Validate(schema, md)
+	if len(fields) > 0 {
+		return &storage.InvalidMetadataError{
+			Source: ms.Source,
+			Fields: fields,
+		}
+	}
+
+	return nil
+}
+
+// Parse parses and checks the schema document, returning an error
+// wrapping storage.ErrInvalidSchema if it isn't a valid schema.
+func Parse(ctx context.Context, doc json.RawMessage) (*openapi3.Schema, error) {
+	schema := &openapi3.Schema{}
+
+	if err := schema.UnmarshalJSON(doc); err != nil {
+		return nil, fmt.Errorf("%w: %s", storage.ErrInvalidSchema, err)
+	}
+
+	if err := schema.Validate(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %s", storage.ErrInvalidSchema, err)
+	}
+
+	return schema, nil
+}
+
+// Validate validates the metadata against the schema, returning an error for
+// each field which doesn't match it. Metadata which isn't set is validated as
+// an empty object, so required fields are still enforced.
+func Validate(schema *openapi3.Schema, md *apiv1.DirectoryMetadata) []storage.FieldError {
+	value := map[string]interface{}{}
+
+	if md != nil {
+		for k, v := range *md {
+			value[k] = v
+		}
+	}
+
+	// The fields refused by additionalProperties are reported without their
+	// key, so they're found beforehand.
+	fields := disallowed(schema, value)
+
+	err := schema.VisitJSON(value, openapi3.MultiErrors())
+	if err != nil {
+		fields = append(fields, fieldErrors(err)...)
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Field < fields[j].Field
+	})
+
+	return fields
+}
+
+// disallowed returns an error for each field which isn't a property of the
+// schema, if it doesn't allow additional ones.
+func disallowed(schema *openapi3.Schema, value map[string]interface{}) []storage.FieldError {
+	if schema.AdditionalProperties.Has == nil || *schema.AdditionalProperties.Has {
+		return nil
+	}
+
+	var fields []storage.FieldError
+
+	for k := range value {
+		if _, ok := schema.Properties[k]; !ok {
+			fields = append(fields, storage.FieldError{Field: k, Message: "property is not allowed"})
+		}
+	}
+
+	return fields
+}
+
+// fieldErrors flattens the errors of a schema validation into field errors,
+// skipping the ones about disallowed fields, which are found by disallowed.
+func fieldErrors(err error) []storage.FieldError {
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		var fields []storage.FieldError
+
+		for _, e := range multi {
+			fields = append(fields, fieldErrors(e)...)
+		}
+
+		return fields
+	}
+
+	var se *openapi3.SchemaError
+	if !errors.As(err, &se) {
+		return []storage.FieldError{{Message: err.Error()}}
+	}
+
+	pointer := se.JSONPointer()
+	if len(pointer) == 0 && se.SchemaField == "properties" {
+		return nil
+	}
+
+	return []storage.FieldError{{
+		Field:   strings.Join(pointer, "/"),
+		Message: se.Reason,
+	}}
+}
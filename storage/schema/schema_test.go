@@ -0,0 +1,192 @@
+package schema_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/memory"
+	"github.com/infratographer/fertilesoil/storage/schema"
+	"github.com/infratographer/fertilesoil/storage/storagetest"
+)
+
+// envSchema requires a known environment, and allows an optional team.
+const envSchema = `{
+	"type": "object",
+	"required": ["environment"],
+	"additionalProperties": false,
+	"properties": {
+		"environment": {"type": "string", "enum": ["prod", "dev"]},
+		"team": {"type": "string", "pattern": "^[a-z]+$"}
+	}
+}`
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RunConformance(t, func(t *testing.T) storage.DirectoryAdmin {
+		return schema.StorageWithSchemas(memory.NewDirectoryDriver())
+	})
+}
+
+func createRoot(t *testing.T, store storage.DirectoryAdmin) *v1.Directory {
+	t.Helper()
+
+	d, err := store.CreateRoot(context.Background(), &v1.Directory{Name: "root"})
+	if !assert.NoError(t, err, "error creating root directory") {
+		t.FailNow()
+	}
+
+	if !assert.NoError(t, store.SetSchema(context.Background(), d.Id, json.RawMessage(envSchema)),
+		"error setting schema") {
+		t.FailNow()
+	}
+
+	return d
+}
+
+func metadata(kv ...string) *v1.DirectoryMetadata {
+	md := v1.DirectoryMetadata{}
+
+	for i := 0; i+1 < len(kv); i += 2 {
+		md[kv[i]] = kv[i+1]
+	}
+
+	return &md
+}
+
+// assertInvalid asserts that err tells the fields don't match the schema of the source.
+func assertInvalid(t *testing.T, err error, source v1.DirectoryID, fields ...string) {
+	t.Helper()
+
+	var invalid *storage.InvalidMetadataError
+
+	assert.ErrorIs(t, err, storage.ErrInvalidMetadata, "metadata should be invalid")
+
+	if assert.ErrorAs(t, err, &invalid, "error should tell the invalid fields") {
+		assert.Equal(t, source, invalid.Source, "error should tell the source of the schema")
+
+		got := make([]string, len(invalid.Fields))
+
+		for i, f := range invalid.Fields {
+			got[i] = f.Field
+			assert.NotEmpty(t, f.Message, "field error should have a message")
+		}
+
+		assert.Equal(t, fields, got, "unexpected invalid fields")
+	}
+}
+
+func TestCreateDirectory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := schema.StorageWithSchemas(memory.NewDirectoryDriver())
+	root := createRoot(t, store)
+
+	_, err := store.CreateDirectory(ctx, &v1.Directory{
+		Name:     "dir",
+		Parent:   &root.Id,
+		Metadata: metadata("enviroment", "prod", "team", "A1"),
+	})
+	assertInvalid(t, err, root.Id, "enviroment", "environment", "team")
+
+	// Directories without metadata miss the required fields.
+	_, err = store.CreateDirectory(ctx, &v1.Directory{Name: "dir", Parent: &root.Id})
+	assertInvalid(t, err, root.Id, "environment")
+
+	child, err := store.CreateDirectory(ctx, &v1.Directory{
+		Name:     "dir",
+		Parent:   &root.Id,
+		Metadata: metadata("environment", "prod"),
+	})
+	assert.NoError(t, err, "error creating directory")
+
+	// The schema of the root applies to its whole tree.
+	_, err = store.CreateDirectory(ctx, &v1.Directory{
+		Name:     "dir",
+		Parent:   &child.Id,
+		Metadata: metadata("environment", "staging"),
+	})
+	assertInvalid(t, err, root.Id, "environment")
+
+	// Closer schemas take precedence.
+	assert.NoError(t, store.SetSchema(ctx, child.Id, json.RawMessage(`{"type": "object"}`)), "error setting schema")
+
+	_, err = store.CreateDirectory(ctx, &v1.Directory{
+		Name:     "dir",
+		Parent:   &child.Id,
+		Metadata: metadata("environment", "staging"),
+	})
+	assert.NoError(t, err, "error creating directory")
+
+	// Roots aren't checked.
+	_, err = store.CreateRoot(ctx, &v1.Directory{Name: "other", Metadata: metadata("environment", "staging")})
+	assert.NoError(t, err, "error creating root directory")
+}
+
+func TestUpdateDirectory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := schema.StorageWithSchemas(memory.NewDirectoryDriver())
+	root := createRoot(t, store)
+
+	// The schema applies to the directory it's set on as well.
+	root.Metadata = metadata("environment", "dev", "team", "core")
+	assert.NoError(t, store.UpdateDirectory(ctx, root), "error updating directory")
+
+	dir := *root
+	dir.Metadata = metadata("environment", "dev", "team", "Core")
+
+	err := store.UpdateDirectory(ctx, &dir)
+	assertInvalid(t, err, root.Id, "team")
+
+	err = store.UpdateDirectoryIfRevision(ctx, &dir, root.Revision)
+	assertInvalid(t, err, root.Id, "team")
+
+	got, err := store.GetDirectory(ctx, root.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, metadata("environment", "dev", "team", "core"), got.Metadata, "metadata should be kept")
+}
+
+func TestWithTx(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := schema.StorageWithSchemas(memory.NewDirectoryDriver())
+	root := createRoot(t, store)
+
+	err := store.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		_, err := tx.CreateDirectory(ctx, &v1.Directory{Name: "dir", Parent: &root.Id})
+
+		return err
+	})
+	assertInvalid(t, err, root.Id, "environment")
+}
+
+func TestSetSchema(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := schema.StorageWithSchemas(memory.NewDirectoryDriver())
+	root := createRoot(t, store)
+
+	for _, doc := range []string{
+		`{"type": "nope"}`,
+		`{"type": "object", "typo": true}`,
+		`{"properties": {"team": {"type": "string", "pattern": "("}}}`,
+		`[]`,
+	} {
+		err := store.SetSchema(ctx, root.Id, json.RawMessage(doc))
+		assert.ErrorIs(t, err, storage.ErrInvalidSchema, "schema %s should be invalid", doc)
+	}
+
+	s, err := store.GetSchema(ctx, root.Id)
+	assert.NoError(t, err, "error getting schema")
+	assert.JSONEq(t, envSchema, string(s.Schema), "schema should be kept")
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NameMatch is how a search matches the names of directories.
+type NameMatch string
+
+// Matches supported by name searches.
+const (
+	// MatchExact matches directories named exactly as the query.
+	MatchExact NameMatch = "exact"
+	// MatchPrefix matches directories whose name starts with the query.
+	MatchPrefix NameMatch = "prefix"
+	// MatchContains matches directories whose name contains the query, ignoring case.
+	MatchContains NameMatch = "contains"
+)
+
+// ParseNameMatch parses the name of a match, MatchContains being the default.
+func ParseNameMatch(s string) (NameMatch, error) {
+	switch m := NameMatch(s); m {
+	case "":
+		return MatchContains, nil
+	case MatchExact, MatchPrefix, MatchContains:
+		return m, nil
+	default:
+		return "", fmt.Errorf("%w: unknown match %q", ErrInvalidSearch, s)
+	}
+}
+
+// NameQuery searches directories by name.
+type NameQuery struct {
+	// Name is the name, or the part of it, which is searched.
+	Name string
+
+	// Match is how the name is matched.
+	Match NameMatch
+}
+
+// Validate checks that the query may be run.
+func (q NameQuery) Validate() error {
+	if q.Name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidSearch)
+	}
+
+	switch q.Match {
+	case MatchExact, MatchPrefix, MatchContains:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown match %q", ErrInvalidSearch, q.Match)
+	}
+}
+
+// Matches tells whether the name matches the query.
+func (q NameQuery) Matches(name string) bool {
+	switch q.Match {
+	case MatchExact:
+		return name == q.Name
+	case MatchPrefix:
+		return strings.HasPrefix(name, q.Name)
+	case MatchContains:
+		return strings.Contains(strings.ToLower(name), strings.ToLower(q.Name))
+	default:
+		return false
+	}
+}
@@ -0,0 +1,52 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+func TestParseNameMatch(t *testing.T) {
+	t.Parallel()
+
+	m, err := storage.ParseNameMatch("")
+	assert.NoError(t, err, "empty match should be accepted")
+	assert.Equal(t, storage.MatchContains, m, "unexpected default match")
+
+	m, err = storage.ParseNameMatch("prefix")
+	assert.NoError(t, err, "error parsing match")
+	assert.Equal(t, storage.MatchPrefix, m, "unexpected match")
+
+	_, err = storage.ParseNameMatch("regexp")
+	assert.ErrorIs(t, err, storage.ErrInvalidSearch, "unknown match should be refused")
+}
+
+func TestNameQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		query   storage.NameQuery
+		name    string
+		matches bool
+	}{
+		{storage.NameQuery{Name: "payments", Match: storage.MatchExact}, "payments", true},
+		{storage.NameQuery{Name: "payments", Match: storage.MatchExact}, "Payments", false},
+		{storage.NameQuery{Name: "pay", Match: storage.MatchPrefix}, "payments", true},
+		{storage.NameQuery{Name: "pay", Match: storage.MatchPrefix}, "repay", false},
+		{storage.NameQuery{Name: "MENT", Match: storage.MatchContains}, "payments", true},
+		{storage.NameQuery{Name: "ments%", Match: storage.MatchContains}, "payments", false},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.matches, tc.query.Matches(tc.name), "unexpected match of %q with %+v", tc.name, tc.query)
+	}
+
+	assert.ErrorIs(t, storage.NameQuery{Match: storage.MatchExact}.Validate(), storage.ErrInvalidSearch,
+		"empty name should be refused")
+	assert.ErrorIs(t, storage.NameQuery{Name: "payments"}.Validate(), storage.ErrInvalidSearch,
+		"missing match should be refused")
+	assert.NoError(t, storage.NameQuery{Name: "payments", Match: storage.MatchPrefix}.Validate(),
+		"query should be valid")
+}
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+)
+
+// SelectorOperator is the operator of a metadata selector requirement.
+type SelectorOperator string
+
+// Operators supported by metadata selectors.
+const (
+	// SelectorEquals matches directories where the key is set to the value.
+	SelectorEquals SelectorOperator = "="
+	// SelectorNotEquals matches directories where the key is not set to the value,
+	// including directories where the key isn't set.
+	SelectorNotEquals SelectorOperator = "!="
+	// SelectorIn matches directories where the key is set to one of the values.
+	SelectorIn SelectorOperator = "in"
+	// SelectorNotIn matches directories where the key is not set to any of the values,
+	// including directories where the key isn't set.
+	SelectorNotIn SelectorOperator = "notin"
+	// SelectorExists matches directories where the key is set.
+	SelectorExists SelectorOperator = "exists"
+	// SelectorDoesNotExist matches directories where the key isn't set.
+	SelectorDoesNotExist SelectorOperator = "!"
+)
+
+// Requirement is a single condition of a metadata selector.
+type Requirement struct {
+	// Key is the metadata key the requirement applies to.
+	Key string
+
+	// Operator is how the value of the key is compared.
+	Operator SelectorOperator
+
+	// Values are the values the key is compared to.
+	// Equality operators have a single value, existence operators have none.
+	Values []string
+}
+
+// Selector selects directories by their metadata.
+// A directory is selected if it matches all of the requirements.
+type Selector []Requirement
+
+var (
+	selectorKeyRe   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]*[A-Za-z0-9])?$`)
+	selectorValueRe = regexp.MustCompile(`^[A-Za-z0-9._/:@-]*$`)
+	selectorSetRe   = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+// ParseSelector parses a selector using a syntax similar to the one of
+// Kubernetes label selectors. Requirements are separated by commas:
+//
+//	env=prod           the key is set to the value ("==" is also accepted)
+//	env!=prod          the key is not set to the value
+//	env in (dev,qa)    the key is set to one of the values
+//	env notin (dev,qa) the key is not set to any of the values
+//	env                the key is set
+//	!env               the key isn't set
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+
+	for _, term := range splitSelectorTerms(s) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return nil, fmt.Errorf("%w: empty requirement", ErrInvalidSelector)
+		}
+
+		req, err := parseRequirement(term)
+		if err != nil {
+			return nil, err
+		}
+
+		sel = append(sel, req)
+	}
+
+	return sel, nil
+}
+
+// splitSelectorTerms splits the selector on the commas which are not within parentheses.
+func splitSelectorTerms(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	var (
+		terms []string
+		depth int
+		start int
+	)
+
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(terms, s[start:])
+}
+
+func parseRequirement(term string) (Requirement, error) {
+	var req Requirement
+
+	switch {
+	case strings.HasPrefix(term, "!") && !strings.Contains(term, "="):
+		req = Requirement{Key: strings.TrimSpace(term[1:]), Operator: SelectorDoesNotExist}
+	case selectorSetRe.MatchString(term):
+		m := selectorSetRe.FindStringSubmatch(term)
+		req = Requirement{Key: m[1], Operator: SelectorOperator(m[2])}
+
+		for _, v := range strings.Split(m[3], ",") {
+			req.Values = append(req.Values, strings.TrimSpace(v))
+		}
+	case strings.Contains(term, "!="):
+		key, value, _ := strings.Cut(term, "!=")
+		req = newEqualityRequirement(key, SelectorNotEquals, value)
+	case strings.Contains(term, "="):
+		key, value, _ := strings.Cut(term, "=")
+		req = newEqualityRequirement(key, SelectorEquals, strings.TrimPrefix(value, "="))
+	default:
+		req = Requirement{Key: term, Operator: SelectorExists}
+	}
+
+	if !selectorKeyRe.MatchString(req.Key) {
+		return req, fmt.Errorf("%w: invalid key %q", ErrInvalidSelector, req.Key)
+	}
+
+	for _, v := range req.Values {
+		if !selectorValueRe.MatchString(v) {
+			return req, fmt.Errorf("%w: invalid value %q", ErrInvalidSelector, v)
+		}
+	}
+
+	return req, nil
+}
+
+func newEqualityRequirement(key string, op SelectorOperator, value string) Requirement {
+	return Requirement{
+		Key:      strings.TrimSpace(key),
+		Operator: op,
+		Values:   []string{strings.TrimSpace(value)},
+	}
+}
+
+// String encodes the selector so it can be parsed back with ParseSelector.
+func (s Selector) String() string {
+	terms := make([]string, len(s))
+
+	for i, req := range s {
+		terms[i] = req.String()
+	}
+
+	return strings.Join(terms, ",")
+}
+
+// String encodes the requirement using the selector syntax.
+func (r Requirement) String() string {
+	switch r.Operator {
+	case SelectorExists:
+		return r.Key
+	case SelectorDoesNotExist:
+		return "!" + r.Key
+	case SelectorIn, SelectorNotIn:
+		values := append([]string{}, r.Values...)
+		sort.Strings(values)
+
+		return r.Key + " " + string(r.Operator) + " (" + strings.Join(values, ",") + ")"
+	default:
+		return r.Key + string(r.Operator) + strings.Join(r.Values, "")
+	}
+}
+
+// Matches reports whether the provided metadata matches all the requirements.
+func (s Selector) Matches(md *v1.DirectoryMetadata) bool {
+	for _, req := range s {
+		if !req.Matches(md) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Matches reports whether the provided metadata matches the requirement.
+func (r Requirement) Matches(md *v1.DirectoryMetadata) bool {
+	var (
+		value string
+		found bool
+	)
+
+	if md != nil {
+		value, found = (*md)[r.Key]
+	}
+
+	switch r.Operator {
+	case SelectorExists:
+		return found
+	case SelectorDoesNotExist:
+		return !found
+	case SelectorEquals, SelectorIn:
+		return found && r.hasValue(value)
+	case SelectorNotEquals, SelectorNotIn:
+		return !found || !r.hasValue(value)
+	default:
+		return false
+	}
+}
+
+func (r Requirement) hasValue(value string) bool {
+	for _, v := range r.Values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
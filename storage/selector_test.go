@@ -0,0 +1,67 @@
+package storage_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+func TestSelectorMatches(t *testing.T) {
+	t.Parallel()
+
+	md := &v1.DirectoryMetadata{
+		"env":  "prod",
+		"team": "infra",
+	}
+
+	tests := []struct {
+		selector string
+		matches  bool
+	}{
+		{selector: "", matches: true},
+		{selector: "env=prod", matches: true},
+		{selector: "env==prod", matches: true},
+		{selector: "env=dev", matches: false},
+		{selector: "env!=dev", matches: true},
+		{selector: "missing!=dev", matches: true},
+		{selector: "env in (dev, prod)", matches: true},
+		{selector: "env in (dev,qa)", matches: false},
+		{selector: "env notin (dev,qa)", matches: true},
+		{selector: "env notin (prod)", matches: false},
+		{selector: "team", matches: true},
+		{selector: "!team", matches: false},
+		{selector: "!missing", matches: true},
+		{selector: "env=prod, team=infra", matches: true},
+		{selector: "env in (prod,dev),team=platform", matches: false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.selector, func(t *testing.T) {
+			t.Parallel()
+
+			sel, err := storage.ParseSelector(tc.selector)
+			assert.NoError(t, err, "error parsing selector")
+			assert.Equal(t, tc.matches, sel.Matches(md), "unexpected match result")
+
+			// The encoded selector parses back to the same requirements
+			reparsed, err := storage.ParseSelector(sel.String())
+			assert.NoError(t, err, "error parsing encoded selector")
+			assert.Equal(t, tc.matches, reparsed.Matches(md), "unexpected match result")
+		})
+	}
+}
+
+func TestParseInvalidSelector(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{",", "env=prod,", "=prod", "env in (a", "env=(prod)", "bad key=prod", "!"} {
+		_, err := storage.ParseSelector(s)
+		assert.True(t, errors.Is(err, storage.ErrInvalidSelector), "expected invalid selector for %q", s)
+	}
+}
@@ -0,0 +1,64 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// Dialect adapts the queries of the driver to a database.
+//
+// Queries are written in the syntax shared by the supported databases,
+// with $N placeholders. The parts which differ are built by the dialect.
+type Dialect interface {
+	// Rebind rewrites the $N placeholders of the query into the ones of the database.
+	Rebind(query string) string
+
+	// ExecuteTx runs fn within a new transaction, which is committed if fn
+	// returns nil and rolled back otherwise. fn may be called more than once
+	// if the transaction is retried.
+	ExecuteTx(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error
+
+	// ForUpdate returns the clause locking the rows read by a statement
+	// until the end of the transaction, if the database has row locks.
+	ForUpdate() string
+
+	// Now returns the expression of the current time, as it is stored.
+	Now() string
+
+	// Timestamp returns the argument to compare the stored timestamps with.
+	Timestamp(t time.Time) any
+
+	// Cast returns the expression casting expr to the provided type,
+	// if the database needs it to infer the type of an argument.
+	Cast(expr, typ string) string
+
+	// IDList returns the argument holding the provided ids, and InIDList
+	// the condition matching the column against it.
+	IDList(ids []v1.DirectoryID) any
+	InIDList(column, arg string) string
+
+	// ReadFrom returns the source the directories are read from, along with
+	// the clause ending the FROM clause of the query, to read them as they
+	// were at the provided point in time if it isn't zero. fastReads is set
+	// when the driver may read slightly stale data.
+	ReadFrom(asOf time.Time, fastReads bool) (source, clause string)
+
+	// MatchSelector returns the condition matching the metadata of the directories
+	// against the requirement. arg adds an argument and returns its placeholder.
+	MatchSelector(req storage.Requirement, arg func(any) string) string
+
+	// MatchName returns the condition matching the name of the directories
+	// against the query, along with its argument, whose placeholder is arg.
+	MatchName(q storage.NameQuery, arg string) (string, any)
+
+	// IsUniqueViolation reports whether err is a unique constraint violation.
+	IsUniqueViolation(err error) bool
+
+	// IsAsOfOutOfRange reports whether err was returned because the data
+	// was read as of a time the database doesn't keep the data of anymore.
+	IsAsOfOutOfRange(err error) bool
+}
@@ -0,0 +1,1652 @@
+// Package sqldriver implements the storage interface on SQL databases.
+//
+// The queries are shared by the supported databases, which are told apart
+// by their Dialect. The drivers of each database wrap this package.
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+var _ storage.DirectoryAdmin = (*Driver)(nil)
+
+type Driver struct {
+	db      *sql.DB
+	dialect Dialect
+	// tx is set when the driver is bound to a transaction by WithTx.
+	tx                 *conn
+	readOnly           bool
+	fastReads          bool
+	uniqueSiblingNames bool
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn runs queries on the database or on a transaction,
+// rebinding their placeholders for the dialect.
+type conn struct {
+	q       querier
+	dialect Dialect
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.q.ExecContext(ctx, c.dialect.Rebind(query), args...)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.q.QueryContext(ctx, c.dialect.Rebind(query), args...)
+}
+
+func (c *conn) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.q.QueryRowContext(ctx, c.dialect.Rebind(query), args...)
+}
+
+// writeError wraps an error returned by a write statement with the provided message.
+// Unique constraint violations mean a live sibling already has the directory's name.
+func (c *conn) writeError(msg string, err error) error {
+	if c.dialect.IsUniqueViolation(err) {
+		return storage.ErrDirectoryNameConflict
+	}
+
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// readError wraps an error returned by a read statement with the provided message.
+// Reads as of a time the database doesn't keep the data of anymore are out of range.
+func (t *Driver) readError(msg string, err error) error {
+	if t.dialect.IsAsOfOutOfRange(err) {
+		return storage.ErrAsOfOutOfRange
+	}
+
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// metadataJSON returns the metadata as JSON text, which every database
+// accepts for its JSON columns.
+func metadataJSON(md *v1.DirectoryMetadata) (string, error) {
+	b, err := json.Marshal(md)
+	if err != nil {
+		return "", fmt.Errorf("error encoding metadata: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// New returns a driver running its queries on db in the provided dialect.
+func New(db *sql.DB, dialect Dialect, opts ...Option) *Driver {
+	d := &Driver{
+		db:      db,
+		dialect: dialect,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// WithTx calls fn with a driver bound to a new transaction.
+// The transaction may be retried by the dialect, calling fn again.
+// If the driver is already bound to a transaction, fn runs within it.
+func (t *Driver) WithTx(ctx context.Context, fn func(storage.DirectoryAdmin) error) error {
+	if t.readOnly {
+		return storage.ErrReadOnly
+	}
+
+	return t.executeTx(ctx, func(c *conn) error {
+		return fn(&Driver{
+			db:                 t.db,
+			dialect:            t.dialect,
+			tx:                 c,
+			uniqueSiblingNames: t.uniqueSiblingNames,
+		})
+	})
+}
+
+// LockDirectories locks the rows of the provided directories one at a time,
+// so transactions locking the same directories in the same order can't deadlock.
+// Directories which aren't found are skipped. Dialects without row locks
+// are expected to serialize their write transactions instead.
+func (t *Driver) LockDirectories(ctx context.Context, ids ...v1.DirectoryID) error {
+	if t.readOnly {
+		return storage.ErrReadOnly
+	}
+
+	c := t.conn()
+
+	for _, id := range ids {
+		rows, err := c.QueryContext(ctx, "SELECT id FROM directories WHERE id = $1"+c.dialect.ForUpdate(), id)
+		if err != nil {
+			return fmt.Errorf("error locking directory: %w", err)
+		}
+
+		if err := rows.Close(); err != nil {
+			return fmt.Errorf("error locking directory: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// conn returns the transaction the driver is bound to, or the database otherwise.
+func (t *Driver) conn() *conn {
+	if t.tx != nil {
+		return t.tx
+	}
+
+	return &conn{q: t.db, dialect: t.dialect}
+}
+
+// executeTx runs fn within the transaction the driver is bound to,
+// or within a new transaction otherwise.
+func (t *Driver) executeTx(ctx context.Context, fn func(*conn) error) error {
+	if t.tx != nil {
+		return fn(t.tx)
+	}
+
+	return t.dialect.ExecuteTx(ctx, t.db, func(tx *sql.Tx) error {
+		return fn(&conn{q: tx, dialect: t.dialect})
+	})
+}
+
+// CreateRoot creates a root directory.
+// Root directories are directories that have no parent directory.
+// ID is generated by the driver, it will be ignored if given.
+// Unique sibling names are enforced in the new tree if requested by
+// the directory or by the driver.
+func (t *Driver) CreateRoot(ctx context.Context, d *v1.Directory) (*v1.Directory, error) {
+	if t.readOnly {
+		return nil, storage.ErrReadOnly
+	}
+
+	if d.Parent != nil {
+		return nil, storage.ErrRootWithParentDirectory
+	}
+
+	if d.Metadata == nil {
+		d.Metadata = &v1.DirectoryMetadata{}
+	}
+
+	md, err := metadataJSON(d.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	id := v1.DirectoryID(uuid.New())
+	uniqueSiblingNames := d.UniqueSiblingNames || t.uniqueSiblingNames
+
+	err = t.executeTx(ctx, func(c *conn) error {
+		now := c.dialect.Now()
+
+		err := c.QueryRowContext(ctx, `
+			INSERT INTO directories (id, name, metadata, created_at, updated_at, unique_sibling_names)
+			VALUES ($1, $2, $3, `+now+`, `+now+`, $4)
+			RETURNING created_at, updated_at, revision, unique_sibling_names
+		`, id, d.Name, md, uniqueSiblingNames).Scan(
+			timestamp{&d.CreatedAt}, timestamp{&d.UpdatedAt}, &d.Revision, &d.UniqueSiblingNames)
+		if err != nil {
+			return fmt.Errorf("error inserting directory: %w", err)
+		}
+
+		d.Id = id
+
+		return recordRevision(ctx, c, v1.EventTypeCreate, nil, d)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// ListRoots returns all root directory ids.
+func (t *Driver) ListRoots(ctx context.Context, options ...storage.Option) ([]v1.DirectoryID, error) {
+	var roots []v1.DirectoryID
+
+	opts := storage.BuildOptions(options)
+
+	withDeleted := "false" //nolint:goconst // unnecessary to make a constant
+
+	if opts.WithDeletedDirectories {
+		withDeleted = "true" //nolint:goconst // unnecessary to make a constant
+	}
+
+	keyset, args := t.keysetCondition(opts, 1)
+	selector, selectorArgs := t.selectorCondition(opts.Selector, len(args)+1)
+
+	q := t.formatQuery(`
+		SELECT id FROM %[1]s d %[2]s
+		WHERE parent_id IS NULL AND (`+withDeleted+` OR deleted_at IS NULL)`+keyset+selector+`
+		ORDER BY created_at ASC, id ASC
+	`+pageClause(opts), opts)
+
+	args = append(args, selectorArgs...)
+
+	rows, err := t.conn().QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, t.readError("error querying directory", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var did v1.DirectoryID
+		err := rows.Scan(&did)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning directory: %w", err)
+		}
+		roots = append(roots, did)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, t.readError("error querying directory", err)
+	}
+
+	return roots, nil
+}
+
+// CountRoots returns the count of root directories, without reading them.
+func (t *Driver) CountRoots(ctx context.Context, options ...storage.Option) (int, error) {
+	var count int
+
+	opts := storage.BuildOptions(options)
+
+	withDeleted := "false"
+
+	if opts.WithDeletedDirectories {
+		withDeleted = "true"
+	}
+
+	selector, args := t.selectorCondition(opts.Selector, 1)
+
+	q := t.formatQuery(`
+		SELECT COUNT(*) FROM %[1]s d %[2]s
+		WHERE parent_id IS NULL AND (`+withDeleted+` OR deleted_at IS NULL)`+selector, opts)
+
+	if err := t.conn().QueryRowContext(ctx, q, args...).Scan(&count); err != nil {
+		return 0, t.readError("error counting directories", err)
+	}
+
+	return count, nil
+}
+
+// CreateDirectory creates the provided directory.
+// The directory enforces unique sibling names if its parent or the driver do.
+func (t *Driver) CreateDirectory(ctx context.Context, d *v1.Directory) (*v1.Directory, error) {
+	if t.readOnly {
+		return nil, storage.ErrReadOnly
+	}
+
+	if d.Parent == nil {
+		return nil, storage.ErrDirectoryWithoutParent
+	}
+
+	if d.Metadata == nil {
+		d.Metadata = &v1.DirectoryMetadata{}
+	}
+
+	md, err := metadataJSON(d.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	id := v1.DirectoryID(uuid.New())
+
+	err = t.executeTx(ctx, func(c *conn) error {
+		now := c.dialect.Now()
+
+		// The arguments are only selected, so their types can't be inferred from the columns.
+		err := c.QueryRowContext(ctx, `
+			INSERT INTO directories (id, name, parent_id, metadata, created_at, updated_at, unique_sibling_names)
+			SELECT `+c.dialect.Cast("$1", "UUID")+`, `+c.dialect.Cast("$2", "TEXT")+`, p.id,
+				`+c.dialect.Cast("$4", "JSONB")+`, `+now+`, `+now+`,
+				p.unique_sibling_names OR `+c.dialect.Cast("$5", "BOOL")+`
+			FROM directories p
+			WHERE p.id = $3 AND p.deleted_at IS NULL
+			RETURNING created_at, updated_at, revision, unique_sibling_names
+		`, id, d.Name, d.Parent, md, t.uniqueSiblingNames).Scan(
+			timestamp{&d.CreatedAt}, timestamp{&d.UpdatedAt}, &d.Revision, &d.UniqueSiblingNames)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return storage.ErrDirectoryNotFound
+			}
+
+			return c.writeError("error inserting directory", err)
+		}
+
+		d.Id = id
+
+		return recordRevision(ctx, c, v1.EventTypeCreate, nil, d)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// UpdateDirectory updates the directory.
+func (t *Driver) UpdateDirectory(ctx context.Context, d *v1.Directory) error {
+	if t.readOnly {
+		return storage.ErrReadOnly
+	}
+
+	if d.Metadata == nil {
+		d.Metadata = &v1.DirectoryMetadata{}
+	}
+
+	return t.executeTx(ctx, func(c *conn) error {
+		before, err := getDirectoryForUpdate(ctx, c, d.Id)
+		if err != nil {
+			return err
+		}
+
+		return updateDirectory(ctx, c, d, before)
+	})
+}
+
+// UpdateDirectoryIfRevision updates the directory only if it is still at the provided revision.
+func (t *Driver) UpdateDirectoryIfRevision(ctx context.Context, d *v1.Directory, revision int64) error {
+	if t.readOnly {
+		return storage.ErrReadOnly
+	}
+
+	if d.Metadata == nil {
+		d.Metadata = &v1.DirectoryMetadata{}
+	}
+
+	return t.executeTx(ctx, func(c *conn) error {
+		before, err := getDirectoryForUpdate(ctx, c, d.Id)
+		if err != nil {
+			return err
+		}
+
+		if before.DeletedAt != nil {
+			return storage.ErrDirectoryNotFound
+		}
+
+		if before.Revision != revision {
+			return storage.ErrRevisionConflict
+		}
+
+		return updateDirectory(ctx, c, d, before)
+	})
+}
+
+// getDirectoryForUpdate locks the provided directory, deleted or not, and returns it.
+func getDirectoryForUpdate(ctx context.Context, c *conn, id v1.DirectoryID) (*v1.Directory, error) {
+	var d v1.Directory
+
+	row := c.QueryRowContext(ctx, "SELECT "+DirectoryColumns+" FROM directories WHERE id = $1"+c.dialect.ForUpdate(), id)
+	if err := scanDirectory(row, &d); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrDirectoryNotFound
+		}
+
+		return nil, fmt.Errorf("error querying directory: %w", err)
+	}
+
+	return &d, nil
+}
+
+// updateDirectory writes the name and metadata of the directory,
+// recording the change from the provided previous state.
+func updateDirectory(ctx context.Context, c *conn, d, before *v1.Directory) error {
+	md, err := metadataJSON(d.Metadata)
+	if err != nil {
+		return err
+	}
+
+	var after v1.Directory
+
+	row := c.QueryRowContext(ctx, `
+		UPDATE directories
+		SET
+			name = $1,
+			metadata = $2,
+			updated_at = `+c.dialect.Now()+`,
+			revision = revision + 1
+		WHERE id = $3
+		RETURNING `+DirectoryColumns+`
+	`, d.Name, md, d.Id)
+	if err := scanDirectory(row, &after); err != nil {
+		return c.writeError("error updating directory", err)
+	}
+
+	d.UpdatedAt = after.UpdatedAt
+	d.Revision = after.Revision
+
+	return recordRevision(ctx, c, v1.EventTypeUpdate, before, &after)
+}
+
+// DeleteDirectory soft deletes the provided directory id.
+// If the provided directory has children, all child directories are soft deleted as well.
+func (t *Driver) DeleteDirectory(ctx context.Context, id v1.DirectoryID) ([]*v1.Directory, error) {
+	return t.deleteDirectory(ctx, id, nil)
+}
+
+// DeleteDirectoryIfRevision soft deletes the provided directory id,
+// only if it is still at the provided revision.
+func (t *Driver) DeleteDirectoryIfRevision(
+	ctx context.Context,
+	id v1.DirectoryID,
+	revision int64,
+) ([]*v1.Directory, error) {
+	return t.deleteDirectory(ctx, id, &revision)
+}
+
+// deleteDirectory soft deletes the provided directory id along with its descendants,
+// checking its revision first if one is provided.
+func (t *Driver) deleteDirectory(ctx context.Context, id v1.DirectoryID, revision *int64) ([]*v1.Directory, error) {
+	if t.readOnly {
+		return nil, storage.ErrReadOnly
+	}
+
+	var affected []*v1.Directory
+
+	err := t.executeTx(ctx, func(c *conn) error {
+		if err := checkRevision(ctx, c, id, revision); err != nil {
+			return err
+		}
+
+		before, err := queryDirectories(ctx, c, `
+			WITH RECURSIVE get_children AS (
+				SELECT id, parent_id FROM directories
+				WHERE id = $1 AND deleted_at IS NULL AND parent_id IS NOT NULL
+
+				UNION
+
+				SELECT d.id, d.parent_id FROM directories d
+				INNER JOIN get_children gc ON d.parent_id = gc.id
+				WHERE d.deleted_at IS NULL
+			)
+			SELECT `+DirectoryColumns+` FROM directories
+			WHERE id IN (SELECT id FROM get_children)
+		`, id)
+		if err != nil {
+			return fmt.Errorf("error querying directory: %w", err)
+		}
+
+		// If no directories are found, there's nothing to delete.
+		if len(before) == 0 {
+			return storage.ErrDirectoryNotFound
+		}
+
+		affected, err = queryDirectories(ctx, c, `
+			UPDATE directories
+			SET
+				deleted_at = `+c.dialect.Now()+`,
+				revision = revision + 1
+			WHERE `+c.dialect.InIDList("id", "$1")+`
+			RETURNING `+DirectoryColumns+`
+		`, c.idList(before))
+		if err != nil {
+			return fmt.Errorf("error deleting directory: %w", err)
+		}
+
+		return recordRevisions(ctx, c, v1.EventTypeDelete, before, affected)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return affected, nil
+}
+
+// RestoreDirectory restores the provided soft deleted directory id.
+// Descendants which were deleted along with the directory are restored as well.
+func (t *Driver) RestoreDirectory(ctx context.Context, id v1.DirectoryID) ([]*v1.Directory, error) {
+	if t.readOnly {
+		return nil, storage.ErrReadOnly
+	}
+
+	var affected []*v1.Directory
+
+	err := t.executeTx(ctx, func(c *conn) error {
+		// The deletion times are kept as returned by the database,
+		// so they're compared as stored.
+		var (
+			deletedAt any
+			parent    *v1.DirectoryID
+		)
+
+		err := c.QueryRowContext(ctx,
+			"SELECT deleted_at, parent_id FROM directories WHERE id = $1"+c.dialect.ForUpdate(),
+			id).Scan(&deletedAt, &parent)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return storage.ErrDirectoryNotFound
+			}
+
+			return fmt.Errorf("error querying directory: %w", err)
+		}
+
+		if deletedAt == nil {
+			return storage.ErrDirectoryNotDeleted
+		}
+
+		if parent != nil {
+			var parentDeletedAt any
+
+			err = c.QueryRowContext(ctx,
+				"SELECT deleted_at FROM directories WHERE id = $1",
+				parent).Scan(&parentDeletedAt)
+			if err != nil {
+				return fmt.Errorf("error querying parent directory: %w", err)
+			}
+
+			if parentDeletedAt != nil {
+				return storage.ErrParentDirectoryDeleted
+			}
+		}
+
+		affected, err = restoreDirectoryTree(ctx, c, id, deletedAt)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return affected, nil
+}
+
+// restoreDirectoryTree restores the provided directory and the descendants
+// which were deleted at the same time.
+func restoreDirectoryTree(ctx context.Context, c *conn, id v1.DirectoryID, deletedAt any) ([]*v1.Directory, error) {
+	before, err := queryDirectories(ctx, c, `
+		WITH RECURSIVE get_children AS (
+			SELECT id, parent_id FROM directories
+			WHERE id = $1
+
+			UNION
+
+			SELECT d.id, d.parent_id FROM directories d
+			INNER JOIN get_children gc ON d.parent_id = gc.id
+			WHERE d.deleted_at = $2
+		)
+		SELECT `+DirectoryColumns+` FROM directories
+		WHERE id IN (SELECT id FROM get_children)
+	`, id, deletedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error querying directory: %w", err)
+	}
+
+	affected, err := queryDirectories(ctx, c, `
+		UPDATE directories
+		SET
+			deleted_at = NULL,
+			updated_at = `+c.dialect.Now()+`,
+			revision = revision + 1
+		WHERE `+c.dialect.InIDList("id", "$1")+`
+		RETURNING `+DirectoryColumns+`
+	`, c.idList(before))
+	if err != nil {
+		return nil, c.writeError("error restoring directory", err)
+	}
+
+	if err := recordRevisions(ctx, c, v1.EventTypeRestore, before, affected); err != nil {
+		return nil, err
+	}
+
+	return affected, nil
+}
+
+// PurgeDirectory permanently removes the provided soft deleted directory id.
+// All descendants of the directory are removed as well.
+func (t *Driver) PurgeDirectory(ctx context.Context, id v1.DirectoryID) ([]*v1.Directory, error) {
+	if t.readOnly {
+		return nil, storage.ErrReadOnly
+	}
+
+	var affected []*v1.Directory
+
+	err := t.executeTx(ctx, func(c *conn) error {
+		var deletedAt any
+
+		err := c.QueryRowContext(ctx,
+			"SELECT deleted_at FROM directories WHERE id = $1"+c.dialect.ForUpdate(),
+			id).Scan(&deletedAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return storage.ErrDirectoryNotFound
+			}
+
+			return fmt.Errorf("error querying directory: %w", err)
+		}
+
+		if deletedAt == nil {
+			return storage.ErrDirectoryNotDeleted
+		}
+
+		affected, err = purgeDirectoryTree(ctx, c, id)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return affected, nil
+}
+
+// purgeDirectoryTree removes the provided directory and all of its descendants,
+// unless one of them isn't deleted. The descendants are read beforehand, as the
+// rows removed by the cascade aren't returned by the DELETE statement on every database.
+func purgeDirectoryTree(ctx context.Context, c *conn, id v1.DirectoryID) ([]*v1.Directory, error) {
+	affected, err := queryDirectories(ctx, c, `
+		WITH RECURSIVE get_children AS (
+			SELECT id, parent_id FROM directories
+			WHERE id = $1
+
+			UNION
+
+			SELECT d.id, d.parent_id FROM directories d
+			INNER JOIN get_children gc ON d.parent_id = gc.id
+		)
+		SELECT `+DirectoryColumns+` FROM directories
+		WHERE id IN (SELECT id FROM get_children)
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying directory: %w", err)
+	}
+
+	for _, d := range affected {
+		if d.DeletedAt == nil {
+			return nil, storage.ErrDescendantNotDeleted
+		}
+	}
+
+	if _, err := c.ExecContext(ctx, "DELETE FROM directories WHERE "+c.dialect.InIDList("id", "$1"),
+		c.idList(affected)); err != nil {
+		return nil, fmt.Errorf("error deleting directory: %w", err)
+	}
+
+	return affected, nil
+}
+
+// MoveDirectory moves the provided non-root directory under the given parent.
+// Root directories must be demoted explicitly with DemoteRoot.
+func (t *Driver) MoveDirectory(
+	ctx context.Context,
+	id, parent v1.DirectoryID,
+) (*v1.Directory, *v1.DirectoryID, error) {
+	return t.moveDirectory(ctx, id, parent, nil)
+}
+
+// MoveDirectoryIfRevision moves the provided non-root directory under the given parent,
+// only if it is still at the provided revision.
+func (t *Driver) MoveDirectoryIfRevision(
+	ctx context.Context,
+	id, parent v1.DirectoryID,
+	revision int64,
+) (*v1.Directory, *v1.DirectoryID, error) {
+	return t.moveDirectory(ctx, id, parent, &revision)
+}
+
+// moveDirectory moves the provided non-root directory under the given parent,
+// checking its revision first if one is provided.
+func (t *Driver) moveDirectory(
+	ctx context.Context,
+	id, parent v1.DirectoryID,
+	revision *int64,
+) (*v1.Directory, *v1.DirectoryID, error) {
+	if t.readOnly {
+		return nil, nil, storage.ErrReadOnly
+	}
+
+	var (
+		moved     *v1.Directory
+		oldParent *v1.DirectoryID
+	)
+
+	err := t.executeTx(ctx, func(c *conn) error {
+		if err := checkRevision(ctx, c, id, revision); err != nil {
+			return err
+		}
+
+		var err error
+
+		oldParent, err = getParentForUpdate(ctx, c, id)
+		if err != nil {
+			return err
+		}
+
+		if oldParent == nil {
+			return storage.ErrRootDirectoryMove
+		}
+
+		if err = checkMoveDestination(ctx, c, id, parent); err != nil {
+			return err
+		}
+
+		moved, err = setParent(ctx, c, id, &parent)
+
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return moved, oldParent, nil
+}
+
+// PromoteToRoot detaches the provided directory from its parent,
+// making it a root directory.
+func (t *Driver) PromoteToRoot(ctx context.Context, id v1.DirectoryID) (*v1.Directory, *v1.DirectoryID, error) {
+	return t.promoteToRoot(ctx, id, nil)
+}
+
+// PromoteToRootIfRevision detaches the provided directory from its parent,
+// only if it is still at the provided revision.
+func (t *Driver) PromoteToRootIfRevision(
+	ctx context.Context,
+	id v1.DirectoryID,
+	revision int64,
+) (*v1.Directory, *v1.DirectoryID, error) {
+	return t.promoteToRoot(ctx, id, &revision)
+}
+
+// promoteToRoot detaches the provided directory from its parent,
+// checking its revision first if one is provided.
+func (t *Driver) promoteToRoot(
+	ctx context.Context,
+	id v1.DirectoryID,
+	revision *int64,
+) (*v1.Directory, *v1.DirectoryID, error) {
+	if t.readOnly {
+		return nil, nil, storage.ErrReadOnly
+	}
+
+	var (
+		promoted  *v1.Directory
+		oldParent *v1.DirectoryID
+	)
+
+	err := t.executeTx(ctx, func(c *conn) error {
+		if err := checkRevision(ctx, c, id, revision); err != nil {
+			return err
+		}
+
+		var err error
+
+		oldParent, err = getParentForUpdate(ctx, c, id)
+		if err != nil {
+			return err
+		}
+
+		if oldParent == nil {
+			return storage.ErrAlreadyRoot
+		}
+
+		promoted, err = setParent(ctx, c, id, nil)
+
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return promoted, oldParent, nil
+}
+
+// DemoteRoot moves the provided root directory under the given parent.
+// Its quota overrides are dropped, as it stops being a root.
+func (t *Driver) DemoteRoot(ctx context.Context, id, parent v1.DirectoryID) (*v1.Directory, error) {
+	return t.demoteRoot(ctx, id, parent, nil)
+}
+
+// DemoteRootIfRevision moves the provided root directory under the given parent,
+// only if it is still at the provided revision.
+func (t *Driver) DemoteRootIfRevision(
+	ctx context.Context,
+	id, parent v1.DirectoryID,
+	revision int64,
+) (*v1.Directory, error) {
+	return t.demoteRoot(ctx, id, parent, &revision)
+}
+
+// demoteRoot moves the provided root directory under the given parent,
+// checking its revision first if one is provided.
+func (t *Driver) demoteRoot(
+	ctx context.Context,
+	id, parent v1.DirectoryID,
+	revision *int64,
+) (*v1.Directory, error) {
+	if t.readOnly {
+		return nil, storage.ErrReadOnly
+	}
+
+	var demoted *v1.Directory
+
+	err := t.executeTx(ctx, func(c *conn) error {
+		if err := checkRevision(ctx, c, id, revision); err != nil {
+			return err
+		}
+
+		oldParent, err := getParentForUpdate(ctx, c, id)
+		if err != nil {
+			return err
+		}
+
+		if oldParent != nil {
+			return storage.ErrNotRoot
+		}
+
+		if err = checkMoveDestination(ctx, c, id, parent); err != nil {
+			return err
+		}
+
+		if err = deleteQuota(ctx, c, id); err != nil {
+			return err
+		}
+
+		demoted, err = setParent(ctx, c, id, &parent)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return demoted, nil
+}
+
+// checkRevision locks the provided live directory and ensures it is still
+// at the provided revision, if any.
+func checkRevision(ctx context.Context, c *conn, id v1.DirectoryID, revision *int64) error {
+	if revision == nil {
+		return nil
+	}
+
+	var current int64
+
+	err := c.QueryRowContext(ctx,
+		"SELECT revision FROM directories WHERE id = $1 AND deleted_at IS NULL"+c.dialect.ForUpdate(),
+		id).Scan(&current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrDirectoryNotFound
+		}
+
+		return fmt.Errorf("error querying directory: %w", err)
+	}
+
+	if current != *revision {
+		return storage.ErrRevisionConflict
+	}
+
+	return nil
+}
+
+// getParentForUpdate locks the provided live directory and returns its parent id.
+func getParentForUpdate(ctx context.Context, c *conn, id v1.DirectoryID) (*v1.DirectoryID, error) {
+	var parent *v1.DirectoryID
+
+	err := c.QueryRowContext(ctx,
+		"SELECT parent_id FROM directories WHERE id = $1 AND deleted_at IS NULL"+c.dialect.ForUpdate(),
+		id).Scan(&parent)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrDirectoryNotFound
+		}
+
+		return nil, fmt.Errorf("error querying directory: %w", err)
+	}
+
+	return parent, nil
+}
+
+// checkMoveDestination ensures the destination parent exists and is not
+// the moved directory itself or one of its descendants.
+func checkMoveDestination(ctx context.Context, c *conn, id, parent v1.DirectoryID) error {
+	rows, err := c.QueryContext(ctx, `
+		WITH RECURSIVE get_parents AS (
+			SELECT id, parent_id FROM directories
+			WHERE id = $1 AND deleted_at IS NULL
+
+			UNION
+
+			SELECT d.id, d.parent_id FROM directories d
+			INNER JOIN get_parents gp ON d.id = gp.parent_id
+		)
+		SELECT id FROM get_parents
+	`, parent)
+	if err != nil {
+		return fmt.Errorf("error querying directory: %w", err)
+	}
+	defer rows.Close()
+
+	var found bool
+
+	for rows.Next() {
+		var did v1.DirectoryID
+		if err := rows.Scan(&did); err != nil {
+			return fmt.Errorf("error scanning directory: %w", err)
+		}
+
+		if did == id {
+			return storage.ErrMoveCycle
+		}
+
+		found = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error querying directory: %w", err)
+	}
+
+	if !found {
+		return storage.ErrDirectoryNotFound
+	}
+
+	return nil
+}
+
+// setParent updates the parent of the provided directory, returning the updated directory.
+// A directory moved under a parent takes the parent's unique sibling names setting,
+// which is applied to its descendants as well.
+func setParent(ctx context.Context, c *conn, id v1.DirectoryID, parent *v1.DirectoryID) (*v1.Directory, error) {
+	before, err := getDirectoryForUpdate(ctx, c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var d v1.Directory
+
+	row := c.QueryRowContext(ctx, `
+		UPDATE directories
+		SET
+			parent_id = $1,
+			unique_sibling_names = COALESCE(
+				(SELECT p.unique_sibling_names FROM directories p WHERE p.id = $1),
+				unique_sibling_names
+			),
+			updated_at = `+c.dialect.Now()+`,
+			revision = revision + 1
+		WHERE id = $2
+		RETURNING `+DirectoryColumns+`
+	`, parent, id)
+	if err := scanDirectory(row, &d); err != nil {
+		return nil, c.writeError("error updating directory", err)
+	}
+
+	if err := recordRevision(ctx, c, v1.EventTypeMove, before, &d); err != nil {
+		return nil, err
+	}
+
+	_, err = c.ExecContext(ctx, `
+		WITH RECURSIVE get_children AS (
+			SELECT id FROM directories
+			WHERE parent_id = $1
+
+			UNION
+
+			SELECT d.id FROM directories d
+			INNER JOIN get_children gc ON d.parent_id = gc.id
+		)
+		UPDATE directories
+		SET unique_sibling_names = $2
+		WHERE id IN (SELECT id FROM get_children) AND unique_sibling_names != $2
+	`, id, d.UniqueSiblingNames)
+	if err != nil {
+		return nil, c.writeError("error updating child directories", err)
+	}
+
+	return &d, nil
+}
+
+// GetDirectoryByID returns a directory by its ID.
+// Note that this call does not give out parent information.
+func (t *Driver) GetDirectory(
+	ctx context.Context,
+	id v1.DirectoryID,
+	options ...storage.Option,
+) (*v1.Directory, error) {
+	var d v1.Directory
+
+	opts := storage.BuildOptions(options)
+
+	withDeleted := "false"
+
+	if opts.WithDeletedDirectories {
+		withDeleted = "true"
+	}
+
+	q := t.formatQuery(`SELECT `+DirectoryColumns+` FROM %[1]s d %[2]s
+WHERE id = $1 AND (`+withDeleted+` OR deleted_at IS NULL)`, opts)
+
+	err := scanDirectory(t.conn().QueryRowContext(ctx, q, id), &d)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrDirectoryNotFound
+		}
+		return nil, t.readError("error querying directory", err)
+	}
+
+	return &d, nil
+}
+
+// GetDirectories returns the directories with the provided ids in a single query.
+// Directories are returned in the order of the ids, and the ids which
+// don't match any directory are skipped.
+func (t *Driver) GetDirectories(
+	ctx context.Context,
+	ids []v1.DirectoryID,
+	options ...storage.Option,
+) ([]*v1.Directory, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	opts := storage.BuildOptions(options)
+
+	withDeleted := "false"
+
+	if opts.WithDeletedDirectories {
+		withDeleted = "true"
+	}
+
+	q := t.formatQuery(`SELECT `+DirectoryColumns+` FROM %[1]s d %[2]s
+WHERE `+t.dialect.InIDList("id", "$1")+` AND (`+withDeleted+` OR deleted_at IS NULL)`, opts)
+
+	rows, err := t.conn().QueryContext(ctx, q, t.dialect.IDList(ids))
+	if err != nil {
+		return nil, t.readError("error querying directories", err)
+	}
+	defer rows.Close()
+
+	found := make(map[v1.DirectoryID]*v1.Directory, len(ids))
+
+	for rows.Next() {
+		var d v1.Directory
+
+		if err := scanDirectory(rows, &d); err != nil {
+			return nil, fmt.Errorf("error scanning directory: %w", err)
+		}
+
+		found[d.Id] = &d
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, t.readError("error querying directories", err)
+	}
+
+	dirs := make([]*v1.Directory, 0, len(found))
+
+	for _, id := range ids {
+		if d, ok := found[id]; ok {
+			dirs = append(dirs, d)
+			// Each directory is only returned once, even if requested multiple times.
+			delete(found, id)
+		}
+	}
+
+	return dirs, nil
+}
+
+// GetParents returns all ids for each parent directory of the provided child id.
+// Parents are returned from the closest to the furthest ancestor.
+func (t *Driver) GetParents(
+	ctx context.Context,
+	child v1.DirectoryID,
+	options ...storage.Option,
+) ([]v1.DirectoryID, error) {
+	return t.getParents(ctx, child, nil, storage.BuildOptions(options))
+}
+
+// GetParentsUntilAncestor returns all ids for each parent directory for the
+// provided child until the provided ancestor is reached.
+func (t *Driver) GetParentsUntilAncestor(
+	ctx context.Context,
+	child, ancestor v1.DirectoryID,
+	options ...storage.Option,
+) ([]v1.DirectoryID, error) {
+	// optimization: we don't need to go through the database
+	// if the child is the ancestor
+	if child == ancestor {
+		return []v1.DirectoryID{}, nil
+	}
+
+	return t.getParents(ctx, child, &ancestor, storage.BuildOptions(options))
+}
+
+// getParents walks up the tree from the provided child, or from the cursor's
+// directory if one is set, stopping once the ancestor is reached if provided.
+// The walk stops at the end of the requested page, so only the ancestors
+// up to that point are visited.
+func (t *Driver) getParents(
+	ctx context.Context,
+	child v1.DirectoryID,
+	ancestor *v1.DirectoryID,
+	opts *storage.Options,
+) ([]v1.DirectoryID, error) {
+	var parents []v1.DirectoryID
+
+	withDeleted := "false"
+
+	if opts.WithDeletedDirectories {
+		withDeleted = "true"
+	}
+
+	start := child
+
+	if opts.Cursor != nil {
+		// The cursor's directory must be one of the directories walked through.
+		if _, err := t.depthBelow(ctx, child, opts.Cursor.ID, ancestor, opts); err != nil {
+			return nil, err
+		}
+
+		start = opts.Cursor.ID
+	}
+
+	args := []any{start, opts.GetPageOffset(), opts.GetPageOffset() + opts.GetPageSize()}
+
+	var until string
+
+	if ancestor != nil {
+		until = " AND gp.id != $4"
+
+		args = append(args, *ancestor)
+	}
+
+	q := t.formatQuery(`
+		WITH RECURSIVE get_parents AS (
+			SELECT id, parent_id, 0 AS depth FROM %[1]s d
+			WHERE id = $1 AND (`+withDeleted+` OR deleted_at IS NULL)
+
+			UNION
+
+			SELECT d.id, d.parent_id, gp.depth + 1 FROM %[1]s d
+			INNER JOIN get_parents gp ON d.id = gp.parent_id
+			WHERE gp.depth < $3`+until+` AND (`+withDeleted+` OR d.deleted_at IS NULL)
+		)
+		SELECT id FROM get_parents %[2]s
+		WHERE depth = 0 OR depth > $2
+		ORDER BY depth ASC
+	`, opts)
+
+	rows, err := t.conn().QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, t.readError("error querying directory", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var did v1.DirectoryID
+		err := rows.Scan(&did)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning directory: %w", err)
+		}
+		parents = append(parents, did)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, t.readError("error querying directory", err)
+	}
+
+	if len(parents) == 0 {
+		return nil, storage.ErrDirectoryNotFound
+	}
+
+	// Skip the first object which is where the walk started.
+	return parents[1:], nil
+}
+
+// GetChildren returns the descendants of the provided directory breadth first,
+// each level ordered by creation. Levels are read one query at a time, each
+// recursing no deeper than its own level, until the page is full. Listing a
+// page thus only walks the levels above its last directory, not the whole subtree.
+func (t *Driver) GetChildren(
+	ctx context.Context,
+	parent v1.DirectoryID,
+	options ...storage.Option,
+) ([]v1.DirectoryID, error) {
+	var children []v1.DirectoryID
+
+	opts := storage.BuildOptions(options)
+
+	depth := 1
+
+	if opts.Cursor != nil {
+		var err error
+
+		depth, err = t.cursorDepth(ctx, parent, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	size, offset := opts.GetPageSize(), opts.GetPageOffset()
+
+	for ; opts.MaxDepth <= 0 || depth <= opts.MaxDepth; depth++ {
+		level, err := t.getChildrenLevel(ctx, parent, depth, size-len(children), offset, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		children = append(children, level.ids...)
+
+		// The offset skips the directories of the levels above.
+		offset -= level.matched
+		if offset < 0 {
+			offset = 0
+		}
+
+		if !level.more || len(children) == size {
+			break
+		}
+
+		// The cursor only applies to its own level.
+		opts.Cursor = nil
+	}
+
+	// An empty page may also mean the parent doesn't exist.
+	if len(children) == 0 {
+		if _, err := t.GetDirectory(ctx, parent, options...); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	return children, nil
+}
+
+// CountChildren returns the count of descendants of the provided directory,
+// without reading them. The subtree is walked down to the requested depth.
+func (t *Driver) CountChildren(
+	ctx context.Context,
+	parent v1.DirectoryID,
+	options ...storage.Option,
+) (int, error) {
+	var count int
+
+	opts := storage.BuildOptions(options)
+
+	withDeleted := "false"
+
+	if opts.WithDeletedDirectories {
+		withDeleted = "true"
+	}
+
+	// The parent id is the first argument.
+	selector, args := t.selectorCondition(opts.Selector, 2) //nolint:gomnd // see above
+
+	var depthLimit string
+
+	if opts.MaxDepth > 0 {
+		depthLimit = " AND gc.depth < " + strconv.Itoa(opts.MaxDepth)
+	}
+
+	q := t.formatQuery(`
+		WITH RECURSIVE get_children AS (
+			SELECT id, metadata, 0 AS depth FROM %[1]s d
+			WHERE id = $1 AND (`+withDeleted+` OR deleted_at IS NULL)
+
+			UNION
+
+			SELECT d.id, d.metadata, gc.depth + 1 FROM %[1]s d
+			INNER JOIN get_children gc ON d.parent_id = gc.id
+			WHERE (`+withDeleted+` OR d.deleted_at IS NULL)`+depthLimit+`
+		)
+		SELECT COUNT(*) FROM get_children %[2]s
+		WHERE id != $1`+selector, opts)
+
+	err := t.conn().QueryRowContext(ctx, q, append([]any{parent}, args...)...).Scan(&count)
+	if err != nil {
+		return 0, t.readError("error counting directories", err)
+	}
+
+	// No descendants may also mean the parent doesn't exist.
+	if count == 0 {
+		if _, err := t.GetDirectory(ctx, parent, options...); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+// childrenLevel is a page of the descendants at a given depth.
+type childrenLevel struct {
+	// ids are the ids of the directories in the page.
+	ids []v1.DirectoryID
+	// matched is the count of directories of the level matching the listing,
+	// whether or not they're in the page.
+	matched int
+	// more is true if the level has any directory, in which case
+	// the next level may have some too.
+	more bool
+}
+
+// getChildrenLevel returns a page of the descendants of the parent at the provided depth,
+// starting after the cursor if any. The recursion stops at that depth.
+func (t *Driver) getChildrenLevel(
+	ctx context.Context,
+	parent v1.DirectoryID,
+	depth, limit, offset int,
+	opts *storage.Options,
+) (*childrenLevel, error) {
+	withDeleted := "false"
+
+	if opts.WithDeletedDirectories {
+		withDeleted = "true"
+	}
+
+	// The parent id and the depth are the first arguments.
+	keyset, args := t.keysetCondition(opts, 3)                                //nolint:gomnd // see above
+	selector, selectorArgs := t.selectorCondition(opts.Selector, len(args)+3) //nolint:gomnd // see above
+
+	// The page is left joined to a single row, so the counts are
+	// returned even when the page is empty.
+	q := t.formatQuery(`
+		WITH RECURSIVE get_children AS (
+			SELECT id, created_at, metadata, 0 AS depth FROM %[1]s d
+			WHERE id = $1 AND (`+withDeleted+` OR deleted_at IS NULL)
+
+			UNION
+
+			SELECT d.id, d.created_at, d.metadata, gc.depth + 1 FROM %[1]s d
+			INNER JOIN get_children gc ON d.parent_id = gc.id
+			WHERE (`+withDeleted+` OR d.deleted_at IS NULL) AND gc.depth < $2
+		), children_level AS (
+			SELECT id, created_at FROM get_children
+			WHERE depth = $2`+keyset+selector+`
+		)
+		SELECT p.id,
+			(SELECT COUNT(*) FROM children_level),
+			EXISTS (SELECT 1 FROM get_children WHERE depth = $2)
+		FROM (SELECT 1) AS one
+		LEFT JOIN (
+			SELECT id, created_at FROM children_level
+			ORDER BY created_at ASC, id ASC
+			LIMIT `+strconv.Itoa(limit)+` OFFSET `+strconv.Itoa(offset)+`
+		) AS p ON true %[2]s
+		ORDER BY p.created_at ASC, p.id ASC
+	`, opts)
+
+	args = append([]any{parent, depth}, append(args, selectorArgs...)...)
+
+	rows, err := t.conn().QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, t.readError("error querying directory", err)
+	}
+	defer rows.Close()
+
+	level := &childrenLevel{}
+
+	for rows.Next() {
+		var did *v1.DirectoryID
+
+		if err := rows.Scan(&did, &level.matched, &level.more); err != nil {
+			return nil, fmt.Errorf("error scanning directory: %w", err)
+		}
+
+		if did != nil {
+			level.ids = append(level.ids, *did)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, t.readError("error scanning directory", err)
+	}
+
+	return level, nil
+}
+
+// cursorDepth returns the depth of the cursor's directory below the parent,
+// walking up from it. Deleted directories are walked through, as the cursor's
+// directory may have been deleted since it was listed.
+// ErrInvalidCursor is returned if the directory isn't one of its descendants.
+func (t *Driver) cursorDepth(ctx context.Context, parent v1.DirectoryID, opts *storage.Options) (int, error) {
+	return t.depthBelow(ctx, opts.Cursor.ID, parent, nil, opts)
+}
+
+// depthBelow returns the depth of the directory below the ancestor, walking up
+// from it, and stopping at until if provided. Deleted directories are walked
+// through. ErrInvalidCursor is returned if the ancestor isn't reached, as one
+// of the two is always the directory of a cursor.
+func (t *Driver) depthBelow(
+	ctx context.Context,
+	id, ancestor v1.DirectoryID,
+	until *v1.DirectoryID,
+	opts *storage.Options,
+) (int, error) {
+	args := []any{id, ancestor}
+
+	var stop string
+
+	if until != nil {
+		stop = " AND gp.id != $3"
+
+		args = append(args, *until)
+	}
+
+	q := t.formatQuery(`
+		WITH RECURSIVE get_parents AS (
+			SELECT id, parent_id, 0 AS depth FROM %[1]s d
+			WHERE id = $1
+
+			UNION
+
+			SELECT d.id, d.parent_id, gp.depth + 1 FROM %[1]s d
+			INNER JOIN get_parents gp ON d.id = gp.parent_id
+			WHERE gp.id != $2`+stop+`
+		)
+		SELECT depth FROM get_parents %[2]s
+		WHERE id = $2
+	`, opts)
+
+	var depth int
+
+	err := t.conn().QueryRowContext(ctx, q, args...).Scan(&depth)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrInvalidCursor
+	} else if err != nil {
+		return 0, t.readError("error querying directory", err)
+	}
+
+	return depth, nil
+}
+
+// GetSubtree returns the provided directory along with its descendants as a nested tree.
+// The whole subtree, up to the requested depth, is fetched in a single query.
+// One directory more than allowed is requested, to tell whether the subtree is too large.
+func (t *Driver) GetSubtree(
+	ctx context.Context,
+	id v1.DirectoryID,
+	options ...storage.Option,
+) (*v1.DirectoryTree, error) {
+	opts := storage.BuildOptions(options)
+
+	withDeleted := "false"
+
+	if opts.WithDeletedDirectories {
+		withDeleted = "true"
+	}
+
+	var depthLimit string
+
+	if opts.MaxDepth > 0 {
+		depthLimit = " AND gs.depth < " + strconv.Itoa(opts.MaxDepth)
+	}
+
+	q := t.formatQuery(`
+		WITH RECURSIVE get_subtree AS (
+			SELECT id, 0 AS depth FROM %[1]s d
+			WHERE id = $1 AND (`+withDeleted+` OR deleted_at IS NULL)
+
+			UNION
+
+			SELECT d.id, gs.depth + 1 FROM %[1]s d
+			INNER JOIN get_subtree gs ON d.parent_id = gs.id
+			WHERE (`+withDeleted+` OR d.deleted_at IS NULL)`+depthLimit+`
+		)
+		SELECT `+qualifiedDirectoryColumns("d")+` FROM get_subtree gs
+		INNER JOIN %[1]s d ON d.id = gs.id %[2]s
+		ORDER BY gs.depth ASC, d.created_at ASC, d.id ASC
+		LIMIT $2
+	`, opts)
+
+	rows, err := t.conn().QueryContext(ctx, q, id, opts.GetMaxNodes()+1)
+	if err != nil {
+		return nil, t.readError("error querying directory", err)
+	}
+	defer rows.Close()
+
+	var dirs []*v1.Directory
+
+	for rows.Next() {
+		var d v1.Directory
+
+		if err := scanDirectory(rows, &d); err != nil {
+			return nil, fmt.Errorf("error scanning directory: %w", err)
+		}
+
+		dirs = append(dirs, &d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, t.readError("error querying directory", err)
+	}
+
+	if len(dirs) == 0 {
+		return nil, storage.ErrDirectoryNotFound
+	}
+
+	if len(dirs) > opts.GetMaxNodes() {
+		return nil, storage.ErrSubtreeTooLarge
+	}
+
+	// The directory itself always comes first, as it is the only one at depth 0.
+	return v1.NewDirectoryTree(dirs[0], dirs[1:]), nil
+}
+
+// GetStats gets the statistics of the subtree of a directory.
+// Soft deleted descendants are always walked, so they're counted as well.
+func (t *Driver) GetStats(
+	ctx context.Context,
+	id v1.DirectoryID,
+	options ...storage.Option,
+) (*v1.DirectoryStats, error) {
+	opts := storage.BuildOptions(options)
+
+	withDeleted := "false"
+
+	if opts.WithDeletedDirectories {
+		withDeleted = "true"
+	}
+
+	q := t.formatQuery(`
+		WITH RECURSIVE get_subtree AS (
+			SELECT id, 0 AS depth, updated_at, deleted_at FROM %[1]s d
+			WHERE id = $1 AND (`+withDeleted+` OR deleted_at IS NULL)
+
+			UNION
+
+			SELECT d.id, gs.depth + 1, d.updated_at, d.deleted_at FROM %[1]s d
+			INNER JOIN get_subtree gs ON d.parent_id = gs.id
+		)
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN depth = 1 AND deleted_at IS NULL THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN depth > 0 AND deleted_at IS NULL THEN 1 ELSE 0 END), 0),
+			COALESCE(MAX(CASE WHEN deleted_at IS NULL THEN depth END), 0),
+			MAX(updated_at),
+			MAX(deleted_at)
+		FROM get_subtree %[2]s
+	`, opts)
+
+	var (
+		count                int
+		updatedAt, deletedAt *time.Time
+	)
+
+	stats := &v1.DirectoryStats{Id: id}
+
+	err := t.conn().QueryRowContext(ctx, q, id).Scan(&count, &stats.Children, &stats.LiveDescendants,
+		&stats.MaxDepth, nullTimestamp{&updatedAt}, nullTimestamp{&deletedAt})
+	if err != nil {
+		return nil, t.readError("error querying directory stats", err)
+	}
+
+	// The directory itself is always counted, unless it wasn't found.
+	if count == 0 {
+		return nil, storage.ErrDirectoryNotFound
+	}
+
+	stats.Descendants = count - 1
+	stats.DeletedDescendants = stats.Descendants - stats.LiveDescendants
+	stats.LastModified = *updatedAt
+
+	if deletedAt != nil && deletedAt.After(*updatedAt) {
+		stats.LastModified = *deletedAt
+	}
+
+	return stats, nil
+}
+
+// keysetCondition returns the condition skipping the rows up to the cursor, if any.
+// Its arguments are numbered starting at argPos.
+func (t *Driver) keysetCondition(opts *storage.Options, argPos int) (string, []any) {
+	if opts.Cursor == nil {
+		return "", nil
+	}
+
+	cond := fmt.Sprintf(" AND (created_at, id) > ($%d, $%d)", argPos, argPos+1)
+
+	return cond, []any{t.dialect.Timestamp(opts.Cursor.CreatedAt), opts.Cursor.ID}
+}
+
+// selectorCondition returns the condition only keeping the directories whose
+// metadata matches the selector, if any. Its arguments are numbered starting at argPos.
+func (t *Driver) selectorCondition(sel storage.Selector, argPos int) (string, []any) {
+	var (
+		cond strings.Builder
+		args []any
+	)
+
+	arg := func(v any) string {
+		args = append(args, v)
+
+		return "$" + strconv.Itoa(argPos+len(args)-1)
+	}
+
+	for _, req := range sel {
+		cond.WriteString(" AND " + t.dialect.MatchSelector(req, arg))
+	}
+
+	return cond.String(), args
+}
+
+// pageClause returns the LIMIT and OFFSET clause for the requested page.
+func pageClause(opts *storage.Options) string {
+	return "LIMIT " + strconv.Itoa(opts.GetPageSize()) + " OFFSET " + strconv.Itoa(opts.GetPageOffset())
+}
+
+// formatQuery sets the source of the directories read by the query, as %[1]s,
+// and the clause ending its FROM clause, as %[2]s. They depend on the point in
+// time the directories are read at, and on whether fast reads are enabled.
+func (t *Driver) formatQuery(query string, opts *storage.Options) string {
+	source, clause := t.dialect.ReadFrom(opts.AsOf, t.fastReads)
+
+	return fmt.Sprintf(query, source, clause)
+}
@@ -0,0 +1,49 @@
+package sqldriver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// ListDeleted lists the directories soft deleted before the provided time,
+// ordered by deletion time and id. It never does fast reads, as the listed
+// directories are usually purged right after.
+func (t *Driver) ListDeleted(
+	ctx context.Context,
+	before time.Time,
+	options ...storage.Option,
+) ([]*v1.Directory, error) {
+	opts := storage.BuildOptions(options)
+
+	rows, err := t.conn().QueryContext(ctx, `
+		SELECT `+DirectoryColumns+` FROM directories
+		WHERE deleted_at IS NOT NULL AND deleted_at < $1
+		ORDER BY deleted_at ASC, id ASC
+	`+pageClause(opts), t.dialect.Timestamp(before))
+	if err != nil {
+		return nil, fmt.Errorf("error listing deleted directories: %w", err)
+	}
+	defer rows.Close()
+
+	var dirs []*v1.Directory
+
+	for rows.Next() {
+		var d v1.Directory
+
+		if err := scanDirectory(rows, &d); err != nil {
+			return nil, fmt.Errorf("error scanning directory: %w", err)
+		}
+
+		dirs = append(dirs, &d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing deleted directories: %w", err)
+	}
+
+	return dirs, nil
+}
@@ -0,0 +1,165 @@
+package sqldriver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// recordRevision records a change of the directory in its history.
+// before is the directory as it was before the change, nil if it was created.
+func recordRevision(ctx context.Context, c *conn, op v1.EventType, before, after *v1.Directory) error {
+	// A nil value is stored as NULL.
+	var beforeJSON any
+
+	if before != nil {
+		b, err := json.Marshal(before)
+		if err != nil {
+			return fmt.Errorf("error encoding directory: %w", err)
+		}
+
+		beforeJSON = string(b)
+	}
+
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return fmt.Errorf("error encoding directory: %w", err)
+	}
+
+	_, err = c.ExecContext(ctx, `
+		INSERT INTO directory_revisions (id, directory_id, revision, operation, actor, created_at, before, after)
+		VALUES ($1, $2, $3, $4, $5, `+c.dialect.Now()+`, $6, $7)
+	`, uuid.New().String(), after.Id, after.Revision, string(op), storage.ActorFromContext(ctx),
+		beforeJSON, string(afterJSON))
+	if err != nil {
+		return fmt.Errorf("error recording directory revision: %w", err)
+	}
+
+	return nil
+}
+
+// recordRevisions records the changes of several directories in their history.
+// The directories before the change are matched by id with the changed ones.
+func recordRevisions(ctx context.Context, c *conn, op v1.EventType, before, after []*v1.Directory) error {
+	byID := make(map[v1.DirectoryID]*v1.Directory, len(before))
+
+	for _, d := range before {
+		byID[d.Id] = d
+	}
+
+	for _, d := range after {
+		if err := recordRevision(ctx, c, op, byID[d.Id], d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryDirectories runs a query returning the DirectoryColumns and scans the resulting directories.
+func queryDirectories(ctx context.Context, c *conn, query string, args ...any) ([]*v1.Directory, error) {
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dirs []*v1.Directory
+
+	for rows.Next() {
+		var d v1.Directory
+
+		if err := scanDirectory(rows, &d); err != nil {
+			return nil, err
+		}
+
+		dirs = append(dirs, &d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dirs, nil
+}
+
+// idList returns the ids of the directories as the dialect's id list argument.
+func (c *conn) idList(dirs []*v1.Directory) any {
+	ids := make([]v1.DirectoryID, len(dirs))
+
+	for i, d := range dirs {
+		ids[i] = d.Id
+	}
+
+	return c.dialect.IDList(ids)
+}
+
+// GetHistory returns the revisions recorded for the directory, newest first.
+// Only the revisions recorded by the requested point in time are returned, if any.
+func (t *Driver) GetHistory(
+	ctx context.Context,
+	id v1.DirectoryID,
+	options ...storage.Option,
+) ([]*v1.DirectoryRevision, error) {
+	opts := storage.BuildOptions(options)
+
+	args := []any{id}
+
+	var asOf string
+
+	if !opts.AsOf.IsZero() {
+		asOf = " AND created_at <= $2"
+
+		args = append(args, t.dialect.Timestamp(opts.AsOf))
+	}
+
+	q := t.formatQuery(`
+		SELECT directory_id, revision, operation, actor, created_at, before, after
+		FROM directory_revisions %[2]s
+		WHERE directory_id = $1`+asOf+`
+		ORDER BY revision DESC, created_at DESC
+	`+pageClause(opts), opts)
+
+	rows, err := t.conn().QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, t.readError("error querying directory history", err)
+	}
+	defer rows.Close()
+
+	var revisions []*v1.DirectoryRevision
+
+	for rows.Next() {
+		var (
+			r             v1.DirectoryRevision
+			before, after []byte
+		)
+
+		err := rows.Scan(&r.DirectoryId, &r.Revision, &r.Operation, &r.Actor, timestamp{&r.Time}, &before, &after)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning directory revision: %w", err)
+		}
+
+		if before != nil {
+			if err := json.Unmarshal(before, &r.Before); err != nil {
+				return nil, fmt.Errorf("error decoding directory revision: %w", err)
+			}
+		}
+
+		if err := json.Unmarshal(after, &r.After); err != nil {
+			return nil, fmt.Errorf("error decoding directory revision: %w", err)
+		}
+
+		revisions = append(revisions, &r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, t.readError("error querying directory history", err)
+	}
+
+	return revisions, nil
+}
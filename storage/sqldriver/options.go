@@ -0,0 +1,29 @@
+package sqldriver
+
+// Option defines ways to configure the driver.
+type Option func(*Driver)
+
+// WithReadOnly configures the driver to be read-only.
+func WithReadOnly() Option {
+	return func(d *Driver) {
+		d.readOnly = true
+	}
+}
+
+// WithFastReads configures the driver to use fast reads, which may return
+// slightly stale data. This is useful for read-only drivers. Dialects
+// which can't read stale data faster ignore it.
+func WithFastReads() Option {
+	return func(d *Driver) {
+		d.fastReads = true
+	}
+}
+
+// WithUniqueSiblingNames configures the driver to enforce unique
+// names among live siblings in every directory it creates.
+// Directories created before are left as they are.
+func WithUniqueSiblingNames() Option {
+	return func(d *Driver) {
+		d.uniqueSiblingNames = true
+	}
+}
@@ -0,0 +1,89 @@
+package sqldriver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// ResolvePath resolves a path in a single query, walking down from the roots
+// named as the first name of the path, and keeping the children named as the
+// next name at each level. At most two directories are read, which is enough
+// to tell whether the path is ambiguous.
+// The names are passed as a JSON array, which every database can index.
+func (t *Driver) ResolvePath(
+	ctx context.Context,
+	path []string,
+	options ...storage.Option,
+) (*v1.Directory, error) {
+	if len(path) == 0 {
+		return nil, storage.ErrDirectoryNotFound
+	}
+
+	opts := storage.BuildOptions(options)
+
+	withDeleted := "false"
+
+	if opts.WithDeletedDirectories {
+		withDeleted = "true"
+	}
+
+	names, err := json.Marshal(path)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding path: %w", err)
+	}
+
+	namesArg := t.dialect.Cast("$1", "JSONB")
+
+	query := t.formatQuery(`
+		WITH RECURSIVE resolved AS (
+			SELECT id, 1 AS depth FROM %[1]s d
+			WHERE parent_id IS NULL AND name = (`+namesArg+` ->> 0) AND (`+withDeleted+` OR deleted_at IS NULL)
+
+			UNION ALL
+
+			SELECT d.id, r.depth + 1 FROM resolved r
+			INNER JOIN %[1]s d ON d.parent_id = r.id
+			WHERE r.depth < $2 AND d.name = (`+namesArg+` ->> r.depth)
+				AND (`+withDeleted+` OR d.deleted_at IS NULL)
+		)
+		SELECT `+qualifiedDirectoryColumns("d")+` FROM resolved r
+		INNER JOIN %[1]s d ON d.id = r.id %[2]s
+		WHERE r.depth = $2
+		LIMIT 2
+	`, opts)
+
+	rows, err := t.conn().QueryContext(ctx, query, string(names), len(path))
+	if err != nil {
+		return nil, t.readError("error resolving path", err)
+	}
+	defer rows.Close()
+
+	var dirs []*v1.Directory
+
+	for rows.Next() {
+		var d v1.Directory
+
+		if err := scanDirectory(rows, &d); err != nil {
+			return nil, fmt.Errorf("error scanning directory: %w", err)
+		}
+
+		dirs = append(dirs, &d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, t.readError("error resolving path", err)
+	}
+
+	switch len(dirs) {
+	case 0:
+		return nil, storage.ErrDirectoryNotFound
+	case 1:
+		return dirs[0], nil
+	default:
+		return nil, storage.AmbiguousPathError(path)
+	}
+}
@@ -0,0 +1,224 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+const (
+	// sqlStateUniqueViolation is the SQLSTATE code of unique constraint violations.
+	sqlStateUniqueViolation = "23505"
+	// sqlStateSerializationFailure is the SQLSTATE code of serializable
+	// transactions aborted because of a concurrent one.
+	sqlStateSerializationFailure = "40001"
+	// sqlStateDeadlockDetected is the SQLSTATE code of transactions
+	// aborted to break a deadlock.
+	sqlStateDeadlockDetected = "40P01"
+
+	// maxTxAttempts is the number of times a transaction is run
+	// before its serialization failure is returned.
+	maxTxAttempts = 10
+)
+
+// revisionDirectoryColumns read the DirectoryColumns, in order, from the
+// directory recorded after the change in a revision.
+const revisionDirectoryColumns = `
+	(r.after->>'id')::UUID AS id,
+	r.after->>'name' AS name,
+	COALESCE(r.after->'metadata', '{}'::JSONB) AS metadata,
+	(r.after->>'createdAt')::TIMESTAMPTZ AS created_at,
+	(r.after->>'updatedAt')::TIMESTAMPTZ AS updated_at,
+	(r.after->>'deletedAt')::TIMESTAMPTZ AS deleted_at,
+	(r.after->>'parent')::UUID AS parent_id,
+	(r.after->>'revision')::INT8 AS revision,
+	COALESCE((r.after->>'uniqueSiblingNames')::BOOL, false) AS unique_sibling_names`
+
+var _ Dialect = PostgresDialect{}
+
+// PostgresDialect is the dialect of PostgreSQL.
+// It is meant to be embedded by the dialects of compatible databases.
+type PostgresDialect struct{}
+
+// Rebind returns the query as is, as PostgreSQL uses $N placeholders.
+func (PostgresDialect) Rebind(query string) string {
+	return query
+}
+
+// ExecuteTx runs fn within a new serializable transaction, so the checks
+// made by fn, such as the ancestors of a move destination, still hold as
+// it commits. Transactions failing to serialize are retried.
+func (PostgresDialect) ExecuteTx(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
+	var err error
+
+	for attempt := 0; attempt < maxTxAttempts; attempt++ {
+		if err = executeSerializableTx(ctx, db, fn); !isSerializationFailure(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// executeSerializableTx runs fn once within a new serializable transaction.
+func executeSerializableTx(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		//nolint:errcheck // The error of fn is the one worth returning.
+		tx.Rollback()
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// isSerializationFailure checks whether the transaction was aborted to keep
+// the concurrent ones serializable, or to break a deadlock between them.
+func isSerializationFailure(err error) bool {
+	var sqlErr interface{ SQLState() string }
+
+	return errors.As(err, &sqlErr) &&
+		(sqlErr.SQLState() == sqlStateSerializationFailure || sqlErr.SQLState() == sqlStateDeadlockDetected)
+}
+
+// ForUpdate returns the FOR UPDATE clause.
+func (PostgresDialect) ForUpdate() string {
+	return " FOR UPDATE"
+}
+
+// Now returns the start time of the transaction.
+func (PostgresDialect) Now() string {
+	return "NOW()"
+}
+
+// Timestamp returns the time as is.
+func (PostgresDialect) Timestamp(t time.Time) any {
+	return t
+}
+
+// Cast returns the expression with a type cast.
+func (PostgresDialect) Cast(expr, typ string) string {
+	return expr + "::" + typ
+}
+
+// IDList returns the ids as a UUID[] array literal.
+func (PostgresDialect) IDList(ids []v1.DirectoryID) any {
+	strs := make([]string, len(ids))
+
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+
+	return "{" + strings.Join(strs, ",") + "}"
+}
+
+// InIDList returns the condition matching the column against any id of the array.
+func (PostgresDialect) InIDList(column, arg string) string {
+	return column + " = ANY(" + arg + "::UUID[])"
+}
+
+// ReadFrom reads the directories from the directories table, unless they are
+// read at a point in time. They are then rebuilt from their revision history,
+// each taken from its latest revision by then. Directories without any recorded
+// revision are taken as they are, if they were created by then.
+// Purged directories are gone along with their history.
+func (PostgresDialect) ReadFrom(asOf time.Time, _ bool) (string, string) {
+	if asOf.IsZero() {
+		return "directories", ""
+	}
+
+	ts := "'" + asOf.UTC().Format(time.RFC3339Nano) + "'::TIMESTAMPTZ"
+
+	return `((
+		SELECT DISTINCT ON (r.directory_id) ` + revisionDirectoryColumns + `
+		FROM directory_revisions r
+		WHERE r.created_at <= ` + ts + `
+		ORDER BY r.directory_id, r.created_at DESC, r.revision DESC
+	) UNION ALL (
+		SELECT ` + DirectoryColumns + ` FROM directories
+		WHERE created_at <= ` + ts + ` AND NOT EXISTS (
+			SELECT 1 FROM directory_revisions r WHERE r.directory_id = directories.id
+		)
+	))`, ""
+}
+
+// MatchSelector compares the values with JSONB containment,
+// so the inverted index on metadata is used.
+func (PostgresDialect) MatchSelector(req storage.Requirement, arg func(any) string) string {
+	switch req.Operator {
+	case storage.SelectorExists:
+		return "metadata ? " + arg(req.Key)
+	case storage.SelectorDoesNotExist:
+		return "NOT (metadata ? " + arg(req.Key) + ")"
+	}
+
+	matches := make([]string, len(req.Values))
+
+	for i, v := range req.Values {
+		// Marshalling a map of strings can't fail.
+		contained, _ := json.Marshal(map[string]string{req.Key: v})
+		matches[i] = "metadata @> " + arg(string(contained)) + "::JSONB"
+	}
+
+	cond := "(" + strings.Join(matches, " OR ") + ")"
+
+	if req.Operator == storage.SelectorNotEquals || req.Operator == storage.SelectorNotIn {
+		return "NOT " + cond
+	}
+
+	return cond
+}
+
+// MatchName matches prefixes with LIKE, which may use the index on names,
+// and substrings with ILIKE, ignoring case.
+func (PostgresDialect) MatchName(q storage.NameQuery, arg string) (string, any) {
+	switch q.Match {
+	case storage.MatchExact:
+		return "name = " + arg, q.Name
+	case storage.MatchPrefix:
+		return "name LIKE " + arg + ` ESCAPE '\'`, EscapeLike(q.Name) + "%"
+	case storage.MatchContains:
+		return "name ILIKE " + arg + ` ESCAPE '\'`, "%" + EscapeLike(q.Name) + "%"
+	}
+
+	// The query was validated, so the match is known.
+	return "name = " + arg, q.Name
+}
+
+// IsUniqueViolation checks the SQLSTATE code of the error.
+func (PostgresDialect) IsUniqueViolation(err error) bool {
+	var sqlErr interface{ SQLState() string }
+
+	return errors.As(err, &sqlErr) && sqlErr.SQLState() == sqlStateUniqueViolation
+}
+
+// IsAsOfOutOfRange returns false, as past data is read from the revision
+// history, which is kept as long as the directories are.
+func (PostgresDialect) IsAsOfOutOfRange(error) bool {
+	return false
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// EscapeLike escapes the wildcards of s, to match it literally in a
+// LIKE pattern whose escape character is a backslash.
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,81 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// GetQuota returns the quota overrides of the provided root directory.
+func (t *Driver) GetQuota(ctx context.Context, root v1.DirectoryID) (*v1.DirectoryQuota, error) {
+	var (
+		q      v1.DirectoryQuota
+		isRoot bool
+	)
+
+	err := t.conn().QueryRowContext(ctx, `
+		SELECT d.parent_id IS NULL, q.max_depth, q.max_children, q.max_descendants
+		FROM directories d
+		LEFT JOIN root_quotas q ON q.root_id = d.id
+		WHERE d.id = $1 AND d.deleted_at IS NULL
+	`, root).Scan(&isRoot, &q.MaxDepth, &q.MaxChildren, &q.MaxDescendants)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrDirectoryNotFound
+		}
+
+		return nil, fmt.Errorf("error querying quota: %w", err)
+	}
+
+	if !isRoot {
+		return nil, storage.ErrNotRoot
+	}
+
+	return &q, nil
+}
+
+// SetQuota replaces the quota overrides of the provided root directory.
+func (t *Driver) SetQuota(ctx context.Context, root v1.DirectoryID, q *v1.DirectoryQuota) error {
+	if t.readOnly {
+		return storage.ErrReadOnly
+	}
+
+	return t.executeTx(ctx, func(c *conn) error {
+		parent, err := getParentForUpdate(ctx, c, root)
+		if err != nil {
+			return err
+		}
+
+		if parent != nil {
+			return storage.ErrNotRoot
+		}
+
+		_, err = c.ExecContext(ctx, `
+			INSERT INTO root_quotas (root_id, max_depth, max_children, max_descendants)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (root_id) DO UPDATE SET
+				max_depth = excluded.max_depth,
+				max_children = excluded.max_children,
+				max_descendants = excluded.max_descendants
+		`, root, q.MaxDepth, q.MaxChildren, q.MaxDescendants)
+		if err != nil {
+			return fmt.Errorf("error setting quota: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// deleteQuota removes the quota overrides of the provided directory,
+// e.g. as it stops being a root.
+func deleteQuota(ctx context.Context, c *conn, id v1.DirectoryID) error {
+	if _, err := c.ExecContext(ctx, "DELETE FROM root_quotas WHERE root_id = $1", id); err != nil {
+		return fmt.Errorf("error deleting quota: %w", err)
+	}
+
+	return nil
+}
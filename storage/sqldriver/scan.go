@@ -0,0 +1,82 @@
+package sqldriver
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+)
+
+// DirectoryColumns are the columns read by scanDirectory, in order.
+const DirectoryColumns = "id, name, metadata, created_at, updated_at, deleted_at, parent_id, revision, unique_sibling_names"
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanDirectory scans a row holding the DirectoryColumns into d.
+func scanDirectory(row scanner, d *v1.Directory) error {
+	return row.Scan(&d.Id, &d.Name, &d.Metadata, timestamp{&d.CreatedAt}, timestamp{&d.UpdatedAt},
+		nullTimestamp{&d.DeletedAt}, &d.Parent, &d.Revision, &d.UniqueSiblingNames)
+}
+
+// qualifiedDirectoryColumns returns the DirectoryColumns qualified with the provided table alias.
+func qualifiedDirectoryColumns(alias string) string {
+	return alias + "." + strings.ReplaceAll(DirectoryColumns, ", ", ", "+alias+".")
+}
+
+// timestamp scans a timestamp into a time. Databases without a timestamp
+// type return it as RFC 3339 text.
+type timestamp struct {
+	t *time.Time
+}
+
+func (ts timestamp) Scan(value any) error {
+	switch v := value.(type) {
+	case time.Time:
+		*ts.t = v
+	case string:
+		return ts.parse(v)
+	case []byte:
+		return ts.parse(string(v))
+	default:
+		return fmt.Errorf("unsupported timestamp type %T", value)
+	}
+
+	return nil
+}
+
+func (ts timestamp) parse(s string) error {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return fmt.Errorf("error parsing timestamp: %w", err)
+	}
+
+	*ts.t = t
+
+	return nil
+}
+
+// nullTimestamp scans a nullable timestamp into a time pointer.
+type nullTimestamp struct {
+	t **time.Time
+}
+
+func (ts nullTimestamp) Scan(value any) error {
+	if value == nil {
+		*ts.t = nil
+		return nil
+	}
+
+	var t time.Time
+
+	if err := (timestamp{&t}).Scan(value); err != nil {
+		return err
+	}
+
+	*ts.t = &t
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// GetSchema returns the schema applying to the provided directory:
+// its own, or the one of its closest ancestor having one.
+func (t *Driver) GetSchema(ctx context.Context, id v1.DirectoryID) (*storage.MetadataSchema, error) {
+	var (
+		source v1.DirectoryID
+		schema []byte
+	)
+
+	err := t.conn().QueryRowContext(ctx, `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, 0 AS depth FROM directories
+			WHERE id = $1 AND deleted_at IS NULL
+
+			UNION ALL
+
+			SELECT d.id, d.parent_id, a.depth + 1 FROM ancestors a
+			INNER JOIN directories d ON d.id = a.parent_id
+			WHERE d.deleted_at IS NULL
+		)
+		SELECT a.id, s.json_schema FROM ancestors a
+		LEFT JOIN directory_schemas s ON s.directory_id = a.id
+		ORDER BY s.directory_id IS NULL, a.depth
+		LIMIT 1
+	`, id).Scan(&source, &schema)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrDirectoryNotFound
+		}
+
+		return nil, fmt.Errorf("error querying schema: %w", err)
+	}
+
+	if schema == nil {
+		return nil, storage.ErrSchemaNotFound
+	}
+
+	return &storage.MetadataSchema{
+		Source: source,
+		Schema: schema,
+	}, nil
+}
+
+// SetSchema replaces the schema of the provided directory.
+func (t *Driver) SetSchema(ctx context.Context, id v1.DirectoryID, schema json.RawMessage) error {
+	if t.readOnly {
+		return storage.ErrReadOnly
+	}
+
+	return t.executeTx(ctx, func(c *conn) error {
+		if _, err := getParentForUpdate(ctx, c, id); err != nil {
+			return err
+		}
+
+		_, err := c.ExecContext(ctx, `
+			INSERT INTO directory_schemas (directory_id, json_schema)
+			VALUES ($1, $2)
+			ON CONFLICT (directory_id) DO UPDATE SET
+				json_schema = excluded.json_schema
+		`, id, string(schema))
+		if err != nil {
+			return fmt.Errorf("error setting schema: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// DeleteSchema removes the schema of the provided directory.
+func (t *Driver) DeleteSchema(ctx context.Context, id v1.DirectoryID) error {
+	if t.readOnly {
+		return storage.ErrReadOnly
+	}
+
+	return t.executeTx(ctx, func(c *conn) error {
+		if _, err := getParentForUpdate(ctx, c, id); err != nil {
+			return err
+		}
+
+		res, err := c.ExecContext(ctx, "DELETE FROM directory_schemas WHERE directory_id = $1", id)
+		if err != nil {
+			return fmt.Errorf("error deleting schema: %w", err)
+		}
+
+		deleted, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error deleting schema: %w", err)
+		}
+
+		if deleted == 0 {
+			return storage.ErrSchemaNotFound
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,120 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// SearchDirectories searches the descendants of a directory by name.
+// Its subtree is walked down, collecting the parents of each descendant,
+// and only then filtered by name, so that directories outside of it are
+// never looked at.
+func (t *Driver) SearchDirectories(
+	ctx context.Context,
+	id v1.DirectoryID,
+	q storage.NameQuery,
+	options ...storage.Option,
+) ([]*v1.DirectorySearchResult, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	opts := storage.BuildOptions(options)
+
+	withDeleted := "false"
+
+	if opts.WithDeletedDirectories {
+		withDeleted = "true"
+	}
+
+	// The directory id and the name are the first arguments.
+	nameCond, name := t.dialect.MatchName(q, "$2")
+	keyset, args := t.keysetCondition(opts, 3) //nolint:gomnd // see above
+
+	// The parents are collected as comma-separated text, as not every database has arrays.
+	query := t.formatQuery(`
+		WITH RECURSIVE subtree AS (
+			SELECT id AS descendant_id, CAST(parent_id AS TEXT) AS parents FROM %[1]s d
+			WHERE parent_id = $1
+
+			UNION ALL
+
+			SELECT d.id, CAST(d.parent_id AS TEXT) || ',' || s.parents FROM subtree s
+			INNER JOIN %[1]s d ON d.parent_id = s.descendant_id
+		)
+		SELECT `+qualifiedDirectoryColumns("d")+`, s.parents FROM subtree s
+		INNER JOIN %[1]s d ON d.id = s.descendant_id %[2]s
+		WHERE `+nameCond+` AND (`+withDeleted+` OR deleted_at IS NULL)`+keyset+`
+		ORDER BY d.created_at ASC, d.id ASC
+	`+pageClause(opts), opts)
+
+	rows, err := t.conn().QueryContext(ctx, query, append([]any{id, name}, args...)...)
+	if err != nil {
+		return nil, t.readError("error searching directories", err)
+	}
+	defer rows.Close()
+
+	var results []*v1.DirectorySearchResult
+
+	for rows.Next() {
+		var (
+			r       v1.DirectorySearchResult
+			parents string
+		)
+
+		if err := scanDirectory(withParents{rows, &parents}, &r.Directory); err != nil {
+			return nil, fmt.Errorf("error scanning directory: %w", err)
+		}
+
+		if r.Parents, err = parseIDs(parents); err != nil {
+			return nil, fmt.Errorf("error scanning directory parents: %w", err)
+		}
+
+		results = append(results, &r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, t.readError("error searching directories", err)
+	}
+
+	// No results may also mean the directory doesn't exist.
+	if len(results) == 0 {
+		if _, err := t.GetDirectory(ctx, id, options...); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// withParents scans the directory columns of a row, followed by the parents of the directory.
+type withParents struct {
+	rows    *sql.Rows
+	parents *string
+}
+
+func (w withParents) Scan(dest ...any) error {
+	return w.rows.Scan(append(dest, w.parents)...)
+}
+
+// parseIDs parses a comma-separated list of ids.
+func parseIDs(s string) ([]v1.DirectoryID, error) {
+	strs := strings.Split(s, ",")
+	ids := make([]v1.DirectoryID, len(strs))
+
+	for i, str := range strs {
+		id, err := v1.ParseDirectoryID(str)
+		if err != nil {
+			return nil, err
+		}
+
+		ids[i] = id
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,214 @@
+// Package driver implements the storage interface on an embedded SQLite database.
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/sqldriver"
+)
+
+// timestampFormat is the format timestamps are stored in. It has a fixed width,
+// so timestamps compare as text the same way they compare as times.
+const timestampFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// revisionDirectoryColumns read the DirectoryColumns, in order, from the
+// directory recorded after the change in a revision. The creation time is
+// taken from the directory itself, as it never changes and is stored in
+// the format the directories are sorted by.
+const revisionDirectoryColumns = `
+	r.directory_id AS id,
+	json_extract(r.after, '$.name') AS name,
+	COALESCE(json_extract(r.after, '$.metadata'), '{}') AS metadata,
+	c.created_at AS created_at,
+	json_extract(r.after, '$.updatedAt') AS updated_at,
+	json_extract(r.after, '$.deletedAt') AS deleted_at,
+	json_extract(r.after, '$.parent') AS parent_id,
+	json_extract(r.after, '$.revision') AS revision,
+	COALESCE(json_extract(r.after, '$.uniqueSiblingNames'), 0) AS unique_sibling_names`
+
+// placeholder matches the $N placeholders of the queries.
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+// Driver is the SQLite driver.
+type Driver = sqldriver.Driver
+
+func NewDirectoryDriver(db *sql.DB, opts ...Options) *Driver {
+	return sqldriver.New(db, dialect{}, opts...)
+}
+
+// formatTimestamp returns the provided time as it is stored in the database.
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampFormat)
+}
+
+var _ sqldriver.Dialect = dialect{}
+
+// dialect is the dialect of SQLite. Ids and timestamps are generated by
+// the driver, and JSON is stored as text, expanded with json_each.
+type dialect struct{}
+
+// Rebind rewrites the $N placeholders into ?N ones.
+func (dialect) Rebind(query string) string {
+	return placeholder.ReplaceAllString(query, "?$1")
+}
+
+// ExecuteTx runs fn within a new transaction.
+// SQLite has no row locks, so writes rely on transactions taking the
+// database write lock as they begin (see utils.DSN).
+func (dialect) ExecuteTx(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		//nolint:errcheck // The error of fn is the one worth returning.
+		tx.Rollback()
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ForUpdate returns nothing, as SQLite has no row locks.
+func (dialect) ForUpdate() string {
+	return ""
+}
+
+// Now returns the current time as a literal, as SQLite has no
+// function returning it in the format timestamps are stored in.
+func (dialect) Now() string {
+	return "'" + formatTimestamp(time.Now()) + "'"
+}
+
+// Timestamp returns the time as it is stored.
+func (dialect) Timestamp(t time.Time) any {
+	return formatTimestamp(t)
+}
+
+// Cast returns the expression as is, as SQLite doesn't need to infer the types of arguments.
+func (dialect) Cast(expr, _ string) string {
+	return expr
+}
+
+// IDList returns the ids as a JSON array.
+func (dialect) IDList(ids []v1.DirectoryID) any {
+	strs := make([]string, len(ids))
+
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+
+	// Marshalling a slice of strings can't fail.
+	b, _ := json.Marshal(strs)
+
+	return string(b)
+}
+
+// InIDList returns the condition matching the column against any id of the
+// JSON array, expanded with json_each.
+func (dialect) InIDList(column, arg string) string {
+	return column + " IN (SELECT value FROM json_each(" + arg + "))"
+}
+
+// ReadFrom reads the directories from the directories table, unless they are
+// read at a point in time. They are then rebuilt from their revision history,
+// each taken from its latest revision by then. Directories without any recorded
+// revision are taken as they are, if they were created by then.
+// Purged directories are gone along with their history.
+func (dialect) ReadFrom(asOf time.Time, _ bool) (string, string) {
+	if asOf.IsZero() {
+		return "directories", ""
+	}
+
+	ts := "'" + formatTimestamp(asOf) + "'"
+
+	return `(
+		SELECT ` + revisionDirectoryColumns + `
+		FROM (
+			SELECT r.*, ROW_NUMBER() OVER (
+				PARTITION BY r.directory_id ORDER BY r.created_at DESC, r.revision DESC
+			) AS latest
+			FROM directory_revisions r
+			WHERE r.created_at <= ` + ts + `
+		) r
+		INNER JOIN directories c ON c.id = r.directory_id
+		WHERE r.latest = 1
+
+		UNION ALL
+
+		SELECT ` + sqldriver.DirectoryColumns + ` FROM directories
+		WHERE created_at <= ` + ts + ` AND NOT EXISTS (
+			SELECT 1 FROM directory_revisions r WHERE r.directory_id = directories.id
+		)
+	)`, ""
+}
+
+// MatchSelector expands the metadata with json_each, as SQLite has no JSON containment.
+func (dialect) MatchSelector(req storage.Requirement, arg func(any) string) string {
+	match := "EXISTS (SELECT 1 FROM json_each(metadata) m WHERE m.key = " + arg(req.Key)
+
+	if len(req.Values) > 0 {
+		values := make([]string, len(req.Values))
+
+		for i, v := range req.Values {
+			values[i] = arg(v)
+		}
+
+		match += " AND m.value IN (" + strings.Join(values, ", ") + ")"
+	}
+
+	match += ")"
+
+	switch req.Operator {
+	case storage.SelectorDoesNotExist, storage.SelectorNotEquals, storage.SelectorNotIn:
+		return "NOT " + match
+	default:
+		return match
+	}
+}
+
+// MatchName compares prefixes as substrings, as LIKE ignores case in SQLite,
+// and matches substrings with LIKE.
+func (dialect) MatchName(q storage.NameQuery, arg string) (string, any) {
+	switch q.Match {
+	case storage.MatchExact:
+		return "name = " + arg, q.Name
+	case storage.MatchPrefix:
+		return "substr(name, 1, length(" + arg + ")) = " + arg, q.Name
+	case storage.MatchContains:
+		return "name LIKE " + arg + ` ESCAPE '\'`, "%" + sqldriver.EscapeLike(q.Name) + "%"
+	}
+
+	// The query was validated, so the match is known.
+	return "name = " + arg, q.Name
+}
+
+// IsUniqueViolation checks the extended code of the error.
+func (dialect) IsUniqueViolation(err error) bool {
+	var sqlErr sqlite3.Error
+
+	return errors.As(err, &sqlErr) && sqlErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// IsAsOfOutOfRange returns false, as past data is read from the revision
+// history, which is kept as long as the directories are.
+func (dialect) IsAsOfOutOfRange(error) bool {
+	return false
+}
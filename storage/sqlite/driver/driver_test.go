@@ -0,0 +1,43 @@
+package driver_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/sqlite/driver"
+	"github.com/infratographer/fertilesoil/storage/sqlite/utils"
+)
+
+func TestPurgeLiveDescendants(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := utils.GetNewTestDB(t)
+	store := driver.NewDirectoryDriver(db)
+
+	root, err := store.CreateRoot(ctx, &v1.Directory{Name: "root"})
+	assert.NoError(t, err, "error creating root directory")
+
+	parent, err := store.CreateDirectory(ctx, &v1.Directory{Name: "parent", Parent: &root.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	child, err := store.CreateDirectory(ctx, &v1.Directory{Name: "child", Parent: &parent.Id})
+	assert.NoError(t, err, "error creating directory")
+
+	_, err = store.DeleteDirectory(ctx, parent.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	// Such trees can't be made through the driver, but may have been before it refused to.
+	_, err = db.ExecContext(ctx, "UPDATE directories SET deleted_at = NULL WHERE id = ?", child.Id)
+	assert.NoError(t, err, "error reviving directory")
+
+	_, err = store.PurgeDirectory(ctx, parent.Id)
+	assert.ErrorIs(t, err, storage.ErrDescendantNotDeleted, "directory with live descendants should not be purged")
+
+	_, err = store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "live descendant should be kept")
+}
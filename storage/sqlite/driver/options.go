@@ -0,0 +1,18 @@
+package driver
+
+import "github.com/infratographer/fertilesoil/storage/sqldriver"
+
+// Options defines ways to configure the SQLite driver.
+type Options = sqldriver.Option
+
+// WithReadOnly configures the driver to be read-only.
+func WithReadOnly() Options {
+	return sqldriver.WithReadOnly()
+}
+
+// WithUniqueSiblingNames configures the driver to enforce unique
+// names among live siblings in every directory it creates.
+// Directories created before are left as they are.
+func WithUniqueSiblingNames() Options {
+	return sqldriver.WithUniqueSiblingNames()
+}
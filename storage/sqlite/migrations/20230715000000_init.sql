@@ -0,0 +1,60 @@
+-- This contains the database model for the directory tree on SQLite.
+-- It matches the CockroachDB model as of its latest migration.
+-- Ids and timestamps are generated by the driver, and timestamps are
+-- stored as fixed-width RFC 3339 text in UTC, so they sort as they compare.
+
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS directories (
+    id TEXT NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL,
+    metadata TEXT NOT NULL DEFAULT '{}' CHECK (json_valid(metadata)),
+    parent_id TEXT REFERENCES directories(id) ON DELETE CASCADE,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    deleted_at TEXT,
+    revision INTEGER NOT NULL DEFAULT 1,
+    unique_sibling_names INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT parent_child_not_equal CHECK (id != parent_id)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS directories_parent_id ON directories (parent_id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE UNIQUE INDEX IF NOT EXISTS directories_unique_sibling_names ON directories (parent_id, name)
+    WHERE deleted_at IS NULL AND unique_sibling_names;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS directory_revisions (
+    id TEXT NOT NULL PRIMARY KEY,
+    directory_id TEXT NOT NULL REFERENCES directories(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    operation TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    before TEXT CHECK (before IS NULL OR json_valid(before)),
+    after TEXT NOT NULL CHECK (json_valid(after))
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS directory_revisions_directory_id_revision
+    ON directory_revisions (directory_id, revision DESC);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS directory_revisions_created_at ON directory_revisions (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS directory_revisions;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS directories;
+-- +goose StatementEnd
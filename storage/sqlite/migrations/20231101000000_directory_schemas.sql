@@ -0,0 +1,17 @@
+-- This holds the JSON Schemas the metadata of directories is validated
+-- against. A schema applies to the subtree of its directory, unless one
+-- of its descendants has its own. Schemas are removed along with their
+-- directory when it is purged.
+
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS directory_schemas (
+    directory_id TEXT NOT NULL PRIMARY KEY REFERENCES directories(id) ON DELETE CASCADE,
+    json_schema TEXT NOT NULL CHECK (json_valid(json_schema))
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS directory_schemas;
+-- +goose StatementEnd
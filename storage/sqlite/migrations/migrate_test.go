@@ -0,0 +1,29 @@
+package migrations_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infratographer/fertilesoil/storage/sqlite/migrations"
+	"github.com/infratographer/fertilesoil/storage/sqlite/utils"
+)
+
+func TestMigrations(t *testing.T) {
+	t.Parallel()
+
+	dbdialect := "sqlite3"
+	dbConn, dbopenerr := sql.Open(dbdialect, utils.DSN(filepath.Join(t.TempDir(), "directory.db")))
+	assert.NoError(t, dbopenerr, "failed to open db connection")
+
+	defer dbConn.Close()
+
+	assert.NoError(t, migrations.Migrate(dbConn), "failed to run migrations")
+
+	goose.SetBaseFS(migrations.Migrations)
+	assert.NoError(t, goose.SetDialect(dbdialect), "failed to set dialect")
+	assert.NoError(t, goose.Down(dbConn, "."), "failed to roll back migrations")
+}
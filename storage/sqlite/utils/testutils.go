@@ -0,0 +1,64 @@
+//go:build testtools
+// +build testtools
+
+package utils
+
+import (
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/infratographer/fertilesoil/storage/sqlite/migrations"
+)
+
+var gooseMutex sync.Mutex
+
+// Returns a new test database for an application.
+// The database is not migrated.
+func GetNewTestDBForApp(t *testing.T) *sql.DB {
+	t.Helper()
+
+	return getNewTestDB(t, "app")
+}
+
+// Returns a new test database for the tree manager
+// with the migrations applied.
+func GetNewTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	gooseMutex.Lock()
+	defer gooseMutex.Unlock()
+
+	dbConn := getNewTestDB(t, "directory")
+
+	goose.SetBaseFS(migrations.Migrations)
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("error setting dialect: %v", err)
+	}
+
+	if err := goose.Up(dbConn, "."); err != nil {
+		t.Fatalf("error running migrations: %v", err)
+	}
+	return dbConn
+}
+
+// getNewTestDB opens a database file within a temporary directory
+// which is removed once the test ends.
+func getNewTestDB(t *testing.T, name string) *sql.DB {
+	t.Helper()
+
+	dbConn, err := sql.Open("sqlite3", DSN(filepath.Join(t.TempDir(), name+".db")))
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		dbConn.Close()
+	})
+
+	return dbConn
+}
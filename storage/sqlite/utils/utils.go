@@ -0,0 +1,79 @@
+// Package utils provides helpers to set up the SQLite storage driver.
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3" // Register the SQLite driver.
+	"github.com/spf13/viper"
+
+	"github.com/infratographer/fertilesoil/storage/sqlite/driver"
+)
+
+// DefaultDSN is the data source name used when none is configured.
+const DefaultDSN = "file:fertilesoil.db"
+
+// dsnParams are the connection parameters set on every data source name,
+// unless it sets them itself:
+//   - WAL mode lets readers carry on while a write is in progress.
+//   - Foreign keys are enforced, so purging a directory removes its descendants.
+//   - Connections wait for the database to be unlocked instead of failing.
+//   - Transactions take the write lock as they begin, which stands in for
+//     the row locks the other drivers take.
+var dsnParams = []string{
+	"_journal_mode=WAL",
+	"_foreign_keys=1",
+	"_busy_timeout=5000",
+	"_txlock=immediate",
+}
+
+// DSN returns the provided data source name, e.g. a file path or a
+// file: URI, along with the connection parameters the driver relies on.
+func DSN(dsn string) string {
+	path, query, _ := strings.Cut(dsn, "?")
+
+	params := []string{}
+	if query != "" {
+		params = append(params, query)
+	}
+
+	for _, p := range dsnParams {
+		name, _, _ := strings.Cut(p, "=")
+		if !strings.Contains("&"+query, "&"+name+"=") {
+			params = append(params, p)
+		}
+	}
+
+	return path + "?" + strings.Join(params, "&")
+}
+
+// GetDBConnection opens the database set in sqlite.dsn and verifies it.
+func GetDBConnection(v *viper.Viper) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", DSN(v.GetString("sqlite.dsn")))
+	if err != nil {
+		return nil, fmt.Errorf("failed opening database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed verifying database connection: %w", err)
+	}
+
+	return db, nil
+}
+
+// WithStorageOptions returns the storage options for the driver.
+// Fast reads rely on CockroachDB follower reads, so they aren't supported.
+func WithStorageOptions(v *viper.Viper) []driver.Options {
+	var opts []driver.Options
+	if v.GetBool("storage.read_only") {
+		opts = append(opts, driver.WithReadOnly())
+	}
+
+	if v.GetBool("storage.unique_sibling_names") {
+		opts = append(opts, driver.WithUniqueSiblingNames())
+	}
+
+	return opts
+}
@@ -0,0 +1,45 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infratographer/fertilesoil/storage/sqlite/utils"
+)
+
+func TestDSN(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		dsn  string
+		want string
+	}{
+		{
+			name: "path",
+			dsn:  "/var/lib/treeman/directories.db",
+			want: "/var/lib/treeman/directories.db?_journal_mode=WAL&_foreign_keys=1&_busy_timeout=5000&_txlock=immediate",
+		},
+		{
+			name: "uri with params",
+			dsn:  "file:directories.db?cache=shared",
+			want: "file:directories.db?cache=shared&_journal_mode=WAL&_foreign_keys=1&_busy_timeout=5000&_txlock=immediate",
+		},
+		{
+			name: "overridden params",
+			dsn:  "file:directories.db?_busy_timeout=100&_journal_mode=DELETE",
+			want: "file:directories.db?_busy_timeout=100&_journal_mode=DELETE&_foreign_keys=1&_txlock=immediate",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, utils.DSN(tt.dsn))
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package storagetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+func testCreateRoot(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+
+	rd, err := store.CreateRoot(ctx, &v1.Directory{
+		Name:     "root",
+		Metadata: &v1.DirectoryMetadata{"key": "value"},
+	})
+	assert.NoError(t, err, "error creating root directory")
+	assert.NotEqual(t, v1.DirectoryID(uuid.Nil), rd.Id, "id should be generated")
+	assert.Nil(t, rd.Parent, "root directory should have no parent")
+	assert.Nil(t, rd.DeletedAt, "root directory should not be deleted")
+	assert.Equal(t, int64(1), rd.Revision, "revision should start at 1")
+	assert.False(t, rd.CreatedAt.IsZero(), "creation time should be set")
+	assert.False(t, rd.UpdatedAt.IsZero(), "update time should be set")
+
+	d, err := store.GetDirectory(ctx, rd.Id)
+	assert.NoError(t, err, "error getting root directory")
+	assert.Equal(t, "root", d.Name, "name should match")
+	assert.Equal(t, v1.DirectoryMetadata{"key": "value"}, *d.Metadata, "metadata should match")
+	assert.Nil(t, d.Parent, "root directory should have no parent")
+
+	roots, err := store.ListRoots(ctx)
+	assert.NoError(t, err, "error listing roots")
+	assert.Equal(t, []v1.DirectoryID{rd.Id}, roots, "root directory should be listed")
+
+	// Root directories may not have a parent.
+	_, err = store.CreateRoot(ctx, &v1.Directory{Name: "other", Parent: &rd.Id})
+	assert.ErrorIs(t, err, storage.ErrRootWithParentDirectory, "root directory with parent should be refused")
+}
+
+func testCreateDirectory(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+
+	cd, err := store.CreateDirectory(ctx, &v1.Directory{Name: "child", Parent: &root.Id})
+	assert.NoError(t, err, "error creating directory")
+	assert.NotNil(t, cd.Metadata, "metadata should be set")
+	assert.Equal(t, int64(1), cd.Revision, "revision should start at 1")
+
+	d, err := store.GetDirectory(ctx, cd.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, "child", d.Name, "name should match")
+	assert.Equal(t, &root.Id, d.Parent, "parent should match")
+	assert.NotNil(t, d.Metadata, "metadata should be set")
+
+	// Child directories aren't roots.
+	roots, err := store.ListRoots(ctx)
+	assert.NoError(t, err, "error listing roots")
+	assert.Equal(t, []v1.DirectoryID{root.Id}, roots, "only the root directory should be listed")
+
+	_, err = store.CreateDirectory(ctx, &v1.Directory{Name: "orphan"})
+	assert.ErrorIs(t, err, storage.ErrDirectoryWithoutParent, "directory without parent should be refused")
+
+	unknown := v1.DirectoryID(uuid.New())
+
+	_, err = store.CreateDirectory(ctx, &v1.Directory{Name: "orphan", Parent: &unknown})
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "directory with unknown parent should be refused")
+}
+
+func testGetUnknownDirectory(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	unknown := v1.DirectoryID(uuid.New())
+
+	d, err := store.GetDirectory(ctx, unknown)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "unknown directory should not be found")
+	assert.Nil(t, d, "no directory should be returned")
+
+	d, err = store.GetDirectory(ctx, unknown, storage.WithDeletedDirectories)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "unknown directory should not be found")
+	assert.Nil(t, d, "no directory should be returned")
+
+	roots, err := store.ListRoots(ctx)
+	assert.NoError(t, err, "error listing roots")
+	assert.Empty(t, roots, "there should be no roots")
+}
+
+func testGetDirectories(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 2)
+	unknown := v1.DirectoryID(uuid.New())
+
+	dirs, err := store.GetDirectories(ctx, []v1.DirectoryID{chain[2].Id, unknown, chain[0].Id, chain[2].Id})
+	assert.NoError(t, err, "error getting directories")
+	assert.Equal(t, []v1.DirectoryID{chain[2].Id, chain[0].Id}, ids(dirs),
+		"directories should be returned once, in the requested order")
+
+	dirs, err = store.GetDirectories(ctx, []v1.DirectoryID{unknown})
+	assert.NoError(t, err, "error getting directories")
+	assert.Empty(t, dirs, "unknown directories should be skipped")
+
+	dirs, err = store.GetDirectories(ctx, nil)
+	assert.NoError(t, err, "error getting directories")
+	assert.Empty(t, dirs, "no directories should be returned")
+}
+
+func testUpdateDirectory(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+	other := createRoot(t, store, "other")
+	child := createChild(t, store, root, "child")
+
+	// Only the name and metadata are updated, the parent is changed with MoveDirectory.
+	update := &v1.Directory{
+		Id:       child.Id,
+		Name:     "renamed",
+		Metadata: &v1.DirectoryMetadata{"key": "value"},
+		Parent:   &other.Id,
+	}
+
+	err := store.UpdateDirectory(ctx, update)
+	assert.NoError(t, err, "error updating directory")
+	assert.Equal(t, int64(2), update.Revision, "revision should be returned")
+
+	d, err := store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, "renamed", d.Name, "name should have been updated")
+	assert.Equal(t, v1.DirectoryMetadata{"key": "value"}, *d.Metadata, "metadata should have been updated")
+	assert.Equal(t, &root.Id, d.Parent, "parent should not have changed")
+	assert.Equal(t, int64(2), d.Revision, "revision should have been incremented")
+	assert.False(t, d.UpdatedAt.Before(d.CreatedAt), "update time should not be before creation time")
+
+	unknown := v1.DirectoryID(uuid.New())
+
+	err = store.UpdateDirectory(ctx, &v1.Directory{Id: unknown, Name: "unknown"})
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "unknown directory should not be updated")
+
+	_, err = store.GetDirectory(ctx, unknown, storage.WithDeletedDirectories)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "unknown directory should not have been created")
+}
+
+func testUpdateDirectoryIfRevision(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+	child := createChild(t, store, root, "child")
+
+	err := store.UpdateDirectoryIfRevision(ctx, &v1.Directory{Id: child.Id, Name: "renamed"}, child.Revision)
+	assert.NoError(t, err, "error updating directory at the expected revision")
+
+	err = store.UpdateDirectoryIfRevision(ctx, &v1.Directory{Id: child.Id, Name: "stale"}, child.Revision)
+	assert.ErrorIs(t, err, storage.ErrRevisionConflict, "stale revision should conflict")
+
+	d, err := store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, "renamed", d.Name, "stale update should not have been applied")
+
+	err = store.UpdateDirectoryIfRevision(ctx, &v1.Directory{Id: v1.DirectoryID(uuid.New())}, 1)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "unknown directory should not be updated")
+
+	_, err = store.DeleteDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	err = store.UpdateDirectoryIfRevision(ctx, &v1.Directory{Id: child.Id, Name: "deleted"}, d.Revision+1)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "deleted directory should not be updated")
+}
+
+func testDeleteDirectoryIfRevision(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+	child := createChild(t, store, root, "child")
+
+	_, err := store.DeleteDirectoryIfRevision(ctx, child.Id, child.Revision+1)
+	assert.ErrorIs(t, err, storage.ErrRevisionConflict, "stale revision should conflict")
+
+	_, err = store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "stale delete should not have been applied")
+
+	affected, err := store.DeleteDirectoryIfRevision(ctx, child.Id, child.Revision)
+	assert.NoError(t, err, "error deleting directory at the expected revision")
+	assert.Len(t, affected, 1, "expected the directory to be deleted")
+
+	_, err = store.DeleteDirectoryIfRevision(ctx, child.Id, child.Revision)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "deleted directory should not be deleted again")
+}
+
+func testMoveDirectoryIfRevision(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+	child := createChild(t, store, root, "child")
+	other := createChild(t, store, root, "other")
+
+	_, _, err := store.MoveDirectoryIfRevision(ctx, child.Id, other.Id, child.Revision+1)
+	assert.ErrorIs(t, err, storage.ErrRevisionConflict, "stale revision should conflict")
+
+	d, err := store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, root.Id, *d.Parent, "stale move should not have been applied")
+
+	moved, _, err := store.MoveDirectoryIfRevision(ctx, child.Id, other.Id, child.Revision)
+	assert.NoError(t, err, "error moving directory at the expected revision")
+	assert.Equal(t, other.Id, *moved.Parent, "expected the directory to be moved")
+
+	_, _, err = store.PromoteToRootIfRevision(ctx, child.Id, child.Revision)
+	assert.ErrorIs(t, err, storage.ErrRevisionConflict, "stale revision should conflict")
+
+	promoted, _, err := store.PromoteToRootIfRevision(ctx, child.Id, moved.Revision)
+	assert.NoError(t, err, "error promoting directory at the expected revision")
+	assert.Nil(t, promoted.Parent, "expected the directory to be promoted")
+
+	_, err = store.DemoteRootIfRevision(ctx, child.Id, root.Id, moved.Revision)
+	assert.ErrorIs(t, err, storage.ErrRevisionConflict, "stale revision should conflict")
+
+	demoted, err := store.DemoteRootIfRevision(ctx, child.Id, root.Id, promoted.Revision)
+	assert.NoError(t, err, "error demoting directory at the expected revision")
+	assert.Equal(t, root.Id, *demoted.Parent, "expected the directory to be demoted")
+
+	_, _, err = store.MoveDirectoryIfRevision(ctx, v1.DirectoryID(uuid.New()), root.Id, 1)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "unknown directory should not be moved")
+}
+
+func testConcurrentUpdatesIfRevision(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+	child := createChild(t, store, root, "child")
+
+	const attempts = 5
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, attempts)
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errs <- store.UpdateDirectoryIfRevision(ctx, &v1.Directory{Id: child.Id, Name: "renamed"}, child.Revision)
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	var updated int
+
+	for err := range errs {
+		if err == nil {
+			updated++
+			continue
+		}
+
+		assert.ErrorIs(t, err, storage.ErrRevisionConflict, "concurrent updates should only fail on the revision")
+	}
+
+	assert.Equal(t, 1, updated, "only one update should be applied at the expected revision")
+
+	d, err := store.GetDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, child.Revision+1, d.Revision, "only one update should be applied at the expected revision")
+}
+
+func testUniqueSiblingNames(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+
+	root, err := store.CreateRoot(ctx, &v1.Directory{Name: "root", UniqueSiblingNames: true})
+	assert.NoError(t, err, "error creating root directory")
+	assert.True(t, root.UniqueSiblingNames, "root directory should enforce unique sibling names")
+
+	child := createChild(t, store, root, "child")
+	assert.True(t, child.UniqueSiblingNames, "child directory should inherit the setting")
+
+	_, err = store.CreateDirectory(ctx, &v1.Directory{Name: "child", Parent: &root.Id})
+	assert.ErrorIs(t, err, storage.ErrDirectoryNameConflict, "sibling with the same name should be refused")
+
+	sibling := createChild(t, store, root, "sibling")
+
+	err = store.UpdateDirectory(ctx, &v1.Directory{Id: sibling.Id, Name: "child"})
+	assert.ErrorIs(t, err, storage.ErrDirectoryNameConflict, "rename to a sibling's name should be refused")
+
+	// Names of deleted siblings may be reused.
+	_, err = store.DeleteDirectory(ctx, child.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	createChild(t, store, root, "child")
+
+	// Roots are not siblings.
+	createRoot(t, store, "root")
+}
@@ -0,0 +1,205 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+func testDeleteDirectory(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 3)
+
+	affected, err := store.DeleteDirectory(ctx, chain[1].Id)
+	assert.NoError(t, err, "error deleting directory")
+	assert.ElementsMatch(t, ids(chain[1:]), ids(affected), "directory and its descendants should be deleted")
+
+	for _, d := range affected {
+		assert.NotNil(t, d.DeletedAt, "deleted directories should be returned deleted")
+		assert.Equal(t, affected[0].DeletedAt.Unix(), d.DeletedAt.Unix(), "directories should be deleted together")
+	}
+
+	_, err = store.DeleteDirectory(ctx, chain[1].Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "deleted directory should not be deleted again")
+
+	_, err = store.DeleteDirectory(ctx, v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "unknown directory should not be deleted")
+
+	// The root directory is left as it was.
+	d, err := store.GetDirectory(ctx, chain[0].Id)
+	assert.NoError(t, err, "error getting root directory")
+	assert.Nil(t, d.DeletedAt, "root directory should not be deleted")
+}
+
+func testDeleteLargeSubtree(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+	parent := createChild(t, store, root, "parent")
+
+	// More descendants than fit in a page are deleted.
+	children := createChildren(t, store, parent, storage.DefaultPageSize+5)
+
+	affected, err := store.DeleteDirectory(ctx, parent.Id)
+	assert.NoError(t, err, "error deleting directory")
+	assert.Len(t, affected, len(children)+1, "all descendants should be deleted")
+
+	dirs, err := store.GetDirectories(ctx, ids(children))
+	assert.NoError(t, err, "error getting directories")
+	assert.Empty(t, dirs, "all descendants should be deleted")
+}
+
+func testDeletedDirectoriesAreHidden(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 2)
+
+	_, err := store.DeleteDirectory(ctx, chain[1].Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	for _, d := range chain[1:] {
+		_, err = store.GetDirectory(ctx, d.Id)
+		assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "deleted directory should not be found")
+
+		_, err = store.GetParents(ctx, d.Id)
+		assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "parents of deleted directory should not be found")
+
+		_, err = store.GetChildren(ctx, d.Id)
+		assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "children of deleted directory should not be found")
+
+		_, err = store.GetSubtree(ctx, d.Id)
+		assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "subtree of deleted directory should not be found")
+	}
+
+	dirs, err := store.GetDirectories(ctx, ids(chain))
+	assert.NoError(t, err, "error getting directories")
+	assert.Equal(t, ids(chain[:1]), ids(dirs), "deleted directories should be skipped")
+
+	children, err := store.GetChildren(ctx, chain[0].Id)
+	assert.NoError(t, err, "error getting children")
+	assert.Empty(t, children, "deleted children should not be listed")
+
+	tree, err := store.GetSubtree(ctx, chain[0].Id)
+	assert.NoError(t, err, "error getting subtree")
+	assert.Empty(t, tree.Children, "deleted children should not be in the subtree")
+
+	_, _, err = store.MoveDirectory(ctx, chain[2].Id, chain[0].Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "deleted directory should not be moved")
+
+	moved := createChild(t, store, chain[0], "moved")
+
+	_, _, err = store.MoveDirectory(ctx, moved.Id, chain[1].Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "directory should not be moved under a deleted one")
+
+	_, err = store.CreateDirectory(ctx, &v1.Directory{Name: "created", Parent: &chain[1].Id})
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "directory should not be created under a deleted one")
+
+	children, err = store.GetChildren(ctx, chain[1].Id, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting children")
+	assert.Equal(t, ids(chain[2:]), children, "no directory should be created under a deleted one")
+}
+
+func testWithDeletedDirectories(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 2)
+
+	_, err := store.DeleteDirectory(ctx, chain[1].Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	d, err := store.GetDirectory(ctx, chain[2].Id, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting deleted directory")
+	assert.NotNil(t, d.DeletedAt, "directory should be deleted")
+
+	dirs, err := store.GetDirectories(ctx, ids(chain), storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting directories")
+	assert.Equal(t, ids(chain), ids(dirs), "deleted directories should be returned")
+
+	children, err := store.GetChildren(ctx, chain[0].Id, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting children")
+	assert.Equal(t, ids(chain[1:]), children, "deleted children should be listed")
+
+	parents, err := store.GetParents(ctx, chain[2].Id, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting parents")
+	assert.Equal(t, reversed(chain[:2]), parents, "deleted parents should be listed")
+
+	parents, err = store.GetParentsUntilAncestor(ctx, chain[2].Id, chain[1].Id, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting parents")
+	assert.Equal(t, ids(chain[1:2]), parents, "deleted parents should be listed")
+
+	tree, err := store.GetSubtree(ctx, chain[1].Id, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting subtree")
+	assert.Equal(t, chain[1].Id, tree.Directory.Id, "subtree should start at the directory")
+	assert.Len(t, tree.Children, 1, "deleted children should be in the subtree")
+}
+
+func testRestoreDirectory(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 3)
+
+	// The last directory is deleted separately, before its parents.
+	_, err := store.DeleteDirectory(ctx, chain[3].Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	_, err = store.DeleteDirectory(ctx, chain[1].Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	_, err = store.RestoreDirectory(ctx, chain[2].Id)
+	assert.ErrorIs(t, err, storage.ErrParentDirectoryDeleted, "directory should not be restored under a deleted parent")
+
+	restored, err := store.RestoreDirectory(ctx, chain[1].Id)
+	assert.NoError(t, err, "error restoring directory")
+	assert.ElementsMatch(t, ids(chain[1:3]), ids(restored), "directories deleted together should be restored")
+
+	for _, d := range restored {
+		assert.Nil(t, d.DeletedAt, "restored directories should be returned restored")
+	}
+
+	_, err = store.GetDirectory(ctx, chain[2].Id)
+	assert.NoError(t, err, "restored directory should be found")
+
+	_, err = store.GetDirectory(ctx, chain[3].Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "separately deleted directory should not be restored")
+
+	_, err = store.RestoreDirectory(ctx, chain[1].Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotDeleted, "live directory should not be restored")
+
+	_, err = store.RestoreDirectory(ctx, v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "unknown directory should not be restored")
+}
+
+func testPurgeDirectory(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 3)
+
+	_, err := store.PurgeDirectory(ctx, chain[1].Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotDeleted, "live directory should not be purged")
+
+	_, err = store.DeleteDirectory(ctx, chain[1].Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	purged, err := store.PurgeDirectory(ctx, chain[1].Id)
+	assert.NoError(t, err, "error purging directory")
+	assert.ElementsMatch(t, ids(chain[1:]), ids(purged), "directory and its descendants should be purged")
+
+	for _, d := range chain[1:] {
+		_, err = store.GetDirectory(ctx, d.Id, storage.WithDeletedDirectories)
+		assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "purged directory should not be found")
+
+		history, err := store.GetHistory(ctx, d.Id)
+		assert.NoError(t, err, "error getting history")
+		assert.Empty(t, history, "history should be purged along with the directory")
+	}
+
+	children, err := store.GetChildren(ctx, chain[0].Id, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting children")
+	assert.Empty(t, children, "purged directories should not be listed")
+
+	_, err = store.PurgeDirectory(ctx, chain[1].Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "purged directory should not be purged again")
+
+	_, err = store.RestoreDirectory(ctx, chain[1].Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "purged directory should not be restored")
+}
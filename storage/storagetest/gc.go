@@ -0,0 +1,59 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+func testListDeleted(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+	first := createChild(t, store, root, "first")
+	createChild(t, store, first, "grandchild")
+	second := createChild(t, store, root, "second")
+	createChild(t, store, root, "live")
+
+	deleted, err := store.DeleteDirectory(ctx, first.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	// The deletions are told apart by their time.
+	time.Sleep(10 * time.Millisecond) //nolint:gomnd // see above
+
+	last, err := store.DeleteDirectory(ctx, second.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	dirs, err := store.ListDeleted(ctx, time.Now().Add(time.Minute), storage.Pagination(1, 10)) //nolint:gomnd // see above
+	assert.NoError(t, err, "error listing deleted directories")
+
+	if !assert.Len(t, dirs, 3, "unexpected count of deleted directories") {
+		return
+	}
+
+	assert.ElementsMatch(t, ids(deleted), ids(dirs[:2]), "oldest deletions should come first")
+	assert.Equal(t, ids(last), ids(dirs[2:]), "unexpected deleted directories")
+
+	dirs, err = store.ListDeleted(ctx, *last[0].DeletedAt)
+	assert.NoError(t, err, "error listing deleted directories")
+	assert.ElementsMatch(t, ids(deleted), ids(dirs), "only directories deleted before the time should be listed")
+
+	dirs, err = store.ListDeleted(ctx, *deleted[0].DeletedAt)
+	assert.NoError(t, err, "error listing deleted directories")
+	assert.Empty(t, dirs, "no directory should be deleted before the time")
+
+	dirs, err = store.ListDeleted(ctx, time.Now().Add(time.Minute), storage.Pagination(2, 2)) //nolint:gomnd // see above
+	assert.NoError(t, err, "error listing deleted directories")
+	assert.Equal(t, ids(last), ids(dirs), "unexpected page")
+
+	// Purged directories aren't listed anymore.
+	_, err = store.PurgeDirectory(ctx, first.Id)
+	assert.NoError(t, err, "error purging directory")
+
+	dirs, err = store.ListDeleted(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err, "error listing deleted directories")
+	assert.Equal(t, ids(last), ids(dirs), "purged directories should not be listed")
+}
@@ -0,0 +1,111 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+func testGetHistory(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := storage.WithActor(context.Background(), "tester")
+	root := createRoot(t, store, "root")
+
+	d, err := store.CreateDirectory(ctx, &v1.Directory{
+		Name:     "child",
+		Parent:   &root.Id,
+		Metadata: &v1.DirectoryMetadata{"env": "dev"},
+	})
+	assert.NoError(t, err, "error creating directory")
+
+	d.Metadata = &v1.DirectoryMetadata{"env": "prod"}
+
+	err = store.UpdateDirectory(ctx, d)
+	assert.NoError(t, err, "error updating directory")
+
+	_, err = store.DeleteDirectory(ctx, d.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	_, err = store.RestoreDirectory(ctx, d.Id)
+	assert.NoError(t, err, "error restoring directory")
+
+	history, err := store.GetHistory(ctx, d.Id)
+	assert.NoError(t, err, "error getting history")
+
+	expected := []v1.EventType{v1.EventTypeRestore, v1.EventTypeDelete, v1.EventTypeUpdate, v1.EventTypeCreate}
+
+	if !assert.Len(t, history, len(expected), "every change should be recorded") {
+		return
+	}
+
+	for i, r := range history {
+		assert.Equal(t, expected[i], r.Operation, "revisions should be newest first")
+		assert.Equal(t, int64(len(expected)-i), r.Revision, "unexpected revision")
+		assert.Equal(t, "tester", r.Actor, "the actor should be recorded")
+	}
+
+	assert.Nil(t, history[3].Before, "created directories have no previous state")
+	assert.Equal(t, "dev", (*history[2].Before.Metadata)["env"], "the previous metadata should be recorded")
+	assert.Equal(t, "prod", (*history[2].After.Metadata)["env"], "the new metadata should be recorded")
+	assert.NotNil(t, history[1].After.DeletedAt, "the directory should be deleted after")
+
+	history, err = store.GetHistory(ctx, d.Id, storage.Pagination(2, 3)) //nolint:gomnd // see above
+	assert.NoError(t, err, "error getting history")
+
+	if assert.Len(t, history, 1, "unexpected page size") {
+		assert.Equal(t, v1.EventTypeCreate, history[0].Operation, "unexpected revision")
+	}
+
+	// A failed transaction doesn't record anything.
+	err = store.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		if _, err := tx.DeleteDirectory(ctx, d.Id); err != nil {
+			return err
+		}
+
+		_, err := tx.DeleteDirectory(ctx, v1.DirectoryID(uuid.New()))
+
+		return err
+	})
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "transaction should fail")
+
+	history, err = store.GetHistory(ctx, d.Id)
+	assert.NoError(t, err, "error getting history")
+	assert.Len(t, history, len(expected), "rolled back changes should not be recorded")
+}
+
+func testAsOf(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+	d := createChild(t, store, root, "original")
+
+	// The changes are told apart from the point in time by their time.
+	time.Sleep(100 * time.Millisecond) //nolint:gomnd // see above
+	asOf := time.Now()
+	time.Sleep(100 * time.Millisecond) //nolint:gomnd // see above
+
+	d.Name = "renamed"
+
+	err := store.UpdateDirectory(ctx, d)
+	assert.NoError(t, err, "error updating directory")
+
+	_, err = store.DeleteDirectory(ctx, d.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	past, err := store.GetDirectory(ctx, d.Id, storage.WithAsOf(asOf))
+	if assert.NoError(t, err, "error getting directory as of a past time") {
+		assert.Equal(t, "original", past.Name, "the directory should be returned as it was")
+	}
+
+	children, err := store.GetChildren(ctx, root.Id, storage.WithAsOf(asOf))
+	assert.NoError(t, err, "error getting children as of a past time")
+	assert.Equal(t, []v1.DirectoryID{d.Id}, children, "children deleted since should be listed")
+
+	history, err := store.GetHistory(ctx, d.Id, storage.WithAsOf(asOf))
+	assert.NoError(t, err, "error getting history as of a past time")
+	assert.Len(t, history, 1, "later revisions should not be returned")
+}
@@ -0,0 +1,177 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// extra is the count of directories past the first page created by the pagination tests.
+const extra = 5
+
+// checkPages checks the pages returned by list against all the expected ids, which
+// span a full page and a partial one.
+func checkPages(t *testing.T, all []v1.DirectoryID, list func(...storage.Option) ([]v1.DirectoryID, error)) {
+	t.Helper()
+
+	page, err := list()
+	assert.NoError(t, err, "error listing first page")
+	assert.Equal(t, all[:storage.DefaultPageSize], page, "first page should have the default size")
+
+	page, err = list(storage.Pagination(2, storage.DefaultPageSize))
+	assert.NoError(t, err, "error listing second page")
+	assert.Equal(t, all[storage.DefaultPageSize:], page, "second page should have the remaining results")
+
+	page, err = list(storage.Pagination(3, storage.DefaultPageSize))
+	assert.NoError(t, err, "error listing page past the end")
+	assert.Empty(t, page, "page past the end should be empty")
+
+	page, err = list(storage.Pagination(1, len(all)*2))
+	assert.NoError(t, err, "error listing page larger than the results")
+	assert.Equal(t, all, page, "page larger than the results should have all of them")
+
+	page, err = list(storage.Pagination(2, 1))
+	assert.NoError(t, err, "error listing page of one")
+	assert.Equal(t, all[1:2], page, "page of one should have a single result")
+
+	// Invalid pages and sizes fall back to the defaults.
+	page, err = list(storage.Pagination(-1, 0))
+	assert.NoError(t, err, "error listing page with invalid pagination")
+	assert.Equal(t, all[:storage.DefaultPageSize], page, "invalid pagination should fall back to the first page")
+}
+
+func testListRootsPagination(t *testing.T, store storage.DirectoryAdmin) {
+	roots := make([]*v1.Directory, storage.DefaultPageSize+extra)
+
+	for i := range roots {
+		roots[i] = createRoot(t, store, "root")
+	}
+
+	checkPages(t, ids(roots), func(opts ...storage.Option) ([]v1.DirectoryID, error) {
+		return store.ListRoots(context.Background(), opts...)
+	})
+}
+
+func testGetChildrenPagination(t *testing.T, store storage.DirectoryAdmin) {
+	root := createRoot(t, store, "root")
+	children := createChildren(t, store, root, storage.DefaultPageSize+extra)
+
+	checkPages(t, ids(children), func(opts ...storage.Option) ([]v1.DirectoryID, error) {
+		return store.GetChildren(context.Background(), root.Id, opts...)
+	})
+}
+
+func testGetParentsPagination(t *testing.T, store storage.DirectoryAdmin) {
+	chain := createChain(t, store, storage.DefaultPageSize+extra)
+	last := chain[len(chain)-1]
+
+	checkPages(t, reversed(chain[:len(chain)-1]), func(opts ...storage.Option) ([]v1.DirectoryID, error) {
+		return store.GetParents(context.Background(), last.Id, opts...)
+	})
+
+	checkPages(t, reversed(chain[:len(chain)-1]), func(opts ...storage.Option) ([]v1.DirectoryID, error) {
+		return store.GetParentsUntilAncestor(context.Background(), last.Id, chain[0].Id, opts...)
+	})
+}
+
+func testCursorPagination(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, storage.DefaultPageSize+extra)
+	last := chain[len(chain)-1]
+
+	// The root of the chain is listed along with the other roots.
+	for i := 1; i < storage.DefaultPageSize+extra; i++ {
+		createRoot(t, store, "root")
+	}
+
+	cursorAfter := func(ids []v1.DirectoryID) storage.Option {
+		d, err := store.GetDirectory(ctx, ids[len(ids)-1])
+		assert.NoError(t, err, "error getting last directory of page")
+
+		return storage.WithCursor(storage.NewCursor(d))
+	}
+
+	lists := map[string]func(...storage.Option) ([]v1.DirectoryID, error){
+		"roots": func(opts ...storage.Option) ([]v1.DirectoryID, error) {
+			return store.ListRoots(ctx, opts...)
+		},
+		"children": func(opts ...storage.Option) ([]v1.DirectoryID, error) {
+			return store.GetChildren(ctx, chain[0].Id, opts...)
+		},
+		"parents": func(opts ...storage.Option) ([]v1.DirectoryID, error) {
+			return store.GetParents(ctx, last.Id, opts...)
+		},
+		"parents until ancestor": func(opts ...storage.Option) ([]v1.DirectoryID, error) {
+			return store.GetParentsUntilAncestor(ctx, last.Id, chain[0].Id, opts...)
+		},
+	}
+
+	for name, list := range lists {
+		all, err := list(storage.Pagination(1, 2*storage.DefaultPageSize+extra))
+		assert.NoError(t, err, "error listing all %s", name)
+		assert.Len(t, all, storage.DefaultPageSize+extra, "unexpected count of %s", name)
+
+		page1, err := list()
+		assert.NoError(t, err, "error listing %s", name)
+		assert.Equal(t, all[:storage.DefaultPageSize], page1, "first page of %s doesn't match", name)
+
+		// Cursors take precedence over pages.
+		page2, err := list(cursorAfter(page1), storage.Pagination(3, storage.DefaultPageSize))
+		assert.NoError(t, err, "error listing %s with cursor", name)
+		assert.Equal(t, all[storage.DefaultPageSize:], page2, "cursor page of %s doesn't match", name)
+
+		page, err := list(cursorAfter(all))
+		assert.NoError(t, err, "error listing %s with cursor", name)
+		assert.Empty(t, page, "cursor after the last of %s should return an empty page", name)
+	}
+}
+
+func testCount(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 3)
+	children := createChildren(t, store, chain[0], storage.DefaultPageSize+extra)
+
+	labelled, err := store.CreateRoot(ctx, &v1.Directory{Name: "labelled", Metadata: &v1.DirectoryMetadata{"env": "prod"}})
+	assert.NoError(t, err, "error creating root directory")
+
+	sel, err := storage.ParseSelector("env=prod")
+	assert.NoError(t, err, "error parsing selector")
+
+	count, err := store.CountRoots(ctx, storage.Pagination(1, 1))
+	assert.NoError(t, err, "error counting roots")
+	assert.Equal(t, 2, count, "all roots should be counted, whatever the page")
+
+	count, err = store.CountRoots(ctx, storage.WithSelector(sel))
+	assert.NoError(t, err, "error counting roots")
+	assert.Equal(t, 1, count, "only the matching roots should be counted")
+
+	count, err = store.CountChildren(ctx, chain[0].Id)
+	assert.NoError(t, err, "error counting children")
+	assert.Equal(t, len(children)+3, count, "all descendants should be counted")
+
+	count, err = store.CountChildren(ctx, chain[0].Id, storage.WithMaxDepth(1), storage.Pagination(2, 1))
+	assert.NoError(t, err, "error counting children")
+	assert.Equal(t, len(children)+1, count, "descendants below the depth should not be counted")
+
+	_, err = store.DeleteDirectory(ctx, chain[1].Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	count, err = store.CountChildren(ctx, chain[0].Id)
+	assert.NoError(t, err, "error counting children")
+	assert.Equal(t, len(children), count, "deleted descendants should not be counted")
+
+	count, err = store.CountChildren(ctx, chain[0].Id, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error counting children")
+	assert.Equal(t, len(children)+3, count, "deleted descendants should be counted when requested")
+
+	count, err = store.CountChildren(ctx, labelled.Id)
+	assert.NoError(t, err, "error counting children")
+	assert.Zero(t, count, "leaf directories have no children")
+
+	_, err = store.CountChildren(ctx, chain[1].Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "children of deleted directory should not be counted")
+}
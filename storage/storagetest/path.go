@@ -0,0 +1,79 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+func testResolvePath(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	acme := createRoot(t, store, "acme")
+	emea := createChild(t, store, acme, "emea")
+	payments := createChild(t, store, emea, "payments")
+	slashed := createChild(t, store, emea, "a/b")
+
+	d, err := store.ResolvePath(ctx, []string{"acme", "emea", "payments"})
+	assert.NoError(t, err, "error resolving path")
+	assert.Equal(t, payments, d, "unexpected directory")
+
+	d, err = store.ResolvePath(ctx, []string{"acme"})
+	assert.NoError(t, err, "error resolving path")
+	assert.Equal(t, acme.Id, d.Id, "roots should be resolved")
+
+	d, err = store.ResolvePath(ctx, []string{"acme", "emea", "a/b"})
+	assert.NoError(t, err, "error resolving path")
+	assert.Equal(t, slashed.Id, d.Id, "names should be matched as a whole")
+
+	path, err := storage.PathOf(ctx, store, payments)
+	assert.NoError(t, err, "error getting path")
+	assert.Equal(t, []string{"acme", "emea", "payments"}, path, "unexpected path")
+
+	for _, path := range [][]string{
+		{},
+		{"emea"},
+		{"acme", "payments"},
+		{"acme", "emea", "payments", "api"},
+		{"acme", "EMEA", "payments"},
+	} {
+		_, err = store.ResolvePath(ctx, path)
+		assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "path %v should not be found", path)
+	}
+
+	// Siblings sharing a name make the path ambiguous, but not the paths going
+	// through only one of them.
+	other := createChild(t, store, acme, "emea")
+	createChild(t, store, other, "support")
+
+	_, err = store.ResolvePath(ctx, []string{"acme", "emea"})
+	assert.ErrorIs(t, err, storage.ErrAmbiguousPath, "path should be ambiguous")
+
+	d, err = store.ResolvePath(ctx, []string{"acme", "emea", "payments"})
+	assert.NoError(t, err, "error resolving path")
+	assert.Equal(t, payments.Id, d.Id, "unexpected directory")
+
+	// Deleted directories are only resolved if requested.
+	_, err = store.DeleteDirectory(ctx, other.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	d, err = store.ResolvePath(ctx, []string{"acme", "emea"})
+	assert.NoError(t, err, "error resolving path")
+	assert.Equal(t, emea.Id, d.Id, "deleted directories should not be resolved")
+
+	_, err = store.ResolvePath(ctx, []string{"acme", "emea", "support"})
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "deleted directories should not be resolved")
+
+	_, err = store.ResolvePath(ctx, []string{"acme", "emea"}, storage.WithDeletedDirectories)
+	assert.ErrorIs(t, err, storage.ErrAmbiguousPath, "deleted directories should be resolved if requested")
+
+	d, err = store.ResolvePath(ctx, []string{"acme", "emea", "support"}, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error resolving path")
+	assert.True(t, d.IsDeleted(), "deleted directories should be resolved if requested")
+
+	path, err = storage.PathOf(ctx, store, d, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting path")
+	assert.Equal(t, []string{"acme", "emea", "support"}, path, "unexpected path")
+}
@@ -0,0 +1,132 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+	"github.com/infratographer/fertilesoil/storage/quota"
+)
+
+func testQuota(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 1)
+
+	q, err := store.GetQuota(ctx, chain[0].Id)
+	assert.NoError(t, err, "error getting quota")
+	assert.Equal(t, &v1.DirectoryQuota{}, q, "quota should not be overridden by default")
+
+	depth, children := 3, 0
+	set := &v1.DirectoryQuota{MaxDepth: &depth, MaxChildren: &children}
+
+	assert.NoError(t, store.SetQuota(ctx, chain[0].Id, set), "error setting quota")
+
+	q, err = store.GetQuota(ctx, chain[0].Id)
+	assert.NoError(t, err, "error getting quota")
+	assert.Equal(t, set, q, "quota should be the one set")
+
+	// The overrides are replaced as a whole.
+	descendants := 10
+	set = &v1.DirectoryQuota{MaxDescendants: &descendants}
+
+	assert.NoError(t, store.SetQuota(ctx, chain[0].Id, set), "error setting quota")
+
+	q, err = store.GetQuota(ctx, chain[0].Id)
+	assert.NoError(t, err, "error getting quota")
+	assert.Equal(t, set, q, "quota should be replaced")
+
+	_, err = store.GetQuota(ctx, chain[1].Id)
+	assert.ErrorIs(t, err, storage.ErrNotRoot, "quota of non-root should not be read")
+
+	err = store.SetQuota(ctx, chain[1].Id, set)
+	assert.ErrorIs(t, err, storage.ErrNotRoot, "quota of non-root should not be set")
+
+	_, err = store.GetQuota(ctx, v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "quota of unknown directory should not be read")
+
+	err = store.SetQuota(ctx, v1.DirectoryID(uuid.New()), set)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "quota of unknown directory should not be set")
+
+	// The quota is dropped once the root is demoted.
+	other := createRoot(t, store, "other")
+
+	_, err = store.DemoteRoot(ctx, chain[0].Id, other.Id)
+	assert.NoError(t, err, "error demoting root")
+
+	_, _, err = store.PromoteToRoot(ctx, chain[0].Id)
+	assert.NoError(t, err, "error promoting directory")
+
+	q, err = store.GetQuota(ctx, chain[0].Id)
+	assert.NoError(t, err, "error getting quota")
+	assert.Equal(t, &v1.DirectoryQuota{}, q, "quota should be dropped by demotion")
+}
+
+func testQuotaWithTx(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+	depth := 1
+	errRollback := errors.New("rollback")
+
+	err := store.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		if err := tx.SetQuota(ctx, root.Id, &v1.DirectoryQuota{MaxDepth: &depth}); err != nil {
+			return err
+		}
+
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback, "transaction should fail")
+
+	q, err := store.GetQuota(ctx, root.Id)
+	assert.NoError(t, err, "error getting quota")
+	assert.Equal(t, &v1.DirectoryQuota{}, q, "quota should be rolled back")
+}
+
+func testQuotaConcurrentCreates(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+	quotas := quota.StorageWithQuotas(store, quota.WithLimits(quota.Limits{MaxChildren: 1}))
+
+	const attempts = 5
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, attempts)
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := quotas.CreateDirectory(ctx, &v1.Directory{Name: "child", Parent: &root.Id})
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	var created int
+
+	for err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+
+		var quotaErr *storage.QuotaExceededError
+		assert.ErrorAs(t, err, &quotaErr, "concurrent creates should only fail on the quota")
+	}
+
+	assert.Equal(t, 1, created, "concurrent creates should not exceed the quota")
+
+	count, err := store.CountChildren(ctx, root.Id)
+	assert.NoError(t, err, "error counting children")
+	assert.Equal(t, 1, count, "concurrent creates should not exceed the quota")
+}
@@ -0,0 +1,103 @@
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+func testSchema(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 2)
+	rootSchema := json.RawMessage(`{"type": "object", "required": ["env"]}`)
+	childSchema := json.RawMessage(`{"type": "object", "maxProperties": 1}`)
+
+	_, err := store.GetSchema(ctx, chain[2].Id)
+	assert.ErrorIs(t, err, storage.ErrSchemaNotFound, "no schema should apply by default")
+
+	assert.NoError(t, store.SetSchema(ctx, chain[0].Id, rootSchema), "error setting schema")
+
+	// The schema of the root applies to its whole tree.
+	for _, d := range chain {
+		s, err := store.GetSchema(ctx, d.Id)
+		if assert.NoError(t, err, "error getting schema") {
+			assert.Equal(t, chain[0].Id, s.Source, "schema should come from the root")
+			assert.JSONEq(t, string(rootSchema), string(s.Schema), "schema should be the one set")
+		}
+	}
+
+	// The closest schema applies.
+	assert.NoError(t, store.SetSchema(ctx, chain[1].Id, childSchema), "error setting schema")
+
+	s, err := store.GetSchema(ctx, chain[2].Id)
+	if assert.NoError(t, err, "error getting schema") {
+		assert.Equal(t, chain[1].Id, s.Source, "schema should come from the closest ancestor")
+		assert.JSONEq(t, string(childSchema), string(s.Schema), "schema should be the closest one")
+	}
+
+	// Schemas are replaced as a whole.
+	assert.NoError(t, store.SetSchema(ctx, chain[1].Id, rootSchema), "error setting schema")
+
+	s, err = store.GetSchema(ctx, chain[1].Id)
+	if assert.NoError(t, err, "error getting schema") {
+		assert.JSONEq(t, string(rootSchema), string(s.Schema), "schema should be replaced")
+	}
+
+	// Once deleted, the schema of the ancestors applies again.
+	assert.NoError(t, store.DeleteSchema(ctx, chain[1].Id), "error deleting schema")
+
+	s, err = store.GetSchema(ctx, chain[2].Id)
+	if assert.NoError(t, err, "error getting schema") {
+		assert.Equal(t, chain[0].Id, s.Source, "schema should come from the root again")
+	}
+
+	err = store.DeleteSchema(ctx, chain[1].Id)
+	assert.ErrorIs(t, err, storage.ErrSchemaNotFound, "directory without schema should have none to delete")
+
+	unknown := v1.DirectoryID(uuid.New())
+
+	_, err = store.GetSchema(ctx, unknown)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "schema of unknown directory should not be read")
+
+	err = store.SetSchema(ctx, unknown, rootSchema)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "schema of unknown directory should not be set")
+
+	err = store.DeleteSchema(ctx, unknown)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "schema of unknown directory should not be deleted")
+
+	assert.NoError(t, store.SetSchema(ctx, chain[1].Id, childSchema), "error setting schema")
+
+	_, err = store.DeleteDirectory(ctx, chain[1].Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	_, err = store.GetSchema(ctx, chain[2].Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "schema of deleted directory should not be read")
+
+	_, err = store.PurgeDirectory(ctx, chain[1].Id)
+	assert.NoError(t, err, "error purging directory with schema")
+}
+
+func testSchemaWithTx(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+	errRollback := errors.New("rollback")
+
+	err := store.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		if err := tx.SetSchema(ctx, root.Id, json.RawMessage(`{"type": "object"}`)); err != nil {
+			return err
+		}
+
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback, "transaction should fail")
+
+	_, err = store.GetSchema(ctx, root.Id)
+	assert.ErrorIs(t, err, storage.ErrSchemaNotFound, "schema should be rolled back")
+}
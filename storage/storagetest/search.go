@@ -0,0 +1,93 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// resultIDs returns the ids of the directories of the search results.
+func resultIDs(results []*v1.DirectorySearchResult) []v1.DirectoryID {
+	res := make([]v1.DirectoryID, len(results))
+
+	for i, r := range results {
+		res[i] = r.Directory.Id
+	}
+
+	return res
+}
+
+func testSearchDirectories(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+	apps := createChild(t, store, root, "apps")
+	payments := createChild(t, store, apps, "payments")
+	paymentsAPI := createChild(t, store, payments, "payments-api")
+	legacy := createChild(t, store, root, "Legacy_Payments")
+	pay := createChild(t, store, root, "pay")
+
+	// Directories of other trees are never returned.
+	other := createRoot(t, store, "other")
+	createChild(t, store, other, "payments")
+
+	results, err := store.SearchDirectories(ctx, root.Id, storage.NameQuery{Name: "payments", Match: storage.MatchExact})
+	assert.NoError(t, err, "error searching directories")
+
+	if assert.Len(t, results, 1, "unexpected results") {
+		assert.Equal(t, payments.Id, results[0].Directory.Id, "unexpected directory")
+		assert.Equal(t, "payments", results[0].Directory.Name, "full directories should be returned")
+		assert.Equal(t, []v1.DirectoryID{apps.Id, root.Id}, results[0].Parents,
+			"parents should be returned up to the searched directory")
+	}
+
+	results, err = store.SearchDirectories(ctx, root.Id, storage.NameQuery{Name: "payments", Match: storage.MatchPrefix})
+	assert.NoError(t, err, "error searching directories")
+	assert.Equal(t, ids([]*v1.Directory{payments, paymentsAPI}), resultIDs(results), "unexpected prefix matches")
+
+	results, err = store.SearchDirectories(ctx, root.Id, storage.NameQuery{Name: "PAYMENTS", Match: storage.MatchContains})
+	assert.NoError(t, err, "error searching directories")
+	assert.Equal(t, ids([]*v1.Directory{payments, paymentsAPI, legacy}), resultIDs(results),
+		"substring matches should ignore case")
+
+	results, err = store.SearchDirectories(ctx, root.Id, storage.NameQuery{Name: "y_p", Match: storage.MatchContains})
+	assert.NoError(t, err, "error searching directories")
+	assert.Equal(t, ids([]*v1.Directory{legacy}), resultIDs(results), "wildcards should be matched literally")
+
+	// Only the descendants of the directory are searched.
+	results, err = store.SearchDirectories(ctx, payments.Id,
+		storage.NameQuery{Name: "payments", Match: storage.MatchPrefix})
+	assert.NoError(t, err, "error searching directories")
+
+	if assert.Len(t, results, 1, "unexpected results") {
+		assert.Equal(t, paymentsAPI.Id, results[0].Directory.Id, "unexpected directory")
+		assert.Equal(t, []v1.DirectoryID{payments.Id}, results[0].Parents, "unexpected parents")
+	}
+
+	query := storage.NameQuery{Name: "pay", Match: storage.MatchContains}
+
+	results, err = store.SearchDirectories(ctx, root.Id, query, storage.Pagination(2, 2))
+	assert.NoError(t, err, "error searching directories")
+	assert.Equal(t, ids([]*v1.Directory{legacy, pay}), resultIDs(results), "unexpected page")
+
+	_, err = store.DeleteDirectory(ctx, payments.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	results, err = store.SearchDirectories(ctx, root.Id, query)
+	assert.NoError(t, err, "error searching directories")
+	assert.Len(t, results, 2, "deleted directories should be left out")
+
+	results, err = store.SearchDirectories(ctx, root.Id, query, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error searching directories")
+	assert.Len(t, results, 4, "deleted directories should be included")
+
+	_, err = store.SearchDirectories(ctx, root.Id, storage.NameQuery{Match: storage.MatchExact})
+	assert.ErrorIs(t, err, storage.ErrInvalidSearch, "empty name should be refused")
+
+	_, err = store.SearchDirectories(ctx, v1.DirectoryID(uuid.New()), query)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "unknown directory should not be found")
+}
@@ -0,0 +1,164 @@
+// Package storagetest implements a conformance test suite for
+// storage.DirectoryAdmin implementations.
+//
+// Drivers run the suite from their own tests, providing a factory
+// which returns a new driver backed by an empty database:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.RunConformance(t, func(t *testing.T) storage.DirectoryAdmin {
+//			return driver.NewDirectoryDriver(newTestDB(t))
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+// Factory returns a new driver backed by an empty database.
+// It is called once per test, and may be called from parallel tests.
+// Any cleanup should be registered with t.Cleanup.
+type Factory func(t *testing.T) storage.DirectoryAdmin
+
+// RunConformance runs the conformance test suite against the
+// drivers returned by the factory. Each test runs as a parallel
+// subtest of t, with a driver of its own.
+func RunConformance(t *testing.T, factory Factory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(*testing.T, storage.DirectoryAdmin)
+	}{
+		{"CreateRoot", testCreateRoot},
+		{"CreateDirectory", testCreateDirectory},
+		{"GetUnknownDirectory", testGetUnknownDirectory},
+		{"GetDirectories", testGetDirectories},
+		{"UpdateDirectory", testUpdateDirectory},
+		{"UpdateDirectoryIfRevision", testUpdateDirectoryIfRevision},
+		{"DeleteDirectoryIfRevision", testDeleteDirectoryIfRevision},
+		{"MoveDirectoryIfRevision", testMoveDirectoryIfRevision},
+		{"ConcurrentUpdatesIfRevision", testConcurrentUpdatesIfRevision},
+		{"UniqueSiblingNames", testUniqueSiblingNames},
+		{"DeleteDirectory", testDeleteDirectory},
+		{"DeleteLargeSubtree", testDeleteLargeSubtree},
+		{"DeletedDirectoriesAreHidden", testDeletedDirectoriesAreHidden},
+		{"WithDeletedDirectories", testWithDeletedDirectories},
+		{"RestoreDirectory", testRestoreDirectory},
+		{"PurgeDirectory", testPurgeDirectory},
+		{"ListDeleted", testListDeleted},
+		{"GetHistory", testGetHistory},
+		{"AsOf", testAsOf},
+		{"ListRootsPagination", testListRootsPagination},
+		{"GetChildrenPagination", testGetChildrenPagination},
+		{"GetParentsPagination", testGetParentsPagination},
+		{"CursorPagination", testCursorPagination},
+		{"Count", testCount},
+		{"GetChildren", testGetChildren},
+		{"GetChildrenBreadthFirst", testGetChildrenBreadthFirst},
+		{"GetParents", testGetParents},
+		{"GetParentsUntilAncestor", testGetParentsUntilAncestor},
+		{"GetSubtree", testGetSubtree},
+		{"GetStats", testGetStats},
+		{"SearchDirectories", testSearchDirectories},
+		{"ResolvePath", testResolvePath},
+		{"RootRules", testRootRules},
+		{"MoveDirectory", testMoveDirectory},
+		{"ConcurrentMoves", testConcurrentMoves},
+		{"WithTx", testWithTx},
+		{"Quota", testQuota},
+		{"QuotaWithTx", testQuotaWithTx},
+		{"QuotaConcurrentCreates", testQuotaConcurrentCreates},
+		{"Schema", testSchema},
+		{"SchemaWithTx", testSchemaWithTx},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.fn(t, factory(t))
+		})
+	}
+}
+
+// createRoot creates a root directory with the provided name.
+func createRoot(t *testing.T, store storage.DirectoryAdmin, name string) *v1.Directory {
+	t.Helper()
+
+	d, err := store.CreateRoot(context.Background(), &v1.Directory{Name: name})
+	if !assert.NoError(t, err, "error creating root directory") {
+		t.FailNow()
+	}
+
+	return d
+}
+
+// createChild creates a directory with the provided name under the parent.
+func createChild(t *testing.T, store storage.DirectoryAdmin, parent *v1.Directory, name string) *v1.Directory {
+	t.Helper()
+
+	d, err := store.CreateDirectory(context.Background(), &v1.Directory{Name: name, Parent: &parent.Id})
+	if !assert.NoError(t, err, "error creating directory") {
+		t.FailNow()
+	}
+
+	return d
+}
+
+// createChain creates a root directory with a chain of descendants of the
+// provided length under it. The directories are returned from the root down.
+func createChain(t *testing.T, store storage.DirectoryAdmin, length int) []*v1.Directory {
+	t.Helper()
+
+	chain := []*v1.Directory{createRoot(t, store, "root")}
+
+	for i := 0; i < length; i++ {
+		chain = append(chain, createChild(t, store, chain[i], "dir"))
+	}
+
+	return chain
+}
+
+// createChildren creates the provided number of children under the parent.
+func createChildren(t *testing.T, store storage.DirectoryAdmin, parent *v1.Directory, n int) []*v1.Directory {
+	t.Helper()
+
+	children := make([]*v1.Directory, n)
+
+	for i := range children {
+		children[i] = createChild(t, store, parent, "child")
+	}
+
+	return children
+}
+
+// ids returns the ids of the directories.
+func ids(dirs []*v1.Directory) []v1.DirectoryID {
+	res := make([]v1.DirectoryID, len(dirs))
+
+	for i, d := range dirs {
+		res[i] = d.Id
+	}
+
+	return res
+}
+
+// reversed returns the ids of the directories in reverse order.
+func reversed(dirs []*v1.Directory) []v1.DirectoryID {
+	res := make([]v1.DirectoryID, len(dirs))
+
+	for i, d := range dirs {
+		res[len(dirs)-1-i] = d.Id
+	}
+
+	return res
+}
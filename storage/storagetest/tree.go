@@ -0,0 +1,402 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/infratographer/fertilesoil/api/v1"
+	"github.com/infratographer/fertilesoil/storage"
+)
+
+func testGetChildren(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 3)
+
+	children, err := store.GetChildren(ctx, chain[0].Id)
+	assert.NoError(t, err, "error getting children")
+	assert.Equal(t, ids(chain[1:]), children, "all descendants should be listed in creation order")
+
+	children, err = store.GetChildren(ctx, chain[0].Id, storage.WithMaxDepth(1))
+	assert.NoError(t, err, "error getting children")
+	assert.Equal(t, ids(chain[1:2]), children, "only immediate children should be listed")
+
+	children, err = store.GetChildren(ctx, chain[0].Id, storage.WithMaxDepth(2))
+	assert.NoError(t, err, "error getting children")
+	assert.Equal(t, ids(chain[1:3]), children, "descendants below the depth should not be listed")
+
+	children, err = store.GetChildren(ctx, chain[3].Id)
+	assert.NoError(t, err, "error getting children")
+	assert.Empty(t, children, "leaf directories have no children")
+
+	children, err = store.GetChildren(ctx, v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "children of unknown directory should not be found")
+	assert.Nil(t, children, "no children should be returned")
+
+	labelled, err := store.CreateDirectory(ctx, &v1.Directory{
+		Name:     "labelled",
+		Parent:   &chain[0].Id,
+		Metadata: &v1.DirectoryMetadata{"env": "prod"},
+	})
+	assert.NoError(t, err, "error creating directory")
+
+	tests := map[string][]v1.DirectoryID{
+		"env=prod": {labelled.Id},
+		"!env":     ids(chain[1:]),
+		"env=dev":  nil,
+	}
+
+	for selector, expected := range tests {
+		sel, err := storage.ParseSelector(selector)
+		assert.NoError(t, err, "error parsing selector")
+
+		children, err := store.GetChildren(ctx, chain[0].Id, storage.WithSelector(sel))
+		assert.NoError(t, err, "error getting children")
+		assert.ElementsMatch(t, expected, children, "unexpected children for %q", selector)
+	}
+}
+
+func testGetChildrenBreadthFirst(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+	first := createChild(t, store, root, "first")
+	grandchild := createChild(t, store, first, "grandchild")
+	second := createChild(t, store, root, "second")
+
+	expected := []v1.DirectoryID{first.Id, second.Id, grandchild.Id}
+
+	children, err := store.GetChildren(ctx, root.Id)
+	assert.NoError(t, err, "error getting children")
+	assert.Equal(t, expected, children, "children should be listed level by level")
+
+	for i, id := range expected {
+		children, err := store.GetChildren(ctx, root.Id, storage.Pagination(i+1, 1))
+		assert.NoError(t, err, "error getting page %d of children", i+1)
+		assert.Equal(t, []v1.DirectoryID{id}, children, "unexpected page %d of children", i+1)
+	}
+
+	for i, d := range []*v1.Directory{first, second, grandchild} {
+		children, err := store.GetChildren(ctx, root.Id, storage.WithCursor(storage.NewCursor(d)))
+		assert.NoError(t, err, "error getting children with cursor")
+		// The last page is empty, and returned as nil.
+		assert.Equal(t, append([]v1.DirectoryID(nil), expected[i+1:]...), children,
+			"unexpected children after %s", d.Name)
+	}
+
+	other := createRoot(t, store, "other")
+
+	_, err = store.GetChildren(ctx, root.Id, storage.WithCursor(storage.NewCursor(other)))
+	assert.ErrorIs(t, err, storage.ErrInvalidCursor, "cursor outside of the subtree should be refused")
+}
+
+func testGetParents(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 3)
+
+	parents, err := store.GetParents(ctx, chain[3].Id)
+	assert.NoError(t, err, "error getting parents")
+	assert.Equal(t, reversed(chain[:3]), parents, "parents should go from the closest to the furthest")
+
+	parents, err = store.GetParents(ctx, chain[0].Id)
+	assert.NoError(t, err, "error getting parents")
+	assert.Empty(t, parents, "root directories have no parents")
+
+	parents, err = store.GetParents(ctx, v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "parents of unknown directory should not be found")
+	assert.Nil(t, parents, "no parents should be returned")
+
+	parents, err = store.GetParents(ctx, chain[3].Id, storage.WithCursor(storage.NewCursor(chain[1])))
+	assert.NoError(t, err, "error getting parents with cursor")
+	assert.Equal(t, ids(chain[:1]), parents, "parents should continue after the cursor")
+
+	sibling := createChild(t, store, chain[1], "sibling")
+
+	_, err = store.GetParents(ctx, chain[3].Id, storage.WithCursor(storage.NewCursor(sibling)))
+	assert.ErrorIs(t, err, storage.ErrInvalidCursor, "cursor outside of the parents should be refused")
+}
+
+func testGetParentsUntilAncestor(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 3)
+	other := createRoot(t, store, "other")
+
+	parents, err := store.GetParentsUntilAncestor(ctx, chain[3].Id, chain[1].Id)
+	assert.NoError(t, err, "error getting parents")
+	assert.Equal(t, reversed(chain[1:3]), parents, "parents up to and including the ancestor should be listed")
+
+	parents, err = store.GetParentsUntilAncestor(ctx, chain[3].Id, chain[2].Id)
+	assert.NoError(t, err, "error getting parents")
+	assert.Equal(t, ids(chain[2:3]), parents, "only the parent should be listed")
+
+	parents, err = store.GetParentsUntilAncestor(ctx, chain[1].Id, chain[1].Id)
+	assert.NoError(t, err, "error getting parents")
+	assert.Empty(t, parents, "a directory has no parents until itself")
+
+	// The walk continues up to the root if the ancestor isn't met.
+	parents, err = store.GetParentsUntilAncestor(ctx, chain[3].Id, other.Id)
+	assert.NoError(t, err, "error getting parents")
+	assert.Equal(t, reversed(chain[:3]), parents, "all parents should be listed")
+
+	parents, err = store.GetParentsUntilAncestor(ctx, v1.DirectoryID(uuid.New()), chain[0].Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "parents of unknown directory should not be found")
+	assert.Nil(t, parents, "no parents should be returned")
+
+	_, err = store.GetParentsUntilAncestor(ctx, chain[3].Id, chain[1].Id, storage.WithCursor(storage.NewCursor(other)))
+	assert.ErrorIs(t, err, storage.ErrInvalidCursor, "cursor outside of the parents should be refused")
+
+	_, err = store.GetParentsUntilAncestor(ctx, chain[3].Id, chain[1].Id, storage.WithCursor(storage.NewCursor(chain[0])))
+	assert.ErrorIs(t, err, storage.ErrInvalidCursor, "cursor beyond the ancestor should be refused")
+}
+
+func testGetSubtree(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 2)
+	sibling := createChild(t, store, chain[0], "sibling")
+
+	tree, err := store.GetSubtree(ctx, chain[0].Id)
+	assert.NoError(t, err, "error getting subtree")
+	assert.Equal(t, chain[0].Id, tree.Directory.Id, "subtree should start at the directory")
+
+	if assert.Len(t, tree.Children, 2, "unexpected children") {
+		assert.Equal(t, chain[1].Id, tree.Children[0].Directory.Id, "children should be in creation order")
+		assert.Equal(t, "dir", tree.Children[0].Directory.Name, "full directories should be returned")
+		assert.Equal(t, sibling.Id, tree.Children[1].Directory.Id, "children should be in creation order")
+		assert.Len(t, tree.Children[0].Children, 1, "unexpected grandchildren")
+	}
+
+	tree, err = store.GetSubtree(ctx, chain[0].Id, storage.WithMaxDepth(1))
+	assert.NoError(t, err, "error getting subtree")
+
+	if assert.Len(t, tree.Children, 2, "unexpected children") {
+		assert.Empty(t, tree.Children[0].Children, "directories below the depth should be left out")
+	}
+
+	_, err = store.GetSubtree(ctx, chain[0].Id, storage.WithMaxNodes(3))
+	assert.ErrorIs(t, err, storage.ErrSubtreeTooLarge, "subtree should be too large")
+
+	_, err = store.GetSubtree(ctx, chain[0].Id, storage.WithMaxNodes(4))
+	assert.NoError(t, err, "subtree should fit")
+
+	_, err = store.GetSubtree(ctx, v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "unknown directory should not be found")
+}
+
+func testGetStats(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 2)
+	sibling := createChild(t, store, chain[0], "sibling")
+	createChild(t, store, sibling, "dir")
+
+	deleted, err := store.DeleteDirectory(ctx, sibling.Id)
+	if !assert.NoError(t, err, "error deleting directory") {
+		t.FailNow()
+	}
+
+	stats, err := store.GetStats(ctx, chain[0].Id)
+	assert.NoError(t, err, "error getting stats")
+	assert.Equal(t, chain[0].Id, stats.Id, "stats should be of the directory")
+	assert.Equal(t, 1, stats.Children, "deleted children should not be counted")
+	assert.Equal(t, 4, stats.Descendants, "all descendants should be counted")
+	assert.Equal(t, 2, stats.LiveDescendants, "unexpected live descendants")
+	assert.Equal(t, 2, stats.DeletedDescendants, "unexpected deleted descendants")
+	assert.Equal(t, 2, stats.MaxDepth, "unexpected depth")
+	assert.WithinDuration(t, *deleted[0].DeletedAt, stats.LastModified, time.Second,
+		"last modification should be the deletion")
+
+	stats, err = store.GetStats(ctx, chain[2].Id)
+	assert.NoError(t, err, "error getting stats")
+	assert.Equal(t, &v1.DirectoryStats{Id: chain[2].Id, LastModified: stats.LastModified}, stats,
+		"directory without descendants should have empty stats")
+
+	_, err = store.GetStats(ctx, sibling.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "deleted directory should not be found")
+
+	stats, err = store.GetStats(ctx, sibling.Id, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting stats of deleted directory")
+	assert.Equal(t, 1, stats.DeletedDescendants, "unexpected deleted descendants")
+	assert.Equal(t, 0, stats.MaxDepth, "deleted descendants should not count towards the depth")
+
+	_, err = store.GetStats(ctx, v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "unknown directory should not be found")
+}
+
+func testRootRules(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 2)
+	other := createRoot(t, store, "other")
+
+	_, err := store.DeleteDirectory(ctx, chain[0].Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "root directory should not be deleted")
+
+	_, _, err = store.MoveDirectory(ctx, chain[0].Id, other.Id)
+	assert.ErrorIs(t, err, storage.ErrRootDirectoryMove, "root directory should not be moved")
+
+	_, _, err = store.PromoteToRoot(ctx, chain[0].Id)
+	assert.ErrorIs(t, err, storage.ErrAlreadyRoot, "root directory should not be promoted")
+
+	_, err = store.DemoteRoot(ctx, chain[1].Id, other.Id)
+	assert.ErrorIs(t, err, storage.ErrNotRoot, "child directory should not be demoted")
+
+	_, err = store.DemoteRoot(ctx, chain[0].Id, chain[2].Id)
+	assert.ErrorIs(t, err, storage.ErrMoveCycle, "root directory should not be demoted under its descendant")
+
+	promoted, oldParent, err := store.PromoteToRoot(ctx, chain[1].Id)
+	assert.NoError(t, err, "error promoting directory")
+	assert.Nil(t, promoted.Parent, "promoted directory should have no parent")
+	assert.Equal(t, &chain[0].Id, oldParent, "previous parent should be returned")
+
+	roots, err := store.ListRoots(ctx)
+	assert.NoError(t, err, "error listing roots")
+	assert.ElementsMatch(t, []v1.DirectoryID{chain[0].Id, chain[1].Id, other.Id}, roots,
+		"promoted directory should be a root")
+
+	demoted, err := store.DemoteRoot(ctx, other.Id, chain[2].Id)
+	assert.NoError(t, err, "error demoting directory")
+	assert.Equal(t, &chain[2].Id, demoted.Parent, "demoted directory should have the new parent")
+
+	roots, err = store.ListRoots(ctx)
+	assert.NoError(t, err, "error listing roots")
+	assert.ElementsMatch(t, []v1.DirectoryID{chain[0].Id, chain[1].Id}, roots, "demoted directory should not be a root")
+
+	parents, err := store.GetParents(ctx, other.Id)
+	assert.NoError(t, err, "error getting parents")
+	assert.Equal(t, []v1.DirectoryID{chain[2].Id, chain[1].Id}, parents, "demoted directory should have new parents")
+}
+
+func testMoveDirectory(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	chain := createChain(t, store, 2)
+	other := createRoot(t, store, "other")
+
+	moved, oldParent, err := store.MoveDirectory(ctx, chain[1].Id, other.Id)
+	assert.NoError(t, err, "error moving directory")
+	assert.Equal(t, &other.Id, moved.Parent, "moved directory should have the new parent")
+	assert.Equal(t, &chain[0].Id, oldParent, "previous parent should be returned")
+	assert.Equal(t, chain[1].Revision+1, moved.Revision, "revision should have been incremented")
+
+	parents, err := store.GetParents(ctx, chain[2].Id)
+	assert.NoError(t, err, "error getting parents")
+	assert.Equal(t, []v1.DirectoryID{chain[1].Id, other.Id}, parents, "descendants should have moved along")
+
+	children, err := store.GetChildren(ctx, chain[0].Id)
+	assert.NoError(t, err, "error getting children")
+	assert.Empty(t, children, "moved directories should no longer be children")
+
+	_, _, err = store.MoveDirectory(ctx, chain[1].Id, chain[1].Id)
+	assert.ErrorIs(t, err, storage.ErrMoveCycle, "directory should not be moved under itself")
+
+	_, _, err = store.MoveDirectory(ctx, chain[1].Id, chain[2].Id)
+	assert.ErrorIs(t, err, storage.ErrMoveCycle, "directory should not be moved under its descendant")
+
+	_, _, err = store.MoveDirectory(ctx, chain[1].Id, v1.DirectoryID(uuid.New()))
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "directory should not be moved under an unknown one")
+
+	_, _, err = store.MoveDirectory(ctx, v1.DirectoryID(uuid.New()), other.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "unknown directory should not be moved")
+}
+
+func testConcurrentMoves(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+	a := createChild(t, store, root, "a")
+	b := createChild(t, store, root, "b")
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 2)
+
+	for _, move := range [][2]v1.DirectoryID{{a.Id, b.Id}, {b.Id, a.Id}} {
+		wg.Add(1)
+
+		go func(id, parent v1.DirectoryID) {
+			defer wg.Done()
+
+			_, _, err := store.MoveDirectory(ctx, id, parent)
+			errs <- err
+		}(move[0], move[1])
+	}
+
+	wg.Wait()
+	close(errs)
+
+	var moved int
+
+	for err := range errs {
+		if err == nil {
+			moved++
+			continue
+		}
+
+		assert.ErrorIs(t, err, storage.ErrMoveCycle, "concurrent moves should only fail on the cycle")
+	}
+
+	assert.Equal(t, 1, moved, "only one of the crossed moves should be applied")
+
+	for _, id := range []v1.DirectoryID{a.Id, b.Id} {
+		parents, err := store.GetParents(ctx, id)
+		assert.NoError(t, err, "error getting parents")
+
+		if assert.NotEmpty(t, parents, "directory should have parents") {
+			assert.Equal(t, root.Id, parents[len(parents)-1], "directory should still be under the root")
+		}
+	}
+}
+
+func testWithTx(t *testing.T, store storage.DirectoryAdmin) {
+	ctx := context.Background()
+	root := createRoot(t, store, "root")
+
+	var committed *v1.Directory
+
+	err := store.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		var err error
+
+		committed, err = tx.CreateDirectory(ctx, &v1.Directory{Name: "committed", Parent: &root.Id})
+		if err != nil {
+			return err
+		}
+
+		// Changes are seen within the transaction.
+		_, err = tx.GetDirectory(ctx, committed.Id)
+
+		return err
+	})
+	assert.NoError(t, err, "error running transaction")
+
+	_, err = store.GetDirectory(ctx, committed.Id)
+	assert.NoError(t, err, "committed directory should be found")
+
+	var rolledBack *v1.Directory
+
+	errRollback := errors.New("rollback")
+
+	err = store.WithTx(ctx, func(tx storage.DirectoryAdmin) error {
+		var err error
+
+		rolledBack, err = tx.CreateDirectory(ctx, &v1.Directory{Name: "rolled back", Parent: &root.Id})
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.DeleteDirectory(ctx, committed.Id); err != nil {
+			return err
+		}
+
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback, "error of the transaction should be returned")
+
+	if assert.NotNil(t, rolledBack, "directory should have been created within the transaction") {
+		_, err = store.GetDirectory(ctx, rolledBack.Id, storage.WithDeletedDirectories)
+		assert.ErrorIs(t, err, storage.ErrDirectoryNotFound, "rolled back directory should not be found")
+	}
+
+	d, err := store.GetDirectory(ctx, committed.Id)
+	assert.NoError(t, err, "rolled back deletion should not be applied")
+	assert.Nil(t, d.DeletedAt, "rolled back deletion should not be applied")
+}
@@ -2,7 +2,6 @@ package integration_test
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"testing"
 	"time"
@@ -13,7 +12,6 @@ import (
 
 	apiv1 "github.com/infratographer/fertilesoil/api/v1"
 	"github.com/infratographer/fertilesoil/internal/httpsrv/treemanager"
-	"github.com/infratographer/fertilesoil/storage/crdb/driver"
 	"github.com/infratographer/fertilesoil/tests/integration"
 	testutils "github.com/infratographer/fertilesoil/tests/utils"
 )
@@ -288,10 +286,9 @@ func TestServerWithBadDB(t *testing.T) {
 	assert.NoError(t, err, "error creating logger")
 
 	// We're opening a valid database connection, but there's not database set.
-	dbconn, err := sql.Open("postgres", baseDBURL.String())
-	assert.NoError(t, err, "error creating db connection")
+	dbconn := getEmptyTestDB(t)
 
-	store := driver.NewDirectoryDriver(dbconn)
+	store := newStore(dbconn)
 
 	srv := treemanager.NewServer(
 		tl,
@@ -419,3 +416,326 @@ func TestDeleteDirectory(t *testing.T) {
 
 	integration.DeleteDirectoryTest(t, cli)
 }
+
+func TestMoveDirectory(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.MoveDirectoryTest(t, cli)
+}
+
+func TestPurgeDirectory(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.PurgeDirectoryTest(t, cli)
+}
+
+func TestRestoreDirectory(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.RestoreDirectoryTest(t, cli)
+}
+
+func TestUpdateDirectoryRevision(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.UpdateDirectoryRevisionTest(t, cli)
+}
+
+func TestBatch(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.BatchTest(t, cli)
+}
+
+func TestUniqueSiblingNames(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.UniqueSiblingNamesTest(t, cli)
+}
+
+func TestMetadataSelector(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.MetadataSelectorTest(t, cli)
+}
+
+func TestChildrenDepth(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.ChildrenDepthTest(t, cli)
+}
+
+func TestSubtree(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.SubtreeTest(t, cli)
+}
+
+func TestBatchGetDirectories(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.BatchGetDirectoriesTest(t, cli)
+}
+
+func TestDirectoryHistory(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.DirectoryHistoryTest(t, cli)
+}
+
+func TestAsOf(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.AsOfTest(t, cli)
+}
+
+func TestQuota(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.QuotaTest(t, cli)
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.StatsTest(t, cli)
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.SearchTest(t, cli)
+}
+
+func TestPath(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.PathTest(t, cli)
+}
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+
+	skt := testutils.NewUnixsocketPath(t)
+	srv := newTestServer(t, skt)
+	defer func() {
+		err := srv.Shutdown()
+		assert.NoError(t, err, "error shutting down server")
+	}()
+
+	go testutils.RunTestServer(t, srv)
+
+	cli := testutils.NewTestClient(t, skt, baseServerAddress, nil)
+
+	testutils.WaitForServer(t, cli)
+
+	integration.SchemaTest(t, cli)
+}
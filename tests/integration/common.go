@@ -9,11 +9,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"net/http"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -313,3 +315,1432 @@ func DeleteDirectoryTest(t *testing.T, cli clientv1.HTTPRootClient) {
 	assert.Equal(t, 1, len(listResp.Directories), "unexpected number of children returned")
 	assert.Equal(t, ch2.Directory.Id, listResp.Directories[0], "unexpected child directory id")
 }
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func MoveDirectoryTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+	isRoot := true
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	ch1, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "child1",
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "error creating child1")
+
+	ch2, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "child2",
+	}, ch1.Directory.Id)
+	assert.NoError(t, err, "error creating child2")
+
+	// Move child2 directly under the root
+	mvResp, err := cli.MoveDirectory(ctx, ch2.Directory.Id, &apiv1.MoveDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Parent:  &rd.Directory.Id,
+	})
+	assert.NoError(t, err, "error moving directory")
+	assert.NotNil(t, mvResp, "response should not be nil")
+	assert.Equal(t, rd.Directory.Id, *mvResp.Directory.Parent, "unexpected parent")
+
+	children, err := cli.GetChildren(ctx, rd.Directory.Id)
+	assert.NoError(t, err, "error getting children")
+	assert.Len(t, children.Directories, 2, "expected 2 children") //nolint:gomnd // runs as part of tests
+
+	// Moving a directory under itself creates a cycle
+	_, err = cli.MoveDirectory(ctx, ch1.Directory.Id, &apiv1.MoveDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Parent:  &ch1.Directory.Id,
+	})
+	assert.Error(t, err, "should have errored moving directory under itself")
+
+	// Moving a directory under one of its descendants creates a cycle
+	_, err = cli.MoveDirectory(ctx, rd.Directory.Id, &apiv1.MoveDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Parent:  &ch1.Directory.Id,
+		Root:    &isRoot,
+	})
+	assert.Error(t, err, "should have errored moving root under its descendant")
+
+	// Moving a root requires explicit demotion
+	rd2, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root2",
+	})
+	assert.NoError(t, err, "error creating root2")
+
+	_, err = cli.MoveDirectory(ctx, rd2.Directory.Id, &apiv1.MoveDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Parent:  &rd.Directory.Id,
+	})
+	assert.Error(t, err, "should have errored moving root without demotion")
+
+	// Moving without a parent requires explicit promotion
+	_, err = cli.MoveDirectory(ctx, ch1.Directory.Id, &apiv1.MoveDirectoryRequest{
+		Version: apiv1.APIVersion,
+	})
+	assert.Error(t, err, "should have errored moving directory without parent")
+
+	// Moving under an unknown parent
+	unknown := apiv1.DirectoryID(uuid.New())
+	_, err = cli.MoveDirectory(ctx, ch1.Directory.Id, &apiv1.MoveDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Parent:  &unknown,
+	})
+	assert.Error(t, err, "should have errored moving directory under unknown parent")
+
+	// Demote root2 under the first root
+	dmResp, err := cli.MoveDirectory(ctx, rd2.Directory.Id, &apiv1.MoveDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Parent:  &rd.Directory.Id,
+		Root:    &isRoot,
+	})
+	assert.NoError(t, err, "error demoting root")
+	assert.False(t, dmResp.Directory.IsRoot(), "directory should no longer be a root")
+
+	// Promote child1 to root
+	prResp, err := cli.MoveDirectory(ctx, ch1.Directory.Id, &apiv1.MoveDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Root:    &isRoot,
+	})
+	assert.NoError(t, err, "error promoting directory")
+	assert.True(t, prResp.Directory.IsRoot(), "directory should be a root")
+
+	// Promoting a root again fails
+	_, err = cli.MoveDirectory(ctx, ch1.Directory.Id, &apiv1.MoveDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Root:    &isRoot,
+	})
+	assert.Error(t, err, "should have errored promoting a root")
+
+	// Moving via an invalid id
+	resp, err := cli.DoRaw(ctx, http.MethodPost, "/api/v1/directories/invalid/move", nil)
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "unexpected status code")
+	resp.Body.Close()
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func PurgeDirectoryTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	ch1, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "child1",
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "error creating child1")
+
+	ch2, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "child2",
+	}, ch1.Directory.Id)
+	assert.NoError(t, err, "error creating child2")
+
+	// Purging a directory which isn't deleted is refused
+	purgeResp, err := cli.PurgeDirectory(ctx, ch1.Directory.Id)
+	assert.Error(t, err, "should have errored purging a live directory")
+	assert.Nil(t, purgeResp, "response should be nil")
+
+	_, err = cli.DeleteDirectory(ctx, ch1.Directory.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	// Purge the deleted directory
+	purgeResp, err = cli.PurgeDirectory(ctx, ch1.Directory.Id)
+	assert.NoError(t, err, "error purging directory")
+	assert.NotNil(t, purgeResp, "response should not be nil")
+	assert.ElementsMatch(t, []apiv1.DirectoryID{ch1.Directory.Id, ch2.Directory.Id}, purgeResp.Directories,
+		"unexpected affected directories")
+
+	// Purged directories aren't fetchable, even when including deleted directories
+	getResp, err := cli.GetDirectory(ctx, ch2.Directory.Id, storage.WithDeletedDirectories)
+	assert.Error(t, err, "should have errored getting purged directory")
+	assert.Nil(t, getResp, "directory should be nil")
+
+	// Purging again fails
+	purgeResp, err = cli.PurgeDirectory(ctx, ch1.Directory.Id)
+	assert.Error(t, err, "should have errored purging a purged directory")
+	assert.Nil(t, purgeResp, "response should be nil")
+
+	// The root is left untouched
+	getResp, err = cli.GetDirectory(ctx, rd.Directory.Id)
+	assert.NoError(t, err, "error getting root")
+	assert.Equal(t, rd.Directory.Id, getResp.Directory.Id, "unexpected root")
+
+	// Purge with an invalid id
+	resp, err := cli.DoRaw(ctx, http.MethodPost, "/api/v1/directories/invalid/purge", nil)
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "unexpected status code")
+	resp.Body.Close()
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func RestoreDirectoryTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	ch1, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "child1",
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "error creating child1")
+
+	ch2, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "child2",
+	}, ch1.Directory.Id)
+	assert.NoError(t, err, "error creating child2")
+
+	ch3, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "child3",
+	}, ch1.Directory.Id)
+	assert.NoError(t, err, "error creating child3")
+
+	// Restoring a directory which isn't deleted is refused
+	restoreResp, err := cli.RestoreDirectory(ctx, ch1.Directory.Id)
+	assert.Error(t, err, "should have errored restoring a live directory")
+	assert.Nil(t, restoreResp, "response should be nil")
+
+	// child3 is deleted on its own before its parent
+	_, err = cli.DeleteDirectory(ctx, ch3.Directory.Id)
+	assert.NoError(t, err, "error deleting child3")
+
+	_, err = cli.DeleteDirectory(ctx, ch1.Directory.Id)
+	assert.NoError(t, err, "error deleting child1")
+
+	// Restoring a directory whose parent is deleted is refused
+	restoreResp, err = cli.RestoreDirectory(ctx, ch2.Directory.Id)
+	assert.Error(t, err, "should have errored restoring a directory with a deleted parent")
+	assert.Nil(t, restoreResp, "response should be nil")
+
+	// Restoring child1 only brings back the directories deleted with it
+	restoreResp, err = cli.RestoreDirectory(ctx, ch1.Directory.Id)
+	assert.NoError(t, err, "error restoring directory")
+	assert.NotNil(t, restoreResp, "response should not be nil")
+	assert.ElementsMatch(t, []apiv1.DirectoryID{ch1.Directory.Id, ch2.Directory.Id}, restoreResp.Directories,
+		"unexpected affected directories")
+
+	getResp, err := cli.GetDirectory(ctx, ch2.Directory.Id)
+	assert.NoError(t, err, "restored directory should be fetchable")
+	assert.Nil(t, getResp.Directory.DeletedAt, "restored directory should not be deleted")
+
+	getResp, err = cli.GetDirectory(ctx, ch3.Directory.Id)
+	assert.Error(t, err, "separately deleted directory should still be deleted")
+	assert.Nil(t, getResp, "directory should be nil")
+
+	// child3 can now be restored on its own
+	restoreResp, err = cli.RestoreDirectory(ctx, ch3.Directory.Id)
+	assert.NoError(t, err, "error restoring child3")
+	assert.Equal(t, []apiv1.DirectoryID{ch3.Directory.Id}, restoreResp.Directories, "unexpected affected directories")
+
+	// Restoring an unknown directory
+	restoreResp, err = cli.RestoreDirectory(ctx, apiv1.DirectoryID(uuid.New()))
+	assert.Error(t, err, "should have errored restoring an unknown directory")
+	assert.Nil(t, restoreResp, "response should be nil")
+
+	// Restore with an invalid id
+	resp, err := cli.DoRaw(ctx, http.MethodPost, "/api/v1/directories/invalid/restore", nil)
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "unexpected status code")
+	resp.Body.Close()
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func UpdateDirectoryRevisionTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	d, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "child",
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "error creating directory")
+	assert.Equal(t, int64(1), d.Directory.Revision, "new directories start at revision 1")
+
+	// Updating at the current revision succeeds and bumps the revision
+	updated, err := cli.UpdateDirectory(ctx, d.Directory.Id, &apiv1.UpdateDirectoryRequest{
+		Metadata: &apiv1.DirectoryMetadata{"owner": "first"},
+	}, &d.Directory.Revision)
+	assert.NoError(t, err, "error updating directory")
+	assert.Equal(t, int64(2), updated.Directory.Revision, "update should bump the revision")
+
+	// A concurrent update based on the old revision is refused
+	conflict, err := cli.UpdateDirectory(ctx, d.Directory.Id, &apiv1.UpdateDirectoryRequest{
+		Metadata: &apiv1.DirectoryMetadata{"owner": "second"},
+	}, &d.Directory.Revision)
+	assert.ErrorIs(t, err, storage.ErrRevisionConflict, "expected revision conflict")
+	assert.Nil(t, conflict, "response should be nil")
+
+	got, err := cli.GetDirectory(ctx, d.Directory.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, "first", (*got.Directory.Metadata)["owner"], "conflicting update should not be applied")
+
+	// Unconditional updates still go through
+	updated, err = cli.UpdateDirectory(ctx, d.Directory.Id, &apiv1.UpdateDirectoryRequest{
+		Metadata: &apiv1.DirectoryMetadata{"owner": "third"},
+	}, nil)
+	assert.NoError(t, err, "error updating directory")
+	assert.Equal(t, int64(3), updated.Directory.Revision, "update should bump the revision")
+
+	// The revision is returned as the ETag
+	resp, err := cli.DoRaw(ctx, http.MethodGet, "/api/v1/directories/"+d.Directory.Id.String(), nil)
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, `"3"`, resp.Header.Get("ETag"), "unexpected ETag")
+	resp.Body.Close()
+
+	// Moving bumps the revision
+	isRoot := true
+
+	moved, err := cli.MoveDirectory(ctx, d.Directory.Id, &apiv1.MoveDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Root:    &isRoot,
+	})
+	assert.NoError(t, err, "error promoting directory")
+	assert.Equal(t, int64(4), moved.Directory.Revision, "move should bump the revision")
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func BatchTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+
+	rootIdx := 0
+	rootName := "tenant"
+	appsName := "apps"
+	dataName := "data"
+
+	// Create a tenant skeleton in a single batch
+	resp, err := cli.Batch(ctx, &apiv1.BatchRequest{
+		Version: apiv1.APIVersion,
+		Operations: []apiv1.BatchOperation{
+			{Op: "create", Name: &rootName},
+			{Op: "create", Name: &appsName, ParentIndex: &rootIdx},
+			{Op: "create", Name: &dataName, ParentIndex: &rootIdx},
+		},
+	})
+	assert.NoError(t, err, "error applying batch")
+	assert.Len(t, resp.Results, 3, "expected a result per operation")
+
+	root := resp.Results[0].Directory
+	apps := resp.Results[1].Directory
+	data := resp.Results[2].Directory
+
+	assert.Nil(t, root.Parent, "first directory should be a root")
+	assert.Equal(t, root.Id, *apps.Parent, "apps should be created under the root")
+	assert.Equal(t, root.Id, *data.Parent, "data should be created under the root")
+
+	children, err := cli.GetChildren(ctx, root.Id)
+	assert.NoError(t, err, "error listing children")
+	assert.ElementsMatch(t, []apiv1.DirectoryID{apps.Id, data.Id}, children.Directories, "unexpected children")
+
+	// Update and delete in a single batch
+	newName := "applications"
+
+	resp, err = cli.Batch(ctx, &apiv1.BatchRequest{
+		Version: apiv1.APIVersion,
+		Operations: []apiv1.BatchOperation{
+			{Op: "update", Id: &apps.Id, Name: &newName, Revision: &apps.Revision},
+			{Op: "delete", Id: &data.Id},
+		},
+	})
+	assert.NoError(t, err, "error applying batch")
+	assert.Equal(t, newName, resp.Results[0].Directory.Name, "directory should be renamed")
+	assert.Equal(t, []apiv1.DirectoryID{data.Id}, *resp.Results[1].Affected, "unexpected deleted directories")
+
+	// A failing operation rolls back the whole batch
+	otherName := "other"
+
+	resp, err = cli.Batch(ctx, &apiv1.BatchRequest{
+		Version: apiv1.APIVersion,
+		Operations: []apiv1.BatchOperation{
+			{Op: "create", Name: &otherName, Parent: &root.Id},
+			{Op: "update", Id: &apps.Id, Name: &otherName, Revision: &apps.Revision},
+		},
+	})
+	assert.ErrorIs(t, err, storage.ErrRevisionConflict, "stale update should conflict")
+	assert.Nil(t, resp, "response should be nil")
+
+	children, err = cli.GetChildren(ctx, root.Id)
+	assert.NoError(t, err, "error listing children")
+	assert.Equal(t, []apiv1.DirectoryID{apps.Id}, children.Directories, "failed batch should not create directories")
+
+	got, err := cli.GetDirectory(ctx, apps.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, newName, got.Directory.Name, "failed batch should not update directories")
+
+	// Invalid batches are refused
+	for _, ops := range [][]apiv1.BatchOperation{
+		{},
+		{{Op: "unknown"}},
+		{{Op: "create"}},
+		{{Op: "create", Name: &otherName, ParentIndex: &rootIdx}},
+		{{Op: "update"}},
+		{{Op: "delete"}},
+	} {
+		resp, err = cli.Batch(ctx, &apiv1.BatchRequest{
+			Version:    apiv1.APIVersion,
+			Operations: ops,
+		})
+		assert.Error(t, err, "invalid batch should be refused")
+		assert.Nil(t, resp, "response should be nil")
+	}
+
+	// Unknown directories aren't found
+	unknown := apiv1.DirectoryID(uuid.New())
+
+	resp, err = cli.Batch(ctx, &apiv1.BatchRequest{
+		Version:    apiv1.APIVersion,
+		Operations: []apiv1.BatchOperation{{Op: "delete", Id: &unknown}},
+	})
+	assert.Error(t, err, "deleting an unknown directory should fail")
+	assert.Nil(t, resp, "response should be nil")
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func UniqueSiblingNamesTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+	unique := true
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version:            apiv1.APIVersion,
+		Name:               "unique-root",
+		UniqueSiblingNames: &unique,
+	})
+	assert.NoError(t, err, "error creating root")
+	assert.True(t, rd.Directory.UniqueSiblingNames, "root should enforce unique sibling names")
+
+	first, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "child",
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "error creating directory")
+	assert.True(t, first.Directory.UniqueSiblingNames, "children should inherit unique sibling names")
+
+	// A live sibling with the same name is refused
+	_, err = cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "child",
+	}, rd.Directory.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNameConflict, "expected name conflict")
+
+	var conflictErr *clientv1.DirectoryNameConflictError
+	assert.ErrorAs(t, err, &conflictErr, "expected a typed name conflict error")
+
+	// Renaming onto a sibling's name is refused and leaves the directory untouched
+	other, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "other",
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "error creating directory")
+
+	name := "child"
+	_, err = cli.UpdateDirectory(ctx, other.Directory.Id, &apiv1.UpdateDirectoryRequest{
+		Name: &name,
+	}, nil)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNameConflict, "expected name conflict")
+
+	got, err := cli.GetDirectory(ctx, other.Directory.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, "other", got.Directory.Name, "conflicting update should not be applied")
+
+	// Soft deleted directories don't take part in the rule
+	_, err = cli.DeleteDirectory(ctx, first.Directory.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	_, err = cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "child",
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "deleted siblings should not conflict")
+
+	// ... until they're restored
+	_, err = cli.RestoreDirectory(ctx, first.Directory.Id)
+	assert.ErrorIs(t, err, storage.ErrDirectoryNameConflict, "expected name conflict")
+
+	// Trees which didn't opt in allow duplicate names
+	plain, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "plain-root",
+	})
+	assert.NoError(t, err, "error creating root")
+	assert.False(t, plain.Directory.UniqueSiblingNames, "roots should not enforce unique sibling names by default")
+
+	var moved *apiv1.DirectoryFetch
+
+	for i := 0; i < 2; i++ {
+		moved, err = cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+			Version: apiv1.APIVersion,
+			Name:    "child",
+		}, plain.Directory.Id)
+		assert.NoError(t, err, "duplicate names should be allowed")
+	}
+
+	// Moving into a tree which enforces the rule checks the name
+	_, err = cli.MoveDirectory(ctx, moved.Directory.Id, &apiv1.MoveDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Parent:  &rd.Directory.Id,
+	})
+	assert.ErrorIs(t, err, storage.ErrDirectoryNameConflict, "expected name conflict")
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func MetadataSelectorTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+
+	prodRoot, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version:  apiv1.APIVersion,
+		Name:     "prod-root",
+		Metadata: &apiv1.DirectoryMetadata{"billing_account": "selector-123"},
+	})
+	assert.NoError(t, err, "error creating root")
+
+	_, err = cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version:  apiv1.APIVersion,
+		Name:     "other-root",
+		Metadata: &apiv1.DirectoryMetadata{"billing_account": "selector-456"},
+	})
+	assert.NoError(t, err, "error creating root")
+
+	sel, err := storage.ParseSelector("billing_account=selector-123")
+	assert.NoError(t, err, "error parsing selector")
+
+	roots, err := cli.ListRoots(ctx, storage.WithSelector(sel))
+	assert.NoError(t, err, "error listing roots")
+	assert.Equal(t, []apiv1.DirectoryID{prodRoot.Directory.Id}, roots.Directories, "unexpected roots")
+
+	envs := []string{"prod", "dev", "prod", "qa", ""}
+	expected := map[string][]apiv1.DirectoryID{}
+
+	for i, env := range envs {
+		md := apiv1.DirectoryMetadata{}
+		if env != "" {
+			md["env"] = env
+		}
+
+		d, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+			Version:  apiv1.APIVersion,
+			Name:     fmt.Sprintf("child-%d", i),
+			Metadata: &md,
+		}, prodRoot.Directory.Id)
+		assert.NoError(t, err, "error creating directory")
+
+		expected[env] = append(expected[env], d.Directory.Id)
+	}
+
+	withEnv := func(envs ...string) []apiv1.DirectoryID {
+		var ids []apiv1.DirectoryID
+
+		for _, env := range envs {
+			ids = append(ids, expected[env]...)
+		}
+
+		return ids
+	}
+
+	tests := []struct {
+		selector string
+		expected []apiv1.DirectoryID
+	}{
+		{selector: "env=prod", expected: withEnv("prod")},
+		{selector: "env in (dev,qa)", expected: withEnv("dev", "qa")},
+		{selector: "env", expected: withEnv("prod", "dev", "qa")},
+		{selector: "!env", expected: withEnv("")},
+		{selector: "env!=prod", expected: withEnv("dev", "qa", "")},
+		{selector: "env notin (prod,dev,qa)", expected: withEnv("")},
+	}
+
+	for _, tc := range tests {
+		sel, err := storage.ParseSelector(tc.selector)
+		assert.NoError(t, err, "error parsing selector")
+
+		children, err := cli.GetChildren(ctx, prodRoot.Directory.Id, storage.WithSelector(sel))
+		assert.NoError(t, err, "error listing children")
+		assert.ElementsMatch(t, tc.expected, children.Directories, "unexpected children for %q", tc.selector)
+	}
+
+	// The selector is kept across pages
+	sel, err = storage.ParseSelector("env=prod")
+	assert.NoError(t, err, "error parsing selector")
+
+	firstPage, err := cli.GetChildren(ctx, prodRoot.Directory.Id, storage.WithSelector(sel), storage.Pagination(1, 1))
+	assert.NoError(t, err, "error listing children")
+	assert.Len(t, firstPage.Directories, 1, "unexpected page size")
+	assert.NotNil(t, firstPage.Links.Next, "expected a next page")
+	assert.Contains(t, firstPage.Links.Next.HREF, "selector=", "next link should keep the selector")
+
+	// Following the next links only returns matching directories
+	all, err := cli.GetChildren(ctx, prodRoot.Directory.Id, storage.WithSelector(sel), storage.Pagination(0, 1))
+	assert.NoError(t, err, "error listing children")
+	assert.ElementsMatch(t, expected["prod"], all.Directories, "unexpected children")
+
+	// Invalid selectors are refused
+	resp, err := cli.DoRaw(ctx, http.MethodGet, "/api/v1/roots?selector=env%3D%28prod", nil)
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "invalid selectors should be refused")
+	resp.Body.Close()
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func ChildrenDepthTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	// Build a tree three levels deep, with two directories per level
+	levels := make([][]apiv1.DirectoryID, 3)
+	parents := []apiv1.DirectoryID{rd.Directory.Id}
+
+	for depth := range levels {
+		for _, parent := range parents {
+			for i := 0; i < 2; i++ {
+				d, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+					Version: apiv1.APIVersion,
+					Name:    fmt.Sprintf("dir-%d-%d", depth, i),
+				}, parent)
+				assert.NoError(t, err, "error creating directory")
+
+				levels[depth] = append(levels[depth], d.Directory.Id)
+			}
+		}
+
+		parents = levels[depth]
+	}
+
+	var expected []apiv1.DirectoryID
+
+	for depth, level := range levels {
+		expected = append(expected, level...)
+
+		children, err := cli.GetChildren(ctx, rd.Directory.Id, storage.WithMaxDepth(depth+1))
+		assert.NoError(t, err, "error listing children")
+		assert.ElementsMatch(t, expected, children.Directories, "unexpected children at depth %d", depth+1)
+	}
+
+	// No depth returns all descendants
+	children, err := cli.GetChildren(ctx, rd.Directory.Id)
+	assert.NoError(t, err, "error listing children")
+	assert.ElementsMatch(t, expected, children.Directories, "unexpected children")
+
+	// The depth is kept across pages
+	children, err = cli.GetChildren(ctx, rd.Directory.Id, storage.WithMaxDepth(1), storage.Pagination(0, 1))
+	assert.NoError(t, err, "error listing children")
+	assert.ElementsMatch(t, levels[0], children.Directories, "unexpected children")
+
+	// Negative depths are refused
+	resp, err := cli.DoRaw(ctx, http.MethodGet, "/api/v1/directories/"+rd.Directory.Id.String()+"/children?depth=-1", nil)
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "negative depths should be refused")
+	resp.Body.Close()
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func SubtreeTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	create := func(name string, parent apiv1.DirectoryID) apiv1.DirectoryID {
+		d, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+			Version:  apiv1.APIVersion,
+			Name:     name,
+			Metadata: &apiv1.DirectoryMetadata{"name": name},
+		}, parent)
+		assert.NoError(t, err, "error creating directory")
+
+		return d.Directory.Id
+	}
+
+	apps := create("apps", rd.Directory.Id)
+	web := create("web", apps)
+	create("db", apps)
+	create("static", web)
+	deleted := create("deleted", rd.Directory.Id)
+
+	_, err = cli.DeleteDirectory(ctx, deleted)
+	assert.NoError(t, err, "error deleting directory")
+
+	tree, err := cli.GetSubtree(ctx, rd.Directory.Id)
+	assert.NoError(t, err, "error getting tree")
+	assert.Equal(t, rd.Directory.Id, tree.Tree.Directory.Id, "unexpected tree root")
+	assert.Len(t, tree.Tree.Children, 1, "deleted directories should be left out")
+
+	appsTree := tree.Tree.Children[0]
+	assert.Equal(t, apps, appsTree.Directory.Id, "unexpected child")
+	assert.Equal(t, "apps", (*appsTree.Directory.Metadata)["name"], "full directories should be returned")
+	assert.Len(t, appsTree.Children, 2, "unexpected children")
+	assert.Equal(t, "web", appsTree.Children[0].Directory.Name, "children should be ordered by creation")
+	assert.Equal(t, "db", appsTree.Children[1].Directory.Name, "children should be ordered by creation")
+	assert.Len(t, appsTree.Children[0].Children, 1, "unexpected grandchildren")
+	assert.Equal(t, "static", appsTree.Children[0].Children[0].Directory.Name, "unexpected grandchild")
+
+	// Depth limits the levels returned
+	tree, err = cli.GetSubtree(ctx, rd.Directory.Id, storage.WithMaxDepth(2))
+	assert.NoError(t, err, "error getting tree")
+	assert.Len(t, tree.Tree.Children[0].Children, 2, "unexpected children")
+	assert.Empty(t, tree.Tree.Children[0].Children[0].Children, "directories below the depth should be left out")
+
+	// Deleted directories can be included
+	tree, err = cli.GetSubtree(ctx, rd.Directory.Id, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting tree")
+	assert.Len(t, tree.Tree.Children, 2, "deleted directories should be included")
+
+	// Trees with more directories than allowed are refused
+	_, err = cli.GetSubtree(ctx, rd.Directory.Id, storage.WithMaxNodes(4))
+	assert.ErrorIs(t, err, storage.ErrSubtreeTooLarge, "expected the tree to be too large")
+
+	_, err = cli.GetSubtree(ctx, rd.Directory.Id, storage.WithMaxNodes(5))
+	assert.NoError(t, err, "the tree should fit")
+
+	// Larger maximums are lowered to the server's
+	_, err = cli.GetSubtree(ctx, rd.Directory.Id, storage.WithMaxNodes(math.MaxInt32))
+	assert.NoError(t, err, "the tree should fit")
+
+	resp, err := cli.DoRaw(ctx, http.MethodGet, "/api/v1/directories/"+rd.Directory.Id.String()+"/tree?max_nodes=-1", nil)
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "maximums which aren't positive should be refused")
+	resp.Body.Close()
+
+	_, err = cli.GetSubtree(ctx, apiv1.DirectoryID(uuid.New()))
+	assert.Error(t, err, "unknown directories should not be found")
+	assert.NotErrorIs(t, err, storage.ErrSubtreeTooLarge, "unexpected error")
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func StatsTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	create := func(name string, parent apiv1.DirectoryID) apiv1.DirectoryID {
+		d, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+			Version: apiv1.APIVersion,
+			Name:    name,
+		}, parent)
+		assert.NoError(t, err, "error creating directory")
+
+		return d.Directory.Id
+	}
+
+	apps := create("apps", rd.Directory.Id)
+	web := create("web", apps)
+	create("db", apps)
+	create("static", web)
+	deleted := create("deleted", rd.Directory.Id)
+
+	_, err = cli.DeleteDirectory(ctx, deleted)
+	assert.NoError(t, err, "error deleting directory")
+
+	stats, err := cli.GetStats(ctx, rd.Directory.Id)
+	assert.NoError(t, err, "error getting stats")
+	assert.Equal(t, rd.Directory.Id, stats.Stats.Id, "unexpected directory")
+	assert.Equal(t, 1, stats.Stats.Children, "deleted children should not be counted")
+	assert.Equal(t, 5, stats.Stats.Descendants, "unexpected descendants")
+	assert.Equal(t, 4, stats.Stats.LiveDescendants, "unexpected live descendants")
+	assert.Equal(t, 1, stats.Stats.DeletedDescendants, "unexpected deleted descendants")
+	assert.Equal(t, 3, stats.Stats.MaxDepth, "unexpected depth")
+
+	stats, err = cli.GetStats(ctx, apps)
+	assert.NoError(t, err, "error getting stats")
+	assert.Equal(t, 2, stats.Stats.Children, "unexpected children")
+	assert.Equal(t, 2, stats.Stats.MaxDepth, "unexpected depth")
+
+	_, err = cli.GetStats(ctx, apiv1.DirectoryID(uuid.New()))
+	assert.Error(t, err, "unknown directories should not be found")
+
+	// Listings only return their total count when asked for it
+	children, err := cli.GetChildren(ctx, rd.Directory.Id, storage.Pagination(1, 2))
+	assert.NoError(t, err, "error getting children")
+	assert.Nil(t, children.Total, "total should not be returned")
+
+	children, err = cli.GetChildren(ctx, rd.Directory.Id, storage.Pagination(1, 2), storage.WithTotal)
+	assert.NoError(t, err, "error getting children")
+	assert.Len(t, children.Directories, 2, "unexpected page")
+
+	if assert.NotNil(t, children.Total, "total should be returned") {
+		assert.Equal(t, 4, *children.Total, "total should count all pages")
+	}
+
+	resp, err := cli.DoRaw(ctx, http.MethodGet, "/api/v1/directories/"+apps.String()+"/children?total=true&limit=1", nil)
+	if assert.NoError(t, err, "error listing children") {
+		defer resp.Body.Close()
+
+		assert.Equal(t, "3", resp.Header.Get("X-Total-Count"), "total should be returned in a header")
+	}
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func SearchTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	create := func(name string, parent apiv1.DirectoryID) apiv1.DirectoryID {
+		d, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+			Version: apiv1.APIVersion,
+			Name:    name,
+		}, parent)
+		assert.NoError(t, err, "error creating directory")
+
+		return d.Directory.Id
+	}
+
+	apps := create("apps", rd.Directory.Id)
+	payments := create("payments", apps)
+	paymentsAPI := create("payments-api", payments)
+	legacy := create("Legacy Payments", rd.Directory.Id)
+
+	results, err := cli.SearchDirectories(ctx, rd.Directory.Id,
+		storage.NameQuery{Name: "payments", Match: storage.MatchExact})
+	assert.NoError(t, err, "error searching directories")
+
+	if assert.Len(t, results.Results, 1, "unexpected results") {
+		assert.Equal(t, payments, results.Results[0].Directory.Id, "unexpected directory")
+		assert.Equal(t, "payments", results.Results[0].Directory.Name, "full directories should be returned")
+		assert.Equal(t, []apiv1.DirectoryID{apps, rd.Directory.Id}, results.Results[0].Parents, "unexpected parents")
+	}
+
+	resultIDs := func(results *apiv1.DirectorySearchResults) []apiv1.DirectoryID {
+		var ids []apiv1.DirectoryID
+
+		for _, r := range results.Results {
+			ids = append(ids, r.Directory.Id)
+		}
+
+		return ids
+	}
+
+	results, err = cli.SearchDirectories(ctx, rd.Directory.Id,
+		storage.NameQuery{Name: "pay", Match: storage.MatchPrefix})
+	assert.NoError(t, err, "error searching directories")
+	assert.Equal(t, []apiv1.DirectoryID{payments, paymentsAPI}, resultIDs(results), "unexpected prefix matches")
+
+	// Substring matches are the default, and all pages are returned
+	results, err = cli.SearchDirectories(ctx, rd.Directory.Id, storage.NameQuery{Name: "PAYMENTS"},
+		storage.Pagination(0, 1))
+	assert.NoError(t, err, "error searching directories")
+	assert.Equal(t, []apiv1.DirectoryID{payments, paymentsAPI, legacy}, resultIDs(results),
+		"unexpected substring matches")
+
+	results, err = cli.SearchDirectories(ctx, rd.Directory.Id, storage.NameQuery{Name: "PAYMENTS"},
+		storage.Pagination(1, 1))
+	assert.NoError(t, err, "error searching directories")
+	assert.Equal(t, []apiv1.DirectoryID{payments}, resultIDs(results), "only the requested page should be returned")
+
+	if assert.NotNil(t, results.Links.Next, "next page should be linked") {
+		assert.Contains(t, results.Links.Next.HREF, "q=PAYMENTS", "next page should keep the query")
+	}
+
+	_, err = cli.SearchDirectories(ctx, rd.Directory.Id, storage.NameQuery{})
+	assert.ErrorIs(t, err, storage.ErrInvalidSearch, "empty names should be refused")
+
+	_, err = cli.SearchDirectories(ctx, rd.Directory.Id, storage.NameQuery{Name: "pay", Match: "regexp"})
+	assert.ErrorIs(t, err, storage.ErrInvalidSearch, "unknown matches should be refused")
+
+	_, err = cli.SearchDirectories(ctx, apiv1.DirectoryID(uuid.New()), storage.NameQuery{Name: "pay"})
+	assert.Error(t, err, "unknown directories should not be found")
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func BatchGetDirectoriesTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	create := func(name string) apiv1.DirectoryID {
+		d, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+			Version:  apiv1.APIVersion,
+			Name:     name,
+			Metadata: &apiv1.DirectoryMetadata{"name": name},
+		}, rd.Directory.Id)
+		assert.NoError(t, err, "error creating directory")
+
+		return d.Directory.Id
+	}
+
+	first := create("first")
+	second := create("second")
+	deleted := create("deleted")
+	unknown := apiv1.DirectoryID(uuid.New())
+
+	_, err = cli.DeleteDirectory(ctx, deleted)
+	assert.NoError(t, err, "error deleting directory")
+
+	resp, err := cli.GetDirectories(ctx, []apiv1.DirectoryID{second, unknown, first, deleted, second})
+	assert.NoError(t, err, "error getting directories")
+	assert.Len(t, resp.Directories, 2, "unexpected directories")
+	assert.Equal(t, second, resp.Directories[0].Id, "directories should be in the requested order")
+	assert.Equal(t, first, resp.Directories[1].Id, "directories should be in the requested order")
+	assert.Equal(t, "first", (*resp.Directories[1].Metadata)["name"], "full directories should be returned")
+	assert.Equal(t, []apiv1.DirectoryID{unknown, deleted}, resp.Missing, "unexpected missing directories")
+
+	// Deleted directories can be included
+	resp, err = cli.GetDirectories(ctx, []apiv1.DirectoryID{deleted}, storage.WithDeletedDirectories)
+	assert.NoError(t, err, "error getting directories")
+	assert.Len(t, resp.Directories, 1, "deleted directories should be included")
+	assert.Empty(t, resp.Missing, "no directory should be missing")
+
+	// The count of ids is bounded
+	_, err = cli.GetDirectories(ctx, nil)
+	assert.Error(t, err, "requests without ids should be refused")
+
+	tooMany := make([]apiv1.DirectoryID, 101)
+	for i := range tooMany {
+		tooMany[i] = apiv1.DirectoryID(uuid.New())
+	}
+
+	_, err = cli.GetDirectories(ctx, tooMany)
+	assert.Error(t, err, "requests with too many ids should be refused")
+
+	// Other custom methods are unknown
+	raw, err := cli.DoRaw(ctx, http.MethodPost, "/api/v1/directories:unknown", nil)
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, http.StatusNotFound, raw.StatusCode, "unknown methods should not be found")
+	raw.Body.Close()
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func DirectoryHistoryTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	dest, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "dest",
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "error creating directory")
+
+	d, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version:  apiv1.APIVersion,
+		Name:     "child",
+		Metadata: &apiv1.DirectoryMetadata{"env": "dev"},
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "error creating directory")
+
+	id := d.Directory.Id
+
+	_, err = cli.UpdateDirectory(ctx, id, &apiv1.UpdateDirectoryRequest{
+		Version:  apiv1.APIVersion,
+		Metadata: &apiv1.DirectoryMetadata{"env": "prod"},
+	}, nil)
+	assert.NoError(t, err, "error updating directory")
+
+	_, err = cli.MoveDirectory(ctx, id, &apiv1.MoveDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Parent:  &dest.Directory.Id,
+	})
+	assert.NoError(t, err, "error moving directory")
+
+	_, err = cli.DeleteDirectory(ctx, id)
+	assert.NoError(t, err, "error deleting directory")
+
+	_, err = cli.RestoreDirectory(ctx, id)
+	assert.NoError(t, err, "error restoring directory")
+
+	history, err := cli.GetHistory(ctx, id)
+	assert.NoError(t, err, "error getting history")
+	assert.Len(t, history.Revisions, 5, "every change should be recorded")
+
+	expected := []apiv1.EventType{
+		apiv1.EventTypeRestore,
+		apiv1.EventTypeDelete,
+		apiv1.EventTypeMove,
+		apiv1.EventTypeUpdate,
+		apiv1.EventTypeCreate,
+	}
+
+	for i, r := range history.Revisions {
+		assert.Equal(t, expected[i], r.Operation, "revisions should be newest first")
+		assert.Equal(t, id, r.DirectoryId, "unexpected directory")
+		assert.Equal(t, int64(len(expected)-i), r.Revision, "unexpected revision")
+		assert.NotNil(t, r.After, "the directory after the change should be recorded")
+	}
+
+	created := history.Revisions[4]
+	assert.Nil(t, created.Before, "created directories have no previous state")
+	assert.Equal(t, "dev", (*created.After.Metadata)["env"], "unexpected metadata")
+
+	updated := history.Revisions[3]
+	assert.Equal(t, "dev", (*updated.Before.Metadata)["env"], "the previous metadata should be recorded")
+	assert.Equal(t, "prod", (*updated.After.Metadata)["env"], "the new metadata should be recorded")
+
+	moved := history.Revisions[2]
+	assert.Equal(t, rd.Directory.Id, *moved.Before.Parent, "the previous parent should be recorded")
+	assert.Equal(t, dest.Directory.Id, *moved.After.Parent, "the new parent should be recorded")
+
+	deleted := history.Revisions[1]
+	assert.Nil(t, deleted.Before.DeletedAt, "the directory wasn't deleted before")
+	assert.NotNil(t, deleted.After.DeletedAt, "the directory should be deleted after")
+
+	// Explicit pages are returned on their own
+	history, err = cli.GetHistory(ctx, id, storage.Pagination(1, 2))
+	assert.NoError(t, err, "error getting history")
+	assert.Len(t, history.Revisions, 2, "unexpected page size")
+	assert.NotNil(t, history.Links.Next, "expected a next page")
+
+	history, err = cli.GetHistory(ctx, id, storage.Pagination(3, 2))
+	assert.NoError(t, err, "error getting history")
+	assert.Len(t, history.Revisions, 1, "unexpected page size")
+	assert.Equal(t, apiv1.EventTypeCreate, history.Revisions[0].Operation, "unexpected revision")
+	assert.Nil(t, history.Links.Next, "expected no next page")
+
+	// Cursors are refused, as revisions are paginated by page
+	resp, err := cli.DoRaw(ctx, http.MethodGet, "/api/v1/directories/"+id.String()+"/history?cursor="+
+		storage.NewCursor(&d.Directory).String(), nil)
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "cursors should be refused")
+	resp.Body.Close()
+
+	// The history of the other directories is kept apart
+	history, err = cli.GetHistory(ctx, dest.Directory.Id)
+	assert.NoError(t, err, "error getting history")
+	assert.Len(t, history.Revisions, 1, "unexpected revisions")
+
+	// The history of purged directories is gone along with them
+	_, err = cli.DeleteDirectory(ctx, id)
+	assert.NoError(t, err, "error deleting directory")
+
+	_, err = cli.PurgeDirectory(ctx, id)
+	assert.NoError(t, err, "error purging directory")
+
+	_, err = cli.GetHistory(ctx, id)
+	assert.Error(t, err, "purged directories should not be found")
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func AsOfTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+
+	beforeRoot := time.Now()
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	d, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "original",
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "error creating directory")
+
+	sibling, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "sibling",
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "error creating directory")
+
+	time.Sleep(10 * time.Millisecond)
+	asOf := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	newName := "renamed"
+
+	_, err = cli.UpdateDirectory(ctx, d.Directory.Id, &apiv1.UpdateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    &newName,
+	}, nil)
+	assert.NoError(t, err, "error updating directory")
+
+	_, err = cli.DeleteDirectory(ctx, sibling.Directory.Id)
+	assert.NoError(t, err, "error deleting directory")
+
+	current, err := cli.GetDirectory(ctx, d.Directory.Id)
+	assert.NoError(t, err, "error getting directory")
+	assert.Equal(t, "renamed", current.Directory.Name, "the current directory should be renamed")
+
+	past, err := cli.GetDirectory(ctx, d.Directory.Id, storage.WithAsOf(asOf))
+	assert.NoError(t, err, "error getting directory as of a past time")
+	assert.Equal(t, "original", past.Directory.Name, "the directory should be returned as it was")
+
+	children, err := cli.GetChildren(ctx, rd.Directory.Id)
+	assert.NoError(t, err, "error getting children")
+	assert.Len(t, children.Directories, 1, "deleted children should not be listed")
+
+	children, err = cli.GetChildren(ctx, rd.Directory.Id, storage.WithAsOf(asOf))
+	assert.NoError(t, err, "error getting children as of a past time")
+	assert.Len(t, children.Directories, 2, "children deleted since should be listed")
+
+	history, err := cli.GetHistory(ctx, d.Directory.Id, storage.WithAsOf(asOf))
+	assert.NoError(t, err, "error getting history as of a past time")
+	assert.Len(t, history.Revisions, 1, "later revisions should not be returned")
+
+	_, err = cli.GetDirectory(ctx, rd.Directory.Id, storage.WithAsOf(beforeRoot))
+	assert.Error(t, err, "directories should not be found before they were created")
+
+	_, err = cli.GetDirectory(ctx, d.Directory.Id, storage.WithAsOf(time.Now().Add(time.Hour)))
+	assert.Error(t, err, "times in the future should be refused")
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func QuotaTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "quota-root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	// No limit applies by default.
+	rq, err := cli.GetQuota(ctx, rd.Directory.Id)
+	assert.NoError(t, err, "error getting quota")
+	assert.Equal(t, rd.Directory.Id, rq.Root, "unexpected root")
+	assert.Equal(t, apiv1.DirectoryQuota{}, rq.Quota, "quota should not be overridden")
+	assert.Equal(t, 0, *rq.Effective.MaxDepth, "depth should not be limited")
+
+	maxDepth, maxChildren := 1, 2
+
+	rq, err = cli.SetQuota(ctx, rd.Directory.Id, &apiv1.SetQuotaRequest{
+		Version:     apiv1.APIVersion,
+		MaxDepth:    &maxDepth,
+		MaxChildren: &maxChildren,
+	})
+	assert.NoError(t, err, "error setting quota")
+	assert.Equal(t, maxDepth, *rq.Quota.MaxDepth, "depth should be overridden")
+	assert.Nil(t, rq.Quota.MaxDescendants, "descendants should not be overridden")
+	assert.Equal(t, maxChildren, *rq.Effective.MaxChildren, "overridden limit should be in effect")
+	assert.Equal(t, 0, *rq.Effective.MaxDescendants, "descendants should not be limited")
+
+	children := make([]*apiv1.DirectoryFetch, maxChildren)
+
+	for i := range children {
+		children[i], err = cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+			Version: apiv1.APIVersion,
+			Name:    fmt.Sprintf("child%d", i),
+		}, rd.Directory.Id)
+		assert.NoError(t, err, "error creating directory")
+	}
+
+	_, err = cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "too-many",
+	}, rd.Directory.Id)
+	assert.ErrorIs(t, err, storage.ErrQuotaExceeded, "children past the limit should be refused")
+
+	var qerr *storage.QuotaExceededError
+	if assert.ErrorAs(t, err, &qerr, "error should tell the exceeded quota") {
+		assert.Equal(t, rd.Directory.Id, qerr.Root, "unexpected root")
+		assert.Equal(t, storage.QuotaMaxChildren, qerr.Quota, "unexpected quota")
+		assert.Equal(t, maxChildren, qerr.Limit, "unexpected limit")
+	}
+
+	_, err = cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "too-deep",
+	}, children[0].Directory.Id)
+	assert.ErrorIs(t, err, storage.ErrQuotaExceeded, "directories past the depth limit should be refused")
+
+	_, err = cli.MoveDirectory(ctx, children[1].Directory.Id, &apiv1.MoveDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Parent:  &children[0].Directory.Id,
+	})
+	assert.ErrorIs(t, err, storage.ErrQuotaExceeded, "moves past the depth limit should be refused")
+
+	// The quota is replaced as a whole.
+	rq, err = cli.SetQuota(ctx, rd.Directory.Id, &apiv1.SetQuotaRequest{Version: apiv1.APIVersion})
+	assert.NoError(t, err, "error clearing quota")
+	assert.Equal(t, apiv1.DirectoryQuota{}, rq.Quota, "quota should be cleared")
+
+	_, err = cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "deep",
+	}, children[0].Directory.Id)
+	assert.NoError(t, err, "error creating directory once the quota is cleared")
+
+	// Quotas only apply to roots.
+	_, err = cli.GetQuota(ctx, children[0].Directory.Id)
+	assert.Error(t, err, "quota of a non-root should not be read")
+
+	_, err = cli.GetQuota(ctx, apiv1.DirectoryID(uuid.New()))
+	assert.Error(t, err, "quota of an unknown directory should not be read")
+
+	negative := -1
+
+	_, err = cli.SetQuota(ctx, rd.Directory.Id, &apiv1.SetQuotaRequest{
+		Version:  apiv1.APIVersion,
+		MaxDepth: &negative,
+	})
+	assert.Error(t, err, "negative limits should be refused")
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func SchemaTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    "schema-root",
+	})
+	assert.NoError(t, err, "error creating root")
+
+	// No schema applies by default.
+	ds, err := cli.GetSchema(ctx, rd.Directory.Id)
+	assert.NoError(t, err, "error getting schema")
+	assert.Equal(t, rd.Directory.Id, ds.Directory, "unexpected directory")
+	assert.Nil(t, ds.Source, "no schema should apply")
+	assert.Nil(t, ds.Schema, "no schema should apply")
+
+	envSchema := map[string]interface{}{
+		"type":                 "object",
+		"required":             []interface{}{"environment"},
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"environment": map[string]interface{}{"type": "string", "enum": []interface{}{"prod", "dev"}},
+			"team":        map[string]interface{}{"type": "string"},
+		},
+	}
+
+	ds, err = cli.SetSchema(ctx, rd.Directory.Id, &apiv1.SetSchemaRequest{
+		Version: apiv1.APIVersion,
+		Schema:  envSchema,
+	})
+	assert.NoError(t, err, "error setting schema")
+	assert.Equal(t, rd.Directory.Id, *ds.Source, "schema should be set on the root")
+	assert.Equal(t, envSchema, *ds.Schema, "schema should be the one set")
+
+	child, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version:  apiv1.APIVersion,
+		Name:     "child",
+		Metadata: &apiv1.DirectoryMetadata{"environment": "prod"},
+	}, rd.Directory.Id)
+	assert.NoError(t, err, "error creating directory matching the schema")
+
+	_, err = cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+		Version:  apiv1.APIVersion,
+		Name:     "typo",
+		Metadata: &apiv1.DirectoryMetadata{"enviroment": "prod"},
+	}, rd.Directory.Id)
+	assert.ErrorIs(t, err, storage.ErrInvalidMetadata, "metadata not matching the schema should be refused")
+
+	var merr *storage.InvalidMetadataError
+	if assert.ErrorAs(t, err, &merr, "error should tell the invalid fields") {
+		assert.Equal(t, rd.Directory.Id, merr.Source, "unexpected schema source")
+
+		fields := make([]string, len(merr.Fields))
+		for i, f := range merr.Fields {
+			fields[i] = f.Field
+		}
+
+		assert.Equal(t, []string{"enviroment", "environment"}, fields, "unexpected invalid fields")
+	}
+
+	// The schema of the root applies to its whole tree.
+	ds, err = cli.GetSchema(ctx, child.Directory.Id)
+	assert.NoError(t, err, "error getting schema")
+	assert.Equal(t, rd.Directory.Id, *ds.Source, "schema should be inherited from the root")
+
+	_, err = cli.UpdateDirectory(ctx, child.Directory.Id, &apiv1.UpdateDirectoryRequest{
+		Version:  apiv1.APIVersion,
+		Metadata: &apiv1.DirectoryMetadata{"environment": "staging"},
+	}, nil)
+	assert.ErrorIs(t, err, storage.ErrInvalidMetadata, "updates not matching the schema should be refused")
+
+	name := "batched"
+
+	_, err = cli.Batch(ctx, &apiv1.BatchRequest{
+		Version: apiv1.APIVersion,
+		Operations: []apiv1.BatchOperation{
+			{Op: "create", Parent: &child.Directory.Id, Name: &name},
+		},
+	})
+	assert.ErrorIs(t, err, storage.ErrInvalidMetadata, "batches not matching the schema should be refused")
+
+	_, err = cli.SetSchema(ctx, child.Directory.Id, &apiv1.SetSchemaRequest{
+		Version: apiv1.APIVersion,
+		Schema:  map[string]interface{}{"type": "nope"},
+	})
+	assert.ErrorIs(t, err, storage.ErrInvalidSchema, "invalid schemas should be refused")
+
+	// Once deleted, no schema applies anymore.
+	ds, err = cli.DeleteSchema(ctx, rd.Directory.Id)
+	assert.NoError(t, err, "error deleting schema")
+	assert.Nil(t, ds.Source, "no schema should apply once deleted")
+
+	_, err = cli.DeleteSchema(ctx, rd.Directory.Id)
+	assert.ErrorIs(t, err, storage.ErrSchemaNotFound, "deleted schema should not be deleted again")
+
+	_, err = cli.UpdateDirectory(ctx, child.Directory.Id, &apiv1.UpdateDirectoryRequest{
+		Version:  apiv1.APIVersion,
+		Metadata: &apiv1.DirectoryMetadata{"environment": "staging"},
+	}, nil)
+	assert.NoError(t, err, "error updating directory once the schema is deleted")
+
+	_, err = cli.GetSchema(ctx, apiv1.DirectoryID(uuid.New()))
+	assert.Error(t, err, "schema of an unknown directory should not be read")
+}
+
+//nolint:thelper // In this case, we don't want to use t.Helper() because we want to see the line number of the caller.
+func PathTest(t *testing.T, cli clientv1.HTTPRootClient) {
+	ctx := context.Background()
+	rootName := "paths-" + uuid.NewString()
+
+	rd, err := cli.CreateRoot(ctx, &apiv1.CreateDirectoryRequest{
+		Version: apiv1.APIVersion,
+		Name:    rootName,
+	})
+	assert.NoError(t, err, "error creating root")
+
+	if assert.NotNil(t, rd.Path, "path should be returned") {
+		assert.Equal(t, "/"+rootName, *rd.Path, "unexpected root path")
+	}
+
+	create := func(name string, parent apiv1.DirectoryID) *apiv1.DirectoryFetch {
+		d, err := cli.CreateDirectory(ctx, &apiv1.CreateDirectoryRequest{
+			Version: apiv1.APIVersion,
+			Name:    name,
+		}, parent)
+		assert.NoError(t, err, "error creating directory")
+
+		return d
+	}
+
+	emea := create("emea", rd.Directory.Id)
+	payments := create("a/b payments", emea.Directory.Id)
+
+	// Slashes within names are escaped.
+	paymentsPath := "/" + rootName + "/emea/a%2Fb payments"
+
+	if assert.NotNil(t, payments.Path, "path should be returned") {
+		assert.Equal(t, paymentsPath, *payments.Path, "unexpected path")
+	}
+
+	fetch, err := cli.GetDirectory(ctx, payments.Directory.Id)
+	assert.NoError(t, err, "error getting directory")
+
+	if assert.NotNil(t, fetch.Path, "path should be returned") {
+		assert.Equal(t, paymentsPath, *fetch.Path, "unexpected path")
+	}
+
+	fetch, err = cli.ResolvePath(ctx, paymentsPath)
+	assert.NoError(t, err, "error resolving path")
+	assert.Equal(t, payments.Directory.Id, fetch.Directory.Id, "unexpected directory")
+
+	_, err = cli.ResolvePath(ctx, "/"+rootName+"/emea/unknown")
+	assert.Error(t, err, "unknown paths should not be resolved")
+
+	_, err = cli.ResolvePath(ctx, rootName)
+	assert.ErrorIs(t, err, apiv1.ErrParsingPath, "relative paths should be refused")
+
+	// Paths may be typed as is, and used in place of ids.
+	resp, err := cli.DoRaw(ctx, http.MethodGet, "/api/v1/paths/"+rootName+"/emea", nil)
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "unexpected status code")
+	resp.Body.Close()
+
+	emeaRef := url.PathEscape("/" + rootName + "/emea")
+
+	resp, err = cli.DoRaw(ctx, http.MethodGet, "/api/v1/directories/"+emeaRef+"/children", nil)
+	assert.NoError(t, err, "error sending request")
+
+	if assert.Equal(t, http.StatusOK, resp.StatusCode, "unexpected status code") {
+		var children apiv1.DirectoryList
+
+		assert.NoError(t, children.Parse(resp.Body), "error parsing children")
+		assert.Equal(t, []apiv1.DirectoryID{payments.Directory.Id}, children.Directories, "unexpected children")
+	}
+
+	resp.Body.Close()
+
+	// The next page links keep the path references escaped.
+	more := create("more", emea.Directory.Id)
+
+	resp, err = cli.DoRaw(ctx, http.MethodGet, "/api/v1/directories/"+emeaRef+"/children?limit=1", nil)
+	assert.NoError(t, err, "error sending request")
+
+	var page apiv1.DirectoryList
+
+	if assert.Equal(t, http.StatusOK, resp.StatusCode, "unexpected status code") {
+		assert.NoError(t, page.Parse(resp.Body), "error parsing children")
+	}
+
+	resp.Body.Close()
+
+	if assert.NotNil(t, page.Links.Next, "next page expected") {
+		assert.Contains(t, page.Links.Next.HREF, emeaRef, "next page should keep the path reference escaped")
+
+		resp, err = cli.DoRaw(ctx, http.MethodGet, page.Links.Next.HREF, nil)
+		assert.NoError(t, err, "error sending request")
+
+		if assert.Equal(t, http.StatusOK, resp.StatusCode, "next page should be found") {
+			var next apiv1.DirectoryList
+
+			assert.NoError(t, next.Parse(resp.Body), "error parsing children")
+			assert.ElementsMatch(t, []apiv1.DirectoryID{payments.Directory.Id, more.Directory.Id},
+				append(page.Directories, next.Directories...), "pages should list every child")
+		}
+
+		resp.Body.Close()
+	}
+
+	resp, err = cli.DoRaw(ctx, http.MethodPatch, "/api/v1/directories/"+url.PathEscape(paymentsPath),
+		strings.NewReader(`{"name": "payments"}`))
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "directories should be updated through their path")
+	resp.Body.Close()
+
+	resp, err = cli.DoRaw(ctx, http.MethodGet, "/api/v1/directories/"+url.PathEscape("/")+"/children", nil)
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "empty names should be refused")
+	resp.Body.Close()
+
+	// Siblings sharing a name make their path ambiguous.
+	create("emea", rd.Directory.Id)
+
+	_, err = cli.ResolvePath(ctx, "/"+rootName+"/emea")
+	assert.ErrorIs(t, err, storage.ErrAmbiguousPath, "path should be ambiguous")
+
+	resp, err = cli.DoRaw(ctx, http.MethodGet, "/api/v1/directories/"+emeaRef+"/children", nil)
+	assert.NoError(t, err, "error sending request")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "unexpected status code")
+	resp.Body.Close()
+
+	fetch, err = cli.ResolvePath(ctx, "/"+rootName+"/emea/payments")
+	assert.NoError(t, err, "paths going through one of the siblings should be resolved")
+	assert.Equal(t, payments.Directory.Id, fetch.Directory.Id, "unexpected directory")
+}
@@ -2,7 +2,11 @@ package integration_test
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"net/url"
+	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -19,14 +23,24 @@ import (
 	"github.com/infratographer/fertilesoil/internal/httpsrv/common"
 	"github.com/infratographer/fertilesoil/internal/httpsrv/treemanager"
 	"github.com/infratographer/fertilesoil/notifier"
+	"github.com/infratographer/fertilesoil/storage"
 	"github.com/infratographer/fertilesoil/storage/crdb/driver"
 	dbutils "github.com/infratographer/fertilesoil/storage/crdb/utils"
+	pgdriver "github.com/infratographer/fertilesoil/storage/postgres/driver"
+	pgutils "github.com/infratographer/fertilesoil/storage/postgres/utils"
+	"github.com/infratographer/fertilesoil/storage/quota"
+	sqlitedriver "github.com/infratographer/fertilesoil/storage/sqlite/driver"
+	sqliteutils "github.com/infratographer/fertilesoil/storage/sqlite/utils"
 )
 
 const (
 	srvhost             = "localhost"
 	debug               = true
 	defaultShutdownTime = 1 * time.Second
+
+	// storageDriverEnv selects the storage driver the tests run against.
+	// CockroachDB is used unless it is set to "postgres" or "sqlite".
+	storageDriverEnv = "FERTILESOIL_TEST_STORAGE_DRIVER"
 )
 
 var (
@@ -35,6 +49,9 @@ var (
 
 	// Goose is not thread-safe, so we need to lock it.
 	gooseDBMutex sync.Mutex
+
+	usePostgres = os.Getenv(storageDriverEnv) == "postgres"
+	useSQLite   = os.Getenv(storageDriverEnv) == "sqlite"
 )
 
 func mustParseURL(u string) *url.URL {
@@ -46,13 +63,70 @@ func mustParseURL(u string) *url.URL {
 }
 
 func TestMain(m *testing.M) {
-	var stop func()
-	baseDBURL, stop = dbutils.NewTestDBServerOrDie()
-	defer stop()
+	switch {
+	case useSQLite:
+		// SQLite databases are files, so there's no server to start.
+	case usePostgres:
+		dbURL, stop, err := pgutils.NewTestDBServer()
+		if err != nil {
+			panic(fmt.Sprintf("error creating test database server: %v", err))
+		}
+		defer stop()
+
+		baseDBURL = dbURL
+	default:
+		var stop func()
+		baseDBURL, stop = dbutils.NewTestDBServerOrDie()
+		defer stop()
+	}
 
 	m.Run()
 }
 
+// newStore returns a driver of the storage the tests run against.
+func newStore(db *sql.DB) storage.DirectoryAdmin {
+	switch {
+	case useSQLite:
+		return sqlitedriver.NewDirectoryDriver(db)
+	case usePostgres:
+		return pgdriver.NewDirectoryDriver(db)
+	}
+
+	return driver.NewDirectoryDriver(db)
+}
+
+// getNewTestDB returns a new migrated database of the storage the tests run against.
+func getNewTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	switch {
+	case useSQLite:
+		return sqliteutils.GetNewTestDB(t)
+	case usePostgres:
+		return pgutils.GetNewTestDB(t, baseDBURL)
+	}
+
+	return dbutils.GetNewTestDB(t, baseDBURL)
+}
+
+// getEmptyTestDB returns a connection to a database of the storage the tests
+// run against, which holds no tables.
+func getEmptyTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	if useSQLite {
+		dbconn, err := sql.Open("sqlite3", sqliteutils.DSN(filepath.Join(t.TempDir(), "empty.db")))
+		assert.NoError(t, err, "error creating db connection")
+
+		return dbconn
+	}
+
+	dbconn, err := sql.Open("postgres", baseDBURL.String())
+	assert.NoError(t, err, "error creating db connection")
+
+	return dbconn
+}
+
 func newTestServer(t *testing.T, skt string) *common.Server {
 	t.Helper()
 
@@ -67,11 +141,11 @@ func newTestServerWithNotifier(t *testing.T, skt string, notif notifier.Notifier
 
 	gooseDBMutex.Lock()
 
-	dbconn := dbutils.GetNewTestDB(t, baseDBURL)
+	dbconn := getNewTestDB(t)
 
 	gooseDBMutex.Unlock()
 
-	store := driver.NewDirectoryDriver(dbconn)
+	store := newStore(dbconn)
 
 	tm := treemanager.NewServer(
 		tl,
@@ -82,6 +156,7 @@ func newTestServerWithNotifier(t *testing.T, skt string, notif notifier.Notifier
 		treemanager.WithUnix(skt),
 		treemanager.WithNotifier(notif),
 		treemanager.WithStorageDriver(store),
+		treemanager.WithQuotaLimits(quota.Limits{}),
 	)
 
 	return tm
@@ -92,9 +167,22 @@ func setupAppStorage(t *testing.T) appv1.AppStorage {
 
 	gooseDBMutex.Lock()
 	defer gooseDBMutex.Unlock()
-	dbConn := dbutils.GetNewTestDBForApp(t, baseDBURL)
+	var (
+		dbConn  *sql.DB
+		dialect = "postgres"
+	)
+
+	switch {
+	case useSQLite:
+		dbConn = sqliteutils.GetNewTestDBForApp(t)
+		dialect = "sqlite3"
+	case usePostgres:
+		dbConn = pgutils.GetNewTestDBForApp(t, baseDBURL)
+	default:
+		dbConn = dbutils.GetNewTestDBForApp(t, baseDBURL)
+	}
 
-	err := appsqlmig.BootStrap("postgres", dbConn)
+	err := appsqlmig.BootStrap(dialect, dbConn)
 	assert.NoError(t, err, "error bootstrapping app storage")
 
 	return appv1sql.New(dbConn)